@@ -3,10 +3,10 @@ package main
 import (
 	"fmt"
 	"regexp"
-	"sort"
 	"strconv"
 	"strings"
-	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 type IssueLevel string
@@ -15,19 +15,28 @@ const (
 	LevelError   IssueLevel = "error"
 	LevelWarning IssueLevel = "warning"
 	LevelInfo    IssueLevel = "info"
+	LevelOff     IssueLevel = "off"
 )
 
 const rulesVersion = "v1.3"
 
 type AnalyzeOptions struct {
-	DisabledRules map[string]struct{}
+	DisabledRules     map[string]struct{}
+	SeverityOverrides map[string]IssueLevel
+	RuleParams        map[string]map[string]any
+	Changes           *lineChanges
+	Catalog           *schemaCatalog
+	Detection         *EngineDetection
 }
 
 type RuleDefinition struct {
-	Code        string     `json:"code"`
-	Level       IssueLevel `json:"level"`
-	Description string     `json:"description"`
-	Category    string     `json:"category"`
+	Code          string      `json:"code"`
+	Level         IssueLevel  `json:"level"`
+	Description   string      `json:"description"`
+	Category      string      `json:"category"`
+	Scope         RuleScope   `json:"scope"`
+	AlwaysEnabled bool        `json:"alwaysEnabled,omitempty"`
+	Params        []RuleParam `json:"params,omitempty"`
 }
 
 type Issue struct {
@@ -37,68 +46,150 @@ type Issue struct {
 	Message        string     `json:"message"`
 	Suggestion     string     `json:"suggestion"`
 	Statement      string     `json:"statement"`
+	StartLine      int        `json:"startLine,omitempty"`
+	StartColumn    int        `json:"startColumn,omitempty"`
+	EndLine        int        `json:"endLine,omitempty"`
+	EndColumn      int        `json:"endColumn,omitempty"`
 }
 
 type Summary struct {
-	StatementCount int `json:"statementCount"`
-	ErrorCount     int `json:"errorCount"`
-	WarningCount   int `json:"warningCount"`
-	InfoCount      int `json:"infoCount"`
+	StatementCount  int `json:"statementCount"`
+	ErrorCount      int `json:"errorCount"`
+	WarningCount    int `json:"warningCount"`
+	InfoCount       int `json:"infoCount"`
+	SuppressedCount int `json:"suppressedCount,omitempty"`
 }
 
 type CheckResponse struct {
-	RulesVersion string   `json:"rulesVersion"`
-	CheckedAt    string   `json:"checkedAt"`
-	Summary      Summary  `json:"summary"`
-	Issues       []Issue  `json:"issues"`
-	Advice       []string `json:"advice"`
+	RulesVersion string            `json:"rulesVersion"`
+	CheckedAt    string            `json:"checkedAt"`
+	Summary      Summary           `json:"summary"`
+	Issues       []Issue           `json:"issues"`
+	Suppressed   []SuppressedIssue `json:"suppressed,omitempty"`
+	Diff         *DiffScope        `json:"diff,omitempty"`
+	Advice       []string          `json:"advice"`
 }
 
 var (
-	reUpdateNoWhere      = regexp.MustCompile(`(?is)^\s*UPDATE\s+.+?\s+SET\s+.+$`)
-	reDeleteNoWhere      = regexp.MustCompile(`(?is)^\s*DELETE\s+FROM\s+.+$`)
-	reSelectStar         = regexp.MustCompile(`(?is)^\s*SELECT\s+\*\s+FROM\s+`)
-	reSelect             = regexp.MustCompile(`(?is)^\s*SELECT\s+`)
-	reLimit              = regexp.MustCompile(`(?is)\s+LIMIT\s+\d+`)
-	reDropObj            = regexp.MustCompile(`(?is)^\s*DROP\s+(TABLE|DATABASE|VIEW|INDEX)\b`)
-	reTruncate           = regexp.MustCompile(`(?is)^\s*TRUNCATE\s+TABLE\b`)
-	reAlterDropCol       = regexp.MustCompile(`(?is)^\s*ALTER\s+TABLE\s+.+\s+DROP\s+COLUMN\b`)
-	reSelectIntoOut      = regexp.MustCompile(`(?is)\bINTO\s+OUTFILE\b`)
-	reLikeLeadWild       = regexp.MustCompile(`(?is)LIKE\s+['"]%[^'"]*['"]`)
-	reOrderByRand        = regexp.MustCompile(`(?is)ORDER\s+BY\s+RAND\s*\(`)
-	reWhereOneEqOne      = regexp.MustCompile(`(?is)\bWHERE\s+1\s*=\s*1\b`)
-	reInsertNoCols       = regexp.MustCompile(`(?is)^\s*INSERT\s+INTO\s+[\w.]+\s+VALUES\s*\(`)
-	reCreateTable        = regexp.MustCompile(`(?is)^\s*CREATE\s+TABLE\s+`)
-	reCreateIfNE         = regexp.MustCompile(`(?is)^\s*CREATE\s+TABLE\s+IF\s+NOT\s+EXISTS\s+`)
-	reBeginTx            = regexp.MustCompile(`(?is)^\s*(BEGIN|START\s+TRANSACTION)\b`)
-	reCommitTx           = regexp.MustCompile(`(?is)^\s*COMMIT\b`)
-	reRiskWrite          = regexp.MustCompile(`(?is)^\s*(UPDATE|DELETE|INSERT|ALTER|DROP|TRUNCATE)\b`)
 	reRoutineDefinition  = regexp.MustCompile(`(?is)\bCREATE\s+(?:DEFINER\s*=\s*[^\s]+\s+)?(?:PROCEDURE|FUNCTION|TRIGGER|EVENT)\b`)
 	reStatementStart     = regexp.MustCompile(`(?im)^\s*(SELECT|INSERT|UPDATE|DELETE|CREATE|ALTER|DROP|TRUNCATE|WITH|CALL|REPLACE|MERGE|BEGIN|START\s+TRANSACTION|COMMIT|ROLLBACK)\b`)
 	reHardStatementStart = regexp.MustCompile(`(?im)^\s*(INSERT|UPDATE|DELETE|CREATE|ALTER|DROP|TRUNCATE|CALL|REPLACE|MERGE|BEGIN|START\s+TRANSACTION|COMMIT|ROLLBACK)\b`)
 )
 
 func BuiltInRules() []RuleDefinition {
-	return []RuleDefinition{
-		{Code: "empty_input", Level: LevelError, Category: "输入校验", Description: "输入为空"},
-		{Code: "too_many_statements", Level: LevelWarning, Category: "变更规模", Description: "语句数过多，建议拆批执行"},
-		{Code: "missing_statement_terminator", Level: LevelError, Category: "脚本语法", Description: "多条 SQL 场景疑似缺少结束符"},
-		{Code: "fullwidth_statement_terminator", Level: LevelError, Category: "脚本语法", Description: "检测到中文结束符（；）"},
-		{Code: "routine_definition_detected", Level: LevelInfo, Category: "脚本语法", Description: "检测到存储过程/函数/触发器定义，已按 DELIMITER 语法解析"},
-		{Code: "dangerous_drop", Level: LevelError, Category: "高危DDL", Description: "检测到 DROP 高危对象删除"},
-		{Code: "dangerous_truncate", Level: LevelError, Category: "高危DDL", Description: "检测到 TRUNCATE 全表清理"},
-		{Code: "alter_drop_column", Level: LevelWarning, Category: "DDL兼容", Description: "检测到 DROP COLUMN 结构破坏性变更"},
-		{Code: "update_without_where", Level: LevelError, Category: "DML安全", Description: "UPDATE 无 WHERE"},
-		{Code: "delete_without_where", Level: LevelError, Category: "DML安全", Description: "DELETE 无 WHERE"},
-		{Code: "where_1_eq_1", Level: LevelWarning, Category: "条件有效性", Description: "WHERE 1=1 可能掩盖条件缺失"},
-		{Code: "select_star", Level: LevelWarning, Category: "查询规范", Description: "SELECT * 可维护性与性能风险"},
-		{Code: "select_without_limit", Level: LevelInfo, Category: "查询规范", Description: "SELECT 未设置 LIMIT"},
-		{Code: "like_leading_wildcard", Level: LevelWarning, Category: "查询性能", Description: "LIKE 前导 % 可能导致索引失效"},
-		{Code: "order_by_rand", Level: LevelWarning, Category: "查询性能", Description: "ORDER BY RAND 大表开销高"},
-		{Code: "into_outfile", Level: LevelError, Category: "数据安全", Description: "INTO OUTFILE 存在数据外流风险"},
-		{Code: "insert_without_column_list", Level: LevelInfo, Category: "可维护性", Description: "INSERT 未显式列清单"},
-		{Code: "create_table_without_if_not_exists", Level: LevelInfo, Category: "幂等性", Description: "CREATE TABLE 未使用 IF NOT EXISTS"},
-		{Code: "risky_writes_without_transaction", Level: LevelWarning, Category: "事务一致性", Description: "多条写语句未显式事务包裹"},
+	return ruleDefinitions(RegisteredRules(EngineMySQL))
+}
+
+func mysqlRules() []Rule {
+	return []Rule{
+		emptyInputRule("SQL 内容为空", "请上传 SQL 文件或粘贴 SQL 语句后再检查"),
+		tooManyStatementsRule(60, "建议按业务模块拆分后分批审核，降低误判并方便回滚"),
+		sqlMissingTerminatorRule(),
+		sqlFullwidthTerminatorRule(),
+		newScriptRule(
+			RuleDefinition{Code: "routine_definition_detected", Level: LevelInfo, Category: "脚本语法", Description: "检测到存储过程/函数/触发器定义，已按 DELIMITER 语法解析"},
+			func(ctx *RuleContext) []Issue {
+				if !ctx.ContainsRoutine {
+					return nil
+				}
+				return []Issue{{Message: "检测到存储过程/函数/触发器定义", Suggestion: "已按 DELIMITER 语法解析，请重点关注过程体中的写操作与权限控制"}}
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "dangerous_drop", Level: LevelError, Category: "高危DDL", Description: "检测到 DROP 高危对象删除"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				return Issue{Message: "检测到 DROP 高风险语句", Suggestion: "生产建议禁用 DROP；确需执行请先做完整备份并审批"}, stmt.SQL.isDangerousDrop()
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "dangerous_truncate", Level: LevelError, Category: "高危DDL", Description: "检测到 TRUNCATE 全表清理"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				return Issue{Message: "检测到 TRUNCATE 语句", Suggestion: "TRUNCATE 回滚代价高，请确认窗口期与恢复方案"}, stmt.SQL.Kind == sqlStmtTruncate
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "alter_drop_column", Level: LevelWarning, Category: "DDL兼容", Description: "检测到 DROP COLUMN 结构破坏性变更"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				token, found := stmt.SQL.findAlterDropColumn()
+				return ctx.Source.locate(Issue{Message: "检测到 ALTER TABLE DROP COLUMN", Suggestion: "请确认上下游代码兼容，并提前完成历史数据归档"}, token.span()), found
+			},
+		),
+		alterTableCopyRule(),
+		alterTableRebuildRule(),
+		alterWithoutAlgorithmLockRule(),
+		alterTableNotMergedRule(),
+		newStatementRule(
+			RuleDefinition{Code: "update_without_where", Level: LevelError, Category: "DML安全", Description: "UPDATE 无 WHERE"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				return Issue{Message: "UPDATE 缺少 WHERE 条件", Suggestion: "请添加精确 WHERE 条件，避免全表更新"}, stmt.SQL.Kind == sqlStmtUpdate && stmt.SQL.Where == nil
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "delete_without_where", Level: LevelError, Category: "DML安全", Description: "DELETE 无 WHERE"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				return Issue{Message: "DELETE 缺少 WHERE 条件", Suggestion: "请添加 WHERE 条件，或改为分批删除并保留回滚点"}, stmt.SQL.Kind == sqlStmtDelete && stmt.SQL.Where == nil
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "where_1_eq_1", Level: LevelWarning, Category: "条件有效性", Description: "WHERE 1=1 可能掩盖条件缺失"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				token, found := stmt.SQL.findWhereOneEqOne()
+				return ctx.Source.locate(Issue{Message: "检测到 WHERE 1=1，可能导致条件失效", Suggestion: "请核查动态 SQL 拼接逻辑，避免误更新/误删除"}, token.span()), found
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "select_star", Level: LevelWarning, Category: "查询规范", Description: "SELECT * 可维护性与性能风险"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				token, found := stmt.SQL.findSelectStar()
+				return ctx.Source.locate(Issue{Message: "SELECT * 可能带来性能和兼容风险", Suggestion: "建议显式列出字段，减少 I/O 并降低结构变更影响"}, token.span()), found
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "select_without_limit", Level: LevelInfo, Category: "查询规范", Description: "SELECT 未设置 LIMIT"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				return Issue{Message: "SELECT 未检测到 LIMIT", Suggestion: "在线查询建议补充 LIMIT，避免大结果集拖慢库实例"}, stmt.SQL.Kind == sqlStmtSelect && stmt.SQL.From != nil && !stmt.SQL.hasLimit()
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "like_leading_wildcard", Level: LevelWarning, Category: "查询性能", Description: "LIKE 前导 % 可能导致索引失效"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				token, found := stmt.SQL.findLeadingWildcardLike("LIKE")
+				return ctx.Source.locate(Issue{Message: "LIKE 前导通配符可能导致索引失效", Suggestion: "可考虑全文检索、倒排索引或改写匹配策略"}, token.span()), found
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "order_by_rand", Level: LevelWarning, Category: "查询性能", Description: "ORDER BY RAND 大表开销高"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				token, found := stmt.SQL.findOrderByRand()
+				return ctx.Source.locate(Issue{Message: "ORDER BY RAND() 在大表上性能差", Suggestion: "建议改用随机主键范围抽样或预生成随机池"}, token.span()), found
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "into_outfile", Level: LevelError, Category: "数据安全", Description: "INTO OUTFILE 存在数据外流风险"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				token, found := stmt.SQL.findTokenSequence("INTO", "OUTFILE")
+				return ctx.Source.locate(Issue{Message: "检测到 INTO OUTFILE，存在数据外流风险", Suggestion: "请确认导出合规性、审计记录及数据库账号最小权限"}, token.span()), found
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "insert_without_column_list", Level: LevelInfo, Category: "可维护性", Description: "INSERT 未显式列清单"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				return Issue{Message: "INSERT 未显式字段列表", Suggestion: "建议 INSERT INTO t(col1,col2...) VALUES(...)，提高可维护性"}, stmt.SQL.Kind == sqlStmtInsert && len(stmt.SQL.Tables) > 0 && !stmt.SQL.HasColumnList
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "create_table_without_if_not_exists", Level: LevelInfo, Category: "幂等性", Description: "CREATE TABLE 未使用 IF NOT EXISTS"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				return Issue{Message: "CREATE TABLE 未使用 IF NOT EXISTS", Suggestion: "建议补充 IF NOT EXISTS，提升脚本重放幂等性"}, stmt.SQL.Kind == sqlStmtCreateTable && !stmt.SQL.IfNotExists
+			},
+		),
+		unknownTableRule("unknown_table"),
+		unknownColumnRule("unknown_column"),
+		writePredicateNotIndexedRule("write_predicate_not_indexed"),
+		indexAdvisorRule("index_advice"),
+		riskyWritesWithoutTransactionRule("建议用 BEGIN/COMMIT 包裹，保证批量变更一致性"),
+		engineDetectionAmbiguousRule(),
+		invalidSuppressionRule(),
 	}
 }
 
@@ -107,188 +198,13 @@ func AnalyzeSQL(content string) CheckResponse {
 }
 
 func AnalyzeSQLWithOptions(content string, options AnalyzeOptions) CheckResponse {
-	result := CheckResponse{
-		RulesVersion: rulesVersion,
-		CheckedAt:    time.Now().Format(time.RFC3339),
-		Advice:       make([]string, 0),
-	}
-
-	ruleEnabled := func(rule string) bool {
-		if options.DisabledRules == nil {
-			return true
-		}
-		_, found := options.DisabledRules[rule]
-		return !found
-	}
-
-	trimmed := strings.TrimSpace(content)
-	if trimmed == "" {
-		result.Summary = Summary{StatementCount: 0, ErrorCount: 1, WarningCount: 0, InfoCount: 0}
-		if ruleEnabled("empty_input") {
-			result.Issues = []Issue{{
-				StatementIndex: 0,
-				Level:          LevelError,
-				Rule:           "empty_input",
-				Message:        "SQL 内容为空",
-				Suggestion:     "请上传 SQL 文件或粘贴 SQL 语句后再检查",
-				Statement:      "",
-			}}
-		} else {
-			result.Issues = []Issue{}
-		}
-		result.Advice = append(result.Advice, "请输入待审核 SQL 后重试")
-		return result
-	}
-
-	statements := splitSQLStatements(content)
-	issues := make([]Issue, 0)
-	addIssue := func(issue Issue) {
-		if ruleEnabled(issue.Rule) {
-			issues = append(issues, issue)
-		}
-	}
-
-	containsRoutine := reRoutineDefinition.MatchString(content)
-	if containsRoutine {
-		addIssue(Issue{
-			StatementIndex: 0,
-			Level:          LevelInfo,
-			Rule:           "routine_definition_detected",
-			Message:        "检测到存储过程/函数/触发器定义",
-			Suggestion:     "已按 DELIMITER 语法解析，请重点关注过程体中的写操作与权限控制",
-			Statement:      "",
-		})
-	}
-
-	fullwidthTerminatorStatements := detectFullwidthTerminatorStatements(content, containsRoutine)
-	if len(fullwidthTerminatorStatements) > 0 {
-		addIssue(Issue{
-			StatementIndex: fullwidthTerminatorStatements[0].Index,
-			Level:          LevelError,
-			Rule:           "fullwidth_statement_terminator",
-			Message:        buildFullwidthTerminatorIssueMessage(fullwidthTerminatorStatements),
-			Suggestion:     "请将中文结束符（；）替换为英文半角分号（;），避免解析歧义",
-			Statement:      buildMissingTerminatorStatementSnippet(fullwidthTerminatorStatements),
-		})
-	}
-
-	missingTerminatorStatements := detectMissingTerminatorStatements(content, statements, containsRoutine)
-	missingTerminatorStatements = excludeMissingTerminatorStatements(missingTerminatorStatements, fullwidthTerminatorStatements)
-	if len(missingTerminatorStatements) > 0 {
-		addIssue(Issue{
-			StatementIndex: missingTerminatorStatements[0].Index,
-			Level:          LevelError,
-			Rule:           "missing_statement_terminator",
-			Message:        buildMissingTerminatorIssueMessage(missingTerminatorStatements),
-			Suggestion:     "建议为每条语句补齐结束符，避免自动审查/执行阶段误拆分",
-			Statement:      buildMissingTerminatorStatementSnippet(missingTerminatorStatements),
-		})
-	}
-
-	containsRiskWrite := false
-	hasBegin := false
-	hasCommit := false
-
-	if len(statements) > 60 {
-		addIssue(Issue{
-			StatementIndex: 0,
-			Level:          LevelWarning,
-			Rule:           "too_many_statements",
-			Message:        fmt.Sprintf("SQL 语句数量较多（%d 条）", len(statements)),
-			Suggestion:     "建议按业务模块拆分后分批审核，降低误判并方便回滚",
-			Statement:      "",
-		})
-	}
-
-	for i, st := range statements {
-		stmt := strings.TrimSpace(st)
-		if stmt == "" {
-			continue
-		}
-		upper := strings.ToUpper(stmt)
-
-		if reRiskWrite.MatchString(upper) {
-			containsRiskWrite = true
-		}
-		if reBeginTx.MatchString(upper) {
-			hasBegin = true
-		}
-		if reCommitTx.MatchString(upper) {
-			hasCommit = true
-		}
-
-		if reDropObj.MatchString(upper) {
-			addIssue(Issue{StatementIndex: i + 1, Level: LevelError, Rule: "dangerous_drop", Message: "检测到 DROP 高风险语句", Suggestion: "生产建议禁用 DROP；确需执行请先做完整备份并审批", Statement: stmt})
-		}
-		if reTruncate.MatchString(upper) {
-			addIssue(Issue{StatementIndex: i + 1, Level: LevelError, Rule: "dangerous_truncate", Message: "检测到 TRUNCATE 语句", Suggestion: "TRUNCATE 回滚代价高，请确认窗口期与恢复方案", Statement: stmt})
-		}
-		if reAlterDropCol.MatchString(upper) {
-			addIssue(Issue{StatementIndex: i + 1, Level: LevelWarning, Rule: "alter_drop_column", Message: "检测到 ALTER TABLE DROP COLUMN", Suggestion: "请确认上下游代码兼容，并提前完成历史数据归档", Statement: stmt})
-		}
-		if reUpdateNoWhere.MatchString(upper) && !strings.Contains(upper, " WHERE ") {
-			addIssue(Issue{StatementIndex: i + 1, Level: LevelError, Rule: "update_without_where", Message: "UPDATE 缺少 WHERE 条件", Suggestion: "请添加精确 WHERE 条件，避免全表更新", Statement: stmt})
-		}
-		if reDeleteNoWhere.MatchString(upper) && !strings.Contains(upper, " WHERE ") {
-			addIssue(Issue{StatementIndex: i + 1, Level: LevelError, Rule: "delete_without_where", Message: "DELETE 缺少 WHERE 条件", Suggestion: "请添加 WHERE 条件，或改为分批删除并保留回滚点", Statement: stmt})
-		}
-		if reWhereOneEqOne.MatchString(upper) {
-			addIssue(Issue{StatementIndex: i + 1, Level: LevelWarning, Rule: "where_1_eq_1", Message: "检测到 WHERE 1=1，可能导致条件失效", Suggestion: "请核查动态 SQL 拼接逻辑，避免误更新/误删除", Statement: stmt})
-		}
-		if reSelectStar.MatchString(upper) {
-			addIssue(Issue{StatementIndex: i + 1, Level: LevelWarning, Rule: "select_star", Message: "SELECT * 可能带来性能和兼容风险", Suggestion: "建议显式列出字段，减少 I/O 并降低结构变更影响", Statement: stmt})
-		}
-		if reSelect.MatchString(upper) && !reLimit.MatchString(upper) {
-			addIssue(Issue{StatementIndex: i + 1, Level: LevelInfo, Rule: "select_without_limit", Message: "SELECT 未检测到 LIMIT", Suggestion: "在线查询建议补充 LIMIT，避免大结果集拖慢库实例", Statement: stmt})
-		}
-		if reLikeLeadWild.MatchString(stmt) {
-			addIssue(Issue{StatementIndex: i + 1, Level: LevelWarning, Rule: "like_leading_wildcard", Message: "LIKE 前导通配符可能导致索引失效", Suggestion: "可考虑全文检索、倒排索引或改写匹配策略", Statement: stmt})
-		}
-		if reOrderByRand.MatchString(upper) {
-			addIssue(Issue{StatementIndex: i + 1, Level: LevelWarning, Rule: "order_by_rand", Message: "ORDER BY RAND() 在大表上性能差", Suggestion: "建议改用随机主键范围抽样或预生成随机池", Statement: stmt})
-		}
-		if reSelectIntoOut.MatchString(upper) {
-			addIssue(Issue{StatementIndex: i + 1, Level: LevelError, Rule: "into_outfile", Message: "检测到 INTO OUTFILE，存在数据外流风险", Suggestion: "请确认导出合规性、审计记录及数据库账号最小权限", Statement: stmt})
-		}
-		if reInsertNoCols.MatchString(upper) {
-			addIssue(Issue{StatementIndex: i + 1, Level: LevelInfo, Rule: "insert_without_column_list", Message: "INSERT 未显式字段列表", Suggestion: "建议 INSERT INTO t(col1,col2...) VALUES(...)，提高可维护性", Statement: stmt})
-		}
-		if reCreateTable.MatchString(upper) && !reCreateIfNE.MatchString(upper) {
-			addIssue(Issue{StatementIndex: i + 1, Level: LevelInfo, Rule: "create_table_without_if_not_exists", Message: "CREATE TABLE 未使用 IF NOT EXISTS", Suggestion: "建议补充 IF NOT EXISTS，提升脚本重放幂等性", Statement: stmt})
-		}
-	}
-
-	if containsRiskWrite && len(statements) > 1 && (!hasBegin || !hasCommit) {
-		addIssue(Issue{StatementIndex: 0, Level: LevelWarning, Rule: "risky_writes_without_transaction", Message: "检测到多条写语句但未发现完整事务边界", Suggestion: "建议用 BEGIN/COMMIT 包裹，保证批量变更一致性", Statement: ""})
-	}
+	ctx := newSQLRuleContext(EngineMySQL, content, mysqlDialect)
+	ctx.ContainsRoutine = reRoutineDefinition.MatchString(content)
 
-	sort.SliceStable(issues, func(i, j int) bool {
-		if issues[i].StatementIndex == issues[j].StatementIndex {
-			return severityWeight(issues[i].Level) > severityWeight(issues[j].Level)
-		}
-		return issues[i].StatementIndex < issues[j].StatementIndex
-	})
-
-	summary := Summary{StatementCount: len(statements)}
-	for _, issue := range issues {
-		switch issue.Level {
-		case LevelError:
-			summary.ErrorCount++
-		case LevelWarning:
-			summary.WarningCount++
-		case LevelInfo:
-			summary.InfoCount++
-		}
-	}
-
-	advice := buildAdvice(summary)
-	if containsRoutine {
-		advice = append(advice, "检测到存储过程/函数定义，建议补充过程权限控制、异常处理与审计日志检查")
+	result := analyzeWithRules(ctx, options, "请输入待审核 SQL 后重试")
+	if ctx.ContainsRoutine {
+		result.Advice = append(result.Advice, "检测到存储过程/函数定义，建议补充过程权限控制、异常处理与审计日志检查")
 	}
-
-	result.Summary = summary
-	result.Issues = issues
-	result.Advice = advice
 	return result
 }
 
@@ -306,15 +222,17 @@ func severityWeight(level IssueLevel) int {
 type missingTerminatorStatement struct {
 	Index     int
 	Statement string
+	Location  sourceLocation
 }
 
 type sqlHeuristicStatement struct {
 	Text                string
 	Terminated          bool
 	FullwidthTerminator bool
+	Location            sourceLocation
 }
 
-func detectMissingTerminatorStatements(content string, statements []string, containsRoutine bool) []missingTerminatorStatement {
+func detectMissingTerminatorStatements(content string, spans []sqlStatementSpan, containsRoutine bool) []missingTerminatorStatement {
 	if containsRoutine {
 		return nil
 	}
@@ -328,6 +246,17 @@ func detectMissingTerminatorStatements(content string, statements []string, cont
 		return nil
 	}
 
+	source := newSourceIndex(content)
+	statements := make([]string, 0, len(spans))
+	batches := make([]sourceLocation, 0)
+	for _, span := range spans {
+		if span.Batch {
+			batches = append(batches, source.location(span.Span))
+			continue
+		}
+		statements = append(statements, span.Text)
+	}
+
 	if hasLikelyMergedStatements(statements) {
 		detailed := splitSQLByLineStartHeuristicDetailed(content)
 		if len(detailed) <= 1 {
@@ -336,29 +265,40 @@ func detectMissingTerminatorStatements(content string, statements []string, cont
 
 		missing := make([]missingTerminatorStatement, 0)
 		for idx, statement := range detailed {
-			if statement.Terminated {
+			if statement.Terminated || withinBatchLines(statement.Location, batches) {
 				continue
 			}
 			normalizedStatement := normalizeStatementWithTerminator(statement.Text)
 			if normalizedStatement == "" {
 				continue
 			}
-			missing = append(missing, missingTerminatorStatement{Index: idx + 1, Statement: normalizedStatement})
+			missing = append(missing, missingTerminatorStatement{Index: idx + 1, Statement: normalizedStatement, Location: statement.Location})
 		}
 		return missing
 	}
 
-	if len(statements) >= 1 && !hasStatementTerminatorSuffix(normalized) {
-		last := normalizeStatementWithTerminator(statements[len(statements)-1])
+	if len(spans) >= 1 && !hasStatementTerminatorSuffix(normalized) {
+		lastSpan := spans[len(spans)-1]
+		last := normalizeStatementWithTerminator(lastSpan.Text)
 		if last == "" {
 			return nil
 		}
-		return []missingTerminatorStatement{{Index: len(statements), Statement: last}}
+		location := source.location(lastSpan.Span)
+		return []missingTerminatorStatement{{Index: len(spans), Statement: last, Location: location}}
 	}
 
 	return nil
 }
 
+func withinBatchLines(location sourceLocation, batches []sourceLocation) bool {
+	for _, batch := range batches {
+		if location.StartLine >= batch.StartLine && location.EndLine <= batch.EndLine {
+			return true
+		}
+	}
+	return false
+}
+
 func buildMissingTerminatorIssueMessage(items []missingTerminatorStatement) string {
 	return buildMissingTerminatorIssueMessageWithSubject(items, "SQL")
 }
@@ -418,7 +358,7 @@ func detectFullwidthTerminatorStatements(content string, containsRoutine bool) [
 		if normalizedStatement == "" {
 			continue
 		}
-		items = append(items, missingTerminatorStatement{Index: idx + 1, Statement: normalizedStatement})
+		items = append(items, missingTerminatorStatement{Index: idx + 1, Statement: normalizedStatement, Location: statement.Location})
 	}
 	return items
 }
@@ -467,6 +407,7 @@ func splitSQLByLineStartHeuristicDetailed(content string) []sqlHeuristicStatemen
 	lines := strings.Split(normalized, "\n")
 	statements := make([]sqlHeuristicStatement, 0, len(lines))
 	var builder strings.Builder
+	location := sourceLocation{}
 
 	flush := func(terminated bool, fullwidthTerminator bool) {
 		statement := strings.TrimSpace(builder.String())
@@ -474,10 +415,10 @@ func splitSQLByLineStartHeuristicDetailed(content string) []sqlHeuristicStatemen
 		if statement == "" {
 			return
 		}
-		statements = append(statements, sqlHeuristicStatement{Text: statement, Terminated: terminated, FullwidthTerminator: fullwidthTerminator})
+		statements = append(statements, sqlHeuristicStatement{Text: statement, Terminated: terminated, FullwidthTerminator: fullwidthTerminator, Location: location})
 	}
 
-	for _, rawLine := range lines {
+	for lineIndex, rawLine := range lines {
 		line := strings.TrimSpace(rawLine)
 		if line == "" {
 			continue
@@ -489,10 +430,15 @@ func splitSQLByLineStartHeuristicDetailed(content string) []sqlHeuristicStatemen
 		if builder.Len() > 0 && reStatementStart.MatchString(line) {
 			flush(false, false)
 		}
+		column := utf8.RuneCountInString(rawLine[:len(rawLine)-len(strings.TrimLeftFunc(rawLine, unicode.IsSpace))]) + 1
 		if builder.Len() > 0 {
 			builder.WriteByte('\n')
+		} else {
+			location = sourceLocation{StartLine: lineIndex + 1, StartColumn: column}
 		}
 		builder.WriteString(line)
+		location.EndLine = lineIndex + 1
+		location.EndColumn = column + utf8.RuneCountInString(line)
 
 		terminated, fullwidthTerminator := detectLineTerminator(line)
 		if terminated {
@@ -517,6 +463,14 @@ func splitSQLByLineStartHeuristic(content string) []string {
 	return statements
 }
 
+func sqlStatementTexts(spans []sqlStatementSpan) []string {
+	items := make([]string, 0, len(spans))
+	for _, span := range spans {
+		items = append(items, span.Text)
+	}
+	return items
+}
+
 func hasLikelyMergedStatements(statements []string) bool {
 	for _, statement := range statements {
 		normalized := strings.TrimSpace(stripCommentsAndStrings(statement))
@@ -537,9 +491,20 @@ func hasLikelyMergedStatements(statements []string) bool {
 	return false
 }
 
+type sqlStatementSpan struct {
+	Text  string
+	Span  sourceSpan
+	Batch bool
+}
+
 func splitSQLStatements(content string) []string {
-	items := make([]string, 0)
+	return sqlStatementTexts(splitSQLStatementSpans(content))
+}
+
+func splitSQLStatementSpans(content string) []sqlStatementSpan {
+	items := make([]sqlStatementSpan, 0)
 	var builder strings.Builder
+	start, end := -1, 0
 
 	delimiter := ";"
 	inSingleQuote := false
@@ -547,12 +512,25 @@ func splitSQLStatements(content string) []string {
 	inBacktick := false
 	inBlockComment := false
 
+	flush := func() {
+		piece := strings.TrimSpace(builder.String())
+		if piece != "" {
+			items = append(items, sqlStatementSpan{Text: piece, Span: sourceSpan{Start: start, End: end}})
+		}
+		builder.Reset()
+		start = -1
+	}
+
 	lines := strings.SplitAfter(content, "\n")
 	if len(lines) == 0 {
 		lines = []string{content}
 	}
 
+	lineOffset := 0
 	for _, line := range lines {
+		lineStart := lineOffset
+		lineOffset += len(line)
+
 		if !inSingleQuote && !inDoubleQuote && !inBacktick && !inBlockComment {
 			if delim, ok := parseDelimiterDirective(line); ok {
 				delimiter = delim
@@ -561,9 +539,21 @@ func splitSQLStatements(content string) []string {
 		}
 
 		runes := []rune(line)
+		offsets := runeByteOffsets(line)
 		delimRunes := []rune(delimiter)
 		inLineComment := false
 
+		write := func(i int) {
+			builder.WriteRune(runes[i])
+			if unicode.IsSpace(runes[i]) {
+				return
+			}
+			if start < 0 {
+				start = lineStart + offsets[i]
+			}
+			end = lineStart + offsets[i+1]
+		}
+
 		for i := 0; i < len(runes); i++ {
 			ch := runes[i]
 			next := rune(0)
@@ -574,7 +564,7 @@ func splitSQLStatements(content string) []string {
 			if inLineComment {
 				if ch == '\n' {
 					inLineComment = false
-					builder.WriteRune(ch)
+					write(i)
 				}
 				continue
 			}
@@ -620,36 +610,32 @@ func splitSQLStatements(content string) []string {
 
 			if !inSingleQuote && !inDoubleQuote && !inBacktick && len(delimRunes) > 0 {
 				if matchRunesAt(runes, i, delimRunes) {
-					piece := strings.TrimSpace(builder.String())
-					if piece != "" {
-						items = append(items, piece)
-					}
-					builder.Reset()
+					flush()
 					i += len(delimRunes) - 1
 					continue
 				}
 				if delimiter == ";" && isFullwidthSemicolon(ch) {
-					piece := strings.TrimSpace(builder.String())
-					if piece != "" {
-						items = append(items, piece)
-					}
-					builder.Reset()
+					flush()
 					continue
 				}
 			}
 
-			builder.WriteRune(ch)
-
+			write(i)
 		}
 	}
 
-	if tail := strings.TrimSpace(builder.String()); tail != "" {
-		items = append(items, tail)
-	}
-
+	flush()
 	return items
 }
 
+func runeByteOffsets(text string) []int {
+	offsets := make([]int, 0, len(text)+1)
+	for i := range text {
+		offsets = append(offsets, i)
+	}
+	return append(offsets, len(text))
+}
+
 func parseDelimiterDirective(line string) (string, bool) {
 	trimmed := strings.TrimSpace(line)
 	if trimmed == "" {
@@ -219,6 +219,34 @@ func hasRule(issues []Issue, code string) bool {
 	return false
 }
 
+type statementRules map[int]map[string]bool
+
+func issuesByStatement(issues []Issue) statementRules {
+	rules := make(statementRules)
+	for _, issue := range issues {
+		if rules[issue.StatementIndex] == nil {
+			rules[issue.StatementIndex] = make(map[string]bool)
+		}
+		rules[issue.StatementIndex][issue.Rule] = true
+	}
+	return rules
+}
+
+func (rules statementRules) expect(t *testing.T, index int, rule string, want bool) {
+	t.Helper()
+	if rules[index][rule] != want {
+		t.Fatalf("statement %d rule %s: want %v, got %+v", index, rule, want, rules[index])
+	}
+}
+
+func messagesByStatement(issues []Issue) map[int]string {
+	messages := make(map[int]string)
+	for _, issue := range issues {
+		messages[issue.StatementIndex] += issue.Message
+	}
+	return messages
+}
+
 func hasRuleWithLevel(issues []Issue, code string, level IssueLevel) bool {
 	for _, issue := range issues {
 		if issue.Rule == code && issue.Level == level {
@@ -244,3 +272,29 @@ func getIssueByRule(issues []Issue, code string) *Issue {
 	}
 	return nil
 }
+
+func TestAnalyzeSQLUpdateWhereOnlyInSubqueryOrLiteral(t *testing.T) {
+	sql := `UPDATE users SET level = (SELECT max(level) FROM levels WHERE levels.id = 1);
+UPDATE users SET remark = ' WHERE id = 1 ';`
+
+	res := AnalyzeSQL(sql)
+	count := 0
+	for _, issue := range res.Issues {
+		if issue.Rule == "update_without_where" {
+			count++
+		}
+	}
+	if count != 2 {
+		t.Fatalf("expected update_without_where for both statements, got %d: %+v", count, res.Issues)
+	}
+}
+
+func TestAnalyzeSQLDetectsSelectStarInSubquery(t *testing.T) {
+	res := AnalyzeSQL(`SELECT id FROM (SELECT * FROM users) u LIMIT 10;`)
+	if !hasRule(res.Issues, "select_star") {
+		t.Fatalf("expected select_star for subquery, got issues: %+v", res.Issues)
+	}
+	if hasRule(res.Issues, "select_without_limit") {
+		t.Fatalf("select_without_limit should not fire when top-level LIMIT exists")
+	}
+}
@@ -0,0 +1,724 @@
+package main
+
+import (
+	"errors"
+	"strings"
+)
+
+type SchemaColumn struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Nullable bool   `json:"nullable"`
+}
+
+type SchemaIndex struct {
+	Name    string   `json:"name"`
+	Columns []string `json:"columns"`
+	Unique  bool     `json:"unique,omitempty"`
+	Primary bool     `json:"primary,omitempty"`
+	Method  string   `json:"method,omitempty"`
+}
+
+type SchemaTable struct {
+	Name         string         `json:"name"`
+	Columns      []SchemaColumn `json:"columns"`
+	PrimaryKey   []string       `json:"primaryKey,omitempty"`
+	Indexes      []SchemaIndex  `json:"indexes"`
+	Opaque       bool           `json:"opaque,omitempty"`
+	TableEngine  string         `json:"tableEngine,omitempty"`
+	PartitionKey []string       `json:"partitionKey,omitempty"`
+}
+
+type schemaCatalog struct {
+	engine DBEngine
+	tables map[string]*SchemaTable
+}
+
+var sqlColumnConstraintWords = map[string]struct{}{
+	"NOT": {}, "NULL": {}, "DEFAULT": {}, "PRIMARY": {}, "UNIQUE": {}, "REFERENCES": {}, "CHECK": {}, "CONSTRAINT": {},
+	"AUTO_INCREMENT": {}, "COMMENT": {}, "COLLATE": {}, "GENERATED": {}, "AS": {}, "ON": {}, "CHARSET": {}, "KEY": {},
+	"IDENTITY": {}, "VISIBLE": {}, "INVISIBLE": {}, "STORAGE": {}, "COLUMN_FORMAT": {}, "FIRST": {}, "AFTER": {},
+	"SRID": {}, "USING": {}, "CODEC": {}, "MATERIALIZED": {}, "ALIAS": {}, "EPHEMERAL": {}, "TTL": {}, "STATIC": {},
+}
+
+var sqlIndexNameSkipWords = map[string]struct{}{
+	"KEY": {}, "INDEX": {}, "USING": {}, "BTREE": {}, "HASH": {}, "IF": {}, "NOT": {}, "EXISTS": {}, "CONCURRENTLY": {},
+}
+
+var sqlSystemSchemas = map[string]struct{}{
+	"information_schema": {}, "pg_catalog": {}, "mysql": {}, "performance_schema": {}, "sys": {},
+}
+
+func newSchemaCatalog(engine DBEngine) *schemaCatalog {
+	return &schemaCatalog{engine: engine, tables: make(map[string]*SchemaTable)}
+}
+
+func buildSchemaCatalog(engine DBEngine, ddl string) (*schemaCatalog, error) {
+	catalog := newSchemaCatalog(engine)
+	dialect := mysqlDialect
+	switch engine {
+	case EnginePostgreSQL:
+		dialect = postgresDialect
+	case EngineClickHouse:
+		dialect = clickhouseDialect
+	case EngineCQL:
+		dialect = cqlDialect
+	}
+	spans := splitSQLStatementSpans(ddl)
+	if engine == EngineCQL {
+		spans = splitCQLStatementSpans(ddl)
+	}
+	for _, span := range spans {
+		catalog.apply(parseSQLStatementAt(ddl, span.Span, dialect))
+	}
+	if len(catalog.tables) == 0 {
+		return nil, errors.New("schema contains no CREATE TABLE statements")
+	}
+	return catalog, nil
+}
+
+func catalogKey(name string) string {
+	if index := strings.LastIndexByte(name, '.'); index >= 0 {
+		name = name[index+1:]
+	}
+	return strings.ToLower(name)
+}
+
+func (catalog *schemaCatalog) Tables() []SchemaTable {
+	tables := make([]SchemaTable, 0, len(catalog.tables))
+	for _, key := range sortedKeys(catalog.tables) {
+		tables = append(tables, *catalog.tables[key])
+	}
+	return tables
+}
+
+func (catalog *schemaCatalog) table(name string) (*SchemaTable, bool) {
+	table, found := catalog.tables[catalogKey(name)]
+	return table, found
+}
+
+func (catalog *schemaCatalog) clone() *schemaCatalog {
+	next := newSchemaCatalog(catalog.engine)
+	for key, table := range catalog.tables {
+		next.tables[key] = table.clone()
+	}
+	return next
+}
+
+func (table *SchemaTable) clone() *SchemaTable {
+	copied := *table
+	copied.Columns = append([]SchemaColumn(nil), table.Columns...)
+	copied.PrimaryKey = append([]string(nil), table.PrimaryKey...)
+	copied.PartitionKey = append([]string(nil), table.PartitionKey...)
+	copied.Indexes = make([]SchemaIndex, 0, len(table.Indexes))
+	for _, index := range table.Indexes {
+		index.Columns = append([]string(nil), index.Columns...)
+		copied.Indexes = append(copied.Indexes, index)
+	}
+	return &copied
+}
+
+func (table *SchemaTable) column(name string) (*SchemaColumn, bool) {
+	for i := range table.Columns {
+		if strings.EqualFold(table.Columns[i].Name, name) {
+			return &table.Columns[i], true
+		}
+	}
+	return nil, false
+}
+
+func (table *SchemaTable) hasColumn(name string) bool {
+	_, found := table.column(name)
+	return table.Opaque || found
+}
+
+func (table *SchemaTable) hasIndexPrefix(columns []string) bool {
+	for _, index := range table.Indexes {
+		if len(index.Columns) < len(columns) {
+			continue
+		}
+		matched := true
+		for i, column := range columns {
+			if !strings.EqualFold(index.Columns[i], column) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+func (table *SchemaTable) addIndex(index SchemaIndex) {
+	if len(index.Columns) == 0 {
+		return
+	}
+	if index.Name == "" {
+		index.Name = strings.Join(index.Columns, "_")
+	}
+	table.dropIndex(index.Name)
+	table.Indexes = append(table.Indexes, index)
+}
+
+func (table *SchemaTable) dropIndex(name string) bool {
+	for i, index := range table.Indexes {
+		if strings.EqualFold(index.Name, name) {
+			if index.Primary {
+				table.PrimaryKey = nil
+			}
+			table.Indexes = append(table.Indexes[:i], table.Indexes[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (table *SchemaTable) setPrimaryKey(columns []string) {
+	if len(columns) == 0 {
+		return
+	}
+	table.PrimaryKey = columns
+	for _, name := range columns {
+		if column, found := table.column(name); found {
+			column.Nullable = false
+		}
+	}
+	table.addIndex(SchemaIndex{Name: "PRIMARY", Columns: columns, Unique: true, Primary: true})
+}
+
+func (table *SchemaTable) dropColumn(name string) {
+	for i := range table.Columns {
+		if strings.EqualFold(table.Columns[i].Name, name) {
+			table.Columns = append(table.Columns[:i], table.Columns[i+1:]...)
+			break
+		}
+	}
+	kept := table.Indexes[:0]
+	for _, index := range table.Indexes {
+		columns := make([]string, 0, len(index.Columns))
+		for _, column := range index.Columns {
+			if !strings.EqualFold(column, name) {
+				columns = append(columns, column)
+			}
+		}
+		if len(columns) == 0 {
+			if index.Primary {
+				table.PrimaryKey = nil
+			}
+			continue
+		}
+		index.Columns = columns
+		kept = append(kept, index)
+	}
+	table.Indexes = kept
+}
+
+func (table *SchemaTable) renameColumn(from, to string) {
+	if column, found := table.column(from); found {
+		column.Name = to
+	}
+	for i := range table.Indexes {
+		for j, column := range table.Indexes[i].Columns {
+			if strings.EqualFold(column, from) {
+				table.Indexes[i].Columns[j] = to
+			}
+		}
+	}
+	for i, column := range table.PrimaryKey {
+		if strings.EqualFold(column, from) {
+			table.PrimaryKey[i] = to
+		}
+	}
+}
+
+func (stmt *sqlStatement) changesCatalog() bool {
+	switch stmt.Kind {
+	case sqlStmtCreateTable, sqlStmtCreateIndex, sqlStmtAlterTable, sqlStmtDrop:
+		return true
+	case sqlStmtOther:
+		return len(stmt.Tokens) > 1 && stmt.Tokens[0].Upper == "RENAME" && stmt.Tokens[1].Upper == "TABLE"
+	}
+	return false
+}
+
+func (catalog *schemaCatalog) apply(stmt *sqlStatement) {
+	switch stmt.Kind {
+	case sqlStmtCreateTable:
+		catalog.applyCreateTable(stmt)
+	case sqlStmtCreateIndex:
+		catalog.applyCreateIndex(stmt)
+	case sqlStmtAlterTable:
+		catalog.applyAlterTable(stmt)
+	case sqlStmtDrop:
+		catalog.applyDrop(stmt)
+	case sqlStmtOther:
+		if stmt.changesCatalog() {
+			catalog.applyRenameTable(stmt.Tokens[2:])
+		}
+	}
+}
+
+func (catalog *schemaCatalog) applyCreateTable(stmt *sqlStatement) {
+	if len(stmt.Tables) == 0 {
+		return
+	}
+	name := stmt.Tables[0].Name
+	if _, exists := catalog.table(name); exists && stmt.IfNotExists {
+		return
+	}
+
+	table := &SchemaTable{Name: catalogKey(name), Columns: make([]SchemaColumn, 0), Indexes: make([]SchemaIndex, 0)}
+	catalog.tables[table.Name] = table
+
+	tokens := stmt.Tokens
+	start := 0
+	for start < len(tokens) && tokens[start].End <= stmt.Tables[0].Token.End {
+		start++
+	}
+	if start+2 < len(tokens) && tokens[start].Upper == "ON" && tokens[start+1].Upper == "CLUSTER" {
+		start += 3
+	}
+	if start < len(tokens) && tokens[start].Upper == "LIKE" && start+1 < len(tokens) {
+		if source, found := catalog.table(sqlIdentifierName(tokens[start+1])); found {
+			copied := source.clone()
+			copied.Name = table.Name
+			catalog.tables[table.Name] = copied
+		} else {
+			table.Opaque = true
+		}
+		return
+	}
+	if start >= len(tokens) || tokens[start].Text != "(" || tokens[start].Kind != sqlTokenOperator {
+		table.Opaque = true
+		return
+	}
+
+	closing, ok := matchingSQLParen(tokens, start)
+	if !ok {
+		table.Opaque = true
+		return
+	}
+	elements := splitSQLTokensTopLevel(tokens[start+1:closing], tokens[start].Depth+1)
+	if catalog.engine == EngineCQL {
+		elements = splitCQLTopLevel(tokens[start+1:closing], tokens[start].Depth+1)
+	}
+	for _, element := range elements {
+		catalog.applyTableElement(table, element)
+	}
+	if closing+1 < len(tokens) && (tokens[closing+1].Upper == "AS" || tokens[closing+1].Upper == "SELECT") {
+		table.Opaque = true
+	}
+	if catalog.engine == EngineClickHouse {
+		table.applyClickHouseOptions(tokens[closing+1:], tokens[start].Depth)
+	}
+	if catalog.engine == EngineCQL && len(table.PartitionKey) == 0 && len(table.PrimaryKey) > 0 {
+		table.PartitionKey = table.PrimaryKey[:1]
+	}
+}
+
+func (catalog *schemaCatalog) applyTableElement(table *SchemaTable, element []sqlToken) {
+	if len(element) == 0 {
+		return
+	}
+	constraintName := ""
+	if element[0].Upper == "CONSTRAINT" && len(element) > 2 {
+		constraintName = sqlIdentifierName(element[1])
+		element = element[2:]
+	}
+
+	switch element[0].Upper {
+	case "PRIMARY":
+		if catalog.engine == EngineCQL {
+			table.applyCQLPrimaryKey(element)
+			return
+		}
+		_, columns := sqlIndexDefinition(element, 1)
+		table.setPrimaryKey(columns)
+	case "UNIQUE":
+		name, columns := sqlIndexDefinition(element, 1)
+		table.addIndex(SchemaIndex{Name: firstNonEmpty(name, constraintName), Columns: columns, Unique: true})
+	case "INDEX", "KEY":
+		name, columns := sqlIndexDefinition(element, 1)
+		table.addIndex(SchemaIndex{Name: name, Columns: columns})
+	case "FULLTEXT", "SPATIAL":
+		name, columns := sqlIndexDefinition(element, 1)
+		table.addIndex(SchemaIndex{Name: name, Columns: columns, Method: element[0].Upper})
+	case "FOREIGN":
+		name, columns := sqlIndexDefinition(element, 1)
+		if catalog.engine == EngineMySQL && len(columns) > 0 && !table.hasIndexPrefix(columns) {
+			table.addIndex(SchemaIndex{Name: firstNonEmpty(constraintName, name), Columns: columns})
+		}
+	case "LIKE":
+		table.Opaque = true
+	case "CHECK", "EXCLUDE", "PERIOD":
+	default:
+		if constraintName == "" {
+			table.applyColumnDefinition(element, "")
+		}
+	}
+}
+
+func (table *SchemaTable) applyColumnDefinition(element []sqlToken, replace string) {
+	if len(element) == 0 || (element[0].Kind != sqlTokenWord && element[0].Kind != sqlTokenQuotedIdent) {
+		return
+	}
+	column := SchemaColumn{Name: sqlIdentifierName(element[0]), Nullable: true}
+	depth := element[0].Depth
+	typeEnd := 1
+	for typeEnd < len(element) {
+		token := element[typeEnd]
+		if token.Depth == depth && token.Kind == sqlTokenWord {
+			if _, stop := sqlColumnConstraintWords[token.Upper]; stop {
+				break
+			}
+			if token.Upper == "CHARACTER" && typeEnd+1 < len(element) && element[typeEnd+1].Upper == "SET" {
+				break
+			}
+		}
+		typeEnd++
+	}
+	column.Type = joinSQLTokens(element[1:typeEnd])
+
+	primary, unique := false, false
+	for i := typeEnd; i < len(element); i++ {
+		if element[i].Depth != depth {
+			continue
+		}
+		switch element[i].Upper {
+		case "NOT":
+			if i+1 < len(element) && element[i+1].Upper == "NULL" {
+				column.Nullable = false
+			}
+		case "PRIMARY":
+			primary = true
+		case "UNIQUE":
+			unique = true
+		}
+	}
+
+	if replace != "" {
+		if existing, found := table.column(replace); found {
+			table.renameColumn(replace, column.Name)
+			*existing = column
+		} else {
+			table.Columns = append(table.Columns, column)
+		}
+	} else if existing, found := table.column(column.Name); found {
+		*existing = column
+	} else {
+		table.Columns = append(table.Columns, column)
+	}
+
+	if primary {
+		table.setPrimaryKey([]string{column.Name})
+	}
+	if unique {
+		table.addIndex(SchemaIndex{Name: column.Name, Columns: []string{column.Name}, Unique: true})
+	}
+}
+
+func (catalog *schemaCatalog) applyCreateIndex(stmt *sqlStatement) {
+	if len(stmt.Tables) == 0 {
+		return
+	}
+	table, found := catalog.table(stmt.Tables[0].Name)
+	if !found {
+		return
+	}
+
+	tokens := stmt.Tokens
+	name := ""
+	on := len(tokens)
+	for i := 1; i < len(tokens); i++ {
+		if tokens[i].Upper == "ON" && tokens[i].Depth == tokens[0].Depth {
+			on = i
+			break
+		}
+	}
+	for i := 1; i < on; i++ {
+		if _, skip := sqlIndexNameSkipWords[tokens[i].Upper]; skip || tokens[i].Kind == sqlTokenOperator {
+			continue
+		}
+		switch tokens[i].Upper {
+		case "CREATE", "UNIQUE", "FULLTEXT", "SPATIAL", "OR", "REPLACE":
+			continue
+		}
+		name = sqlIdentifierName(tokens[i])
+		for i+2 < on && tokens[i+1].Text == "." {
+			i += 2
+			name = sqlIdentifierName(tokens[i])
+		}
+		break
+	}
+
+	index := SchemaIndex{Name: name, Unique: stmt.ObjectType == "UNIQUE"}
+	if stmt.ObjectType == "FULLTEXT" || stmt.ObjectType == "SPATIAL" {
+		index.Method = stmt.ObjectType
+	}
+	for i := on + 1; i < len(tokens); i++ {
+		if tokens[i].Upper == "USING" && i+1 < len(tokens) && tokens[i+1].Upper != "BTREE" {
+			index.Method = tokens[i+1].Upper
+		}
+		if tokens[i].Kind == sqlTokenOperator && tokens[i].Text == "(" {
+			index.Columns = sqlParenColumns(tokens, i)
+			break
+		}
+	}
+	table.addIndex(index)
+}
+
+func (catalog *schemaCatalog) applyAlterTable(stmt *sqlStatement) {
+	if len(stmt.Tables) == 0 {
+		return
+	}
+	table, found := catalog.table(stmt.Tables[0].Name)
+	if !found {
+		return
+	}
+
+	for _, clause := range stmt.AlterClauses {
+		if len(clause) < 2 {
+			continue
+		}
+		rest := clause[1:]
+		switch clause[0].Upper {
+		case "ADD":
+			rest = trimSQLWords(rest, "COLUMN")
+			rest = trimSQLWords(rest, "IF", "NOT", "EXISTS")
+			if len(rest) > 0 && rest[0].Text == "(" && rest[0].Kind == sqlTokenOperator {
+				closing, _ := matchingSQLParen(rest, 0)
+				for _, element := range splitSQLTokensTopLevel(rest[1:closing], rest[0].Depth+1) {
+					catalog.applyTableElement(table, element)
+				}
+				continue
+			}
+			catalog.applyTableElement(table, rest)
+		case "DROP":
+			catalog.applyAlterDrop(table, rest)
+		case "MODIFY":
+			table.applyColumnDefinition(trimSQLWords(rest, "COLUMN"), "")
+		case "CHANGE":
+			rest = trimSQLWords(rest, "COLUMN")
+			if len(rest) > 1 {
+				table.applyColumnDefinition(rest[1:], sqlIdentifierName(rest[0]))
+			}
+		case "RENAME":
+			catalog.applyAlterRename(table, rest)
+		case "ALTER":
+			rest = trimSQLWords(rest, "COLUMN")
+			if len(rest) < 2 {
+				continue
+			}
+			column, found := table.column(sqlIdentifierName(rest[0]))
+			if !found {
+				continue
+			}
+			switch {
+			case rest[1].Upper == "TYPE":
+				column.Type = joinSQLTokens(trimSQLTypeTail(rest[2:]))
+			case len(rest) > 3 && rest[1].Upper == "SET" && rest[2].Upper == "DATA" && rest[3].Upper == "TYPE":
+				column.Type = joinSQLTokens(trimSQLTypeTail(rest[4:]))
+			case len(rest) > 3 && rest[1].Upper == "SET" && rest[2].Upper == "NOT" && rest[3].Upper == "NULL":
+				column.Nullable = false
+			case len(rest) > 3 && rest[1].Upper == "DROP" && rest[2].Upper == "NOT" && rest[3].Upper == "NULL":
+				column.Nullable = true
+			}
+		}
+	}
+}
+
+func (catalog *schemaCatalog) applyAlterDrop(table *SchemaTable, rest []sqlToken) {
+	switch rest[0].Upper {
+	case "INDEX", "KEY":
+		rest = trimSQLWords(rest[1:], "IF", "EXISTS")
+		if len(rest) > 0 {
+			table.dropIndex(sqlIdentifierName(rest[0]))
+		}
+	case "PRIMARY":
+		table.dropIndex("PRIMARY")
+		table.PrimaryKey = nil
+	case "CONSTRAINT":
+		rest = trimSQLWords(rest[1:], "IF", "EXISTS")
+		if len(rest) > 0 {
+			table.dropIndex(sqlIdentifierName(rest[0]))
+		}
+	case "FOREIGN", "CHECK", "PARTITION", "DEFAULT", "SYSTEM":
+	default:
+		rest = trimSQLWords(trimSQLWords(rest, "COLUMN"), "IF", "EXISTS")
+		if len(rest) > 0 {
+			table.dropColumn(sqlIdentifierName(rest[0]))
+		}
+	}
+}
+
+func (catalog *schemaCatalog) applyAlterRename(table *SchemaTable, rest []sqlToken) {
+	switch {
+	case rest[0].Upper == "COLUMN" && len(rest) >= 4:
+		table.renameColumn(sqlIdentifierName(rest[1]), sqlIdentifierName(rest[3]))
+	case (rest[0].Upper == "INDEX" || rest[0].Upper == "KEY") && len(rest) >= 4:
+		for i := range table.Indexes {
+			if strings.EqualFold(table.Indexes[i].Name, sqlIdentifierName(rest[1])) {
+				table.Indexes[i].Name = sqlIdentifierName(rest[3])
+			}
+		}
+	case rest[0].Upper == "TO" || rest[0].Upper == "AS" || len(rest) == 1:
+		catalog.renameTable(table.Name, sqlIdentifierName(rest[len(rest)-1]))
+	case len(rest) >= 3 && rest[1].Upper == "TO":
+		table.renameColumn(sqlIdentifierName(rest[0]), sqlIdentifierName(rest[2]))
+	}
+}
+
+func (catalog *schemaCatalog) renameTable(from, to string) {
+	table, found := catalog.table(from)
+	if !found {
+		return
+	}
+	delete(catalog.tables, catalogKey(from))
+	table.Name = catalogKey(to)
+	catalog.tables[table.Name] = table
+}
+
+func (catalog *schemaCatalog) applyRenameTable(tokens []sqlToken) {
+	p := &sqlParser{tokens: tokens}
+	for i := 0; i < len(tokens); {
+		from, _, next := p.readQualifiedName(i)
+		if from == "" || p.upper(next) != "TO" {
+			return
+		}
+		to, _, after := p.readQualifiedName(next + 1)
+		if to == "" {
+			return
+		}
+		catalog.renameTable(from, to)
+		i = after
+		if p.upper(i) == "," {
+			i++
+		}
+	}
+}
+
+func (catalog *schemaCatalog) applyDrop(stmt *sqlStatement) {
+	switch stmt.ObjectType {
+	case "TABLE":
+		for _, ref := range stmt.Tables {
+			delete(catalog.tables, catalogKey(ref.Name))
+		}
+	case "INDEX":
+		for _, ref := range stmt.Tables {
+			for _, key := range sortedKeys(catalog.tables) {
+				if catalog.tables[key].dropIndex(catalogKey(ref.Name)) {
+					break
+				}
+			}
+		}
+	}
+}
+
+func sqlIndexDefinition(element []sqlToken, from int) (string, []string) {
+	name := ""
+	for i := from; i < len(element); i++ {
+		token := element[i]
+		if token.Kind == sqlTokenOperator && token.Text == "(" {
+			return name, sqlParenColumns(element, i)
+		}
+		if _, skip := sqlIndexNameSkipWords[token.Upper]; skip && token.Kind == sqlTokenWord {
+			continue
+		}
+		if name == "" && (token.Kind == sqlTokenWord || token.Kind == sqlTokenQuotedIdent) {
+			name = sqlIdentifierName(token)
+		}
+	}
+	return name, nil
+}
+
+func sqlParenColumns(tokens []sqlToken, open int) []string {
+	closing, _ := matchingSQLParen(tokens, open)
+	columns := make([]string, 0)
+	for _, element := range splitSQLTokensTopLevel(tokens[open+1:closing], tokens[open].Depth+1) {
+		if len(element) == 0 || (element[0].Kind != sqlTokenWord && element[0].Kind != sqlTokenQuotedIdent) {
+			return columns
+		}
+		if len(element) > 1 && element[1].Text == "(" && (len(element) < 3 || element[2].Kind != sqlTokenNumber) {
+			return columns
+		}
+		columns = append(columns, sqlIdentifierName(element[0]))
+	}
+	return columns
+}
+
+func splitSQLTokensTopLevel(tokens []sqlToken, depth int) [][]sqlToken {
+	parts := make([][]sqlToken, 0)
+	start := 0
+	for i, token := range tokens {
+		if token.Kind == sqlTokenOperator && token.Text == "," && token.Depth == depth {
+			parts = append(parts, tokens[start:i])
+			start = i + 1
+		}
+	}
+	if start < len(tokens) {
+		parts = append(parts, tokens[start:])
+	}
+	return parts
+}
+
+func trimSQLWords(tokens []sqlToken, words ...string) []sqlToken {
+	if len(tokens) < len(words) {
+		return tokens
+	}
+	for i, word := range words {
+		if tokens[i].Kind != sqlTokenWord || tokens[i].Upper != word {
+			return tokens
+		}
+	}
+	return tokens[len(words):]
+}
+
+func trimSQLTypeTail(tokens []sqlToken) []sqlToken {
+	for i, token := range tokens {
+		if token.Upper == "USING" || token.Upper == "COLLATE" {
+			return tokens[:i]
+		}
+	}
+	return tokens
+}
+
+func joinSQLTokens(tokens []sqlToken) string {
+	var builder strings.Builder
+	for i, token := range tokens {
+		text := token.Text
+		if token.Kind == sqlTokenWord {
+			text = token.Upper
+		}
+		if i > 0 && token.Kind != sqlTokenOperator {
+			previous := tokens[i-1].Text
+			if previous != "(" && previous != "," && previous != "." {
+				builder.WriteByte(' ')
+			}
+		}
+		builder.WriteString(text)
+	}
+	return builder.String()
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func (ctx *RuleContext) replayCatalog(base *schemaCatalog) {
+	current := base
+	for i := range ctx.Statements {
+		ctx.Statements[i].Catalog = current
+		if stmt := ctx.Statements[i].SQL; stmt != nil && stmt.changesCatalog() {
+			next := current.clone()
+			next.apply(stmt)
+			current = next
+		}
+	}
+}
@@ -0,0 +1,599 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type sqlColumnRef struct {
+	Qualifier string
+	Name      string
+	Operator  string
+	Token     sqlToken
+}
+
+type catalogScope struct {
+	catalog  *schemaCatalog
+	tables   []sqlTableRef
+	complete bool
+}
+
+var sqlPredicateOperators = map[string]struct{}{
+	"=": {}, "<=>": {}, "<": {}, ">": {}, "<=": {}, ">=": {}, "<>": {}, "!=": {},
+	"IN": {}, "LIKE": {}, "ILIKE": {}, "BETWEEN": {}, "IS": {}, "NOT": {}, "REGEXP": {}, "RLIKE": {},
+}
+
+var sqlIndexableOperators = map[string]struct{}{
+	"=": {}, "<=>": {}, "<": {}, ">": {}, "<=": {}, ">=": {}, "IN": {}, "BETWEEN": {}, "IS": {}, "LIKE": {},
+}
+
+var sqlNonColumnWords = map[string]struct{}{
+	"NULL": {}, "TRUE": {}, "FALSE": {}, "END": {}, "CURRENT_DATE": {}, "CURRENT_TIME": {}, "CURRENT_TIMESTAMP": {},
+	"LOCALTIME": {}, "LOCALTIMESTAMP": {}, "CURRENT_USER": {}, "SESSION_USER": {}, "USER": {}, "SYSDATE": {},
+	"CTID": {}, "XMIN": {}, "XMAX": {}, "CMIN": {}, "CMAX": {}, "TABLEOID": {}, "OID": {}, "ROWID": {}, "ROWNUM": {}, "_ROWID": {},
+}
+
+func unknownTableRule(code string) Rule {
+	return newStatementRule(
+		RuleDefinition{Code: code, Level: LevelError, Category: "结构校验", Description: "引用的表不在结构快照中（需提供结构快照）"},
+		func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+			if stmt.Catalog == nil || stmt.SQL == nil {
+				return Issue{}, false
+			}
+			missing := make([]sqlTableRef, 0)
+			seen := make(map[string]struct{})
+			for _, ref := range stmt.SQL.referencedTables() {
+				key := catalogKey(ref.Name)
+				if _, found := stmt.Catalog.table(ref.Name); found {
+					continue
+				}
+				if _, duplicate := seen[key]; duplicate {
+					continue
+				}
+				seen[key] = struct{}{}
+				missing = append(missing, ref)
+			}
+			if len(missing) == 0 {
+				return Issue{}, false
+			}
+
+			names := make([]string, 0, len(missing))
+			for _, ref := range missing {
+				names = append(names, ref.Name)
+			}
+			return ctx.Source.locate(Issue{
+				Message:    fmt.Sprintf("表 %s 不在结构快照中", strings.Join(names, "、")),
+				Suggestion: "请确认表名拼写，或先在脚本中创建该表；如结构快照已过期请及时更新",
+			}, missing[0].Token.span()), true
+		},
+	)
+}
+
+func unknownColumnRule(code string) Rule {
+	return newStatementRule(
+		RuleDefinition{Code: code, Level: LevelError, Category: "结构校验", Description: "引用的列不在结构快照中（需提供结构快照）"},
+		func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+			if stmt.Catalog == nil || stmt.SQL == nil {
+				return Issue{}, false
+			}
+			missing := stmt.SQL.unknownColumns(stmt.Catalog)
+			if len(missing) == 0 {
+				return Issue{}, false
+			}
+
+			names := make([]string, 0, len(missing))
+			for _, ref := range missing {
+				names = append(names, ref.Name)
+			}
+			return ctx.Source.locate(Issue{
+				Message:    fmt.Sprintf("列 %s 不在结构快照中", strings.Join(names, "、")),
+				Suggestion: "请确认列名拼写，以及脚本中此前的 DDL 是否已删除或重命名该列",
+			}, missing[0].Token.span()), true
+		},
+	)
+}
+
+func writePredicateNotIndexedRule(code string) Rule {
+	return newStatementRule(
+		RuleDefinition{Code: code, Level: LevelWarning, Category: "DML安全", Description: "UPDATE/DELETE 的 WHERE 条件未命中索引（需提供结构快照）"},
+		func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+			table, target, columns, found := stmt.unindexedWritePredicate()
+			if !found {
+				return Issue{}, false
+			}
+
+			suggestion := "请为条件列补充索引，或改为按主键/索引列定位后分批执行"
+			if ddl := stmt.writePredicateIndexDDL(ctx.Engine, table, target); ddl != "" {
+				suggestion = fmt.Sprintf("请为条件列补充索引（如 %s），或改为按主键/索引列定位后分批执行", ddl)
+			}
+			return ctx.Source.locate(Issue{
+				Message:    fmt.Sprintf("%s 的 WHERE 条件列 %s 未命中表 %s 的任何索引（按最左前缀），可能全表扫描并长时间持锁", stmt.SQL.Kind, strings.Join(uniqueFold(columns), "、"), table.Name),
+				Suggestion: suggestion,
+			}, stmt.SQL.Where.Keyword.span()), true
+		},
+	)
+}
+
+func (stmt RuleStatement) unindexedWritePredicate() (*SchemaTable, sqlTableRef, []string, bool) {
+	parsed := stmt.SQL
+	if stmt.Catalog == nil || parsed == nil || (parsed.Kind != sqlStmtUpdate && parsed.Kind != sqlStmtDelete) || parsed.Where == nil || len(parsed.Tables) != 1 {
+		return nil, sqlTableRef{}, nil, false
+	}
+	if parsed.Kind == sqlStmtDelete && parsed.isMultiTableDelete() {
+		return nil, sqlTableRef{}, nil, false
+	}
+	target := parsed.Tables[0]
+	table, found := stmt.Catalog.table(target.Name)
+	if !found || table.Opaque {
+		return nil, sqlTableRef{}, nil, false
+	}
+
+	columns := make([]string, 0)
+	indexable := make(map[string]struct{})
+	for _, ref := range sqlPredicateColumns(parsed.Where.Tokens) {
+		if ref.Qualifier != "" && !target.matches(ref.Qualifier) {
+			continue
+		}
+		if !table.hasColumn(ref.Name) {
+			continue
+		}
+		columns = append(columns, ref.Name)
+		if _, ok := sqlIndexableOperators[ref.Operator]; ok {
+			indexable[strings.ToLower(ref.Name)] = struct{}{}
+		}
+	}
+	if len(columns) == 0 {
+		return nil, sqlTableRef{}, nil, false
+	}
+	for _, index := range table.Indexes {
+		if index.Method != "" || len(index.Columns) == 0 {
+			continue
+		}
+		if _, found := indexable[strings.ToLower(index.Columns[0])]; found {
+			return nil, sqlTableRef{}, nil, false
+		}
+	}
+	return table, target, columns, true
+}
+
+func (stmt RuleStatement) writePredicateIndexDDL(engine DBEngine, table *SchemaTable, target sqlTableRef) string {
+	for _, access := range stmt.SQL.indexAccesses(stmt.Catalog) {
+		if access.Ref.Token.Start != target.Token.Start {
+			continue
+		}
+		columns := access.columns()
+		if len(columns) == 0 || access.servedBy(table.Indexes) {
+			return ""
+		}
+		return advisedIndexDDL(engine, target.Name, SchemaIndex{Name: advisedIndexName(engine, table, columns), Columns: columns})
+	}
+	return ""
+}
+
+func (ref sqlTableRef) matches(qualifier string) bool {
+	return strings.EqualFold(ref.Alias, qualifier) || catalogKey(ref.Name) == strings.ToLower(qualifier)
+}
+
+func (stmt *sqlStatement) isMultiTableDelete() bool {
+	return stmt.Kind == sqlStmtDelete && stmt.From != nil && len(stmt.Tables) > 0 && stmt.Tables[0].Token.Start < stmt.From.Keyword.Start
+}
+
+func (stmt *sqlStatement) hasTopLevelWord(words ...string) bool {
+	if len(stmt.Tokens) == 0 {
+		return false
+	}
+	p := &sqlParser{tokens: stmt.Tokens, base: stmt.Tokens[0].Depth}
+	return p.findTop(1, len(stmt.Tokens), words...) >= 0
+}
+
+func (stmt *sqlStatement) clauseTableRefs(clause *sqlClause) []sqlTableRef {
+	if clause == nil || len(clause.Tokens) == 0 {
+		return nil
+	}
+	p := &sqlParser{tokens: clause.Tokens, base: clause.Keyword.Depth}
+	return p.parseTableRefs(0, len(clause.Tokens))
+}
+
+func (stmt *sqlStatement) referencedTables() []sqlTableRef {
+	refs := make([]sqlTableRef, 0)
+	switch stmt.Kind {
+	case sqlStmtInsert, sqlStmtReplace, sqlStmtUpdate, sqlStmtTruncate, sqlStmtCreateIndex:
+		refs = append(refs, stmt.Tables...)
+	case sqlStmtDelete:
+		if stmt.isMultiTableDelete() {
+			refs = append(refs, stmt.clauseTableRefs(stmt.From)...)
+		} else {
+			refs = append(refs, stmt.Tables...)
+		}
+	case sqlStmtAlterTable:
+		if !stmt.IfExists {
+			refs = append(refs, stmt.Tables...)
+		}
+	case sqlStmtDrop:
+		if stmt.ObjectType == "TABLE" && !stmt.IfExists {
+			refs = append(refs, stmt.Tables...)
+		}
+	}
+	stmt.walkSelects(func(sel *sqlStatement) {
+		for _, ref := range sel.Tables {
+			if !sel.isTableFunction(ref) {
+				refs = append(refs, ref)
+			}
+		}
+	})
+
+	ctes := make(map[string]struct{})
+	stmt.collectCTENames(ctes)
+	kept := refs[:0]
+	for _, ref := range refs {
+		if _, found := ctes[catalogKey(ref.Name)]; found || isSystemTableRef(ref.Name) {
+			continue
+		}
+		kept = append(kept, ref)
+	}
+	return kept
+}
+
+func (stmt *sqlStatement) collectCTENames(names map[string]struct{}) {
+	if stmt == nil {
+		return
+	}
+	for _, name := range stmt.CTENames {
+		names[catalogKey(name)] = struct{}{}
+	}
+	stmt.Source.collectCTENames(names)
+	for _, sub := range stmt.Subqueries {
+		sub.collectCTENames(names)
+	}
+}
+
+func (stmt *sqlStatement) isTableFunction(ref sqlTableRef) bool {
+	for _, token := range stmt.Tokens {
+		if token.Start >= ref.Token.End {
+			return token.Kind == sqlTokenOperator && token.Text == "("
+		}
+	}
+	return false
+}
+
+func isSystemTableRef(name string) bool {
+	if strings.EqualFold(name, "dual") {
+		return true
+	}
+	if schema, _, found := strings.Cut(name, "."); found {
+		_, system := sqlSystemSchemas[strings.ToLower(schema)]
+		return system
+	}
+	return false
+}
+
+func (stmt *sqlStatement) unknownColumns(catalog *schemaCatalog) []sqlColumnRef {
+	missing := make([]sqlColumnRef, 0)
+	seen := make(map[string]struct{})
+	report := func(ref sqlColumnRef) {
+		key := strings.ToLower(ref.Qualifier + "." + ref.Name)
+		if _, duplicate := seen[key]; duplicate {
+			return
+		}
+		seen[key] = struct{}{}
+		missing = append(missing, ref)
+	}
+	check := func(scope catalogScope, refs []sqlColumnRef) {
+		for _, ref := range refs {
+			if scope.missing(ref) {
+				report(ref)
+			}
+		}
+	}
+
+	switch stmt.Kind {
+	case sqlStmtInsert, sqlStmtReplace:
+		if len(stmt.Tables) > 0 {
+			check(catalogScope{catalog: catalog, tables: stmt.Tables[:1], complete: true}, stmt.insertColumnRefs())
+		}
+	case sqlStmtUpdate:
+		scope := catalogScope{catalog: catalog, tables: stmt.Tables, complete: !stmt.hasTopLevelWord("FROM")}
+		check(scope, stmt.updateSetColumns())
+		if stmt.Where != nil {
+			check(scope, sqlPredicateColumns(stmt.Where.Tokens))
+		}
+	case sqlStmtDelete:
+		scope := catalogScope{catalog: catalog, tables: stmt.Tables, complete: !stmt.hasTopLevelWord("USING")}
+		if stmt.isMultiTableDelete() {
+			scope = catalogScope{catalog: catalog, tables: stmt.clauseTableRefs(stmt.From), complete: !stmt.clauseHasDerivedTable(stmt.From)}
+		}
+		if stmt.Where != nil {
+			check(scope, sqlPredicateColumns(stmt.Where.Tokens))
+		}
+	case sqlStmtAlterTable:
+		if len(stmt.Tables) > 0 {
+			check(catalogScope{catalog: catalog, tables: stmt.Tables, complete: true}, stmt.alterColumnRefs())
+		}
+	case sqlStmtCreateIndex:
+		if len(stmt.Tables) > 0 {
+			check(catalogScope{catalog: catalog, tables: stmt.Tables, complete: true}, stmt.indexColumnRefs())
+		}
+	}
+
+	stmt.walkSelects(func(sel *sqlStatement) {
+		scope := catalogScope{catalog: catalog, tables: sel.Tables, complete: !sel.clauseHasDerivedTable(sel.From)}
+		projection, aliases := sel.projectionColumnRefs()
+		check(scope, projection)
+		check(scope, sqlJoinColumns(sel.From))
+		if sel.Where != nil {
+			check(scope, sqlPredicateColumns(sel.Where.Tokens))
+		}
+		for _, clause := range []*sqlClause{sel.GroupBy, sel.OrderBy} {
+			for _, ref := range sqlOrderColumns(clause) {
+				if _, alias := aliases[strings.ToLower(ref.Name)]; alias && ref.Qualifier == "" {
+					continue
+				}
+				check(scope, []sqlColumnRef{ref})
+			}
+		}
+	})
+	return missing
+}
+
+func (stmt *sqlStatement) projectionColumnRefs() ([]sqlColumnRef, map[string]struct{}) {
+	refs := make([]sqlColumnRef, 0)
+	aliases := make(map[string]struct{})
+	for _, item := range stmt.Projection {
+		tokens := item.Tokens
+		if item.Star || len(tokens) == 0 {
+			continue
+		}
+		last := tokens[len(tokens)-1]
+		hasAlias := len(tokens) > 1 && (last.Kind == sqlTokenWord || last.Kind == sqlTokenQuotedIdent) && tokens[len(tokens)-2].Text != "."
+		if _, keyword := sqlNonColumnWords[last.Upper]; keyword && last.Kind == sqlTokenWord {
+			hasAlias = false
+		}
+		if hasAlias {
+			aliases[strings.ToLower(sqlIdentifierName(last))] = struct{}{}
+		}
+
+		name, token, next := (&sqlParser{tokens: tokens}).readQualifiedName(0)
+		rest := tokens[next:]
+		simple := len(rest) == 0 || (hasAlias && (len(rest) == 1 || (len(rest) == 2 && rest[0].Upper == "AS")))
+		if name == "" || !simple {
+			continue
+		}
+		if _, keyword := sqlNonColumnWords[token.Upper]; keyword && token.Kind == sqlTokenWord && !strings.Contains(name, ".") {
+			continue
+		}
+		ref := sqlColumnRef{Name: name, Token: token}
+		if index := strings.LastIndexByte(name, '.'); index >= 0 {
+			ref.Qualifier, ref.Name = catalogKey(name[:index]), name[index+1:]
+		}
+		refs = append(refs, ref)
+	}
+	return refs, aliases
+}
+
+func (stmt *sqlStatement) clauseHasDerivedTable(clause *sqlClause) bool {
+	if clause == nil {
+		return false
+	}
+	for _, token := range clause.Tokens {
+		if token.Kind == sqlTokenOperator && token.Text == "(" && token.Depth == clause.Keyword.Depth {
+			return true
+		}
+	}
+	return false
+}
+
+func (scope catalogScope) missing(ref sqlColumnRef) bool {
+	if ref.Qualifier != "" {
+		for _, table := range scope.tables {
+			if !table.matches(ref.Qualifier) {
+				continue
+			}
+			known, found := scope.catalog.table(table.Name)
+			return found && !known.hasColumn(ref.Name)
+		}
+		return false
+	}
+
+	if !scope.complete || len(scope.tables) == 0 {
+		return false
+	}
+	for _, table := range scope.tables {
+		known, found := scope.catalog.table(table.Name)
+		if !found || known.hasColumn(ref.Name) {
+			return false
+		}
+	}
+	return true
+}
+
+func (stmt *sqlStatement) insertColumnRefs() []sqlColumnRef {
+	refs := make([]sqlColumnRef, 0, len(stmt.Columns))
+	if !stmt.HasColumnList || len(stmt.Tables) == 0 {
+		return refs
+	}
+	for _, name := range stmt.Columns {
+		ref := sqlColumnRef{Name: name, Token: stmt.Tables[0].Token}
+		for _, token := range stmt.Tokens {
+			if token.Start > stmt.Tables[0].Token.End && (token.Kind == sqlTokenWord || token.Kind == sqlTokenQuotedIdent) && sqlIdentifierName(token) == name {
+				ref.Token = token
+				break
+			}
+		}
+		refs = append(refs, ref)
+	}
+	return refs
+}
+
+func (stmt *sqlStatement) updateSetColumns() []sqlColumnRef {
+	tokens := stmt.Tokens
+	if len(tokens) == 0 {
+		return nil
+	}
+	p := &sqlParser{tokens: tokens, base: tokens[0].Depth}
+	set := p.findTop(1, len(tokens), "SET")
+	if set < 0 {
+		return nil
+	}
+	end := p.nextClauseEnd(set+1, map[string]struct{}{"WHERE": {}, "FROM": {}, "ORDER": {}, "LIMIT": {}, "RETURNING": {}})
+
+	refs := make([]sqlColumnRef, 0)
+	for _, element := range splitSQLTokensTopLevel(tokens[set+1:end], tokens[set].Depth) {
+		name, token, next := (&sqlParser{tokens: element}).readQualifiedName(0)
+		if name == "" || next >= len(element) || element[next].Text != "=" {
+			continue
+		}
+		ref := sqlColumnRef{Name: name, Token: token}
+		if index := strings.LastIndexByte(name, '.'); index >= 0 {
+			ref.Qualifier, ref.Name = catalogKey(name[:index]), name[index+1:]
+		}
+		refs = append(refs, ref)
+	}
+	return refs
+}
+
+func (stmt *sqlStatement) alterColumnRefs() []sqlColumnRef {
+	added := make(map[string]struct{})
+	for _, clause := range stmt.AlterClauses {
+		if len(clause) > 1 && clause[0].Upper == "ADD" {
+			rest := trimSQLWords(trimSQLWords(clause[1:], "COLUMN"), "IF", "NOT", "EXISTS")
+			if len(rest) > 0 {
+				added[strings.ToLower(sqlIdentifierName(rest[0]))] = struct{}{}
+			}
+		}
+	}
+
+	refs := make([]sqlColumnRef, 0)
+	for _, clause := range stmt.AlterClauses {
+		if len(clause) < 2 {
+			continue
+		}
+		rest := clause[1:]
+		switch clause[0].Upper {
+		case "DROP":
+			switch rest[0].Upper {
+			case "INDEX", "KEY", "PRIMARY", "FOREIGN", "CONSTRAINT", "CHECK", "PARTITION", "DEFAULT", "SYSTEM", "UNIQUE", "FULLTEXT", "SPATIAL":
+				continue
+			}
+			rest = trimSQLWords(rest, "COLUMN")
+			if len(trimSQLWords(rest, "IF", "EXISTS")) != len(rest) {
+				continue
+			}
+		case "MODIFY", "CHANGE", "ALTER":
+			switch rest[0].Upper {
+			case "INDEX", "CONSTRAINT", "CHECK":
+				continue
+			}
+			rest = trimSQLWords(rest, "COLUMN")
+		case "RENAME":
+			if rest[0].Upper != "COLUMN" {
+				continue
+			}
+			rest = rest[1:]
+		default:
+			continue
+		}
+		if len(rest) == 0 || (rest[0].Kind != sqlTokenWord && rest[0].Kind != sqlTokenQuotedIdent) {
+			continue
+		}
+		if _, found := added[strings.ToLower(sqlIdentifierName(rest[0]))]; !found {
+			refs = append(refs, sqlColumnRef{Name: sqlIdentifierName(rest[0]), Token: rest[0]})
+		}
+	}
+	return refs
+}
+
+func (stmt *sqlStatement) indexColumnRefs() []sqlColumnRef {
+	refs := make([]sqlColumnRef, 0)
+	tokens := stmt.Tokens
+	for i, token := range tokens {
+		if token.Kind != sqlTokenOperator || token.Text != "(" || token.Depth != tokens[0].Depth {
+			continue
+		}
+		closing, _ := matchingSQLParen(tokens, i)
+		for _, element := range splitSQLTokensTopLevel(tokens[i+1:closing], token.Depth+1) {
+			if len(element) == 0 || (element[0].Kind != sqlTokenWord && element[0].Kind != sqlTokenQuotedIdent) {
+				continue
+			}
+			if len(element) > 1 && element[1].Text == "(" && (len(element) < 3 || element[2].Kind != sqlTokenNumber) {
+				continue
+			}
+			refs = append(refs, sqlColumnRef{Name: sqlIdentifierName(element[0]), Token: element[0]})
+		}
+		break
+	}
+	return refs
+}
+
+func sqlPredicateColumns(tokens []sqlToken) []sqlColumnRef {
+	refs := make([]sqlColumnRef, 0)
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		if token.Kind == sqlTokenOperator && token.Text == "(" && i+1 < len(tokens) && (tokens[i+1].Upper == "SELECT" || tokens[i+1].Upper == "WITH") {
+			i, _ = matchingSQLParen(tokens, i)
+			continue
+		}
+		if token.Kind != sqlTokenWord && token.Kind != sqlTokenQuotedIdent {
+			continue
+		}
+		if i > 0 && tokens[i-1].Kind == sqlTokenOperator && tokens[i-1].Text == "." {
+			continue
+		}
+
+		parts := []string{sqlIdentifierName(token)}
+		j := i + 1
+		for j+1 < len(tokens) && tokens[j].Kind == sqlTokenOperator && tokens[j].Text == "." &&
+			(tokens[j+1].Kind == sqlTokenWord || tokens[j+1].Kind == sqlTokenQuotedIdent) {
+			parts = append(parts, sqlIdentifierName(tokens[j+1]))
+			j += 2
+		}
+		if j >= len(tokens) {
+			break
+		}
+
+		operator := tokens[j].Text
+		if tokens[j].Kind == sqlTokenWord {
+			operator = tokens[j].Upper
+		}
+		if _, found := sqlPredicateOperators[operator]; !found || (tokens[j].Kind != sqlTokenOperator && tokens[j].Kind != sqlTokenWord) {
+			continue
+		}
+		if operator == "NOT" {
+			if j+1 >= len(tokens) {
+				continue
+			}
+			switch tokens[j+1].Upper {
+			case "IN", "LIKE", "ILIKE", "BETWEEN", "REGEXP", "RLIKE":
+				operator = "NOT " + tokens[j+1].Upper
+			default:
+				continue
+			}
+		}
+		if _, skip := sqlNonColumnWords[token.Upper]; skip && len(parts) == 1 && token.Kind == sqlTokenWord {
+			continue
+		}
+
+		located := token
+		located.End = tokens[j-1].End
+		ref := sqlColumnRef{Name: parts[len(parts)-1], Operator: operator, Token: located}
+		if len(parts) > 1 {
+			ref.Qualifier = parts[len(parts)-2]
+		}
+		refs = append(refs, ref)
+		i = j - 1
+	}
+	return refs
+}
+
+func uniqueFold(values []string) []string {
+	seen := make(map[string]struct{}, len(values))
+	unique := make([]string, 0, len(values))
+	for _, value := range values {
+		key := strings.ToLower(value)
+		if _, found := seen[key]; found {
+			continue
+		}
+		seen[key] = struct{}{}
+		unique = append(unique, value)
+	}
+	return unique
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testMySQLSchema = `
+CREATE TABLE users (
+  id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  email VARCHAR(128) NOT NULL,
+  status TINYINT NOT NULL DEFAULT 0,
+  PRIMARY KEY (id),
+  UNIQUE KEY uk_email (email)
+);
+CREATE TABLE orders (
+  id BIGINT NOT NULL,
+  user_id BIGINT NOT NULL,
+  amount DECIMAL(10,2),
+  created_at DATETIME,
+  PRIMARY KEY (id),
+  KEY idx_user_created (user_id, created_at)
+);
+`
+
+func issueRules(issues []Issue) map[string]int {
+	rules := make(map[string]int)
+	for _, issue := range issues {
+		rules[issue.Rule]++
+	}
+	return rules
+}
+
+func TestBuildSchemaCatalogParsesTables(t *testing.T) {
+	catalog, err := buildSchemaCatalog(EngineMySQL, testMySQLSchema)
+	if err != nil {
+		t.Fatalf("build catalog err: %v", err)
+	}
+	users, found := catalog.table("shop.USERS")
+	if !found {
+		t.Fatalf("expected users table, got %+v", catalog.Tables())
+	}
+	if column, _ := users.column("id"); column == nil || column.Type != "BIGINT UNSIGNED" || column.Nullable {
+		t.Fatalf("unexpected id column: %+v", column)
+	}
+	orders, _ := catalog.table("orders")
+	if column, _ := orders.column("amount"); column == nil || column.Type != "DECIMAL(10,2)" || !column.Nullable {
+		t.Fatalf("unexpected amount column: %+v", column)
+	}
+	if !orders.hasIndexPrefix([]string{"user_id"}) || orders.hasIndexPrefix([]string{"created_at"}) {
+		t.Fatalf("expected leftmost-prefix matching on idx_user_created, got %+v", orders.Indexes)
+	}
+
+	if _, err := buildSchemaCatalog(EngineMySQL, "SELECT 1;"); err == nil {
+		t.Fatalf("expected error for schema without CREATE TABLE")
+	}
+}
+
+func TestAnalyzeWithCatalogReportsUnknownObjects(t *testing.T) {
+	catalog, err := buildSchemaCatalog(EngineMySQL, testMySQLSchema)
+	if err != nil {
+		t.Fatalf("build catalog err: %v", err)
+	}
+	script := strings.Join([]string{
+		"SELECT u.id FROM users u WHERE u.nickname = 'x' AND u.status = 1 LIMIT 10;",
+		"SELECT id FROM user_profiles LIMIT 1;",
+		"ALTER TABLE users DROP COLUMN status;",
+		"SELECT id FROM users WHERE status = 1 LIMIT 1;",
+		"ALTER TABLE users ADD COLUMN nickname VARCHAR(32);",
+		"SELECT id FROM users WHERE nickname = 'x' LIMIT 1;",
+		"WITH recent AS (SELECT id FROM orders LIMIT 5) SELECT id FROM recent LIMIT 5;",
+		"ALTER TABLE orders DROP COLUMN amount;",
+		"SELECT amount FROM orders LIMIT 1;",
+		"SELECT id, nope FROM users LIMIT 1;",
+		"SELECT o.id, COUNT(*) AS total FROM orders o JOIN users u ON u.id = o.owner_id GROUP BY o.id ORDER BY total LIMIT 5;",
+		"SELECT email AS mail, id uid, CURRENT_DATE FROM users GROUP BY email ORDER BY mail LIMIT 5;",
+		"SELECT id FROM users GROUP BY id ORDER BY score LIMIT 5;",
+		"SELECT x.id, x.nope FROM (SELECT id FROM users) x LIMIT 5;",
+	}, "\n")
+
+	result := AnalyzeByEngine(EngineMySQL, script, AnalyzeOptions{Catalog: catalog})
+	byStatement := make(map[int][]string)
+	for _, issue := range result.Issues {
+		if issue.Rule == "unknown_table" || issue.Rule == "unknown_column" {
+			byStatement[issue.StatementIndex] = append(byStatement[issue.StatementIndex], issue.Rule)
+		}
+	}
+	expected := map[int]string{1: "unknown_column", 2: "unknown_table", 4: "unknown_column", 9: "unknown_column", 10: "unknown_column", 11: "unknown_column", 13: "unknown_column"}
+	for index, rule := range expected {
+		if len(byStatement[index]) != 1 || byStatement[index][0] != rule {
+			t.Fatalf("expected %s on statement %d, got %+v", rule, index, byStatement)
+		}
+	}
+	if len(byStatement) != len(expected) {
+		t.Fatalf("unexpected catalog issues: %+v", byStatement)
+	}
+
+	plain := AnalyzeByEngine(EngineMySQL, script, AnalyzeOptions{})
+	if rules := issueRules(plain.Issues); rules["unknown_table"] > 0 || rules["unknown_column"] > 0 {
+		t.Fatalf("catalog rules should be silent without a schema, got %+v", rules)
+	}
+}
+
+func TestWritePredicateNotIndexedUsesLeftmostPrefix(t *testing.T) {
+	catalog, err := buildSchemaCatalog(EngineMySQL, testMySQLSchema)
+	if err != nil {
+		t.Fatalf("build catalog err: %v", err)
+	}
+	script := strings.Join([]string{
+		"UPDATE orders SET amount = 0 WHERE user_id = 1 AND created_at < NOW();",
+		"DELETE FROM orders WHERE created_at < NOW();",
+		"UPDATE users SET status = 2 WHERE email = 'a@b.c';",
+		"CREATE INDEX idx_created ON orders (created_at);",
+		"DELETE FROM orders WHERE created_at < NOW();",
+	}, "\n")
+
+	result := AnalyzeByEngine(EngineMySQL, script, AnalyzeOptions{Catalog: catalog})
+	flagged := make([]int, 0)
+	for _, issue := range result.Issues {
+		if issue.Rule == "write_predicate_not_indexed" {
+			flagged = append(flagged, issue.StatementIndex)
+		}
+	}
+	if len(flagged) != 1 || flagged[0] != 2 {
+		t.Fatalf("expected only statement 2 to be flagged, got %v", flagged)
+	}
+}
+
+func TestAnalyzePostgresWithCatalog(t *testing.T) {
+	schema := `CREATE TABLE public.accounts (
+  id bigserial PRIMARY KEY,
+  owner character varying(64) NOT NULL,
+  balance numeric(12,2)
+);`
+	catalog, err := buildSchemaCatalog(EnginePostgreSQL, schema)
+	if err != nil {
+		t.Fatalf("build catalog err: %v", err)
+	}
+	accounts, _ := catalog.table("accounts")
+	if column, _ := accounts.column("owner"); column == nil || column.Type != "CHARACTER VARYING(64)" {
+		t.Fatalf("unexpected owner column: %+v", column)
+	}
+
+	script := "UPDATE accounts SET balance = 0 WHERE owner = 'x';\nSELECT id FROM accounts WHERE missing IS NULL LIMIT 1;\nSELECT id FROM ledger LIMIT 1;\n"
+	rules := issueRules(AnalyzeByEngine(EnginePostgreSQL, script, AnalyzeOptions{Catalog: catalog}).Issues)
+	for _, code := range []string{"pg_write_predicate_not_indexed", "pg_unknown_column", "pg_unknown_table"} {
+		if rules[code] != 1 {
+			t.Fatalf("expected one %s issue, got %+v", code, rules)
+		}
+	}
+}
+
+func TestRunLintCommandWithSchema(t *testing.T) {
+	dir := t.TempDir()
+	schemaPath := filepath.Join(dir, "schema.sql")
+	if err := os.WriteFile(schemaPath, []byte(testMySQLSchema), 0o644); err != nil {
+		t.Fatalf("write schema err: %v", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	code := runLintCommand([]string{"--schema", schemaPath, "-"}, strings.NewReader("DELETE FROM users WHERE nickname = 'x';\n"), &stdout, &stderr)
+	if code != lintExitThreshold || !strings.Contains(stdout.String(), "unknown_column") {
+		t.Fatalf("expected unknown_column to fail lint, got code=%d stdout=%s stderr=%s", code, stdout.String(), stderr.String())
+	}
+
+	code = runLintCommand([]string{"--engine", "mongodb", "--schema", schemaPath, "-"}, strings.NewReader("db.users.find({})"), &stdout, &stderr)
+	if code != lintExitUsageError {
+		t.Fatalf("expected usage error for mongodb schema, got %d", code)
+	}
+}
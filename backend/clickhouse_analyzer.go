@@ -0,0 +1,293 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+var clickhouseTableOptionWords = map[string]struct{}{
+	"ENGINE": {}, "PARTITION": {}, "ORDER": {}, "PRIMARY": {}, "SAMPLE": {}, "TTL": {}, "SETTINGS": {}, "COMMENT": {}, "AS": {},
+}
+
+func BuiltInClickHouseRules() []RuleDefinition {
+	return ruleDefinitions(RegisteredRules(EngineClickHouse))
+}
+
+func clickhouseRules() []Rule {
+	return []Rule{
+		emptyInputRule("SQL 内容为空", "请上传 ClickHouse SQL 文件或粘贴语句后再检查"),
+		tooManyStatementsRule(60, "建议按业务模块拆分后分批审核与执行"),
+		sqlMissingTerminatorRule(),
+		sqlFullwidthTerminatorRule(),
+		newStatementRule(
+			RuleDefinition{Code: "ch_dangerous_drop", Level: LevelError, Category: "高危DDL", Description: "检测到 DROP 高危对象删除"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				return Issue{Message: "检测到 DROP 高风险语句", Suggestion: "生产建议禁用 DROP；确需执行请先 FREEZE 备份并审批"}, stmt.SQL.isDangerousDrop()
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "ch_dangerous_truncate", Level: LevelError, Category: "高危DDL", Description: "检测到 TRUNCATE 全表清理"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				return Issue{Message: "检测到 TRUNCATE 语句", Suggestion: "TRUNCATE 会删除全部数据片段且无法回滚，请确认恢复方案"}, stmt.SQL.Kind == sqlStmtTruncate
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "ch_alter_mutation", Level: LevelWarning, Category: "数据变更", Description: "ALTER TABLE ... DELETE / UPDATE 触发 mutation 重写"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				clause, found := stmt.SQL.findClickHouseAlterClause("DELETE", "UPDATE")
+				if !found {
+					return Issue{}, false
+				}
+				return ctx.Source.locate(Issue{
+					Message:    fmt.Sprintf("ALTER TABLE ... %s 是 mutation，会在后台异步重写所有涉及的数据片段（part），期间占用大量 IO 与磁盘空间且无法回滚", clause[0].Upper),
+					Suggestion: "优先按分区 DROP PARTITION，或使用轻量删除 DELETE FROM（22.8+）；必须执行时用 IN PARTITION 限定范围，并通过 system.mutations 监控进度",
+				}, clause[0].span()), true
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "ch_drop_partition", Level: LevelError, Category: "高危DDL", Description: "ALTER TABLE ... DROP PARTITION / PART 删除分区数据"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				clause, found := stmt.SQL.findClickHouseAlterClause("DROP")
+				if !found || len(clause) < 2 || (clause[1].Upper != "PARTITION" && clause[1].Upper != "PART") {
+					return Issue{}, false
+				}
+				return ctx.Source.locate(Issue{
+					Message:    fmt.Sprintf("ALTER TABLE ... DROP %s 会立即删除整个分区的数据且不可回滚", clause[1].Upper),
+					Suggestion: "确认分区表达式无误；可先 FREEZE PARTITION 备份，或改用 DETACH PARTITION 观察后再删除",
+				}, clause[0].span()), true
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "ch_missing_on_cluster", Level: LevelWarning, Category: "集群DDL", Description: "复制表 / 分布式表的 DDL 未指定 ON CLUSTER"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				if len(stmt.SQL.Tables) == 0 || stmt.SQL.Tables[0].Name == "" {
+					return Issue{}, false
+				}
+				switch stmt.SQL.Kind {
+				case sqlStmtCreateTable, sqlStmtAlterTable, sqlStmtDrop, sqlStmtTruncate:
+				default:
+					return Issue{}, false
+				}
+				if _, found := stmt.SQL.findTokenSequence("ON", "CLUSTER"); found {
+					return Issue{}, false
+				}
+				table := stmt.SQL.Tables[0]
+				engine := stmt.SQL.clickhouseTableEngine()
+				if stmt.SQL.Kind != sqlStmtCreateTable {
+					engine = clickhouseKnownTableEngine(ctx, stmt, table.Name)
+				}
+				if !isClickHouseClusterEngine(engine) {
+					return Issue{}, false
+				}
+				return ctx.Source.locate(Issue{
+					Message:    fmt.Sprintf("表 %s 使用 %s 引擎，但 DDL 未指定 ON CLUSTER，只会在当前节点（分片）执行，集群各节点结构将不一致", table.Name, engine),
+					Suggestion: "补充 ON CLUSTER <集群名>，通过分布式 DDL 在所有节点执行",
+				}, table.Token.span()), true
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "ch_optimize_final", Level: LevelWarning, Category: "资源消耗", Description: "OPTIMIZE TABLE ... FINAL 强制全量合并"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				if stmt.SQL.Kind != sqlStmtOther || stmt.SQL.Tokens[0].Upper != "OPTIMIZE" {
+					return Issue{}, false
+				}
+				token, found := stmt.SQL.findTokenSequence("FINAL")
+				return ctx.Source.locate(Issue{
+					Message:    "OPTIMIZE ... FINAL 会强制把数据片段合并为一个，大表上耗时很长并占用大量 IO、CPU 与临时磁盘空间",
+					Suggestion: "依赖后台自动合并；确需执行时指定 PARTITION 并在低峰期运行",
+				}, token.span()), found
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "ch_select_final", Level: LevelWarning, Category: "查询性能", Description: "查询使用 FINAL 修饰符"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				token, found := stmt.SQL.findClickHouseSelectFinal()
+				return ctx.Source.locate(Issue{
+					Message:    "SELECT ... FINAL 会在查询时合并数据片段，高频查询下 CPU 与延迟显著上升",
+					Suggestion: "改用 argMax / GROUP BY 去重，或限定分区范围并开启 do_not_merge_across_partitions_select_final",
+				}, token.span()), found
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "ch_select_without_partition_filter", Level: LevelWarning, Category: "查询性能", Description: "查询未按分区键过滤（需结构快照）"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				if stmt.Catalog == nil {
+					return Issue{}, false
+				}
+				issue, found := Issue{}, false
+				stmt.SQL.walkSelects(func(sel *sqlStatement) {
+					if found {
+						return
+					}
+					filters := sel.clickhouseFilterColumns()
+					for _, ref := range sel.Tables {
+						table, exists := stmt.Catalog.table(ref.Name)
+						if !exists || len(table.PartitionKey) == 0 || clickhouseFiltersAny(filters, table.PartitionKey) {
+							continue
+						}
+						issue = ctx.Source.locate(Issue{
+							Message:    fmt.Sprintf("查询表 %s 未按分区键（%s）过滤，将扫描全部分区", ref.Name, strings.Join(table.PartitionKey, ", ")),
+							Suggestion: "在 WHERE / PREWHERE 中加入分区键条件（如时间范围），让查询只读取相关分区",
+						}, ref.Token.span())
+						found = true
+						return
+					}
+				})
+				return issue, found
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "ch_select_star", Level: LevelWarning, Category: "查询规范", Description: "SELECT * 读取全部列"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				token, found := stmt.SQL.findSelectStar()
+				return ctx.Source.locate(Issue{Message: "列式存储下 SELECT * 会读取并解压所有列，代价远高于行存", Suggestion: "只选择需要的列"}, token.span()), found
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "ch_select_without_limit", Level: LevelInfo, Category: "查询规范", Description: "SELECT 未设置 LIMIT"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				return Issue{Message: "SELECT 未检测到 LIMIT", Suggestion: "明细查询建议补充 LIMIT，避免返回超大结果集"}, stmt.SQL.Kind == sqlStmtSelect && stmt.SQL.From != nil && !stmt.SQL.hasLimit()
+			},
+		),
+		engineDetectionAmbiguousRule(),
+		invalidSuppressionRule(),
+	}
+}
+
+func AnalyzeClickHouseWithOptions(content string, options AnalyzeOptions) CheckResponse {
+	return analyzeWithRules(newSQLRuleContext(EngineClickHouse, content, clickhouseDialect), options, "请输入待审核 SQL 后重试")
+}
+
+func (stmt *sqlStatement) findClickHouseAlterClause(words ...string) ([]sqlToken, bool) {
+	for _, clause := range stmt.AlterClauses {
+		for _, word := range words {
+			if len(clause) > 0 && clause[0].Upper == word {
+				return clause, true
+			}
+		}
+	}
+	return nil, false
+}
+
+func (stmt *sqlStatement) clickhouseTableEngine() string {
+	if stmt.Kind != sqlStmtCreateTable || len(stmt.Tokens) == 0 {
+		return ""
+	}
+	base := stmt.Tokens[0].Depth
+	for i := 0; i+2 < len(stmt.Tokens); i++ {
+		if stmt.Tokens[i].Depth == base && stmt.Tokens[i].Upper == "ENGINE" && stmt.Tokens[i+1].Text == "=" {
+			return stmt.Tokens[i+2].Text
+		}
+	}
+	return ""
+}
+
+func clickhouseKnownTableEngine(ctx *RuleContext, stmt RuleStatement, name string) string {
+	if stmt.Catalog != nil {
+		if table, found := stmt.Catalog.table(name); found && table.TableEngine != "" {
+			return table.TableEngine
+		}
+	}
+	engine := ""
+	for _, prev := range ctx.Statements {
+		if prev.Index >= stmt.Index {
+			break
+		}
+		if prev.SQL.Kind == sqlStmtCreateTable && len(prev.SQL.Tables) > 0 && catalogKey(prev.SQL.Tables[0].Name) == catalogKey(name) {
+			engine = prev.SQL.clickhouseTableEngine()
+		}
+	}
+	return engine
+}
+
+func isClickHouseClusterEngine(engine string) bool {
+	return strings.HasPrefix(engine, "Replicated") || engine == "Distributed"
+}
+
+func (stmt *sqlStatement) findClickHouseSelectFinal() (sqlToken, bool) {
+	var found sqlToken
+	ok := false
+	stmt.walkSelects(func(sel *sqlStatement) {
+		if ok || sel.From == nil {
+			return
+		}
+		for _, token := range sel.From.Tokens {
+			if token.Kind == sqlTokenWord && token.Upper == "FINAL" && token.Depth == sel.From.Keyword.Depth {
+				found, ok = token, true
+				return
+			}
+		}
+	})
+	return found, ok
+}
+
+func (stmt *sqlStatement) clickhouseFilterColumns() map[string]struct{} {
+	columns := make(map[string]struct{})
+	if len(stmt.Tokens) == 0 {
+		return columns
+	}
+	p := &sqlParser{tokens: stmt.Tokens, base: stmt.Tokens[0].Depth}
+	for _, keyword := range []string{"PREWHERE", "WHERE"} {
+		start := p.findTop(1, len(stmt.Tokens), keyword)
+		if start < 0 {
+			continue
+		}
+		for _, token := range stmt.Tokens[start+1 : p.nextClauseEnd(start+1, sqlSelectClauseKeywords)] {
+			if token.Kind == sqlTokenWord || token.Kind == sqlTokenQuotedIdent {
+				columns[strings.ToLower(sqlIdentifierName(token))] = struct{}{}
+			}
+		}
+	}
+	return columns
+}
+
+func clickhouseFiltersAny(filters map[string]struct{}, columns []string) bool {
+	for _, column := range columns {
+		if _, found := filters[strings.ToLower(column)]; found {
+			return true
+		}
+	}
+	return false
+}
+
+func (table *SchemaTable) applyClickHouseOptions(tokens []sqlToken, depth int) {
+	for i := 0; i < len(tokens); i++ {
+		if tokens[i].Depth != depth || tokens[i].Kind != sqlTokenWord {
+			continue
+		}
+		switch {
+		case tokens[i].Upper == "ENGINE" && i+2 < len(tokens) && tokens[i+1].Text == "=":
+			table.TableEngine = tokens[i+2].Text
+		case tokens[i].Upper == "PARTITION" && i+1 < len(tokens) && tokens[i+1].Upper == "BY":
+			table.PartitionKey = table.clickhouseKeyColumns(tokens[i+2:], depth)
+		case tokens[i].Upper == "PRIMARY" && i+1 < len(tokens) && tokens[i+1].Upper == "KEY":
+			table.setPrimaryKey(table.clickhouseKeyColumns(tokens[i+2:], depth))
+		case tokens[i].Upper == "ORDER" && i+1 < len(tokens) && tokens[i+1].Upper == "BY" && len(table.PrimaryKey) == 0:
+			table.setPrimaryKey(table.clickhouseKeyColumns(tokens[i+2:], depth))
+		}
+	}
+}
+
+func (table *SchemaTable) clickhouseKeyColumns(tokens []sqlToken, depth int) []string {
+	columns := make([]string, 0)
+	seen := make(map[string]struct{})
+	for _, token := range tokens {
+		if token.Depth == depth && token.Kind == sqlTokenWord {
+			if _, stop := clickhouseTableOptionWords[token.Upper]; stop {
+				break
+			}
+		}
+		if token.Kind != sqlTokenWord && token.Kind != sqlTokenQuotedIdent {
+			continue
+		}
+		column, found := table.column(sqlIdentifierName(token))
+		if !found {
+			continue
+		}
+		if _, duplicate := seen[strings.ToLower(column.Name)]; !duplicate {
+			seen[strings.ToLower(column.Name)] = struct{}{}
+			columns = append(columns, column.Name)
+		}
+	}
+	return columns
+}
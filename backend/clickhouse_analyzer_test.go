@@ -0,0 +1,110 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestBuildClickHouseCatalogParsesTableOptions(t *testing.T) {
+	schema := strings.Join([]string{
+		"CREATE TABLE events ON CLUSTER main (",
+		"  event_date Date,",
+		"  user_id UInt64 CODEC(ZSTD(1)),",
+		"  kind LowCardinality(String) DEFAULT 'view',",
+		"  payload String TTL event_date + INTERVAL 30 DAY",
+		") ENGINE = ReplicatedMergeTree('/clickhouse/tables/{shard}/events', '{replica}')",
+		"PARTITION BY toYYYYMM(event_date)",
+		"ORDER BY (user_id, event_date)",
+		"SETTINGS index_granularity = 8192;",
+	}, "\n")
+	catalog, err := buildSchemaCatalog(EngineClickHouse, schema)
+	if err != nil {
+		t.Fatalf("build catalog err: %v", err)
+	}
+	events, found := catalog.table("events")
+	if !found || len(events.Columns) != 4 {
+		t.Fatalf("unexpected events table: %+v", events)
+	}
+	if events.TableEngine != "ReplicatedMergeTree" || !slices.Equal(events.PartitionKey, []string{"event_date"}) || !slices.Equal(events.PrimaryKey, []string{"user_id", "event_date"}) {
+		t.Fatalf("unexpected table options: engine=%s partition=%v primary=%v", events.TableEngine, events.PartitionKey, events.PrimaryKey)
+	}
+}
+
+func TestBuildClickHouseCatalogToleratesTruncatedCreateTable(t *testing.T) {
+	catalog, err := buildSchemaCatalog(EngineClickHouse, "CREATE TABLE logs (d Date")
+	if err != nil {
+		t.Fatalf("build catalog err: %v", err)
+	}
+	if logs, found := catalog.table("logs"); !found || !logs.Opaque {
+		t.Fatalf("truncated table should be opaque: %+v", logs)
+	}
+
+	schema := "CREATE TABLE events (d Date, id UInt64) ENGINE = MergeTree PARTITION BY toYYYYMM(d) ORDER BY id;"
+	for end := 1; end <= len(schema); end++ {
+		_, _ = buildSchemaCatalog(EngineClickHouse, schema[:end])
+	}
+	AnalyzeByEngine(EngineClickHouse, "CREATE TABLE logs (d Date", AnalyzeOptions{Catalog: catalog})
+}
+
+func TestAnalyzeClickHouseRules(t *testing.T) {
+	script := strings.Join([]string{
+		"CREATE TABLE logs (d Date, msg String) ENGINE = ReplicatedMergeTree ORDER BY d;",
+		"CREATE TABLE logs_local ON CLUSTER main (d Date) ENGINE = ReplicatedMergeTree ORDER BY d;",
+		"ALTER TABLE logs ON CLUSTER main DELETE WHERE d < '2024-01-01';",
+		"ALTER TABLE logs UPDATE msg = '' WHERE 1 = 1;",
+		"ALTER TABLE metrics DROP PARTITION 202401;",
+		"OPTIMIZE TABLE metrics FINAL;",
+		"SELECT d, msg FROM logs FINAL WHERE d = today() LIMIT 10;",
+		"SELECT * FROM metrics;",
+		"TRUNCATE TABLE IF EXISTS metrics;",
+		"DROP TABLE logs ON CLUSTER main;",
+	}, "\n")
+
+	issues := AnalyzeByEngine(EngineClickHouse, script, AnalyzeOptions{}).Issues
+	rules := issuesByStatement(issues)
+	rules.expect(t, 1, "ch_missing_on_cluster", true)
+	rules.expect(t, 2, "ch_missing_on_cluster", false)
+	rules.expect(t, 3, "ch_alter_mutation", true)
+	rules.expect(t, 3, "ch_missing_on_cluster", false)
+	rules.expect(t, 4, "ch_alter_mutation", true)
+	rules.expect(t, 4, "ch_missing_on_cluster", true)
+	rules.expect(t, 5, "ch_drop_partition", true)
+	rules.expect(t, 5, "ch_missing_on_cluster", false)
+	rules.expect(t, 6, "ch_optimize_final", true)
+	rules.expect(t, 7, "ch_select_final", true)
+	rules.expect(t, 7, "ch_select_without_limit", false)
+	rules.expect(t, 8, "ch_select_star", true)
+	rules.expect(t, 8, "ch_select_without_limit", true)
+	rules.expect(t, 9, "ch_dangerous_truncate", true)
+	rules.expect(t, 10, "ch_dangerous_drop", true)
+	rules.expect(t, 10, "ch_missing_on_cluster", false)
+}
+
+func TestAnalyzeClickHouseWithCatalogChecksPartitionFilter(t *testing.T) {
+	catalog, err := buildSchemaCatalog(EngineClickHouse, "CREATE TABLE hits (event_date Date, url String) ENGINE = MergeTree PARTITION BY toYYYYMM(event_date) ORDER BY url;")
+	if err != nil {
+		t.Fatalf("build catalog err: %v", err)
+	}
+	script := strings.Join([]string{
+		"SELECT url FROM hits WHERE url = 'a' LIMIT 1;",
+		"SELECT url FROM hits PREWHERE event_date >= '2024-01-01' LIMIT 1;",
+		"SELECT count() FROM (SELECT url FROM hits WHERE event_date = today());",
+	}, "\n")
+	flagged := make([]int, 0)
+	for _, issue := range AnalyzeByEngine(EngineClickHouse, script, AnalyzeOptions{Catalog: catalog}).Issues {
+		if issue.Rule == "ch_select_without_partition_filter" {
+			flagged = append(flagged, issue.StatementIndex)
+		}
+	}
+	if !slices.Equal(flagged, []int{1}) {
+		t.Fatalf("expected only statement 1 to be flagged, got %v", flagged)
+	}
+}
+
+func TestDetectEngineClickHouse(t *testing.T) {
+	detection := DetectEngine("CREATE TABLE t ON CLUSTER main (id UInt64) ENGINE = MergeTree ORDER BY id;", "")
+	if detection.Engine != EngineClickHouse || detection.Ambiguous {
+		t.Fatalf("expected clickhouse detection, got %+v", detection)
+	}
+}
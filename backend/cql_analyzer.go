@@ -0,0 +1,381 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+func BuiltInCQLRules() []RuleDefinition {
+	return ruleDefinitions(RegisteredRules(EngineCQL))
+}
+
+func cqlRules() []Rule {
+	return []Rule{
+		emptyInputRule("CQL 内容为空", "请上传 CQL 迁移脚本或粘贴语句后再检查"),
+		tooManyStatementsRule(60, "建议按业务模块拆分后分批审核与执行"),
+		sqlMissingTerminatorRule(),
+		sqlFullwidthTerminatorRule(),
+		newStatementRule(
+			RuleDefinition{Code: "cql_dangerous_drop", Level: LevelError, Category: "高危DDL", Description: "DROP KEYSPACE / TABLE 删除数据"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				if stmt.SQL.Kind != sqlStmtDrop {
+					return Issue{}, false
+				}
+				switch stmt.SQL.ObjectType {
+				case "KEYSPACE", "SCHEMA":
+					return Issue{
+						Message:    "检测到 DROP KEYSPACE，键空间下的所有表、类型与函数将被一并删除",
+						Suggestion: "生产禁止删除键空间；确需执行请确认 auto_snapshot 已开启或先在每个节点执行 nodetool snapshot",
+					}, true
+				case "TABLE", "COLUMNFAMILY", "MATERIALIZED":
+					return Issue{
+						Message:    fmt.Sprintf("检测到 DROP %s，数据将从所有副本删除", stmt.SQL.ObjectType),
+						Suggestion: "执行前先 nodetool snapshot 备份，并确认没有应用仍在读写该表",
+					}, true
+				}
+				return Issue{}, false
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "cql_dangerous_truncate", Level: LevelError, Category: "高危DDL", Description: "TRUNCATE 清空全表"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				return Issue{
+					Message:    "检测到 TRUNCATE，所有副本上的数据将被清空，且要求集群全部节点在线",
+					Suggestion: "确认集群无宕机节点并先做快照；按分区清理请改用带分区键的 DELETE",
+				}, stmt.SQL.Kind == sqlStmtTruncate
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "cql_allow_filtering", Level: LevelWarning, Category: "查询性能", Description: "查询使用 ALLOW FILTERING"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				token, found := stmt.SQL.findTokenSequence("ALLOW", "FILTERING")
+				return ctx.Source.locate(Issue{
+					Message:    "ALLOW FILTERING 允许协调节点扫描并在内存中过滤数据，数据量增长后延迟不可控，可能导致超时",
+					Suggestion: "按查询模式建表（把过滤列设计进分区键或聚簇键），或改用二级索引 / SAI",
+				}, token.span()), found
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "cql_select_without_partition_key", Level: LevelWarning, Category: "查询性能", Description: "SELECT 未限定完整分区键（需结构快照）"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				if stmt.Catalog == nil || stmt.SQL.Kind != sqlStmtSelect || len(stmt.SQL.Tables) == 0 {
+					return Issue{}, false
+				}
+				ref := stmt.SQL.Tables[0]
+				table, found := stmt.Catalog.table(ref.Name)
+				if !found || len(table.PartitionKey) == 0 {
+					return Issue{}, false
+				}
+				restricted := stmt.SQL.cqlRestrictedColumns()
+				missing := make([]string, 0)
+				for _, column := range table.PartitionKey {
+					if _, found := restricted[strings.ToLower(column)]; !found {
+						missing = append(missing, column)
+					}
+				}
+				if len(missing) == 0 {
+					return Issue{}, false
+				}
+				return ctx.Source.locate(Issue{
+					Message:    fmt.Sprintf("查询表 %s 未用 = / IN 限定分区键列 %s，将扫描整个集群的所有分区", ref.Name, strings.Join(missing, ", ")),
+					Suggestion: fmt.Sprintf("WHERE 中需包含完整分区键（%s），或为该查询模式单独建表", strings.Join(table.PartitionKey, ", ")),
+				}, ref.Token.span()), true
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "cql_multi_partition_batch", Level: LevelWarning, Category: "写入性能", Description: "BATCH 写入跨多个分区"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				batch, kind, found := stmt.SQL.cqlBatch()
+				if !found {
+					return Issue{}, false
+				}
+				partitions := make([]string, 0)
+				seen := make(map[string]struct{})
+				for _, item := range batch {
+					key := cqlPartitionIdentity(item, stmt.Catalog)
+					if _, duplicate := seen[key]; key != "" && !duplicate {
+						seen[key] = struct{}{}
+						partitions = append(partitions, key)
+					}
+				}
+				if len(partitions) < 2 {
+					return Issue{}, false
+				}
+				message := fmt.Sprintf("BATCH 涉及 %d 个分区（%s），logged batch 需先写 batchlog 再由协调节点分发，跨分区时开销远高于单条写入", len(partitions), strings.Join(partitions, "; "))
+				if kind == "UNLOGGED" {
+					message = fmt.Sprintf("UNLOGGED BATCH 涉及 %d 个分区（%s），既不保证原子性，又把所有写入集中到一个协调节点", len(partitions), strings.Join(partitions, "; "))
+				}
+				return Issue{
+					Message:    message,
+					Suggestion: "BATCH 只用于同一分区内的原子写入或多张反范式表的一致性写入；批量导入请改为并发的单条异步写入",
+				}, true
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "cql_alter_drop_column", Level: LevelWarning, Category: "DDL兼容", Description: "ALTER TABLE ... DROP 删除列"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				for _, clause := range stmt.SQL.AlterClauses {
+					if len(clause) > 0 && clause[0].Upper == "DROP" {
+						return ctx.Source.locate(Issue{
+							Message:    "ALTER TABLE ... DROP 删除列后，仍读写该列的应用会立即报错；列数据在 compaction 前仍占用磁盘，且不能再以不同类型添加同名列",
+							Suggestion: "先下线应用中对该列的读写并完成发布，再执行删除；需要回滚时请使用原类型重新添加",
+						}, clause[0].span()), true
+					}
+				}
+				return Issue{}, false
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "cql_lightweight_transaction", Level: LevelWarning, Category: "写入性能", Description: "DML 使用轻量级事务（IF / IF NOT EXISTS）"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				statements := []*sqlStatement{stmt.SQL}
+				if batch, _, found := stmt.SQL.cqlBatch(); found {
+					statements = batch
+				}
+				for _, item := range statements {
+					if token, found := item.cqlConditionToken(); found {
+						return ctx.Source.locate(Issue{
+							Message:    fmt.Sprintf("%s ... IF 为轻量级事务，需要 Paxos 四轮往返并以 SERIAL 一致性读取，延迟与吞吐远差于普通写入，高频路径中容易出现 WriteTimeout", item.Tokens[0].Upper),
+							Suggestion: "仅在需要比较并设置语义时使用；高频写入应通过幂等主键设计避免 IF NOT EXISTS，且同一数据不要混用 LWT 与普通写入",
+						}, token.span()), true
+					}
+				}
+				return Issue{}, false
+			},
+		),
+		engineDetectionAmbiguousRule(),
+		invalidSuppressionRule(),
+	}
+}
+
+func AnalyzeCQLWithOptions(content string, options AnalyzeOptions) CheckResponse {
+	ctx := newSQLRuleContextFromSpans(EngineCQL, content, splitCQLStatementSpans(content), cqlDialect)
+	return analyzeWithRules(ctx, options, "请输入待审核 CQL 后重试")
+}
+
+func splitCQLStatementSpans(content string) []sqlStatementSpan {
+	items := make([]sqlStatementSpan, 0)
+	tokens := significantSQLTokens(tokenizeSQL(content, cqlDialect))
+	start, end := -1, 0
+	batch, applied := false, false
+
+	flush := func() {
+		if start >= 0 {
+			items = append(items, sqlStatementSpan{Text: content[start:end], Span: sourceSpan{Start: start, End: end}, Batch: batch})
+		}
+		start = -1
+	}
+
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		if token.Kind == sqlTokenOperator && (token.Text == ";" || token.Text == "；") && (!batch || applied) {
+			flush()
+			continue
+		}
+		if start < 0 {
+			start = token.Start
+			batch = isCQLBatchStart(tokens[i:min(i+3, len(tokens))])
+			applied = false
+		}
+		if batch && token.Upper == "APPLY" && i+1 < len(tokens) && tokens[i+1].Upper == "BATCH" {
+			applied = true
+		}
+		end = token.End
+	}
+
+	flush()
+	return items
+}
+
+func isCQLBatchStart(tokens []sqlToken) bool {
+	if len(tokens) < 2 || tokens[0].Upper != "BEGIN" {
+		return false
+	}
+	if tokens[1].Upper == "UNLOGGED" || tokens[1].Upper == "COUNTER" {
+		return len(tokens) > 2 && tokens[2].Upper == "BATCH"
+	}
+	return tokens[1].Upper == "BATCH"
+}
+
+func (stmt *sqlStatement) isCQLBatch() bool {
+	return isCQLBatchStart(stmt.Tokens[:min(3, len(stmt.Tokens))])
+}
+
+func (stmt *sqlStatement) cqlBatch() ([]*sqlStatement, string, bool) {
+	tokens := stmt.Tokens
+	if !stmt.isCQLBatch() {
+		return nil, "", false
+	}
+	kind, i := "LOGGED", 2
+	if tokens[1].Upper != "BATCH" {
+		kind, i = tokens[1].Upper, 3
+	}
+	if i+2 < len(tokens) && tokens[i].Upper == "USING" && tokens[i+1].Upper == "TIMESTAMP" {
+		i += 3
+	}
+
+	statements := make([]*sqlStatement, 0)
+	begin := i
+	for ; i <= len(tokens); i++ {
+		done := i == len(tokens) || (tokens[i].Upper == "APPLY" && i+1 < len(tokens) && tokens[i+1].Upper == "BATCH")
+		if done || (tokens[i].Kind == sqlTokenOperator && tokens[i].Text == ";") {
+			if i > begin {
+				statements = append(statements, parseSQLTokens(tokens[begin:i], cqlDialect))
+			}
+			begin = i + 1
+		}
+		if done {
+			break
+		}
+	}
+	return statements, kind, true
+}
+
+func (stmt *sqlStatement) cqlTargetTable() string {
+	if stmt.Kind == sqlStmtDelete && stmt.From != nil {
+		for i, token := range stmt.From.Tokens {
+			if token.Kind == sqlTokenWord || token.Kind == sqlTokenQuotedIdent {
+				p := &sqlParser{tokens: stmt.From.Tokens, base: token.Depth}
+				name, _, _ := p.readQualifiedName(i)
+				return name
+			}
+		}
+	}
+	if len(stmt.Tables) == 0 {
+		return ""
+	}
+	return stmt.Tables[0].Name
+}
+
+func cqlPartitionIdentity(stmt *sqlStatement, catalog *schemaCatalog) string {
+	name := stmt.cqlTargetTable()
+	if name == "" {
+		return ""
+	}
+	key := catalogKey(name)
+	if catalog == nil {
+		return key
+	}
+	table, found := catalog.table(name)
+	if !found || len(table.PartitionKey) == 0 {
+		return key
+	}
+	values := stmt.cqlColumnValues()
+	parts := []string{key}
+	for _, column := range table.PartitionKey {
+		value, found := values[strings.ToLower(column)]
+		if !found || value == "?" || strings.HasPrefix(value, ":") {
+			return key
+		}
+		parts = append(parts, column+"="+value)
+	}
+	return strings.Join(parts, " ")
+}
+
+func (stmt *sqlStatement) cqlColumnValues() map[string]string {
+	values := make(map[string]string)
+	if stmt.Kind == sqlStmtInsert {
+		for i, token := range stmt.Tokens {
+			if token.Upper != "VALUES" || i+1 >= len(stmt.Tokens) || stmt.Tokens[i+1].Text != "(" {
+				continue
+			}
+			closing, _ := matchingSQLParen(stmt.Tokens, i+1)
+			for j, element := range splitCQLTopLevel(stmt.Tokens[i+2:closing], stmt.Tokens[i+1].Depth+1) {
+				if j < len(stmt.Columns) {
+					values[strings.ToLower(stmt.Columns[j])] = joinSQLTokens(element)
+				}
+			}
+			break
+		}
+		return values
+	}
+	if stmt.Where == nil {
+		return values
+	}
+	tokens := stmt.Where.Tokens
+	for i := 0; i+2 < len(tokens); i++ {
+		if (tokens[i].Kind == sqlTokenWord || tokens[i].Kind == sqlTokenQuotedIdent) && tokens[i+1].Kind == sqlTokenOperator && tokens[i+1].Text == "=" {
+			values[strings.ToLower(sqlIdentifierName(tokens[i]))] = tokens[i+2].Text
+		}
+	}
+	return values
+}
+
+func (stmt *sqlStatement) cqlRestrictedColumns() map[string]struct{} {
+	columns := make(map[string]struct{})
+	if stmt.Where == nil {
+		return columns
+	}
+	tokens := stmt.Where.Tokens
+	for i := 0; i+1 < len(tokens); i++ {
+		if tokens[i].Kind != sqlTokenWord && tokens[i].Kind != sqlTokenQuotedIdent {
+			continue
+		}
+		if (tokens[i+1].Kind == sqlTokenOperator && tokens[i+1].Text == "=") || tokens[i+1].Upper == "IN" {
+			columns[strings.ToLower(sqlIdentifierName(tokens[i]))] = struct{}{}
+		}
+	}
+	return columns
+}
+
+func (stmt *sqlStatement) cqlConditionToken() (sqlToken, bool) {
+	switch stmt.Kind {
+	case sqlStmtInsert, sqlStmtUpdate, sqlStmtDelete:
+	default:
+		return sqlToken{}, false
+	}
+	for _, token := range stmt.Tokens {
+		if token.Kind == sqlTokenWord && token.Upper == "IF" && token.Depth == stmt.Tokens[0].Depth {
+			return token, true
+		}
+	}
+	return sqlToken{}, false
+}
+
+func splitCQLTopLevel(tokens []sqlToken, depth int) [][]sqlToken {
+	parts := make([][]sqlToken, 0)
+	start, nesting := 0, 0
+	for i, token := range tokens {
+		if token.Kind != sqlTokenOperator || token.Depth != depth {
+			continue
+		}
+		nesting += strings.Count(token.Text, "<") + strings.Count(token.Text, "{") + strings.Count(token.Text, "[")
+		nesting -= strings.Count(token.Text, ">") + strings.Count(token.Text, "}") + strings.Count(token.Text, "]")
+		if token.Text == "," && nesting <= 0 {
+			parts = append(parts, tokens[start:i])
+			start = i + 1
+		}
+	}
+	if start < len(tokens) {
+		parts = append(parts, tokens[start:])
+	}
+	return parts
+}
+
+func (table *SchemaTable) applyCQLPrimaryKey(element []sqlToken) {
+	for i, token := range element {
+		if token.Kind != sqlTokenOperator || token.Text != "(" {
+			continue
+		}
+		closing, _ := matchingSQLParen(element, i)
+		parts := splitSQLTokensTopLevel(element[i+1:closing], token.Depth+1)
+		if len(parts) == 0 || len(parts[0]) == 0 {
+			return
+		}
+		partition := make([]string, 0)
+		if parts[0][0].Text == "(" {
+			partition = sqlParenColumns(parts[0], 0)
+		} else {
+			partition = append(partition, sqlIdentifierName(parts[0][0]))
+		}
+		columns := append([]string(nil), partition...)
+		for _, part := range parts[1:] {
+			if len(part) > 0 {
+				columns = append(columns, sqlIdentifierName(part[0]))
+			}
+		}
+		table.setPrimaryKey(columns)
+		table.PartitionKey = partition
+		return
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSplitCQLStatementSpansKeepsBatchesAndLiterals(t *testing.T) {
+	script := strings.Join([]string{
+		"// seed data",
+		"INSERT INTO notes (id, body) VALUES (1, 'it''s; fine');",
+		"CREATE FUNCTION ks.f (x int) RETURNS NULL ON NULL INPUT RETURNS int LANGUAGE java AS $$ return x; $$;",
+		"BEGIN BATCH",
+		"  INSERT INTO a (id) VALUES (1);",
+		"  UPDATE b SET v = 2 WHERE id = 1;",
+		"APPLY BATCH;",
+	}, "\n")
+	spans := splitCQLStatementSpans(script)
+	if len(spans) != 3 {
+		t.Fatalf("expected 3 statements, got %d: %+v", len(spans), spans)
+	}
+	if !strings.HasSuffix(spans[1].Text, "$$") || !strings.HasSuffix(spans[2].Text, "APPLY BATCH") {
+		t.Fatalf("unexpected statement bounds: %q / %q", spans[1].Text, spans[2].Text)
+	}
+	batch, kind, found := parseSQLStatementAt(script, spans[2].Span, cqlDialect).cqlBatch()
+	if !found || kind != "LOGGED" || len(batch) != 2 || batch[1].Kind != sqlStmtUpdate {
+		t.Fatalf("unexpected batch parse: %v %s %+v", found, kind, batch)
+	}
+}
+
+func TestBuildCQLCatalogParsesPartitionKeys(t *testing.T) {
+	schema := strings.Join([]string{
+		"CREATE TABLE ks.events (",
+		"  tenant text, bucket int, ts timeuuid, tags map<text, text>, payload blob,",
+		"  PRIMARY KEY ((tenant, bucket), ts)",
+		") WITH CLUSTERING ORDER BY (ts DESC);",
+		"CREATE TABLE users (id uuid PRIMARY KEY, name text STATIC);",
+	}, "\n")
+	catalog, err := buildSchemaCatalog(EngineCQL, schema)
+	if err != nil {
+		t.Fatalf("build catalog err: %v", err)
+	}
+	events, _ := catalog.table("events")
+	if len(events.Columns) != 5 || !slices.Equal(events.PartitionKey, []string{"tenant", "bucket"}) || !slices.Equal(events.PrimaryKey, []string{"tenant", "bucket", "ts"}) {
+		t.Fatalf("unexpected events table: %+v", events)
+	}
+	users, _ := catalog.table("users")
+	if !slices.Equal(users.PartitionKey, []string{"id"}) {
+		t.Fatalf("unexpected users partition key: %+v", users)
+	}
+}
+
+func TestAnalyzeCQLRules(t *testing.T) {
+	catalog, err := buildSchemaCatalog(EngineCQL, "CREATE TABLE events (tenant text, bucket int, ts timeuuid, v text, PRIMARY KEY ((tenant, bucket), ts));")
+	if err != nil {
+		t.Fatalf("build catalog err: %v", err)
+	}
+	script := strings.Join([]string{
+		"SELECT * FROM events WHERE tenant = 'a' AND bucket IN (1, 2);",
+		"SELECT * FROM events WHERE tenant = 'a' ALLOW FILTERING;",
+		"BEGIN BATCH INSERT INTO events (tenant, bucket, ts, v) VALUES ('a', 1, now(), 'x'); INSERT INTO events (tenant, bucket, ts, v) VALUES ('a', 1, now(), 'y'); APPLY BATCH;",
+		"BEGIN UNLOGGED BATCH INSERT INTO events (tenant, bucket, ts, v) VALUES ('a', 1, now(), 'x'); UPDATE events SET v = 'y' WHERE tenant = 'b' AND bucket = 1 AND ts = now(); APPLY BATCH;",
+		"INSERT INTO events (tenant, bucket, ts, v) VALUES ('a', 1, now(), 'x') IF NOT EXISTS;",
+		"CREATE TABLE IF NOT EXISTS audit (id uuid PRIMARY KEY);",
+		"ALTER TABLE events DROP v;",
+		"TRUNCATE events;",
+		"DROP KEYSPACE ks;",
+	}, "\n")
+
+	issues := AnalyzeByEngine(EngineCQL, script, AnalyzeOptions{Catalog: catalog}).Issues
+	rules := issuesByStatement(issues)
+	messages := messagesByStatement(issues)
+	rules.expect(t, 1, "cql_select_without_partition_key", false)
+	rules.expect(t, 2, "cql_select_without_partition_key", true)
+	rules.expect(t, 2, "cql_allow_filtering", true)
+	if !strings.Contains(messages[2], "bucket") {
+		t.Fatalf("missing partition column should be named: %s", messages[2])
+	}
+	rules.expect(t, 3, "cql_multi_partition_batch", false)
+	rules.expect(t, 3, "missing_statement_terminator", false)
+	rules.expect(t, 4, "cql_multi_partition_batch", true)
+	if !strings.Contains(messages[4], "UNLOGGED") {
+		t.Fatalf("unlogged batch should be described: %s", messages[4])
+	}
+	rules.expect(t, 5, "cql_lightweight_transaction", true)
+	rules.expect(t, 6, "cql_lightweight_transaction", false)
+	rules.expect(t, 7, "cql_alter_drop_column", true)
+	rules.expect(t, 8, "cql_dangerous_truncate", true)
+	rules.expect(t, 9, "cql_dangerous_drop", true)
+}
+
+func TestCQLMultiLineBatchIsNotMissingTerminator(t *testing.T) {
+	batch := strings.Join([]string{
+		"BEGIN BATCH",
+		"INSERT INTO events (tenant, bucket, ts, v) VALUES ('a', 1, now(), 'x');",
+		"UPDATE events SET v = 'y' WHERE tenant = 'a' AND bucket = 1 AND ts = now();",
+		"DELETE FROM events WHERE tenant = 'a' AND bucket = 1 AND ts = now();",
+		"APPLY BATCH;",
+	}, "\n")
+
+	issues := AnalyzeByEngine(EngineCQL, batch+"\nSELECT v FROM events WHERE tenant = 'a' AND bucket = 1;", AnalyzeOptions{}).Issues
+	if hasRule(issues, "missing_statement_terminator") {
+		t.Fatalf("multi-line batch should not be reported as missing a terminator: %+v", issues)
+	}
+
+	merged := batch + "\nSELECT v FROM events WHERE tenant = 'a' AND bucket = 1\nTRUNCATE events;"
+	issues = AnalyzeByEngine(EngineCQL, merged, AnalyzeOptions{}).Issues
+	messages := messagesByStatement(issues)
+	rules := issuesByStatement(issues)
+	rules.expect(t, 2, "missing_statement_terminator", false)
+	rules.expect(t, 3, "missing_statement_terminator", false)
+	rules.expect(t, 4, "missing_statement_terminator", false)
+	if !hasRule(issues, "missing_statement_terminator") || !strings.Contains(messages[6], "第 6 条") {
+		t.Fatalf("unterminated statement after the batch should still be reported: %+v", issues)
+	}
+}
+
+func TestDetectEngineCQL(t *testing.T) {
+	detection := DetectEngine("CREATE KEYSPACE ks WITH replication = {'class': 'NetworkTopologyStrategy', 'dc1': 3};", "")
+	if detection.Engine != EngineCQL || detection.Ambiguous {
+		t.Fatalf("expected cql detection, got %+v", detection)
+	}
+	if byExtension := DetectEngine("SELECT 1;", "V1__init.cql"); byExtension.Engine != EngineCQL {
+		t.Fatalf("expected .cql extension to detect cql, got %+v", byExtension)
+	}
+}
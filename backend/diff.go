@@ -0,0 +1,276 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const maxDiffCells = 4_000_000
+
+type DiffScope struct {
+	Mode              string `json:"mode"`
+	ChangedLines      int    `json:"changedLines"`
+	ChangedStatements []int  `json:"changedStatements"`
+}
+
+type lineChanges struct {
+	Mode         string
+	Added        map[int]struct{}
+	DeletedAfter map[int]struct{}
+}
+
+type diffFile struct {
+	OldPath string
+	NewPath string
+	Changes *lineChanges
+}
+
+var reHunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+func newLineChanges(mode string) *lineChanges {
+	return &lineChanges{Mode: mode, Added: make(map[int]struct{}), DeletedAfter: make(map[int]struct{})}
+}
+
+func (changes *lineChanges) touches(startLine, endLine int) bool {
+	for line := startLine; line <= endLine; line++ {
+		if _, found := changes.Added[line]; found {
+			return true
+		}
+		if _, found := changes.DeletedAfter[line]; found && line < endLine {
+			return true
+		}
+	}
+	return false
+}
+
+func splitDiffLines(content string) []string {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	if content == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
+
+func diffLines(base, head string) *lineChanges {
+	a, b := splitDiffLines(base), splitDiffLines(head)
+	changes := newLineChanges("base")
+
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	if len(midA)*len(midB) > maxDiffCells {
+		for j := range midB {
+			changes.Added[prefix+j+1] = struct{}{}
+		}
+		if len(midA) > 0 {
+			changes.DeletedAfter[prefix] = struct{}{}
+		}
+		return changes
+	}
+
+	lcs := make([][]int32, len(midA)+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, len(midB)+1)
+	}
+	for i := len(midA) - 1; i >= 0; i-- {
+		for j := len(midB) - 1; j >= 0; j-- {
+			if midA[i] == midB[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(midA) || j < len(midB) {
+		switch {
+		case i < len(midA) && j < len(midB) && midA[i] == midB[j]:
+			i++
+			j++
+		case j == len(midB) || (i < len(midA) && lcs[i+1][j] >= lcs[i][j+1]):
+			changes.DeletedAfter[prefix+j] = struct{}{}
+			i++
+		default:
+			changes.Added[prefix+j+1] = struct{}{}
+			j++
+		}
+	}
+	return changes
+}
+
+func parseUnifiedDiff(text string) ([]diffFile, error) {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	files := make([]diffFile, 0)
+	var current *diffFile
+	oldPath := ""
+
+	for index := 0; index < len(lines); index++ {
+		line := lines[index]
+		switch {
+		case strings.HasPrefix(line, "--- "):
+			oldPath = cleanDiffPath(line[4:])
+		case strings.HasPrefix(line, "+++ "):
+			files = append(files, diffFile{OldPath: oldPath, NewPath: cleanDiffPath(line[4:]), Changes: newLineChanges("diff")})
+			current = &files[len(files)-1]
+			oldPath = ""
+		case strings.HasPrefix(line, "@@ "):
+			match := reHunkHeader.FindStringSubmatch(line)
+			if match == nil {
+				return nil, fmt.Errorf("invalid hunk header: %s", line)
+			}
+			if current == nil {
+				files = append(files, diffFile{Changes: newLineChanges("diff")})
+				current = &files[len(files)-1]
+			}
+			oldRemaining := parseHunkCount(match[2])
+			newRemaining := parseHunkCount(match[4])
+			headLine, _ := strconv.Atoi(match[3])
+			if newRemaining == 0 {
+				headLine++
+			}
+
+			for oldRemaining > 0 || newRemaining > 0 {
+				index++
+				if index >= len(lines) {
+					return nil, errors.New("unexpected end of diff inside hunk")
+				}
+				body := lines[index]
+				switch {
+				case strings.HasPrefix(body, "+"):
+					current.Changes.Added[headLine] = struct{}{}
+					headLine++
+					newRemaining--
+				case strings.HasPrefix(body, "-"):
+					current.Changes.DeletedAfter[headLine-1] = struct{}{}
+					oldRemaining--
+				case strings.HasPrefix(body, `\`):
+				default:
+					headLine++
+					oldRemaining--
+					newRemaining--
+				}
+			}
+		}
+	}
+
+	if len(files) == 0 {
+		return nil, errors.New("no hunks found in diff")
+	}
+	return files, nil
+}
+
+func parseHunkCount(raw string) int {
+	if raw == "" {
+		return 1
+	}
+	count, _ := strconv.Atoi(raw)
+	return count
+}
+
+func cleanDiffPath(raw string) string {
+	if tab := strings.IndexByte(raw, '\t'); tab >= 0 {
+		raw = raw[:tab]
+	}
+	raw = strings.Trim(strings.TrimSpace(raw), `"`)
+	if raw == "/dev/null" {
+		return ""
+	}
+	if strings.HasPrefix(raw, "a/") || strings.HasPrefix(raw, "b/") {
+		raw = raw[2:]
+	}
+	return raw
+}
+
+func selectDiffFile(files []diffFile, fileName string) (*lineChanges, error) {
+	if len(files) == 1 {
+		return files[0].Changes, nil
+	}
+	name := strings.TrimSpace(fileName)
+	if name == "" {
+		return nil, errors.New("diff touches multiple files; set fileName to choose one")
+	}
+	for _, file := range files {
+		if file.NewPath == name || path.Base(file.NewPath) == path.Base(name) {
+			return file.Changes, nil
+		}
+	}
+	return nil, fmt.Errorf("diff does not touch file: %s", name)
+}
+
+func resolveReviewChanges(head string, base *string, diff, fileName string) (*lineChanges, error) {
+	if strings.TrimSpace(diff) != "" {
+		files, err := parseUnifiedDiff(diff)
+		if err != nil {
+			return nil, err
+		}
+		return selectDiffFile(files, fileName)
+	}
+	if base != nil {
+		return diffLines(*base, head), nil
+	}
+	return nil, nil
+}
+
+func (ctx *RuleContext) limitToChanges(rules []Rule, changes *lineChanges, issues []Issue, suppressed []SuppressedIssue) ([]Issue, []SuppressedIssue, *DiffScope) {
+	scopes := make(map[string]RuleScope, len(rules))
+	for _, rule := range rules {
+		definition := rule.Definition()
+		scopes[definition.Code] = definition.Scope
+	}
+
+	changed := make(map[int]struct{})
+	scope := &DiffScope{Mode: changes.Mode, ChangedLines: len(changes.Added), ChangedStatements: make([]int, 0)}
+	for _, stmt := range ctx.Statements {
+		start := ctx.statementOffset(stmt.Span)
+		end := strings.TrimRightFunc(ctx.Content[:stmt.Span.End], isDiffSpace)
+		if len(end) <= start {
+			continue
+		}
+		startLine, _ := ctx.Source.position(start)
+		endLine, _ := ctx.Source.position(len(end) - 1)
+		if changes.touches(startLine, endLine) {
+			changed[stmt.Index] = struct{}{}
+			scope.ChangedStatements = append(scope.ChangedStatements, stmt.Index)
+		}
+	}
+	sort.Ints(scope.ChangedStatements)
+
+	keep := func(issue Issue) bool {
+		if scopes[issue.Rule] != RuleScopeStatement {
+			return true
+		}
+		_, found := changed[issue.StatementIndex]
+		return found
+	}
+
+	keptIssues := make([]Issue, 0, len(issues))
+	for _, issue := range issues {
+		if keep(issue) {
+			keptIssues = append(keptIssues, issue)
+		}
+	}
+	keptSuppressed := make([]SuppressedIssue, 0, len(suppressed))
+	for _, item := range suppressed {
+		if keep(item.Issue) {
+			keptSuppressed = append(keptSuppressed, item)
+		}
+	}
+	return keptIssues, keptSuppressed, scope
+}
+
+func isDiffSpace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\n' || r == '\r'
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDiffLinesMarksAddedAndDeletedLines(t *testing.T) {
+	base := "SELECT 1;\nSELECT 2;\nSELECT 3;\n"
+	head := "SELECT 1;\nSELECT 20;\nSELECT 3;\nSELECT 4;\n"
+
+	changes := diffLines(base, head)
+	for _, line := range []int{2, 4} {
+		if _, found := changes.Added[line]; !found {
+			t.Fatalf("expected line %d to be marked as added, got %+v", line, changes.Added)
+		}
+	}
+	if len(changes.Added) != 2 {
+		t.Fatalf("unexpected added lines: %+v", changes.Added)
+	}
+	if _, found := changes.DeletedAfter[1]; !found {
+		t.Fatalf("expected deletion after line 1, got %+v", changes.DeletedAfter)
+	}
+}
+
+func TestParseUnifiedDiffTracksHeadLines(t *testing.T) {
+	diff := strings.Join([]string{
+		"diff --git a/db/a.sql b/db/a.sql",
+		"--- a/db/a.sql",
+		"+++ b/db/a.sql",
+		"@@ -1,3 +1,3 @@",
+		" SELECT 1;",
+		"--- old comment",
+		"+-- new comment",
+		" SELECT 2;",
+		"--- /dev/null",
+		"+++ b/db/b.sql",
+		"@@ -0,0 +1,2 @@",
+		"+CREATE TABLE t (id INT);",
+		"+DROP TABLE t;",
+		"",
+	}, "\n")
+
+	files, err := parseUnifiedDiff(diff)
+	if err != nil {
+		t.Fatalf("parse diff err: %v", err)
+	}
+	if len(files) != 2 || files[0].NewPath != "db/a.sql" || files[1].NewPath != "db/b.sql" || files[1].OldPath != "" {
+		t.Fatalf("unexpected files: %+v", files)
+	}
+	if _, found := files[0].Changes.Added[2]; !found || len(files[0].Changes.Added) != 1 {
+		t.Fatalf("expected only line 2 changed in a.sql, got %+v", files[0].Changes.Added)
+	}
+	if len(files[1].Changes.Added) != 2 {
+		t.Fatalf("expected two added lines in b.sql, got %+v", files[1].Changes.Added)
+	}
+
+	if _, err := selectDiffFile(files, ""); err == nil {
+		t.Fatalf("expected ambiguous diff to require fileName")
+	}
+	changes, err := selectDiffFile(files, "b.sql")
+	if err != nil || changes != files[1].Changes {
+		t.Fatalf("expected b.sql changes, got %v", err)
+	}
+	if _, err := parseUnifiedDiff("not a diff"); err == nil {
+		t.Fatalf("expected error for input without hunks")
+	}
+}
+
+func TestAnalyzeWithChangesReportsOnlyChangedStatements(t *testing.T) {
+	base := "UPDATE users SET status = 1;\nSELECT * FROM orders;\n"
+	head := "UPDATE users SET status = 1;\nSELECT * FROM orders;\nDELETE FROM audit_log;\n"
+
+	result := AnalyzeByEngine(EngineMySQL, head, AnalyzeOptions{Changes: diffLines(base, head)})
+	if result.Diff == nil || len(result.Diff.ChangedStatements) != 1 || result.Diff.ChangedStatements[0] != 3 {
+		t.Fatalf("expected statement 3 to be the only changed statement, got %+v", result.Diff)
+	}
+
+	rules := map[string]bool{}
+	for _, issue := range result.Issues {
+		rules[issue.Rule] = true
+		if issue.StatementIndex == 1 || issue.StatementIndex == 2 {
+			t.Fatalf("unchanged statements should not be reported, got %+v", issue)
+		}
+	}
+	if !rules["delete_without_where"] {
+		t.Fatalf("expected changed statement to be reviewed, got %+v", result.Issues)
+	}
+	if !rules["risky_writes_without_transaction"] {
+		t.Fatalf("expected script-level rules to evaluate the full head, got %+v", result.Issues)
+	}
+	if result.Summary.StatementCount != 3 {
+		t.Fatalf("expected statement count of the full head, got %d", result.Summary.StatementCount)
+	}
+}
+
+func TestRunLintCommandWithDiffLintsChangedFiles(t *testing.T) {
+	dir := t.TempDir()
+	head := "UPDATE users SET status = 1;\nDELETE FROM audit_log;\n"
+	if err := os.WriteFile(filepath.Join(dir, "a.sql"), []byte(head), 0o644); err != nil {
+		t.Fatalf("write file err: %v", err)
+	}
+	diffPath := filepath.Join(dir, "change.diff")
+	diff := "--- a/a.sql\n+++ b/a.sql\n@@ -1,1 +1,2 @@\n UPDATE users SET status = 1;\n+DELETE FROM audit_log;\n"
+	if err := os.WriteFile(diffPath, []byte(diff), 0o644); err != nil {
+		t.Fatalf("write diff err: %v", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	code := runLintCommand([]string{"--diff", diffPath, filepath.Join(dir, "a.sql")}, strings.NewReader(""), &stdout, &stderr)
+	if code != lintExitThreshold {
+		t.Fatalf("expected threshold exit, got %d, stderr=%s", code, stderr.String())
+	}
+	if strings.Contains(stdout.String(), "update_without_where") || !strings.Contains(stdout.String(), "delete_without_where") {
+		t.Fatalf("expected only the added statement to be reported, got: %s", stdout.String())
+	}
+
+	stdout.Reset()
+	code = runLintCommand([]string{"--diff", diffPath, "--base", diffPath, filepath.Join(dir, "a.sql")}, strings.NewReader(""), &stdout, &stderr)
+	if code != lintExitUsageError {
+		t.Fatalf("expected usage error when combining --base and --diff, got %d", code)
+	}
+}
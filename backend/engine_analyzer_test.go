@@ -5,15 +5,56 @@ import (
 	"testing"
 )
 
-func TestNormalizeEngine(t *testing.T) {
-	if NormalizeEngine("pg") != EnginePostgreSQL {
-		t.Fatalf("pg alias should map to postgresql")
+func TestEngineLookupsRequireRegisteredEngines(t *testing.T) {
+	if storedEngine("pg") != EnginePostgreSQL || storedEngine("mongo") != EngineMongoDB {
+		t.Fatalf("stored aliases should map to their engines")
+	}
+	if version, _ := RulesForEngine("pg"); version != postgresRulesVersion {
+		t.Fatalf("pg alias should use postgresql rules, got %q", version)
+	}
+
+	for name, lookup := range map[string]func(){
+		"AnalyzeByEngine": func() { AnalyzeByEngine("unknown", "SELECT 1;", AnalyzeOptions{}) },
+		"RulesForEngine":  func() { RulesForEngine("unknown") },
+		"RegisteredRules": func() { RegisteredRules("unknown") },
+		"buildSARIFLog":   func() { buildSARIFLog("unknown", nil) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("%s should reject an unresolved engine instead of falling back to mysql", name)
+				}
+			}()
+			lookup()
+		}()
 	}
-	if NormalizeEngine("mongo") != EngineMongoDB {
-		t.Fatalf("mongo alias should map to mongodb")
+}
+
+func TestResolveEngineRejectsUnknownEngines(t *testing.T) {
+	for raw, want := range map[string]DBEngine{"": EngineMySQL, " Postgres ": EnginePostgreSQL, "T-SQL": EngineSQLServer, "plsql": EngineOracle} {
+		engine, err := ResolveEngine(raw)
+		if err != nil || engine != want {
+			t.Fatalf("resolve %q: want %s, got %s (%v)", raw, want, engine, err)
+		}
+	}
+	for raw, want := range map[string]DBEngine{"ch": EngineClickHouse, "cassandra": EngineCQL, "sqlite3": EngineSQLite, "mssql": EngineSQLServer, "oracle": EngineOracle} {
+		if engine, err := ResolveEngine(raw); err != nil || engine != want {
+			t.Fatalf("resolve alias %q: want %s, got %s (%v)", raw, want, engine, err)
+		}
+	}
+	if _, err := ResolveEngine("postgress"); err == nil || !strings.Contains(err.Error(), "mysql, postgresql, mongodb") {
+		t.Fatalf("unknown engine should list supported engines, got %v", err)
+	}
+
+	engines := RegisteredEngines()
+	if len(engines) != len(SupportedEngines()) {
+		t.Fatalf("registry metadata should cover every engine, got %+v", engines)
 	}
-	if NormalizeEngine("unknown") != EngineMySQL {
-		t.Fatalf("unknown engine should fallback mysql")
+	for _, engine := range engines {
+		version, _ := RulesForEngine(engine.Name)
+		if engine.RulesVersion != version || len(engine.Extensions) == 0 || engine.Aliases == nil {
+			t.Fatalf("incomplete engine metadata: %+v", engine)
+		}
 	}
 }
 
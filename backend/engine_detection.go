@@ -0,0 +1,303 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+const engineAuto = "auto"
+
+const engineDetectionMinConfidence = 0.6
+
+var engineDetectionDialect = sqlDialect{BacktickIdentifiers: true}
+
+var redisDetectionCommands = map[string]struct{}{
+	"HSET": {}, "HMSET": {}, "HGETALL": {}, "SADD": {}, "SMEMBERS": {}, "LPUSH": {}, "RPUSH": {}, "ZADD": {}, "SETEX": {},
+	"EXPIRE": {}, "FLUSHALL": {}, "FLUSHDB": {}, "EVAL": {}, "EVALSHA": {}, "INCR": {}, "HDEL": {}, "SREM": {}, "UNLINK": {},
+}
+
+type EngineSignal struct {
+	Engine DBEngine `json:"engine"`
+	Signal string   `json:"signal"`
+	Weight int      `json:"weight"`
+}
+
+type EngineScore struct {
+	Engine DBEngine `json:"engine"`
+	Score  int      `json:"score"`
+}
+
+type EngineDetection struct {
+	Engine     DBEngine       `json:"engine"`
+	Confidence float64        `json:"confidence"`
+	Ambiguous  bool           `json:"ambiguous"`
+	Scores     []EngineScore  `json:"scores"`
+	Signals    []EngineSignal `json:"signals"`
+}
+
+type engineDetectionSignal struct {
+	Engine DBEngine
+	Signal string
+	Weight int
+	Match  func(content string, tokens []sqlToken, i int) bool
+}
+
+var engineDetectionSignals = []engineDetectionSignal{
+	{EngineMongoDB, "db.<collection>.<method>() 调用", 3, func(content string, tokens []sqlToken, i int) bool {
+		return tokens[i].Text == "db" && detectionTokensMatch(tokens, i+1, ".", "", ".", "", "(")
+	}},
+	{EngineMongoDB, "db.<method>() 调用", 3, func(content string, tokens []sqlToken, i int) bool {
+		return tokens[i].Text == "db" && detectionTokensMatch(tokens, i+1, ".", "", "(")
+	}},
+	{EngineMongoDB, "ObjectId() / ISODate()", 2, func(content string, tokens []sqlToken, i int) bool {
+		return (tokens[i].Text == "ObjectId" || tokens[i].Text == "ISODate") && detectionTokensMatch(tokens, i+1, "(")
+	}},
+	{EnginePostgreSQL, "$1 占位符", 2, func(content string, tokens []sqlToken, i int) bool {
+		return tokens[i].Kind == sqlTokenVariable && strings.HasPrefix(tokens[i].Text, "$")
+	}},
+	{EnginePostgreSQL, ":: 类型转换", 2, func(content string, tokens []sqlToken, i int) bool {
+		return tokens[i].Kind == sqlTokenOperator && tokens[i].Text == "::"
+	}},
+	{EnginePostgreSQL, "ILIKE", 2, func(content string, tokens []sqlToken, i int) bool {
+		return tokens[i].Upper == "ILIKE"
+	}},
+	{EnginePostgreSQL, "SERIAL 类型", 2, func(content string, tokens []sqlToken, i int) bool {
+		return tokens[i].Upper == "SERIAL" || tokens[i].Upper == "BIGSERIAL" || tokens[i].Upper == "SMALLSERIAL"
+	}},
+	{EnginePostgreSQL, "AS $$ 函数体", 2, func(content string, tokens []sqlToken, i int) bool {
+		return tokens[i].Upper == "AS" && detectionTokensMatch(tokens, i+1, "$", "$") && tokens[i+1].End == tokens[i+2].Start
+	}},
+	{EngineMySQL, "反引号标识符", 2, func(content string, tokens []sqlToken, i int) bool {
+		return tokens[i].Kind == sqlTokenQuotedIdent && strings.HasPrefix(tokens[i].Text, "`")
+	}},
+	{EngineMySQL, "DELIMITER", 3, func(content string, tokens []sqlToken, i int) bool {
+		lineStart, _ := sqlLineBounds(content, tokens[i])
+		return tokens[i].Upper == "DELIMITER" && strings.TrimSpace(content[lineStart:tokens[i].Start]) == ""
+	}},
+	{EngineMySQL, "AUTO_INCREMENT", 2, func(content string, tokens []sqlToken, i int) bool {
+		return tokens[i].Upper == "AUTO_INCREMENT"
+	}},
+	{EngineMySQL, "ENGINE=InnoDB", 2, func(content string, tokens []sqlToken, i int) bool {
+		return tokens[i].Upper == "ENGINE" && (detectionTokensMatch(tokens, i+1, "=", "INNODB") || detectionTokensMatch(tokens, i+1, "=", "MYISAM"))
+	}},
+	{EngineSQLServer, "GO 批次分隔符", 3, func(content string, tokens []sqlToken, i int) bool {
+		_, found := tsqlBatchSeparatorEnd(content, tokens[i])
+		return found
+	}},
+	{EngineSQLServer, "[方括号] 标识符", 2, func(content string, tokens []sqlToken, i int) bool {
+		if !detectionTokensMatch(tokens, i, "[", "", "]") || tokens[i+1].Kind != sqlTokenWord {
+			return false
+		}
+		return i == 0 || (tokens[i-1].Text != ")" && tokens[i-1].Text != "]")
+	}},
+	{EngineSQLServer, "TOP", 2, func(content string, tokens []sqlToken, i int) bool {
+		return tokens[i].Upper == "TOP" && i > 0 && (tokens[i-1].Upper == "SELECT" || tokens[i-1].Upper == "DISTINCT" || tokens[i-1].Upper == "UPDATE" || tokens[i-1].Upper == "DELETE")
+	}},
+	{EngineSQLServer, "NOLOCK", 2, func(content string, tokens []sqlToken, i int) bool {
+		return tokens[i].Upper == "NOLOCK"
+	}},
+	{EngineSQLServer, "IDENTITY 自增列", 2, func(content string, tokens []sqlToken, i int) bool {
+		return tokens[i].Upper == "IDENTITY" && detectionTokensMatch(tokens, i+1, "(")
+	}},
+	{EngineSQLite, "PRAGMA", 3, func(content string, tokens []sqlToken, i int) bool {
+		return tokens[i].Upper == "PRAGMA"
+	}},
+	{EngineSQLite, "WITHOUT ROWID", 3, func(content string, tokens []sqlToken, i int) bool {
+		return tokens[i].Upper == "WITHOUT" && detectionTokensMatch(tokens, i+1, "ROWID")
+	}},
+	{EngineSQLite, "INSERT OR REPLACE / IGNORE", 2, func(content string, tokens []sqlToken, i int) bool {
+		return tokens[i].Upper == "INSERT" && detectionTokensMatch(tokens, i+1, "OR", "") && (tokens[i+2].Upper == "REPLACE" || tokens[i+2].Upper == "IGNORE")
+	}},
+	{EngineSQLite, "ATTACH DATABASE", 3, func(content string, tokens []sqlToken, i int) bool {
+		return tokens[i].Upper == "ATTACH" && detectionTokensMatch(tokens, i+1, "DATABASE")
+	}},
+	{EngineSQLite, "AUTOINCREMENT", 2, func(content string, tokens []sqlToken, i int) bool {
+		return tokens[i].Upper == "AUTOINCREMENT"
+	}},
+	{EngineClickHouse, "ENGINE = *MergeTree", 3, func(content string, tokens []sqlToken, i int) bool {
+		return tokens[i].Upper == "ENGINE" && detectionTokensMatch(tokens, i+1, "=", "") && strings.HasSuffix(tokens[i+2].Upper, "MERGETREE")
+	}},
+	{EngineClickHouse, "ON CLUSTER", 3, func(content string, tokens []sqlToken, i int) bool {
+		return tokens[i].Upper == "ON" && detectionTokensMatch(tokens, i+1, "CLUSTER")
+	}},
+	{EngineClickHouse, "PREWHERE", 3, func(content string, tokens []sqlToken, i int) bool {
+		return tokens[i].Upper == "PREWHERE"
+	}},
+	{EngineClickHouse, "OPTIMIZE TABLE", 2, func(content string, tokens []sqlToken, i int) bool {
+		return tokens[i].Upper == "OPTIMIZE" && detectionTokensMatch(tokens, i+1, "TABLE")
+	}},
+	{EngineClickHouse, "UInt / LowCardinality / Nullable 类型", 2, func(content string, tokens []sqlToken, i int) bool {
+		switch tokens[i].Text {
+		case "UInt8", "UInt16", "UInt32", "UInt64", "Int32", "Int64", "Float64", "DateTime64":
+			return true
+		case "LowCardinality", "Nullable":
+			return detectionTokensMatch(tokens, i+1, "(")
+		}
+		return false
+	}},
+	{EngineRedis, "行首 Redis 命令", 3, func(content string, tokens []sqlToken, i int) bool {
+		if _, found := redisDetectionCommands[tokens[i].Upper]; !found || tokens[i].Kind != sqlTokenWord {
+			return false
+		}
+		lineStart, lineEnd := sqlLineBounds(content, tokens[i])
+		return strings.TrimSpace(content[lineStart:tokens[i].Start]) == "" && !strings.HasSuffix(strings.TrimSpace(content[lineStart:lineEnd]), ";")
+	}},
+	{EngineCQL, "CREATE KEYSPACE", 3, func(content string, tokens []sqlToken, i int) bool {
+		return tokens[i].Upper == "CREATE" && detectionTokensMatch(tokens, i+1, "KEYSPACE")
+	}},
+	{EngineCQL, "WITH replication", 3, func(content string, tokens []sqlToken, i int) bool {
+		return tokens[i].Upper == "WITH" && detectionTokensMatch(tokens, i+1, "REPLICATION", "=")
+	}},
+	{EngineCQL, "ALLOW FILTERING", 3, func(content string, tokens []sqlToken, i int) bool {
+		return tokens[i].Upper == "ALLOW" && detectionTokensMatch(tokens, i+1, "FILTERING")
+	}},
+	{EngineCQL, "APPLY BATCH", 3, func(content string, tokens []sqlToken, i int) bool {
+		return tokens[i].Upper == "APPLY" && detectionTokensMatch(tokens, i+1, "BATCH")
+	}},
+	{EngineCQL, "CLUSTERING ORDER BY", 3, func(content string, tokens []sqlToken, i int) bool {
+		return tokens[i].Upper == "CLUSTERING" && detectionTokensMatch(tokens, i+1, "ORDER", "BY")
+	}},
+	{EngineCQL, "USING TTL", 2, func(content string, tokens []sqlToken, i int) bool {
+		return tokens[i].Upper == "USING" && detectionTokensMatch(tokens, i+1, "TTL")
+	}},
+	{EngineOracle, "/ 结束 PL/SQL 块", 3, func(content string, tokens []sqlToken, i int) bool {
+		lineStart, lineEnd := sqlLineBounds(content, tokens[i])
+		return tokens[i].Kind == sqlTokenOperator && tokens[i].Text == "/" && strings.TrimSpace(content[lineStart:lineEnd]) == "/"
+	}},
+	{EngineOracle, "ROWNUM", 2, func(content string, tokens []sqlToken, i int) bool {
+		return tokens[i].Upper == "ROWNUM"
+	}},
+	{EngineOracle, "VARCHAR2 类型", 2, func(content string, tokens []sqlToken, i int) bool {
+		return tokens[i].Upper == "VARCHAR2" || tokens[i].Upper == "NVARCHAR2"
+	}},
+	{EngineOracle, "EXECUTE IMMEDIATE", 2, func(content string, tokens []sqlToken, i int) bool {
+		return tokens[i].Upper == "EXECUTE" && detectionTokensMatch(tokens, i+1, "IMMEDIATE")
+	}},
+	{EngineOracle, "FROM DUAL", 1, func(content string, tokens []sqlToken, i int) bool {
+		return tokens[i].Upper == "FROM" && detectionTokensMatch(tokens, i+1, "DUAL")
+	}},
+}
+
+func isAutoEngine(raw string) bool {
+	return strings.EqualFold(strings.TrimSpace(raw), engineAuto)
+}
+
+func resolveRequestEngine(raw string) (DBEngine, bool, error) {
+	if isAutoEngine(raw) {
+		return EngineMySQL, true, nil
+	}
+	engine, err := ResolveEngine(raw)
+	return engine, false, err
+}
+
+func DetectEngine(content, fileName string) EngineDetection {
+	signals := make([]EngineSignal, 0)
+	seen := make(map[string]bool)
+	add := func(engine DBEngine, signal string, weight int) {
+		if seen[signal] {
+			return
+		}
+		seen[signal] = true
+		signals = append(signals, EngineSignal{Engine: engine, Signal: signal, Weight: weight})
+	}
+
+	if ext := strings.ToLower(filepath.Ext(strings.TrimSpace(fileName))); ext != "" {
+		owners := make([]DBEngine, 0)
+		for _, definition := range defaultRuleRegistry().definitions {
+			if slices.Contains(definition.Extensions, ext) {
+				owners = append(owners, definition.Engine)
+			}
+		}
+		if len(owners) == 1 {
+			add(owners[0], "扩展名 "+ext, 3)
+		}
+	}
+
+	tokens := significantSQLTokens(tokenizeSQL(content, engineDetectionDialect))
+	for i := range tokens {
+		for _, signal := range engineDetectionSignals {
+			if !seen[signal.Signal] && signal.Match(content, tokens, i) {
+				add(signal.Engine, signal.Signal, signal.Weight)
+			}
+		}
+	}
+
+	return scoreEngineSignals(signals)
+}
+
+func scoreEngineSignals(signals []EngineSignal) EngineDetection {
+	detection := EngineDetection{Engine: EngineMySQL, Ambiguous: true, Scores: make([]EngineScore, 0), Signals: signals}
+	total := 0
+	for _, engine := range SupportedEngines() {
+		score := 0
+		for _, signal := range signals {
+			if signal.Engine == engine {
+				score += signal.Weight
+			}
+		}
+		if score > 0 {
+			detection.Scores = append(detection.Scores, EngineScore{Engine: engine, Score: score})
+			total += score
+		}
+	}
+	if total == 0 {
+		return detection
+	}
+
+	sort.SliceStable(detection.Scores, func(i, j int) bool { return detection.Scores[i].Score > detection.Scores[j].Score })
+	best := detection.Scores[0]
+	detection.Engine = best.Engine
+	detection.Confidence = math.Round(float64(best.Score)/float64(total)*100) / 100
+	tied := len(detection.Scores) > 1 && detection.Scores[1].Score == best.Score
+	detection.Ambiguous = tied || detection.Confidence < engineDetectionMinConfidence
+	return detection
+}
+
+func detectionTokensMatch(tokens []sqlToken, start int, texts ...string) bool {
+	if start+len(texts) > len(tokens) {
+		return false
+	}
+	for offset, text := range texts {
+		token := tokens[start+offset]
+		if text == "" {
+			if token.Kind != sqlTokenWord {
+				return false
+			}
+			continue
+		}
+		if token.Upper != text && (token.Kind != sqlTokenOperator || token.Text != text) {
+			return false
+		}
+	}
+	return true
+}
+
+func engineDetectionAmbiguousRule() Rule {
+	return newScriptRule(
+		RuleDefinition{Code: "engine_detection_ambiguous", Level: LevelInfo, Category: "引擎识别", Description: "engine=auto 自动识别置信度不足"},
+		func(ctx *RuleContext) []Issue {
+			detection := ctx.options.Detection
+			if detection == nil || !detection.Ambiguous || ctx.isEmpty() {
+				return nil
+			}
+			if len(detection.Scores) == 0 {
+				return []Issue{{
+					Message:    fmt.Sprintf("未识别到明确的数据库方言特征，已按 %s 规则检查", detection.Engine),
+					Suggestion: "请在请求中显式指定 engine，避免按错误的方言审核",
+				}}
+			}
+			candidates := make([]string, 0, len(detection.Scores))
+			for _, score := range detection.Scores {
+				candidates = append(candidates, fmt.Sprintf("%s=%d", score.Engine, score.Score))
+			}
+			return []Issue{{
+				Message:    fmt.Sprintf("引擎自动识别结果不确定（候选：%s），已按 %s 规则检查，置信度 %.2f", strings.Join(candidates, ", "), detection.Engine, detection.Confidence),
+				Suggestion: "请在请求中显式指定 engine，或确认脚本未混用多种数据库方言",
+			}}
+		},
+	)
+}
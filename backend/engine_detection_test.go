@@ -0,0 +1,55 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDetectEngineFromContentAndExtension(t *testing.T) {
+	cases := []struct {
+		name     string
+		content  string
+		fileName string
+		want     DBEngine
+	}{
+		{"mongo calls", "db.users.find({age: {$gt: 18}}).limit(10);", "", EngineMongoDB},
+		{"mongo extension", "printjson(1);", "seed.mongo", EngineMongoDB},
+		{"postgres", "SELECT id FROM users WHERE name ILIKE $1 AND created_at > now()::date;", "", EnginePostgreSQL},
+		{"mysql", "CREATE TABLE `users` (id BIGINT AUTO_INCREMENT PRIMARY KEY) ENGINE=InnoDB;", "", EngineMySQL},
+		{"sqlserver", "SELECT TOP 10 id FROM [dbo].[users] WITH (NOLOCK)\nGO", "", EngineSQLServer},
+		{"oracle", "BEGIN\n  EXECUTE IMMEDIATE 'TRUNCATE TABLE t';\nEND;\n/", "", EngineOracle},
+		{"sqlite", "PRAGMA foreign_keys = ON;\nCREATE TABLE t (id INTEGER PRIMARY KEY AUTOINCREMENT);", "", EngineSQLite},
+		{"oracle extension", "SELECT 1 FROM t;", "pkg_orders.pkb", EngineOracle},
+	}
+	for _, tc := range cases {
+		detection := DetectEngine(tc.content, tc.fileName)
+		if detection.Engine != tc.want || detection.Ambiguous {
+			t.Fatalf("%s: want %s, got %+v", tc.name, tc.want, detection)
+		}
+	}
+}
+
+func TestDetectEngineReportsAmbiguity(t *testing.T) {
+	detection := DetectEngine("UPDATE users SET name = 'x' WHERE id = 1;", "patch.sql")
+	if !detection.Ambiguous || detection.Engine != EngineMySQL || detection.Confidence != 0 {
+		t.Fatalf("expected ambiguous fallback to mysql, got %+v", detection)
+	}
+
+	mixed := DetectEngine("CREATE TABLE `t` (id SERIAL);", "")
+	if !mixed.Ambiguous || mixed.Confidence != 0.5 {
+		t.Fatalf("expected tied detection, got %+v", mixed)
+	}
+	result := AnalyzeByEngine(mixed.Engine, "CREATE TABLE `t` (id SERIAL);", AnalyzeOptions{Detection: &mixed})
+	found := false
+	for _, issue := range result.Issues {
+		if issue.Rule == "engine_detection_ambiguous" {
+			found = issue.Level == LevelInfo && strings.Contains(issue.Message, "postgresql=2")
+		}
+	}
+	if !found {
+		t.Fatalf("expected engine_detection_ambiguous info issue, got %+v", result.Issues)
+	}
+	if issues := AnalyzeByEngine(EngineMySQL, "CREATE TABLE `t` (id SERIAL);", AnalyzeOptions{}).Issues; issueRules(issues)["engine_detection_ambiguous"] > 0 {
+		t.Fatalf("explicit engine should not report detection issues: %+v", issues)
+	}
+}
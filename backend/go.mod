@@ -1,3 +1,5 @@
 module sql-review-studio/backend
 
 go 1.22
+
+require github.com/mattn/go-sqlite3 v1.14.33
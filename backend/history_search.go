@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const historyTimeLayout = "2006-01-02T15:04:05.000Z"
+
+var historySortOrders = map[string]string{
+	"newest":   "id DESC",
+	"oldest":   "id ASC",
+	"errors":   "error_count DESC, warning_count DESC, id DESC",
+	"warnings": "warning_count DESC, error_count DESC, id DESC",
+}
+
+type HistoryQuery struct {
+	Text        string
+	Engine      DBEngine
+	Source      string
+	From        time.Time
+	To          time.Time
+	MinErrors   int
+	MinWarnings int
+	Rule        string
+	Sort        string
+	Limit       int
+	Offset      int
+}
+
+type HistoryFacet struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+type HistoryFacets struct {
+	Engines []HistoryFacet `json:"engines"`
+	Rules   []HistoryFacet `json:"rules"`
+}
+
+func parseHistoryQuery(values url.Values) (HistoryQuery, error) {
+	query := HistoryQuery{
+		Text:   strings.TrimSpace(values.Get("query")),
+		Source: strings.TrimSpace(values.Get("source")),
+		Rule:   strings.TrimSpace(values.Get("rule")),
+		Sort:   strings.ToLower(strings.TrimSpace(values.Get("sort"))),
+		Limit:  parseIntWithDefault(values.Get("limit"), 20),
+		Offset: parseIntWithDefault(values.Get("offset"), 0),
+	}
+
+	if raw := strings.TrimSpace(values.Get("engine")); raw != "" {
+		engine, err := ResolveEngine(raw)
+		if err != nil {
+			return HistoryQuery{}, err
+		}
+		query.Engine = engine
+	}
+
+	if query.Sort != "" {
+		if _, ok := historySortOrders[query.Sort]; !ok {
+			return HistoryQuery{}, fmt.Errorf("unknown sort %q, supported sorts: newest, oldest, errors, warnings", query.Sort)
+		}
+	}
+
+	var err error
+	if query.From, err = parseHistoryTime("from", values.Get("from"), false); err != nil {
+		return HistoryQuery{}, err
+	}
+	if query.To, err = parseHistoryTime("to", values.Get("to"), true); err != nil {
+		return HistoryQuery{}, err
+	}
+	if !query.From.IsZero() && !query.To.IsZero() && query.From.After(query.To) {
+		return HistoryQuery{}, fmt.Errorf("from must not be after to")
+	}
+
+	if query.MinErrors, err = parseHistoryCount("minErrors", values.Get("minErrors")); err != nil {
+		return HistoryQuery{}, err
+	}
+	if query.MinWarnings, err = parseHistoryCount("minWarnings", values.Get("minWarnings")); err != nil {
+		return HistoryQuery{}, err
+	}
+
+	return query.normalized(), nil
+}
+
+func parseHistoryTime(name, raw string, endOfDay bool) (time.Time, error) {
+	trimmed := strings.TrimSpace(raw)
+	if trimmed == "" {
+		return time.Time{}, nil
+	}
+	if parsed, err := time.Parse(time.RFC3339Nano, trimmed); err == nil {
+		return parsed.UTC(), nil
+	}
+	parsed, err := time.Parse(time.DateOnly, trimmed)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s %q, expected YYYY-MM-DD or RFC3339", name, trimmed)
+	}
+	if endOfDay {
+		parsed = parsed.AddDate(0, 0, 1).Add(-time.Millisecond)
+	}
+	return parsed, nil
+}
+
+func parseHistoryCount(name, raw string) (int, error) {
+	trimmed := strings.TrimSpace(raw)
+	if trimmed == "" {
+		return 0, nil
+	}
+	value, err := strconv.Atoi(trimmed)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid %s %q, expected a non-negative integer", name, trimmed)
+	}
+	return value, nil
+}
+
+func (query HistoryQuery) normalized() HistoryQuery {
+	if query.Limit <= 0 {
+		query.Limit = 20
+	}
+	if query.Limit > 100 {
+		query.Limit = 100
+	}
+	if query.Offset < 0 {
+		query.Offset = 0
+	}
+	if _, ok := historySortOrders[query.Sort]; !ok {
+		query.Sort = "newest"
+	}
+	return query
+}
+
+func (query HistoryQuery) orderBy() string {
+	return historySortOrders[query.normalized().Sort]
+}
+
+func (query HistoryQuery) where() (string, []any) {
+	conditions := make([]string, 0)
+	args := make([]any, 0)
+	add := func(condition string, arg any) {
+		conditions = append(conditions, condition)
+		args = append(args, arg)
+	}
+
+	if match := historyMatchExpression(query.Text); match != "" {
+		add("id IN (SELECT docid FROM review_history_fts WHERE review_history_fts MATCH ?)", match)
+	}
+	if query.Engine != "" {
+		add("engine = ?", string(query.Engine))
+	}
+	if query.Source != "" {
+		add("source = ?", query.Source)
+	}
+	if !query.From.IsZero() {
+		add("strftime('%Y-%m-%dT%H:%M:%fZ', created_at) >= ?", query.From.UTC().Format(historyTimeLayout))
+	}
+	if !query.To.IsZero() {
+		add("strftime('%Y-%m-%dT%H:%M:%fZ', created_at) <= ?", query.To.UTC().Format(historyTimeLayout))
+	}
+	if query.MinErrors > 0 {
+		add("error_count >= ?", query.MinErrors)
+	}
+	if query.MinWarnings > 0 {
+		add("warning_count >= ?", query.MinWarnings)
+	}
+	if query.Rule != "" {
+		add("id IN (SELECT history_id FROM review_history_rules WHERE rule = ?)", query.Rule)
+	}
+
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+func historyMatchExpression(text string) string {
+	terms := make([]string, 0)
+	for _, field := range strings.Fields(text) {
+		term := strings.ReplaceAll(field, `"`, "")
+		if strings.TrimSpace(term) == "" {
+			continue
+		}
+		terms = append(terms, `"`+term+`*"`)
+	}
+	return strings.Join(terms, " ")
+}
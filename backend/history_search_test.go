@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestParseHistoryQuery(t *testing.T) {
+	query, err := parseHistoryQuery(url.Values{
+		"query":     {" drop orders "},
+		"engine":    {"pg"},
+		"from":      {"2026-10-01"},
+		"to":        {"2026-10-15"},
+		"minErrors": {"1"},
+		"rule":      {"pg_dangerous_drop"},
+		"sort":      {"Errors"},
+		"limit":     {"500"},
+	})
+	if err != nil {
+		t.Fatalf("parse err: %v", err)
+	}
+	if query.Text != "drop orders" || query.Engine != EnginePostgreSQL || query.Sort != "errors" || query.Limit != 100 || query.MinErrors != 1 {
+		t.Fatalf("unexpected query: %+v", query)
+	}
+	if !query.From.Equal(time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)) || query.To.Format(historyTimeLayout) != "2026-10-15T23:59:59.999Z" {
+		t.Fatalf("date-only bounds should cover whole days: %v - %v", query.From, query.To)
+	}
+
+	for _, values := range []url.Values{
+		{"engine": {"db2"}},
+		{"sort": {"score"}},
+		{"from": {"yesterday"}},
+		{"from": {"2026-10-02"}, "to": {"2026-10-01"}},
+		{"minWarnings": {"-1"}},
+	} {
+		if _, err := parseHistoryQuery(values); err == nil {
+			t.Fatalf("expected %v to be rejected", values)
+		}
+	}
+}
+
+func TestHistoryMatchExpressionQuotesTerms(t *testing.T) {
+	if got := historyMatchExpression(`drop "orders" OR`); got != `"drop*" "orders*" "OR*"` {
+		t.Fatalf("unexpected match expression: %s", got)
+	}
+	if got := historyMatchExpression(`""`); got != "" {
+		t.Fatalf("quote-only queries should not filter: %s", got)
+	}
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+const maxAdvisedIndexColumns = 4
+
+type indexAccess struct {
+	Table    *SchemaTable
+	Ref      sqlTableRef
+	Equality []string
+	Sort     []string
+	Range    []string
+}
+
+var sqlEqualityOperators = map[string]struct{}{"=": {}, "<=>": {}, "IN": {}}
+
+var sqlRangeOperators = map[string]struct{}{"<": {}, ">": {}, "<=": {}, ">=": {}, "BETWEEN": {}}
+
+var sqlSortModifiers = map[string]struct{}{"ASC": {}, "DESC": {}, "NULLS": {}, "FIRST": {}, "LAST": {}}
+
+func indexAdvisorRule(code string) Rule {
+	return newScriptRule(
+		RuleDefinition{Code: code, Level: LevelInfo, Category: "查询性能", Description: "过滤、关联、排序列未命中索引时给出建索引建议（需提供结构快照）"},
+		func(ctx *RuleContext) []Issue {
+			issues := make([]Issue, 0)
+			advised := make(map[string][]SchemaIndex)
+			for _, stmt := range ctx.Statements {
+				if stmt.Catalog == nil || stmt.SQL == nil {
+					continue
+				}
+				_, writeTarget, _, unindexedWrite := stmt.unindexedWritePredicate()
+				for _, access := range stmt.SQL.indexAccesses(stmt.Catalog) {
+					key := catalogKey(access.Table.Name)
+					columns := access.columns()
+					if len(columns) == 0 || access.servedBy(access.Table.Indexes) || access.servedBy(advised[key]) {
+						continue
+					}
+					index := SchemaIndex{Name: advisedIndexName(ctx.Engine, access.Table, columns), Columns: columns}
+					advised[key] = append(advised[key], index)
+					if unindexedWrite && access.Ref.Token.Start == writeTarget.Token.Start {
+						continue
+					}
+					issues = append(issues, ctx.Source.locate(Issue{
+						StatementIndex: stmt.Index,
+						Statement:      stmt.Text,
+						Message:        fmt.Sprintf("表 %s 的过滤/关联/排序列 %s 未命中已有索引（按最左前缀）", access.Table.Name, strings.Join(columns, ", ")),
+						Suggestion:     fmt.Sprintf("评估数据分布后可创建索引：%s", advisedIndexDDL(ctx.Engine, access.Ref.Name, index)),
+					}, access.Ref.Token.span()))
+				}
+			}
+			return issues
+		},
+	)
+}
+
+func (stmt *sqlStatement) indexAccesses(catalog *schemaCatalog) []indexAccess {
+	accesses := make([]indexAccess, 0)
+	collect := func(tables []sqlTableRef, complete bool, where, from, orderBy *sqlClause) {
+		scope := make([]indexAccess, 0, len(tables))
+		for _, ref := range tables {
+			table, found := catalog.table(ref.Name)
+			if !found || table.Opaque {
+				complete = false
+				continue
+			}
+			scope = append(scope, indexAccess{Table: table, Ref: ref})
+		}
+		if len(scope) == 0 {
+			return
+		}
+		resolve := func(ref sqlColumnRef) int {
+			if ref.Qualifier == "" && !complete {
+				return -1
+			}
+			match := -1
+			for i := range scope {
+				if ref.Qualifier != "" && !scope[i].Ref.matches(ref.Qualifier) {
+					continue
+				}
+				if _, found := scope[i].Table.column(ref.Name); !found {
+					continue
+				}
+				if match >= 0 {
+					return -1
+				}
+				match = i
+			}
+			return match
+		}
+
+		if where != nil && !where.hasTopLevelWord("OR") {
+			for _, ref := range sqlPredicateColumns(where.Tokens) {
+				i := resolve(ref)
+				if i < 0 || ref.Token.Depth != where.Keyword.Depth {
+					continue
+				}
+				if _, found := sqlEqualityOperators[ref.Operator]; found {
+					scope[i].Equality = append(scope[i].Equality, ref.Name)
+				} else if _, found := sqlRangeOperators[ref.Operator]; found {
+					scope[i].Range = append(scope[i].Range, ref.Name)
+				}
+			}
+		}
+		for _, ref := range sqlJoinColumns(from) {
+			if i := resolve(ref); i >= 0 {
+				scope[i].Equality = append(scope[i].Equality, ref.Name)
+			}
+		}
+		if sort := sqlOrderColumns(orderBy); len(sort) > 0 {
+			target := resolve(sort[0])
+			names := make([]string, 0, len(sort))
+			for _, ref := range sort {
+				if target < 0 || resolve(ref) != target {
+					target = -1
+					break
+				}
+				names = append(names, ref.Name)
+			}
+			if target >= 0 {
+				scope[target].Sort = names
+			}
+		}
+		accesses = append(accesses, scope...)
+	}
+
+	switch stmt.Kind {
+	case sqlStmtUpdate:
+		if len(stmt.Tables) == 1 && !stmt.hasTopLevelWord("FROM") {
+			collect(stmt.Tables, true, stmt.Where, nil, stmt.OrderBy)
+		}
+	case sqlStmtDelete:
+		if len(stmt.Tables) == 1 && !stmt.isMultiTableDelete() && !stmt.hasTopLevelWord("USING") {
+			collect(stmt.Tables, true, stmt.Where, nil, stmt.OrderBy)
+		}
+	}
+	stmt.walkSelects(func(sel *sqlStatement) {
+		collect(sel.Tables, !sel.clauseHasDerivedTable(sel.From), sel.Where, sel.From, sel.OrderBy)
+	})
+	return accesses
+}
+
+func (access indexAccess) columns() []string {
+	if len(access.Equality) == 0 {
+		if len(access.Range) == 0 {
+			return nil
+		}
+		return access.Range[:1]
+	}
+	columns := uniqueFold(append(append([]string{}, access.Equality...), access.Sort...))
+	if len(access.Range) > 0 {
+		columns = uniqueFold(append(columns, access.Range[0]))
+	}
+	if len(columns) > maxAdvisedIndexColumns {
+		columns = columns[:maxAdvisedIndexColumns]
+	}
+	return columns
+}
+
+func (access indexAccess) servedBy(indexes []SchemaIndex) bool {
+	columns := access.columns()
+	equality := min(len(uniqueFold(access.Equality)), len(columns))
+	for _, index := range indexes {
+		if index.Method == "" && index.serves(columns, equality) {
+			return true
+		}
+	}
+	return false
+}
+
+func (index SchemaIndex) serves(columns []string, equality int) bool {
+	pending := make(map[string]struct{}, equality)
+	for _, column := range columns[:equality] {
+		pending[strings.ToLower(column)] = struct{}{}
+	}
+	i := 0
+	for ; i < len(index.Columns) && len(pending) > 0; i++ {
+		key := strings.ToLower(index.Columns[i])
+		if _, found := pending[key]; !found {
+			break
+		}
+		delete(pending, key)
+	}
+	if index.Unique && i > 0 && i == len(index.Columns) {
+		return true
+	}
+	if len(pending) > 0 {
+		return false
+	}
+	for _, column := range columns[equality:] {
+		if i >= len(index.Columns) || !strings.EqualFold(index.Columns[i], column) {
+			return false
+		}
+		i++
+	}
+	return true
+}
+
+func advisedIndexName(engine DBEngine, table *SchemaTable, columns []string) string {
+	limit := 64
+	if engine == EnginePostgreSQL {
+		limit = 63
+	}
+	base := strings.ToLower("idx_" + catalogKey(table.Name) + "_" + strings.Join(columns, "_"))
+	if len(base) > limit {
+		base = base[:limit]
+	}
+	name := base
+	for suffix := 2; table.hasIndexName(name); suffix++ {
+		tail := fmt.Sprintf("_%d", suffix)
+		name = base[:min(len(base), limit-len(tail))] + tail
+	}
+	return name
+}
+
+func (table *SchemaTable) hasIndexName(name string) bool {
+	for _, index := range table.Indexes {
+		if strings.EqualFold(index.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func advisedIndexDDL(engine DBEngine, tableName string, index SchemaIndex) string {
+	create := "CREATE INDEX"
+	if engine == EnginePostgreSQL {
+		create = "CREATE INDEX CONCURRENTLY"
+	}
+	return fmt.Sprintf("%s %s ON %s (%s);", create, index.Name, tableName, strings.Join(index.Columns, ", "))
+}
+
+func (clause *sqlClause) hasTopLevelWord(word string) bool {
+	for _, token := range clause.Tokens {
+		if token.Kind == sqlTokenWord && token.Upper == word && token.Depth == clause.Keyword.Depth {
+			return true
+		}
+	}
+	return false
+}
+
+func sqlJoinColumns(clause *sqlClause) []sqlColumnRef {
+	if clause == nil {
+		return nil
+	}
+	refs := make([]sqlColumnRef, 0)
+	tokens := clause.Tokens
+	inCondition := false
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		if token.Kind == sqlTokenOperator && token.Text == "(" && i+1 < len(tokens) && (tokens[i+1].Upper == "SELECT" || tokens[i+1].Upper == "WITH") {
+			i, _ = matchingSQLParen(tokens, i)
+			continue
+		}
+		if token.Depth == clause.Keyword.Depth {
+			if token.Kind == sqlTokenWord && token.Upper == "ON" {
+				inCondition = true
+				continue
+			}
+			if _, stop := sqlTableAliasStopWords[token.Upper]; (stop && token.Kind == sqlTokenWord) || token.Text == "," {
+				inCondition = false
+			}
+		}
+		if !inCondition || (token.Kind != sqlTokenWord && token.Kind != sqlTokenQuotedIdent) || i+2 >= len(tokens) {
+			continue
+		}
+		if tokens[i+1].Text != "." || (tokens[i+2].Kind != sqlTokenWord && tokens[i+2].Kind != sqlTokenQuotedIdent) {
+			continue
+		}
+		if (i > 0 && tokens[i-1].Text == ".") || (i+3 < len(tokens) && (tokens[i+3].Text == "." || tokens[i+3].Text == "(")) {
+			continue
+		}
+		refs = append(refs, sqlColumnRef{Qualifier: sqlIdentifierName(token), Name: sqlIdentifierName(tokens[i+2]), Operator: "=", Token: token})
+		i += 2
+	}
+	return refs
+}
+
+func sqlOrderColumns(clause *sqlClause) []sqlColumnRef {
+	if clause == nil || len(clause.Tokens) < 2 || clause.Tokens[0].Upper != "BY" {
+		return nil
+	}
+	refs := make([]sqlColumnRef, 0)
+	for _, item := range splitSQLTokensTopLevel(clause.Tokens[1:], clause.Keyword.Depth) {
+		name, token, next := (&sqlParser{tokens: item}).readQualifiedName(0)
+		for next < len(item) {
+			if _, found := sqlSortModifiers[item[next].Upper]; !found {
+				break
+			}
+			next++
+		}
+		if name == "" || next != len(item) {
+			return nil
+		}
+		ref := sqlColumnRef{Name: name, Token: token}
+		if index := strings.LastIndexByte(name, '.'); index >= 0 {
+			ref.Qualifier, ref.Name = catalogKey(name[:index]), name[index+1:]
+		}
+		refs = append(refs, ref)
+	}
+	return refs
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func indexAdvice(result CheckResponse, code string) []Issue {
+	advice := make([]Issue, 0)
+	for _, issue := range result.Issues {
+		if issue.Rule == code {
+			advice = append(advice, issue)
+		}
+	}
+	return advice
+}
+
+func TestIndexAdvisorSuggestsMissingIndexes(t *testing.T) {
+	catalog, err := buildSchemaCatalog(EngineMySQL, testMySQLSchema)
+	if err != nil {
+		t.Fatalf("build catalog err: %v", err)
+	}
+	script := strings.Join([]string{
+		"SELECT id FROM orders WHERE user_id = 1 ORDER BY created_at LIMIT 10;",
+		"SELECT o.id FROM orders o JOIN users u ON u.id = o.user_id WHERE u.status = 1 LIMIT 10;",
+		"SELECT id FROM users WHERE email = 'a@b.c' AND status = 1 LIMIT 1;",
+		"UPDATE users SET email = 'x' WHERE status = 2;",
+		"SELECT id FROM orders WHERE user_id = 1 AND amount = 3 ORDER BY created_at LIMIT 10;",
+		"SELECT id FROM users WHERE status = 1 OR email = 'x' LIMIT 1;",
+	}, "\n")
+
+	result := AnalyzeByEngine(EngineMySQL, script, AnalyzeOptions{Catalog: catalog})
+	advice := indexAdvice(result, "index_advice")
+	if len(advice) != 1 {
+		t.Fatalf("expected one suggestion, got %+v", advice)
+	}
+	if advice[0].StatementIndex != 5 || advice[0].Level != LevelInfo || !strings.Contains(advice[0].Suggestion, "ON orders (user_id, amount, created_at);") {
+		t.Fatalf("unexpected suggestion: %+v", advice[0])
+	}
+	warnings := indexAdvice(result, "write_predicate_not_indexed")
+	if len(warnings) != 1 || warnings[0].StatementIndex != 4 || !strings.Contains(warnings[0].Suggestion, "CREATE INDEX idx_users_status ON users (status);") {
+		t.Fatalf("unindexed UPDATE should carry the index DDL in its warning only, got %+v", warnings)
+	}
+
+	if len(indexAdvice(AnalyzeByEngine(EngineMySQL, script, AnalyzeOptions{}), "index_advice")) != 0 {
+		t.Fatalf("advisor should be silent without a schema")
+	}
+}
+
+func TestIndexAdvisorUsesConcurrentlyForPostgres(t *testing.T) {
+	catalog, err := buildSchemaCatalog(EnginePostgreSQL, "CREATE TABLE events (id bigserial PRIMARY KEY, kind text, created_at timestamptz);")
+	if err != nil {
+		t.Fatalf("build catalog err: %v", err)
+	}
+	script := "SELECT id FROM events WHERE created_at >= now() - interval '1 day' LIMIT 10;\n" +
+		"CREATE INDEX CONCURRENTLY idx_events_kind ON events (kind);\n" +
+		"SELECT id FROM events WHERE kind = 'x' LIMIT 10;\n"
+
+	advice := indexAdvice(AnalyzeByEngine(EnginePostgreSQL, script, AnalyzeOptions{Catalog: catalog}), "pg_index_advice")
+	if len(advice) != 1 || !strings.Contains(advice[0].Suggestion, "CREATE INDEX CONCURRENTLY idx_events_created_at ON events (created_at);") {
+		t.Fatalf("expected a single concurrent index suggestion, got %+v", advice)
+	}
+}
+
+func TestSchemaIndexServesLeftmostPrefix(t *testing.T) {
+	index := SchemaIndex{Columns: []string{"a", "b", "c"}}
+	cases := []struct {
+		columns  []string
+		equality int
+		want     bool
+	}{
+		{[]string{"b", "a"}, 2, true},
+		{[]string{"a", "c"}, 1, false},
+		{[]string{"a", "b", "c"}, 1, true},
+		{[]string{"b"}, 1, false},
+	}
+	for _, tc := range cases {
+		if got := index.serves(tc.columns, tc.equality); got != tc.want {
+			t.Fatalf("serves(%v, %d) = %v, want %v", tc.columns, tc.equality, got, tc.want)
+		}
+	}
+	unique := SchemaIndex{Columns: []string{"email"}, Unique: true}
+	if !unique.serves([]string{"status", "email"}, 2) {
+		t.Fatalf("a unique index covered by equality columns should serve the lookup")
+	}
+}
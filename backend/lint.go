@@ -0,0 +1,397 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+const (
+	lintExitOK         = 0
+	lintExitThreshold  = 1
+	lintExitUsageError = 2
+)
+
+type lintRuleConfig struct {
+	Engine        string          `json:"engine"`
+	DisabledRules []string        `json:"disabledRules"`
+	Rules         map[string]bool `json:"rules"`
+	RuleOverrides
+}
+
+type lintInput struct {
+	Name    string
+	Content string
+	Changes *lineChanges
+}
+
+type lintFileResult struct {
+	File   string        `json:"file"`
+	Engine DBEngine      `json:"engine"`
+	Result CheckResponse `json:"result"`
+}
+
+func runLintCommand(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	engineFlag := flags.String("engine", "", "database engine: mysql | postgresql | mongodb | sqlserver | oracle | sqlite | clickhouse | redis | cql")
+	configFlag := flags.String("config", "", "rule config file (JSON)")
+	failOnFlag := flags.String("fail-on", string(LevelError), "exit non-zero when an issue at or above this level is found: error | warning | info")
+	formatFlag := flags.String("format", "text", "output format: text | json | sarif")
+	baseFlag := flags.String("base", "", "base version of the single input file; only changed statements are reported")
+	diffFlag := flags.String("diff", "", "unified diff file (or - for stdin); only changed statements are reported")
+	schemaFlag := flags.String("schema", "", "CREATE TABLE DDL file used as the schema catalog (mysql | postgresql)")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: sql-review lint [flags] [file|dir|-]...")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return lintExitOK
+		}
+		return lintExitUsageError
+	}
+
+	failOn, err := parseLintFailOn(*failOnFlag)
+	if err != nil {
+		fmt.Fprintf(stderr, "sql-review lint: %v\n", err)
+		return lintExitUsageError
+	}
+	format := strings.ToLower(strings.TrimSpace(*formatFlag))
+	if format != "text" && format != "json" && format != "sarif" {
+		fmt.Fprintf(stderr, "sql-review lint: unsupported format: %s\n", *formatFlag)
+		return lintExitUsageError
+	}
+
+	config := lintRuleConfig{}
+	if path := strings.TrimSpace(*configFlag); path != "" {
+		config, err = loadLintRuleConfig(path)
+		if err != nil {
+			fmt.Fprintf(stderr, "sql-review lint: %v\n", err)
+			return lintExitUsageError
+		}
+	}
+
+	engineName := strings.TrimSpace(*engineFlag)
+	if engineName == "" {
+		engineName = config.Engine
+	}
+	engine, err := ResolveEngine(engineName)
+	if err != nil {
+		fmt.Fprintf(stderr, "sql-review lint: %v\n", err)
+		return lintExitUsageError
+	}
+
+	disabledRules := config.disabledRuleSet()
+	if forced := enforceAlwaysEnabledRules(disabledRules); len(forced) > 0 {
+		fmt.Fprintf(stderr, "sql-review lint: always-enabled rules cannot be disabled: %s\n", strings.Join(forced, ", "))
+	}
+
+	overrides, err := config.RuleOverrides.validate(engine)
+	if err != nil {
+		fmt.Fprintf(stderr, "sql-review lint: %v\n", err)
+		return lintExitUsageError
+	}
+	options := overrides.apply(AnalyzeOptions{DisabledRules: disabledRules})
+	if path := strings.TrimSpace(*schemaFlag); path != "" {
+		options.Catalog, err = loadLintSchemaCatalog(engine, path)
+		if err != nil {
+			fmt.Fprintf(stderr, "sql-review lint: %v\n", err)
+			return lintExitUsageError
+		}
+	}
+
+	inputs, err := collectLintChangeInputs(flags.Args(), engine, stdin, strings.TrimSpace(*baseFlag), strings.TrimSpace(*diffFlag))
+	if err != nil {
+		fmt.Fprintf(stderr, "sql-review lint: %v\n", err)
+		return lintExitUsageError
+	}
+
+	results := make([]lintFileResult, 0, len(inputs))
+	for _, input := range inputs {
+		inputOptions := options
+		inputOptions.Changes = input.Changes
+		results = append(results, lintFileResult{
+			File:   input.Name,
+			Engine: engine,
+			Result: AnalyzeByEngine(engine, input.Content, inputOptions),
+		})
+	}
+
+	switch format {
+	case "json", "sarif":
+		var payload any = results
+		if format == "sarif" {
+			inputs := make([]sarifInput, 0, len(results))
+			for _, item := range results {
+				inputs = append(inputs, sarifInput{URI: filepath.ToSlash(item.File), Result: item.Result})
+			}
+			payload = buildSARIFLog(engine, inputs)
+		}
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(payload); err != nil {
+			fmt.Fprintf(stderr, "sql-review lint: %v\n", err)
+			return lintExitUsageError
+		}
+	default:
+		writeLintText(stdout, results)
+	}
+
+	if lintThresholdReached(results, failOn) {
+		return lintExitThreshold
+	}
+	return lintExitOK
+}
+
+func parseLintFailOn(raw string) (IssueLevel, error) {
+	switch IssueLevel(strings.ToLower(strings.TrimSpace(raw))) {
+	case LevelError:
+		return LevelError, nil
+	case LevelWarning:
+		return LevelWarning, nil
+	case LevelInfo:
+		return LevelInfo, nil
+	default:
+		return "", fmt.Errorf("invalid --fail-on value: %s (expected error, warning or info)", raw)
+	}
+}
+
+func loadLintRuleConfig(path string) (lintRuleConfig, error) {
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return lintRuleConfig{}, fmt.Errorf("read rule config failed: %w", err)
+	}
+	var config lintRuleConfig
+	if err := json.Unmarshal(body, &config); err != nil {
+		return lintRuleConfig{}, fmt.Errorf("invalid rule config %s: %w", path, err)
+	}
+	return config, nil
+}
+
+func (config lintRuleConfig) disabledRuleSet() map[string]struct{} {
+	disabled := make(map[string]struct{})
+	for _, code := range config.DisabledRules {
+		if trimmed := strings.TrimSpace(code); trimmed != "" {
+			disabled[trimmed] = struct{}{}
+		}
+	}
+	for code, enabled := range config.Rules {
+		if trimmed := strings.TrimSpace(code); trimmed != "" && !enabled {
+			disabled[trimmed] = struct{}{}
+		}
+	}
+	return disabled
+}
+
+func loadLintSchemaCatalog(engine DBEngine, path string) (*schemaCatalog, error) {
+	if !supportsSchemaCatalog(engine) {
+		return nil, fmt.Errorf("--schema is not supported for engine %s", engine)
+	}
+	ddl, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read schema file failed: %w", err)
+	}
+	catalog, err := buildSchemaCatalog(engine, string(ddl))
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	return catalog, nil
+}
+
+func collectLintChangeInputs(paths []string, engine DBEngine, stdin io.Reader, basePath, diffPath string) ([]lintInput, error) {
+	switch {
+	case basePath != "" && diffPath != "":
+		return nil, errors.New("--base and --diff cannot be used together")
+	case basePath != "":
+		inputs, err := collectLintInputs(paths, engine, stdin)
+		if err != nil {
+			return nil, err
+		}
+		if len(inputs) != 1 {
+			return nil, errors.New("--base requires exactly one input file")
+		}
+		base, err := os.ReadFile(basePath)
+		if err != nil {
+			return nil, fmt.Errorf("read base file failed: %w", err)
+		}
+		inputs[0].Changes = diffLines(string(base), inputs[0].Content)
+		return inputs, nil
+	case diffPath != "":
+		return collectLintDiffInputs(paths, engine, stdin, diffPath)
+	}
+	return collectLintInputs(paths, engine, stdin)
+}
+
+func collectLintDiffInputs(paths []string, engine DBEngine, stdin io.Reader, diffPath string) ([]lintInput, error) {
+	var body []byte
+	var err error
+	if diffPath == "-" {
+		for _, path := range paths {
+			if path == "-" {
+				return nil, errors.New("--diff - and stdin input cannot be used together")
+			}
+		}
+		body, err = io.ReadAll(io.LimitReader(stdin, maxPayloadBytes))
+	} else {
+		body, err = os.ReadFile(diffPath)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read diff failed: %w", err)
+	}
+	files, err := parseUnifiedDiff(string(body))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(paths) == 0 {
+		inputs := make([]lintInput, 0, len(files))
+		for _, file := range files {
+			if file.NewPath == "" || !isLintTargetFile(file.NewPath, engine) {
+				continue
+			}
+			content, err := os.ReadFile(filepath.FromSlash(file.NewPath))
+			if err != nil {
+				return nil, err
+			}
+			inputs = append(inputs, lintInput{Name: file.NewPath, Content: string(content), Changes: file.Changes})
+		}
+		return inputs, nil
+	}
+
+	collected, err := collectLintInputs(paths, engine, stdin)
+	if err != nil {
+		return nil, err
+	}
+	inputs := make([]lintInput, 0, len(collected))
+	for _, input := range collected {
+		name := filepath.ToSlash(filepath.Clean(input.Name))
+		for _, file := range files {
+			if file.NewPath != "" && (name == file.NewPath || strings.HasSuffix(name, "/"+file.NewPath)) {
+				input.Changes = file.Changes
+				inputs = append(inputs, input)
+				break
+			}
+		}
+	}
+	return inputs, nil
+}
+
+func collectLintInputs(paths []string, engine DBEngine, stdin io.Reader) ([]lintInput, error) {
+	if len(paths) == 0 {
+		paths = []string{"-"}
+	}
+
+	inputs := make([]lintInput, 0, len(paths))
+	readStdin := false
+	for _, path := range paths {
+		if path == "-" {
+			if readStdin {
+				continue
+			}
+			readStdin = true
+			body, err := io.ReadAll(io.LimitReader(stdin, maxPayloadBytes))
+			if err != nil {
+				return nil, fmt.Errorf("read stdin failed: %w", err)
+			}
+			inputs = append(inputs, lintInput{Name: "<stdin>", Content: string(body)})
+			continue
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			body, err := os.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			inputs = append(inputs, lintInput{Name: path, Content: string(body)})
+			continue
+		}
+
+		files := make([]string, 0)
+		err = filepath.WalkDir(path, func(current string, entry fs.DirEntry, walkErr error) error {
+			if walkErr != nil {
+				return walkErr
+			}
+			if entry.IsDir() {
+				if current != path && strings.HasPrefix(entry.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if isLintTargetFile(current, engine) {
+				files = append(files, current)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(files)
+		for _, file := range files {
+			body, err := os.ReadFile(file)
+			if err != nil {
+				return nil, err
+			}
+			inputs = append(inputs, lintInput{Name: file, Content: string(body)})
+		}
+	}
+	return inputs, nil
+}
+
+func isLintTargetFile(path string, engine DBEngine) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return slices.Contains(engineExtensions(engine), ext)
+}
+
+func writeLintText(w io.Writer, results []lintFileResult) {
+	total := Summary{}
+	for _, item := range results {
+		for _, issue := range item.Result.Issues {
+			location := item.File
+			if issue.StartLine > 0 {
+				location = fmt.Sprintf("%s:%d:%d", item.File, issue.StartLine, issue.StartColumn)
+			} else if issue.StatementIndex > 0 {
+				location = fmt.Sprintf("%s:#%d", item.File, issue.StatementIndex)
+			}
+			fmt.Fprintf(w, "%s: %s [%s] %s\n", location, issue.Level, issue.Rule, issue.Message)
+			if issue.Suggestion != "" {
+				fmt.Fprintf(w, "    建议: %s\n", issue.Suggestion)
+			}
+		}
+		total.StatementCount += item.Result.Summary.StatementCount
+		total.ErrorCount += item.Result.Summary.ErrorCount
+		total.WarningCount += item.Result.Summary.WarningCount
+		total.InfoCount += item.Result.Summary.InfoCount
+		total.SuppressedCount += item.Result.Summary.SuppressedCount
+	}
+	fmt.Fprintf(w, "%d file(s), %d statement(s): %d error(s), %d warning(s), %d info",
+		len(results), total.StatementCount, total.ErrorCount, total.WarningCount, total.InfoCount)
+	if total.SuppressedCount > 0 {
+		fmt.Fprintf(w, ", %d suppressed", total.SuppressedCount)
+	}
+	fmt.Fprintln(w)
+}
+
+func lintThresholdReached(results []lintFileResult, failOn IssueLevel) bool {
+	threshold := severityWeight(failOn)
+	for _, item := range results {
+		for _, issue := range item.Result.Issues {
+			if severityWeight(issue.Level) >= threshold {
+				return true
+			}
+		}
+	}
+	return false
+}
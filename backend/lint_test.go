@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunLintCommandFailsOnThreshold(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.sql"), []byte("SELECT * FROM users LIMIT 1;\n"), 0o644); err != nil {
+		t.Fatalf("write file err: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.md"), []byte("DROP TABLE users;"), 0o644); err != nil {
+		t.Fatalf("write file err: %v", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	code := runLintCommand([]string{dir}, strings.NewReader(""), &stdout, &stderr)
+	if code != lintExitOK {
+		t.Fatalf("expected exit 0 for warnings with --fail-on=error, got %d, stderr=%s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "a.sql:1:8: warning [select_star]") {
+		t.Fatalf("expected located select_star finding, got: %s", stdout.String())
+	}
+	if strings.Contains(stdout.String(), "notes.md") {
+		t.Fatalf("non-sql files in directories should be skipped, got: %s", stdout.String())
+	}
+
+	stdout.Reset()
+	code = runLintCommand([]string{"--fail-on=warning", dir}, strings.NewReader(""), &stdout, &stderr)
+	if code != lintExitThreshold {
+		t.Fatalf("expected exit 1 with --fail-on=warning, got %d", code)
+	}
+}
+
+func TestRunLintCommandRejectsUnknownEngine(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := runLintCommand([]string{"--engine", "postgress", "-"}, strings.NewReader("SELECT 1;"), &stdout, &stderr)
+	if code != lintExitUsageError || !strings.Contains(stderr.String(), `unknown engine "postgress"`) {
+		t.Fatalf("expected usage error for unknown engine, got %d, stderr=%s", code, stderr.String())
+	}
+}
+
+func TestRunLintCommandReadsStdinWithConfig(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "rules.json")
+	config := `{"engine": "postgresql", "disabledRules": ["pg_update_without_where"], "rules": {"missing_statement_terminator": false}}`
+	if err := os.WriteFile(configPath, []byte(config), 0o644); err != nil {
+		t.Fatalf("write config err: %v", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	code := runLintCommand([]string{"--config", configPath, "--format", "json"}, strings.NewReader("UPDATE users SET status = 1"), &stdout, &stderr)
+	if code != lintExitThreshold {
+		t.Fatalf("expected threshold exit from always-enabled terminator rule, got %d", code)
+	}
+	if strings.Contains(stdout.String(), "pg_update_without_where") {
+		t.Fatalf("disabled rule should not be reported, got: %s", stdout.String())
+	}
+	if !strings.Contains(stdout.String(), `"engine": "postgresql"`) || !strings.Contains(stdout.String(), "missing_statement_terminator") {
+		t.Fatalf("unexpected json output: %s", stdout.String())
+	}
+}
+
+func TestRunLintCommandAppliesSeverityOverrides(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "rules.json")
+	if err := os.WriteFile(configPath, []byte(`{"severityOverrides": {"select_star": "error"}}`), 0o644); err != nil {
+		t.Fatalf("write config err: %v", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	code := runLintCommand([]string{"--config", configPath}, strings.NewReader("SELECT * FROM users LIMIT 1;\n"), &stdout, &stderr)
+	if code != lintExitThreshold || !strings.Contains(stdout.String(), "error [select_star]") {
+		t.Fatalf("expected select_star upgraded to error, got code=%d output=%s", code, stdout.String())
+	}
+
+	if err := os.WriteFile(configPath, []byte(`{"ruleParams": {"too_many_statements": {"max_statements": -1}}}`), 0o644); err != nil {
+		t.Fatalf("write config err: %v", err)
+	}
+	stderr.Reset()
+	if code := runLintCommand([]string{"--config", configPath}, strings.NewReader("SELECT 1;"), &stdout, &stderr); code != lintExitUsageError {
+		t.Fatalf("expected usage error for invalid param, got %d", code)
+	}
+}
+
+func TestRunLintCommandRejectsInvalidFailOn(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := runLintCommand([]string{"--fail-on=fatal"}, strings.NewReader(""), &stdout, &stderr); code != lintExitUsageError {
+		t.Fatalf("expected usage error exit, got %d", code)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -20,17 +21,19 @@ const maxPayloadBytes = 4 << 20
 
 var historyStore *HistoryStore
 
-var alwaysEnabledRules = map[string]struct{}{
-	"empty_input":                        {},
-	"missing_statement_terminator":       {},
-	"mongo_missing_statement_terminator": {},
-	"fullwidth_statement_terminator":     {},
-}
+var alwaysEnabledRules = defaultRuleRegistry().alwaysEnabledCodes()
 
 type checkRequest struct {
 	SQL           string   `json:"sql"`
 	Engine        string   `json:"engine"`
+	Profile       string   `json:"profile"`
+	FileName      string   `json:"fileName"`
+	Base          *string  `json:"base"`
+	Diff          string   `json:"diff"`
+	Schema        string   `json:"schema"`
+	SchemaName    string   `json:"schemaName"`
 	DisabledRules []string `json:"disabledRules"`
+	RuleOverrides
 }
 
 type errorResponse struct {
@@ -44,13 +47,17 @@ type healthResponse struct {
 }
 
 type checkAPIResponse struct {
-	RequestID      string   `json:"requestId"`
-	HistoryID      int64    `json:"historyId"`
-	HistoryWarning string   `json:"historyWarning,omitempty"`
-	Engine         DBEngine `json:"engine"`
-	Source         string   `json:"source"`
-	FileName       string   `json:"fileName"`
-	DisabledRules  []string `json:"disabledRules"`
+	RequestID      string           `json:"requestId"`
+	HistoryID      int64            `json:"historyId"`
+	HistoryWarning string           `json:"historyWarning,omitempty"`
+	Engine         DBEngine         `json:"engine"`
+	Detection      *EngineDetection `json:"detection,omitempty"`
+	Source         string           `json:"source"`
+	FileName       string           `json:"fileName"`
+	Profile        string           `json:"profile,omitempty"`
+	SchemaName     string           `json:"schemaName,omitempty"`
+	DisabledRules  []string         `json:"disabledRules"`
+	RuleOverrides
 	CheckResponse
 }
 
@@ -59,6 +66,7 @@ type historyListResponse struct {
 	Total  int           `json:"total"`
 	Limit  int           `json:"limit"`
 	Offset int           `json:"offset"`
+	Facets HistoryFacets `json:"facets"`
 }
 
 type historyDeleteRequest struct {
@@ -66,14 +74,26 @@ type historyDeleteRequest struct {
 }
 
 type uploadReadResult struct {
-	SQLContent    string
-	Source        string
-	FileName      string
-	Engine        DBEngine
-	DisabledRules map[string]struct{}
+	SQLContent       string
+	Source           string
+	FileName         string
+	Engine           DBEngine
+	Profile          string
+	DisabledRules    map[string]struct{}
+	HasDisabledRules bool
+	Overrides        RuleOverrides
+	Base             *string
+	Diff             string
+	Schema           string
+	SchemaName       string
+	AutoEngine       bool
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "lint" {
+		os.Exit(runLintCommand(os.Args[2:], os.Stdin, os.Stdout, os.Stderr))
+	}
+
 	dbPath := strings.TrimSpace(os.Getenv("SQL_REVIEW_DB_PATH"))
 	if dbPath == "" {
 		dbPath = "./data/sql_review.db"
@@ -93,9 +113,14 @@ func main() {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/api/v1/health", handleHealth)
 	mux.HandleFunc("/api/v1/rules", handleRules)
+	mux.HandleFunc("/api/v1/engines", handleEngines)
 	mux.HandleFunc("/api/v1/check", handleCheck)
 	mux.HandleFunc("/api/v1/history", handleHistoryList)
 	mux.HandleFunc("/api/v1/history/", handleHistoryDetail)
+	mux.HandleFunc("/api/v1/profiles", handleProfiles)
+	mux.HandleFunc("/api/v1/profiles/", handleProfileDetail)
+	mux.HandleFunc("/api/v1/schemas", handleSchemas)
+	mux.HandleFunc("/api/v1/schemas/", handleSchemaDetail)
 
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -123,7 +148,7 @@ func corsMiddleware(next http.Handler) http.Handler {
 	}
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", allowedOrigin)
-		w.Header().Set("Access-Control-Allow-Methods", "GET,POST,DELETE,OPTIONS")
+		w.Header().Set("Access-Control-Allow-Methods", "GET,POST,PUT,DELETE,OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type,Authorization")
 		if r.Method == http.MethodOptions {
 			w.WriteHeader(http.StatusNoContent)
@@ -151,7 +176,11 @@ func handleRules(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	engine := NormalizeEngine(r.URL.Query().Get("engine"))
+	engine, err := ResolveEngine(r.URL.Query().Get("engine"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
+	}
 	rulesVersionValue, rules := RulesForEngine(engine)
 
 	writeJSON(w, http.StatusOK, map[string]any{
@@ -162,6 +191,17 @@ func handleRules(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+func handleEngines(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "only GET is allowed"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"default": EngineMySQL,
+		"engines": RegisteredEngines(),
+	})
+}
+
 func handleCheck(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "only POST is allowed"})
@@ -174,8 +214,19 @@ func handleCheck(w http.ResponseWriter, r *http.Request) {
 	var sqlContent string
 	source := "paste"
 	fileName := ""
-	engine := NormalizeEngine(r.URL.Query().Get("engine"))
+	engine, autoEngine, err := resolveRequestEngine(r.URL.Query().Get("engine"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
+	}
+	profileName := strings.TrimSpace(r.URL.Query().Get("profile"))
 	disabledRules := make(map[string]struct{})
+	explicitRules := false
+	requestOverrides := RuleOverrides{}
+	var baseContent *string
+	diffContent := ""
+	schemaDDL := ""
+	schemaName := strings.TrimSpace(r.URL.Query().Get("schemaName"))
 
 	switch {
 	case strings.Contains(contentType, "application/json"):
@@ -185,7 +236,22 @@ func handleCheck(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		sqlContent = req.SQL
-		engine = NormalizeEngine(req.Engine)
+		if engine, autoEngine, err = resolveRequestEngine(req.Engine); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+			return
+		}
+		if strings.TrimSpace(req.Profile) != "" {
+			profileName = strings.TrimSpace(req.Profile)
+		}
+		fileName = strings.TrimSpace(req.FileName)
+		explicitRules = req.DisabledRules != nil
+		requestOverrides = req.RuleOverrides
+		baseContent = req.Base
+		diffContent = req.Diff
+		schemaDDL = req.Schema
+		if strings.TrimSpace(req.SchemaName) != "" {
+			schemaName = strings.TrimSpace(req.SchemaName)
+		}
 		for _, code := range req.DisabledRules {
 			if trimmed := strings.TrimSpace(code); trimmed != "" {
 				disabledRules[trimmed] = struct{}{}
@@ -201,7 +267,19 @@ func handleCheck(w http.ResponseWriter, r *http.Request) {
 		source = parsed.Source
 		fileName = parsed.FileName
 		engine = parsed.Engine
+		autoEngine = parsed.AutoEngine
+		if parsed.Profile != "" {
+			profileName = parsed.Profile
+		}
 		disabledRules = parsed.DisabledRules
+		explicitRules = parsed.HasDisabledRules
+		requestOverrides = parsed.Overrides
+		baseContent = parsed.Base
+		diffContent = parsed.Diff
+		schemaDDL = parsed.Schema
+		if parsed.SchemaName != "" {
+			schemaName = parsed.SchemaName
+		}
 	case strings.Contains(contentType, "text/plain"):
 		body, err := io.ReadAll(r.Body)
 		if err != nil {
@@ -214,14 +292,69 @@ func handleCheck(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var detection *EngineDetection
+	if autoEngine {
+		detected := DetectEngine(sqlContent, fileName)
+		detection = &detected
+		engine = detected.Engine
+	}
+
+	changes, err := resolveReviewChanges(sqlContent, baseContent, diffContent, fileName)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
+	}
+
+	catalog, schemaName, err := resolveCheckSchema(r.Context(), engine, schemaDDL, schemaName)
+	if err != nil {
+		if errors.Is(err, ErrSchemaNotFound) {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: fmt.Sprintf("schema snapshot not found for engine %s: %s", engine, schemaName)})
+			return
+		}
+		if errors.Is(err, errSchemaLookupFailed) {
+			log.Printf("resolve schema snapshot failed: %v", err)
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "failed to load schema snapshot"})
+			return
+		}
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
+	}
+
+	profile, err := resolveCheckProfile(r.Context(), engine, profileName, explicitRules)
+	if err != nil {
+		if errors.Is(err, ErrProfileNotFound) {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: fmt.Sprintf("rule profile not found for engine %s: %s", engine, profileName)})
+			return
+		}
+		log.Printf("resolve rule profile failed: %v", err)
+		writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "failed to load rule profile"})
+		return
+	}
+	overrides := requestOverrides
+	if profile != nil {
+		profileName = profile.Name
+		for _, code := range profile.DisabledRules {
+			disabledRules[code] = struct{}{}
+		}
+		overrides = profile.RuleOverrides.merge(requestOverrides)
+	}
+	overrides, err = overrides.validate(engine)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
+	}
+
 	forcedRules := enforceAlwaysEnabledRules(disabledRules)
 	if len(forcedRules) > 0 {
 		log.Printf("enforce always-enabled rules: %s", strings.Join(forcedRules, ", "))
 	}
 
-	result := AnalyzeByEngine(engine, sqlContent, AnalyzeOptions{
+	result := AnalyzeByEngine(engine, sqlContent, overrides.apply(AnalyzeOptions{
 		DisabledRules: disabledRules,
-	})
+		Changes:       changes,
+		Catalog:       catalog,
+		Detection:     detection,
+	}))
 	requestID := fmt.Sprintf("req-%d", time.Now().UnixNano())
 	disabledRulesSlice := disabledRulesToSlice(disabledRules)
 
@@ -229,13 +362,16 @@ func handleCheck(w http.ResponseWriter, r *http.Request) {
 	if len(forcedRules) > 0 {
 		historyWarning = fmt.Sprintf("以下基础规则不可关闭，已自动启用：%s", strings.Join(forcedRules, ", "))
 	}
-	historyID, err := historyStore.Save(SaveHistoryInput{
+	historyID, err := historyStore.Save(r.Context(), SaveHistoryInput{
 		RequestID:     requestID,
 		Engine:        engine,
 		Source:        source,
 		FileName:      fileName,
 		SQLText:       sqlContent,
 		DisabledRules: disabledRulesSlice,
+		ProfileName:   profileName,
+		Profile:       profile,
+		Overrides:     overrides,
 		CheckResult:   result,
 	})
 	if err != nil {
@@ -247,19 +383,42 @@ func handleCheck(w http.ResponseWriter, r *http.Request) {
 		log.Printf("save history failed: %v", err)
 	}
 
+	if acceptsSARIF(r) {
+		report := buildSARIFLog(engine, []sarifInput{{URI: sarifInputURI(fileName, engine), Result: result}})
+		report.Runs[0].Properties["requestId"] = requestID
+		report.Runs[0].Properties["historyId"] = historyID
+		if profileName != "" {
+			report.Runs[0].Properties["profile"] = profileName
+		}
+		if historyWarning != "" {
+			report.Runs[0].Properties["historyWarning"] = historyWarning
+		}
+		if detection != nil {
+			report.Runs[0].Properties["engineDetection"] = detection
+		}
+		writeJSONWithContentType(w, http.StatusOK, sarifContentType, report)
+		return
+	}
+
 	writeJSON(w, http.StatusOK, checkAPIResponse{
 		RequestID:      requestID,
 		HistoryID:      historyID,
 		HistoryWarning: historyWarning,
 		Engine:         engine,
+		Detection:      detection,
 		Source:         source,
 		FileName:       fileName,
+		Profile:        profileName,
+		SchemaName:     schemaName,
 		DisabledRules:  disabledRulesSlice,
+		RuleOverrides:  overrides,
 		CheckResponse: CheckResponse{
 			RulesVersion: result.RulesVersion,
 			CheckedAt:    result.CheckedAt,
 			Summary:      result.Summary,
 			Issues:       result.Issues,
+			Suppressed:   result.Suppressed,
+			Diff:         result.Diff,
 			Advice:       result.Advice,
 		},
 	})
@@ -268,30 +427,32 @@ func handleCheck(w http.ResponseWriter, r *http.Request) {
 func handleHistoryList(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
-		limit := parseIntWithDefault(r.URL.Query().Get("limit"), 20)
-		offset := parseIntWithDefault(r.URL.Query().Get("offset"), 0)
-		if limit <= 0 {
-			limit = 20
-		}
-		if limit > 100 {
-			limit = 100
-		}
-		if offset < 0 {
-			offset = 0
+		query, err := parseHistoryQuery(r.URL.Query())
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+			return
 		}
 
-		items, total, err := historyStore.List(limit, offset)
+		items, total, err := historyStore.List(r.Context(), query)
 		if err != nil {
 			log.Printf("list history failed: %v", err)
 			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "failed to list history"})
 			return
 		}
 
+		facets, err := historyStore.Facets(r.Context(), query)
+		if err != nil {
+			log.Printf("list history facets failed: %v", err)
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "failed to list history"})
+			return
+		}
+
 		writeJSON(w, http.StatusOK, historyListResponse{
 			Items:  items,
 			Total:  total,
-			Limit:  limit,
-			Offset: offset,
+			Limit:  query.Limit,
+			Offset: query.Offset,
+			Facets: facets,
 		})
 	case http.MethodDelete:
 		var req historyDeleteRequest
@@ -306,7 +467,7 @@ func handleHistoryList(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		deleted, err := historyStore.DeleteByIDs(ids)
+		deleted, err := historyStore.DeleteByIDs(r.Context(), ids)
 		if err != nil {
 			log.Printf("delete history failed: %v", err)
 			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "failed to delete history"})
@@ -328,7 +489,7 @@ func handleHistoryDetail(w http.ResponseWriter, r *http.Request) {
 
 	switch r.Method {
 	case http.MethodGet:
-		detail, getErr := historyStore.GetByID(id)
+		detail, getErr := historyStore.GetByID(r.Context(), id)
 		if getErr != nil {
 			if errors.Is(getErr, ErrHistoryNotFound) {
 				writeJSON(w, http.StatusNotFound, errorResponse{Error: "history not found"})
@@ -341,7 +502,7 @@ func handleHistoryDetail(w http.ResponseWriter, r *http.Request) {
 
 		writeJSON(w, http.StatusOK, detail)
 	case http.MethodDelete:
-		deleted, delErr := historyStore.DeleteByIDs([]int64{id})
+		deleted, delErr := historyStore.DeleteByIDs(r.Context(), []int64{id})
 		if delErr != nil {
 			log.Printf("delete history detail failed: %v", delErr)
 			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "failed to delete history"})
@@ -404,15 +565,54 @@ func readSQLFromUpload(r *http.Request) (uploadReadResult, error) {
 		return uploadReadResult{}, err
 	}
 
-	engine := NormalizeEngine(r.FormValue("engine"))
+	overrides, err := parseRuleOverridesForm(r.FormValue("severityOverrides"), r.FormValue("ruleParams"))
+	if err != nil {
+		return uploadReadResult{}, err
+	}
+
+	engine, autoEngine, err := resolveRequestEngine(r.FormValue("engine"))
+	if err != nil {
+		return uploadReadResult{}, err
+	}
+	profile := strings.TrimSpace(r.FormValue("profile"))
+	_, hasDisabledRules := r.MultipartForm.Value["disabledRules"]
+
+	base, hasBase, err := readFormText(r, "base")
+	if err != nil {
+		return uploadReadResult{}, err
+	}
+	diff, _, err := readFormText(r, "diff")
+	if err != nil {
+		return uploadReadResult{}, err
+	}
+	schema, _, err := readFormText(r, "schema")
+	if err != nil {
+		return uploadReadResult{}, err
+	}
+	schemaName := strings.TrimSpace(r.FormValue("schemaName"))
+	var baseContent *string
+	if hasBase {
+		baseContent = &base
+	}
 
 	if sql := strings.TrimSpace(r.FormValue("sql")); sql != "" {
+		if baseContent != nil || strings.TrimSpace(diff) != "" {
+			sql = r.FormValue("sql")
+		}
 		return uploadReadResult{
-			SQLContent:    sql,
-			Source:        "paste",
-			FileName:      "",
-			Engine:        engine,
-			DisabledRules: disabledRules,
+			SQLContent:       sql,
+			Source:           "paste",
+			FileName:         "",
+			Engine:           engine,
+			Profile:          profile,
+			DisabledRules:    disabledRules,
+			HasDisabledRules: hasDisabledRules,
+			Overrides:        overrides,
+			Base:             baseContent,
+			Diff:             diff,
+			Schema:           schema,
+			SchemaName:       schemaName,
+			AutoEngine:       autoEngine,
 		}, nil
 	}
 
@@ -435,14 +635,43 @@ func readSQLFromUpload(r *http.Request) (uploadReadResult, error) {
 	}
 
 	return uploadReadResult{
-		SQLContent:    string(body),
-		Source:        "upload",
-		FileName:      header.Filename,
-		Engine:        engine,
-		DisabledRules: disabledRules,
+		SQLContent:       string(body),
+		Source:           "upload",
+		FileName:         header.Filename,
+		Engine:           engine,
+		Profile:          profile,
+		DisabledRules:    disabledRules,
+		HasDisabledRules: hasDisabledRules,
+		Overrides:        overrides,
+		Base:             baseContent,
+		Diff:             diff,
+		Schema:           schema,
+		SchemaName:       schemaName,
+		AutoEngine:       autoEngine,
 	}, nil
 }
 
+func readFormText(r *http.Request, field string) (string, bool, error) {
+	if values, found := r.MultipartForm.Value[field]; found && len(values) > 0 {
+		return values[0], true, nil
+	}
+	if _, found := r.MultipartForm.File[field]; !found {
+		return "", false, nil
+	}
+
+	file, _, err := r.FormFile(field)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read form file: %s", field)
+	}
+	defer file.Close()
+
+	body, err := io.ReadAll(io.LimitReader(file, maxPayloadBytes))
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read form file: %s", field)
+	}
+	return string(body), true, nil
+}
+
 func parseDisabledRulesString(raw string) (map[string]struct{}, error) {
 	rules := make(map[string]struct{})
 	trimmed := strings.TrimSpace(raw)
@@ -472,6 +701,21 @@ func parseDisabledRulesString(raw string) (map[string]struct{}, error) {
 	return rules, nil
 }
 
+func parseRuleOverridesForm(severityRaw, paramsRaw string) (RuleOverrides, error) {
+	overrides := RuleOverrides{}
+	if trimmed := strings.TrimSpace(severityRaw); trimmed != "" {
+		if err := json.Unmarshal([]byte(trimmed), &overrides.SeverityOverrides); err != nil {
+			return RuleOverrides{}, errors.New("invalid severityOverrides payload")
+		}
+	}
+	if trimmed := strings.TrimSpace(paramsRaw); trimmed != "" {
+		if err := json.Unmarshal([]byte(trimmed), &overrides.RuleParams); err != nil {
+			return RuleOverrides{}, errors.New("invalid ruleParams payload")
+		}
+	}
+	return overrides, nil
+}
+
 func enforceAlwaysEnabledRules(disabled map[string]struct{}) []string {
 	if disabled == nil || len(disabled) == 0 {
 		return nil
@@ -501,14 +745,33 @@ func disabledRulesToSlice(disabled map[string]struct{}) []string {
 func isLikelySQLFile(header *multipart.FileHeader) bool {
 	name := strings.ToLower(header.Filename)
 	ext := strings.ToLower(filepath.Ext(name))
-	if ext == ".sql" || ext == ".txt" || ext == ".js" || ext == ".mongo" {
+	if ext == ".txt" {
 		return true
 	}
+	for _, engine := range RegisteredEngines() {
+		if slices.Contains(engine.Extensions, ext) {
+			return true
+		}
+	}
 
 	contentType := strings.ToLower(header.Header.Get("Content-Type"))
 	return strings.Contains(contentType, "sql") || strings.Contains(contentType, "text/plain")
 }
 
+func checkUnchangedEngine(raw string, existing DBEngine, subject string) error {
+	if strings.TrimSpace(raw) == "" {
+		return nil
+	}
+	engine, err := ResolveEngine(raw)
+	if err != nil {
+		return err
+	}
+	if engine != existing {
+		return fmt.Errorf("%s engine cannot be changed", subject)
+	}
+	return nil
+}
+
 func parseIntWithDefault(raw string, defaultValue int) int {
 	trimmed := strings.TrimSpace(raw)
 	if trimmed == "" {
@@ -522,7 +785,11 @@ func parseIntWithDefault(raw string, defaultValue int) int {
 }
 
 func writeJSON(w http.ResponseWriter, status int, value any) {
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	writeJSONWithContentType(w, status, "application/json", value)
+}
+
+func writeJSONWithContentType(w http.ResponseWriter, status int, contentType string, value any) {
+	w.Header().Set("Content-Type", contentType+"; charset=utf-8")
 	w.WriteHeader(status)
 	if err := json.NewEncoder(w).Encode(value); err != nil {
 		log.Printf("write response error: %v", err)
@@ -2,6 +2,7 @@ package main
 
 import (
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -34,3 +35,18 @@ func TestEnforceAlwaysEnabledRulesNil(t *testing.T) {
 		t.Fatalf("nil map should return empty removed list, got=%v", removed)
 	}
 }
+
+func TestCheckUnchangedEngine(t *testing.T) {
+	if err := checkUnchangedEngine("", EngineMySQL, "profile"); err != nil {
+		t.Fatalf("omitted engine should be accepted: %v", err)
+	}
+	if err := checkUnchangedEngine("pg", EnginePostgreSQL, "profile"); err != nil {
+		t.Fatalf("engine aliases should be accepted: %v", err)
+	}
+	if err := checkUnchangedEngine("postgresql", EngineMySQL, "schema"); err == nil || !strings.Contains(err.Error(), "schema engine cannot be changed") {
+		t.Fatalf("expected engine change to be rejected, got %v", err)
+	}
+	if err := checkUnchangedEngine("postgress", EngineMySQL, "profile"); err == nil || !strings.Contains(err.Error(), "supported engines") {
+		t.Fatalf("expected unknown engine to list supported engines, got %v", err)
+	}
+}
@@ -0,0 +1,588 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type mongoTokenKind int
+
+const (
+	mongoTokenIdent mongoTokenKind = iota
+	mongoTokenString
+	mongoTokenNumber
+	mongoTokenRegex
+	mongoTokenPunct
+)
+
+type mongoToken struct {
+	Kind  mongoTokenKind
+	Text  string
+	Start int
+	End   int
+}
+
+type mongoValueKind int
+
+const (
+	mongoValueObject mongoValueKind = iota
+	mongoValueArray
+	mongoValueString
+	mongoValueNumber
+	mongoValueBool
+	mongoValueNull
+	mongoValueRegex
+	mongoValueExpr
+)
+
+type mongoValue struct {
+	Kind   mongoValueKind
+	Text   string
+	Fields []mongoField
+	Items  []mongoValue
+	Span   sourceSpan
+}
+
+type mongoField struct {
+	Key     string
+	KeySpan sourceSpan
+	Value   mongoValue
+}
+
+type mongoCall struct {
+	Name string
+	Args []mongoValue
+	Span sourceSpan
+}
+
+type mongoCallChain struct {
+	Root       string
+	Collection string
+	Calls      []mongoCall
+	Span       sourceSpan
+}
+
+var mongoMultiCharPuncts = []string{"===", "!==", "...", "=>", "==", "!=", "<=", ">=", "&&", "||", "??"}
+
+func tokenizeMongo(content string, span sourceSpan) []mongoToken {
+	tokens := make([]mongoToken, 0)
+	regexAllowed := func() bool {
+		if len(tokens) == 0 {
+			return true
+		}
+		last := tokens[len(tokens)-1]
+		if last.Kind == mongoTokenIdent {
+			return last.Text == "return" || last.Text == "typeof"
+		}
+		return last.Kind == mongoTokenPunct && last.Text != ")" && last.Text != "]" && last.Text != "}"
+	}
+
+	pos := span.Start
+	for pos < span.End {
+		ch, size := utf8.DecodeRuneInString(content[pos:])
+		next := byte(0)
+		if pos+size < span.End {
+			next = content[pos+size]
+		}
+		start := pos
+		switch {
+		case unicode.IsSpace(ch):
+			pos += size
+			continue
+		case ch == '/' && next == '/':
+			end := strings.IndexByte(content[pos:span.End], '\n')
+			if end < 0 {
+				end = span.End - pos
+			}
+			pos += end
+			continue
+		case ch == '/' && next == '*':
+			end := strings.Index(content[pos+2:span.End], "*/")
+			if end < 0 {
+				pos = span.End
+			} else {
+				pos += end + 4
+			}
+			continue
+		case ch == '"' || ch == '\'' || ch == '`':
+			pos = scanMongoQuoted(content, pos+1, span.End, byte(ch))
+			tokens = append(tokens, mongoToken{Kind: mongoTokenString, Text: content[start:pos], Start: start, End: pos})
+			continue
+		case ch == '/' && regexAllowed():
+			pos = scanMongoRegex(content, pos+1, span.End)
+			tokens = append(tokens, mongoToken{Kind: mongoTokenRegex, Text: content[start:pos], Start: start, End: pos})
+			continue
+		case unicode.IsDigit(ch) || (ch == '.' && next >= '0' && next <= '9'):
+			for pos < span.End && (isMongoIdentByte(content[pos]) || content[pos] == '.') {
+				pos++
+			}
+			tokens = append(tokens, mongoToken{Kind: mongoTokenNumber, Text: content[start:pos], Start: start, End: pos})
+			continue
+		case ch == '_' || ch == '$' || unicode.IsLetter(ch):
+			for pos < span.End {
+				r, width := utf8.DecodeRuneInString(content[pos:])
+				if r != '_' && r != '$' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+					break
+				}
+				pos += width
+			}
+			tokens = append(tokens, mongoToken{Kind: mongoTokenIdent, Text: content[start:pos], Start: start, End: pos})
+			continue
+		}
+
+		width := size
+		for _, punct := range mongoMultiCharPuncts {
+			if strings.HasPrefix(content[pos:span.End], punct) {
+				width = len(punct)
+				break
+			}
+		}
+		pos += width
+		tokens = append(tokens, mongoToken{Kind: mongoTokenPunct, Text: content[start:pos], Start: start, End: pos})
+	}
+	return tokens
+}
+
+func scanMongoQuoted(content string, pos, end int, quote byte) int {
+	for pos < end {
+		switch content[pos] {
+		case '\\':
+			pos += 2
+			continue
+		case quote:
+			return pos + 1
+		}
+		pos++
+	}
+	return end
+}
+
+func scanMongoRegex(content string, pos, end int) int {
+	inClass := false
+	for pos < end {
+		switch ch := content[pos]; {
+		case ch == '\\':
+			pos += 2
+			continue
+		case ch == '[':
+			inClass = true
+		case ch == ']':
+			inClass = false
+		case ch == '\n':
+			return pos
+		case ch == '/' && !inClass:
+			pos++
+			for pos < end && isMongoIdentByte(content[pos]) {
+				pos++
+			}
+			return pos
+		}
+		pos++
+	}
+	return end
+}
+
+func isMongoIdentByte(ch byte) bool {
+	return ch == '_' || ch == '$' || (ch >= '0' && ch <= '9') || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+}
+
+func mongoStringValue(raw string) string {
+	if len(raw) < 2 {
+		return ""
+	}
+	inner := raw[1:]
+	if inner[len(inner)-1] == raw[0] {
+		inner = inner[:len(inner)-1]
+	}
+	if !strings.Contains(inner, `\`) {
+		return inner
+	}
+	var builder strings.Builder
+	for i := 0; i < len(inner); i++ {
+		if inner[i] == '\\' && i+1 < len(inner) {
+			i++
+			switch inner[i] {
+			case 'n':
+				builder.WriteByte('\n')
+			case 't':
+				builder.WriteByte('\t')
+			default:
+				builder.WriteByte(inner[i])
+			}
+			continue
+		}
+		builder.WriteByte(inner[i])
+	}
+	return builder.String()
+}
+
+type mongoParser struct {
+	content  string
+	tokens   []mongoToken
+	pos      int
+	bindings mongoBindings
+}
+
+type mongoBindings map[string]mongoValue
+
+func parseMongoCallChain(content string, span sourceSpan, bindings mongoBindings) *mongoCallChain {
+	p := &mongoParser{content: content, tokens: tokenizeMongo(content, span), bindings: bindings}
+	switch p.text(0) {
+	case "var", "let", "const":
+		p.pos++
+	}
+	if p.text(0) == "await" {
+		p.pos++
+	}
+	if p.kind(0) == mongoTokenIdent && p.text(1) == "=" {
+		p.pos += 2
+	}
+	if p.text(0) == "await" {
+		p.pos++
+	}
+	if p.kind(0) != mongoTokenIdent {
+		return nil
+	}
+
+	chain := &mongoCallChain{Root: p.tokens[p.pos].Text, Span: sourceSpan{Start: p.tokens[p.pos].Start}}
+	p.pos++
+	path := make([]string, 0)
+	for p.pos < len(p.tokens) {
+		switch {
+		case p.text(0) == "." && p.kind(1) == mongoTokenIdent:
+			path = append(path, p.tokens[p.pos+1].Text)
+			p.pos += 2
+			if len(chain.Calls) > 0 && p.text(0) != "(" {
+				return chain.finish()
+			}
+		case p.text(0) == "[" && p.kind(1) == mongoTokenString && p.text(2) == "]" && len(chain.Calls) == 0:
+			path = append(path, mongoStringValue(p.tokens[p.pos+1].Text))
+			p.pos += 3
+		case p.text(0) == "(" && len(path) > 0:
+			start := p.tokens[p.pos].Start
+			name := path[len(path)-1]
+			args := p.parseArguments()
+			call := mongoCall{Name: name, Args: args, Span: sourceSpan{Start: start, End: p.tokens[p.pos-1].End}}
+			switch {
+			case len(chain.Calls) > 0:
+				chain.Calls = append(chain.Calls, call)
+			case name == "getCollection" && len(args) > 0 && args[0].Kind == mongoValueString:
+				chain.Collection, path = args[0].Text, path[:0]
+			case name == "getSiblingDB" || name == "getDB":
+				chain.Collection, path = "", path[:0]
+			default:
+				if len(path) > 1 {
+					chain.Collection = strings.Join(path[:len(path)-1], ".")
+				}
+				chain.Calls = append(chain.Calls, call)
+			}
+		default:
+			return chain.finish()
+		}
+	}
+	return chain.finish()
+}
+
+func (bindings mongoBindings) record(content string, span sourceSpan, chain *mongoCallChain) {
+	p := &mongoParser{content: content, tokens: tokenizeMongo(content, span)}
+	switch p.text(0) {
+	case "var", "let", "const":
+		p.pos++
+	}
+	if p.kind(0) == mongoTokenIdent && p.text(1) == "=" && p.pos+2 < len(p.tokens) {
+		name := p.text(0)
+		delete(bindings, name)
+		p.pos += 2
+		if value := p.parseValue(); p.pos == len(p.tokens) && (value.Kind == mongoValueObject || value.Kind == mongoValueArray) {
+			bindings[name] = value
+		}
+		return
+	}
+	for i, token := range p.tokens {
+		if _, found := bindings[token.Text]; !found || token.Kind != mongoTokenIdent || (i > 0 && p.tokens[i-1].Text == ".") {
+			continue
+		}
+		next := ""
+		if i+1 < len(p.tokens) {
+			next = p.tokens[i+1].Text
+		}
+		if chain == nil || chain.Collection == "" || next == "." || next == "[" || next == "=" {
+			delete(bindings, token.Text)
+		}
+	}
+}
+
+func (chain *mongoCallChain) finish() *mongoCallChain {
+	if len(chain.Calls) == 0 {
+		return nil
+	}
+	chain.Span.End = chain.Calls[len(chain.Calls)-1].Span.End
+	return chain
+}
+
+func (p *mongoParser) text(offset int) string {
+	if p.pos+offset >= len(p.tokens) {
+		return ""
+	}
+	return p.tokens[p.pos+offset].Text
+}
+
+func (p *mongoParser) kind(offset int) mongoTokenKind {
+	if p.pos+offset >= len(p.tokens) {
+		return mongoTokenPunct
+	}
+	return p.tokens[p.pos+offset].Kind
+}
+
+func (p *mongoParser) parseArguments() []mongoValue {
+	p.pos++
+	args := make([]mongoValue, 0)
+	for p.pos < len(p.tokens) && p.text(0) != ")" {
+		if p.text(0) == "," {
+			p.pos++
+			continue
+		}
+		args = append(args, p.parseValue())
+	}
+	if p.pos < len(p.tokens) {
+		p.pos++
+	}
+	return args
+}
+
+func (p *mongoParser) parseValue() mongoValue {
+	if p.pos >= len(p.tokens) {
+		return mongoValue{Kind: mongoValueExpr}
+	}
+	start := p.pos
+	value := p.parsePrimary()
+	switch p.text(0) {
+	case ",", ")", "]", "}", "":
+		return value
+	}
+	p.pos = start
+	return p.parseExpression()
+}
+
+func (p *mongoParser) parsePrimary() mongoValue {
+	token := p.tokens[p.pos]
+	span := sourceSpan{Start: token.Start, End: token.End}
+	switch token.Kind {
+	case mongoTokenString:
+		p.pos++
+		return mongoValue{Kind: mongoValueString, Text: mongoStringValue(token.Text), Span: span}
+	case mongoTokenNumber:
+		p.pos++
+		return mongoValue{Kind: mongoValueNumber, Text: token.Text, Span: span}
+	case mongoTokenRegex:
+		p.pos++
+		return mongoValue{Kind: mongoValueRegex, Text: token.Text, Span: span}
+	case mongoTokenIdent:
+		switch token.Text {
+		case "true", "false":
+			p.pos++
+			return mongoValue{Kind: mongoValueBool, Text: token.Text, Span: span}
+		case "null", "undefined":
+			p.pos++
+			return mongoValue{Kind: mongoValueNull, Text: token.Text, Span: span}
+		}
+		if bound, found := p.bindings[token.Text]; found {
+			p.pos++
+			bound.Span = span
+			return bound
+		}
+	case mongoTokenPunct:
+		switch token.Text {
+		case "{":
+			return p.parseObject()
+		case "[":
+			return p.parseArray()
+		case "-":
+			if p.kind(1) == mongoTokenNumber {
+				p.pos += 2
+				return mongoValue{Kind: mongoValueNumber, Text: "-" + p.tokens[p.pos-1].Text, Span: sourceSpan{Start: token.Start, End: p.tokens[p.pos-1].End}}
+			}
+		}
+	}
+	return p.parseExpression()
+}
+
+func (p *mongoParser) parseObject() mongoValue {
+	value := mongoValue{Kind: mongoValueObject, Span: sourceSpan{Start: p.tokens[p.pos].Start}}
+	p.pos++
+	for p.pos < len(p.tokens) && p.text(0) != "}" {
+		token := p.tokens[p.pos]
+		switch {
+		case token.Text == ",":
+			p.pos++
+			continue
+		case token.Text == "...":
+			p.pos++
+			p.parseValue()
+			continue
+		}
+
+		field := mongoField{KeySpan: sourceSpan{Start: token.Start, End: token.End}}
+		switch {
+		case token.Kind == mongoTokenString:
+			field.Key = mongoStringValue(token.Text)
+			p.pos++
+		case token.Kind == mongoTokenIdent || token.Kind == mongoTokenNumber:
+			field.Key = token.Text
+			p.pos++
+		case token.Text == "[":
+			p.skipBalanced()
+			field.Key = p.content[token.Start:p.tokens[p.pos-1].End]
+			field.KeySpan.End = p.tokens[p.pos-1].End
+		default:
+			p.skipUntil(",", "}")
+			continue
+		}
+
+		switch p.text(0) {
+		case ":":
+			p.pos++
+			field.Value = p.parseValue()
+		case ",", "}":
+			field.Value = mongoValue{Kind: mongoValueExpr, Text: field.Key, Span: field.KeySpan}
+		default:
+			p.skipUntil(",", "}")
+			continue
+		}
+		value.Fields = append(value.Fields, field)
+	}
+	value.Span.End = p.closeSpan()
+	return value
+}
+
+func (p *mongoParser) parseArray() mongoValue {
+	value := mongoValue{Kind: mongoValueArray, Span: sourceSpan{Start: p.tokens[p.pos].Start}}
+	p.pos++
+	for p.pos < len(p.tokens) && p.text(0) != "]" {
+		if p.text(0) == "," {
+			p.pos++
+			continue
+		}
+		value.Items = append(value.Items, p.parseValue())
+	}
+	value.Span.End = p.closeSpan()
+	return value
+}
+
+func (p *mongoParser) parseExpression() mongoValue {
+	start := p.tokens[p.pos].Start
+	p.skipUntil(",", ")", "]", "}")
+	end := start
+	if p.pos > 0 {
+		end = max(start, p.tokens[p.pos-1].End)
+	}
+	return mongoValue{Kind: mongoValueExpr, Text: strings.TrimSpace(p.content[start:end]), Span: sourceSpan{Start: start, End: end}}
+}
+
+func (p *mongoParser) closeSpan() int {
+	if p.pos >= len(p.tokens) {
+		return p.tokens[len(p.tokens)-1].End
+	}
+	p.pos++
+	return p.tokens[p.pos-1].End
+}
+
+func (p *mongoParser) skipBalanced() {
+	depth := 0
+	for p.pos < len(p.tokens) {
+		switch p.text(0) {
+		case "(", "[", "{":
+			depth++
+		case ")", "]", "}":
+			depth--
+		}
+		p.pos++
+		if depth <= 0 {
+			return
+		}
+	}
+}
+
+func (p *mongoParser) skipUntil(stops ...string) {
+	start := p.pos
+	for p.pos < len(p.tokens) {
+		text := p.text(0)
+		for _, stop := range stops {
+			if text == stop && (p.pos > start || (text != ")" && text != "]" && text != "}")) {
+				return
+			}
+		}
+		switch text {
+		case "(", "[", "{":
+			p.skipBalanced()
+		default:
+			p.pos++
+		}
+	}
+}
+
+func (chain *mongoCallChain) method() string {
+	return chain.Calls[0].Name
+}
+
+func (chain *mongoCallChain) modifier(names ...string) (mongoCall, bool) {
+	for _, call := range chain.Calls[1:] {
+		for _, name := range names {
+			if call.Name == name {
+				return call, true
+			}
+		}
+	}
+	return mongoCall{}, false
+}
+
+func (call mongoCall) arg(index int) (mongoValue, bool) {
+	if index >= len(call.Args) {
+		return mongoValue{}, false
+	}
+	return call.Args[index], true
+}
+
+func (value mongoValue) field(key string) (mongoValue, bool) {
+	for _, field := range value.Fields {
+		if field.Key == key {
+			return field.Value, true
+		}
+	}
+	return mongoValue{}, false
+}
+
+func (value mongoValue) isEmptyObject() bool {
+	return value.Kind == mongoValueObject && len(value.Fields) == 0
+}
+
+func (value mongoValue) findKey(keys ...string) (mongoField, bool) {
+	for _, field := range value.Fields {
+		for _, key := range keys {
+			if field.Key == key {
+				return field, true
+			}
+		}
+		if found, ok := field.Value.findKey(keys...); ok {
+			return found, true
+		}
+	}
+	for _, item := range value.Items {
+		if found, ok := item.findKey(keys...); ok {
+			return found, true
+		}
+	}
+	return mongoField{}, false
+}
+
+func (chain *mongoCallChain) findKey(keys ...string) (mongoField, bool) {
+	for _, call := range chain.Calls {
+		for _, arg := range call.Args {
+			if found, ok := arg.findKey(keys...); ok {
+				return found, true
+			}
+		}
+	}
+	return mongoField{}, false
+}
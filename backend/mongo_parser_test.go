@@ -0,0 +1,94 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseMongoCallChain(t *testing.T) {
+	script := `const cursor = db.getCollection("order.items").find(
+  { status: "paid", 'amount': { $gt: -5 }, name: /^tom/i }, // trailing comment
+  { _id: 0, ...extra }
+).sort({ createdAt: -1 })
+  .limit(10);`
+	ops := parseMongoOperations(script)
+	if len(ops) != 1 || ops[0].Chain == nil {
+		t.Fatalf("expected a single parsed chain, got %+v", ops)
+	}
+	chain := ops[0].Chain
+	if chain.Root != "db" || chain.Collection != "order.items" || chain.method() != "find" || len(chain.Calls) != 3 {
+		t.Fatalf("unexpected chain: %+v", chain)
+	}
+	if _, found := chain.modifier("limit"); !found {
+		t.Fatalf("expected limit modifier on continued line, got %+v", chain.Calls)
+	}
+	filter, _ := chain.Calls[0].arg(0)
+	amount, _ := filter.field("amount")
+	gt, _ := amount.field("$gt")
+	name, _ := filter.field("name")
+	if gt.Kind != mongoValueNumber || gt.Text != "-5" || name.Kind != mongoValueRegex || name.Text != "/^tom/i" {
+		t.Fatalf("unexpected filter values: %+v", filter)
+	}
+	projection, _ := chain.Calls[0].arg(1)
+	if len(projection.Fields) != 1 || projection.Fields[0].Key != "_id" {
+		t.Fatalf("spread elements should be skipped, got %+v", projection)
+	}
+
+	dropped := parseMongoCallChain("db.dropDatabase()", sourceSpan{End: len("db.dropDatabase()")}, nil)
+	if dropped == nil || dropped.Collection != "" || dropped.method() != "dropDatabase" {
+		t.Fatalf("unexpected database-level chain: %+v", dropped)
+	}
+	if parseMongoCallChain(`const note = "db.users.find()"`, sourceSpan{End: 30}, nil) != nil {
+		t.Fatalf("string literals must not parse as call chains")
+	}
+}
+
+func TestMongoRulesUseParsedArguments(t *testing.T) {
+	script := strings.Join([]string{
+		"db.users.updateMany( {} /* all */ , { $set: { a: 1 } });",
+		"db.users.deleteMany({\n});",
+		"db.users.deleteMany(filter);",
+		`db.users.find({ note: "call .find( here" }).limit(5);`,
+		`db.users.find({ $and: [{ $where: "this.a > 1" }] }).limit(1);`,
+		`db.logs.aggregate([{ $match: { note: "$out" } }]);`,
+		`db.logs.aggregate([{ $match: {} }, { $merge: { into: "archive" } }]);`,
+	}, "\n")
+
+	issues := AnalyzeMongoWithOptions(script, AnalyzeOptions{}).Issues
+	rules := issuesByStatement(issues)
+	rules.expect(t, 1, "mongo_update_many_without_filter", true)
+	rules.expect(t, 2, "mongo_delete_many_without_filter", true)
+	rules.expect(t, 3, "mongo_delete_many_without_filter", false)
+	rules.expect(t, 4, "mongo_find_without_limit", false)
+	rules.expect(t, 5, "mongo_where_operator", true)
+	rules.expect(t, 6, "mongo_aggregate_out_merge", false)
+	rules.expect(t, 7, "mongo_aggregate_out_merge", true)
+}
+
+func TestMongoRulesResolveLiteralBindings(t *testing.T) {
+	script := strings.Join([]string{
+		"var f = {};",
+		"db.users.deleteMany(f);",
+		"let negations = { status: { $ne: 'active' } };",
+		"db.users.updateMany(negations, { $set: { archived: true } });",
+		"const opts = { upsert: true };",
+		"db.users.updateOne({}, { $set: { a: 1 } }, opts);",
+		"f.status = 'inactive';",
+		"db.users.deleteMany(f);",
+		"var g = {};",
+		"Object.assign(g, { status: 'inactive' });",
+		"db.users.deleteMany(g);",
+		"let h = {};",
+		"h = buildFilter();",
+		"db.users.updateMany(h, { $set: { a: 1 } });",
+	}, "\n")
+
+	issues := AnalyzeMongoWithOptions(script, AnalyzeOptions{}).Issues
+	rules := issuesByStatement(issues)
+	rules.expect(t, 2, "mongo_delete_many_without_filter", true)
+	rules.expect(t, 4, "mongo_unbounded_write_filter", true)
+	rules.expect(t, 6, "mongo_upsert_empty_filter", true)
+	rules.expect(t, 8, "mongo_delete_many_without_filter", false)
+	rules.expect(t, 11, "mongo_delete_many_without_filter", false)
+	rules.expect(t, 14, "mongo_update_many_without_filter", false)
+}
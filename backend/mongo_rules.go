@@ -0,0 +1,231 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+var mongoNegationOperators = map[string]struct{}{"$exists": {}, "$ne": {}, "$nin": {}, "$not": {}}
+
+var mongoUpsertMethods = map[string]int{
+	"updateOne": 2, "updateMany": 2, "update": 2, "replaceOne": 2, "findOneAndUpdate": 2, "findOneAndReplace": 2, "findAndModify": 0,
+}
+
+func mongoDropCollectionRule() Rule {
+	return mongoCallRule(
+		RuleDefinition{Code: "mongo_drop_collection", Level: LevelError, Category: "高危操作", Description: "db.collection.drop() 删除整个集合"},
+		func(chain *mongoCallChain) (sourceSpan, bool) {
+			return chain.Span, chain.Root == "db" && chain.Collection != "" && chain.method() == "drop"
+		},
+		"检测到 drop() 删除集合，数据与索引将全部丢失", "生产建议禁用集合删除；确需执行请先备份并审批",
+	)
+}
+
+func mongoDropDatabaseRule() Rule {
+	return mongoCallRule(
+		RuleDefinition{Code: "mongo_drop_database", Level: LevelError, Category: "高危操作", Description: "db.dropDatabase() 删除整个数据库"},
+		func(chain *mongoCallChain) (sourceSpan, bool) {
+			return chain.Span, chain.Root == "db" && chain.Collection == "" && chain.method() == "dropDatabase"
+		},
+		"检测到 dropDatabase()，当前数据库的全部集合将被删除", "生产禁止删除数据库；确需执行请确认连接的目标库并完成备份审批",
+	)
+}
+
+func mongoDropIndexRule() Rule {
+	return mongoCallRule(
+		RuleDefinition{Code: "mongo_drop_index", Level: LevelWarning, Category: "索引变更", Description: "dropIndex / dropIndexes 删除索引"},
+		func(chain *mongoCallChain) (sourceSpan, bool) {
+			method := chain.method()
+			return chain.Span, chain.Collection != "" && (method == "dropIndex" || method == "dropIndexes")
+		},
+		"检测到删除索引，依赖该索引的查询可能退化为全集合扫描", "删除前请确认索引使用情况（$indexStats），4.4+ 可先 hideIndex 观察一段时间再删除",
+	)
+}
+
+func mongoCreateIndexForegroundRule() Rule {
+	return mongoCallRule(
+		RuleDefinition{Code: "mongo_create_index_foreground", Level: LevelWarning, Category: "索引变更", Description: "createIndex 未指定 background 或 hidden"},
+		func(chain *mongoCallChain) (sourceSpan, bool) {
+			call := chain.Calls[0]
+			switch call.Name {
+			case "createIndex", "createIndexes", "ensureIndex":
+			default:
+				return sourceSpan{}, false
+			}
+			options, _ := call.arg(1)
+			background, _ := options.field("background")
+			hidden, _ := options.field("hidden")
+			return call.Span, !background.isTrue() && !hidden.isTrue()
+		},
+		"createIndex 未指定 background / hidden，大集合建索引耗时长，4.2 之前的前台构建还会阻塞集合读写",
+		"4.2 之前请使用 { background: true }；4.4+ 可先以 { hidden: true } 建立并验证后再 unhideIndex，并在业务低峰期执行",
+	)
+}
+
+func mongoUnboundedWriteFilterRule() Rule {
+	return mongoCallRule(
+		RuleDefinition{Code: "mongo_unbounded_write_filter", Level: LevelWarning, Category: "写入安全", Description: "updateMany / deleteMany 过滤条件只有 $exists / $ne / $nin"},
+		func(chain *mongoCallChain) (sourceSpan, bool) {
+			if method := chain.method(); method != "updateMany" && method != "deleteMany" {
+				return sourceSpan{}, false
+			}
+			filter, found := chain.Calls[0].arg(0)
+			return filter.Span, found && !filter.isEmptyObject() && filter.onlyNegations()
+		},
+		"批量写入的过滤条件只有 $exists / $ne / $nin 等否定条件，几乎无法利用索引，等同全集合扫描并可能命中大部分文档",
+		"请补充可命中索引的等值或范围条件，先用 countDocuments 评估影响行数，大批量变更建议分批执行",
+	)
+}
+
+func (value mongoValue) onlyNegations() bool {
+	if value.Kind != mongoValueObject || len(value.Fields) == 0 {
+		return false
+	}
+	for _, field := range value.Fields {
+		switch field.Key {
+		case "$and":
+			for _, item := range field.Value.Items {
+				if !item.onlyNegations() {
+					return false
+				}
+			}
+			if len(field.Value.Items) == 0 {
+				return false
+			}
+			continue
+		case "$or", "$nor", "$expr", "$text", "$where":
+			return false
+		}
+		if field.Value.Kind != mongoValueObject || len(field.Value.Fields) == 0 {
+			return false
+		}
+		for _, condition := range field.Value.Fields {
+			if _, found := mongoNegationOperators[condition.Key]; !found {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func mongoRegexWithoutAnchorRule() Rule {
+	return mongoCallRule(
+		RuleDefinition{Code: "mongo_regex_without_anchor", Level: LevelWarning, Category: "查询性能", Description: "$regex 未以 ^ 前缀锚定"},
+		func(chain *mongoCallChain) (sourceSpan, bool) {
+			for _, call := range chain.Calls {
+				for _, arg := range call.Args {
+					if span, found := arg.findUnanchoredRegex(); found {
+						return span, true
+					}
+				}
+			}
+			return sourceSpan{}, false
+		},
+		"$regex 未以 ^ 锚定前缀，无法利用索引范围扫描，需要逐条匹配", "尽量改为 ^ 前缀匹配，或使用全文索引 / Atlas Search",
+	)
+}
+
+func (value mongoValue) findUnanchoredRegex() (sourceSpan, bool) {
+	switch value.Kind {
+	case mongoValueRegex:
+		return value.Span, !strings.HasPrefix(value.Text, "/^")
+	case mongoValueObject:
+		for _, field := range value.Fields {
+			if field.Key == "$regex" && field.Value.Kind == mongoValueString {
+				if !strings.HasPrefix(field.Value.Text, "^") {
+					return field.Value.Span, true
+				}
+				continue
+			}
+			if span, found := field.Value.findUnanchoredRegex(); found {
+				return span, true
+			}
+		}
+	case mongoValueArray:
+		for _, item := range value.Items {
+			if span, found := item.findUnanchoredRegex(); found {
+				return span, true
+			}
+		}
+	}
+	return sourceSpan{}, false
+}
+
+func mongoRenameDropTargetRule() Rule {
+	return mongoCallRule(
+		RuleDefinition{Code: "mongo_rename_drop_target", Level: LevelError, Category: "高危操作", Description: "renameCollection 使用 dropTarget: true 覆盖目标集合"},
+		func(chain *mongoCallChain) (sourceSpan, bool) {
+			call := chain.Calls[0]
+			switch {
+			case call.Name == "renameCollection" && chain.Collection != "":
+				dropTarget, found := call.arg(1)
+				if option, ok := dropTarget.field("dropTarget"); ok {
+					dropTarget = option
+				}
+				return dropTarget.Span, found && dropTarget.isTrue()
+			case call.Name == "adminCommand" || call.Name == "runCommand":
+				command, _ := call.arg(0)
+				_, rename := command.field("renameCollection")
+				dropTarget, _ := command.field("dropTarget")
+				return dropTarget.Span, rename && dropTarget.isTrue()
+			}
+			return sourceSpan{}, false
+		},
+		"renameCollection 指定了 dropTarget: true，目标集合已存在时会被直接删除", "请先确认目标集合不存在或已备份，避免覆盖线上数据",
+	)
+}
+
+func mongoAdminCommandRule() Rule {
+	return newStatementRule(
+		RuleDefinition{Code: "mongo_admin_command", Level: LevelWarning, Category: "权限管控", Description: "使用 db.adminCommand / db.runCommand 直接执行数据库命令"},
+		func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+			if stmt.Mongo == nil || stmt.Mongo.Chain == nil {
+				return Issue{}, false
+			}
+			chain := stmt.Mongo.Chain
+			call := chain.Calls[0]
+			if chain.Root != "db" || chain.Collection != "" || (call.Name != "adminCommand" && call.Name != "runCommand") {
+				return Issue{}, false
+			}
+			command := "未知命令"
+			if arg, found := call.arg(0); found {
+				switch {
+				case arg.Kind == mongoValueString:
+					command = arg.Text
+				case arg.Kind == mongoValueObject && len(arg.Fields) > 0:
+					command = arg.Fields[0].Key
+				}
+			}
+			return ctx.Source.locate(Issue{
+				Message:    fmt.Sprintf("通过 %s 直接执行数据库命令 %s，绕过了集合级操作的审核边界", call.Name, command),
+				Suggestion: "管理类命令请走 DBA 变更流程，确认命令参数、影响范围与执行账号权限",
+			}, chain.Span), true
+		},
+	)
+}
+
+func mongoUpsertEmptyFilterRule() Rule {
+	return mongoCallRule(
+		RuleDefinition{Code: "mongo_upsert_empty_filter", Level: LevelError, Category: "写入安全", Description: "upsert: true 搭配空过滤条件"},
+		func(chain *mongoCallChain) (sourceSpan, bool) {
+			call := chain.Calls[0]
+			optionIndex, found := mongoUpsertMethods[call.Name]
+			if !found || chain.Collection == "" {
+				return sourceSpan{}, false
+			}
+			options, _ := call.arg(optionIndex)
+			filter, hasFilter := call.arg(0)
+			if call.Name == "findAndModify" {
+				filter, hasFilter = options.field("query")
+			}
+			upsert, _ := options.field("upsert")
+			return call.Span, upsert.isTrue() && (!hasFilter || filter.isEmptyObject())
+		},
+		"upsert: true 搭配空过滤条件，会任意修改一条已有文档，集合为空时则插入新文档，结果不可预期",
+		"请为 upsert 指定能唯一定位文档的过滤条件（通常为唯一索引字段）",
+	)
+}
+
+func (value mongoValue) isTrue() bool {
+	return (value.Kind == mongoValueBool && value.Text == "true") || (value.Kind == mongoValueNumber && strings.Trim(value.Text, "-0.") != "")
+}
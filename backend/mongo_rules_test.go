@@ -0,0 +1,44 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExtendedMongoRules(t *testing.T) {
+	script := strings.Join([]string{
+		"db.sessions.drop();",
+		"db.dropDatabase();",
+		`db.users.dropIndex("idx_name");`,
+		"db.users.createIndex({ email: 1 }, { unique: true });",
+		"db.users.createIndex({ email: 1 }, { hidden: true });",
+		"db.users.deleteMany({ deletedAt: { $exists: true }, status: { $ne: 1 } });",
+		"db.users.deleteMany({ status: { $ne: 1 }, tenantId: 7 });",
+		`db.users.find({ name: /tom/i, email: { $regex: "^a" } }).limit(5);`,
+		`db.users.find({ name: { $regex: "tom" } }).limit(5);`,
+		`db.users.renameCollection("users_old", true);`,
+		`db.adminCommand({ renameCollection: "app.a", to: "app.b", dropTarget: true });`,
+		`db.runCommand("serverStatus");`,
+		"db.users.updateOne({}, { $set: { a: 1 } }, { upsert: true });",
+		"db.users.updateOne({ _id: 1 }, { $set: { a: 1 } }, { upsert: true });",
+	}, "\n")
+
+	issues := AnalyzeMongoWithOptions(script, AnalyzeOptions{}).Issues
+	rules := issuesByStatement(issues)
+	rules.expect(t, 1, "mongo_drop_collection", true)
+	rules.expect(t, 2, "mongo_drop_database", true)
+	rules.expect(t, 2, "mongo_drop_collection", false)
+	rules.expect(t, 3, "mongo_drop_index", true)
+	rules.expect(t, 4, "mongo_create_index_foreground", true)
+	rules.expect(t, 5, "mongo_create_index_foreground", false)
+	rules.expect(t, 6, "mongo_unbounded_write_filter", true)
+	rules.expect(t, 7, "mongo_unbounded_write_filter", false)
+	rules.expect(t, 8, "mongo_regex_without_anchor", true)
+	rules.expect(t, 9, "mongo_regex_without_anchor", true)
+	rules.expect(t, 10, "mongo_rename_drop_target", true)
+	rules.expect(t, 11, "mongo_rename_drop_target", true)
+	rules.expect(t, 11, "mongo_admin_command", true)
+	rules.expect(t, 12, "mongo_admin_command", true)
+	rules.expect(t, 13, "mongo_upsert_empty_filter", true)
+	rules.expect(t, 14, "mongo_upsert_empty_filter", false)
+}
@@ -0,0 +1,518 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type onlineDDLAlgorithm int
+
+const (
+	onlineDDLInstant onlineDDLAlgorithm = iota
+	onlineDDLInplace
+	onlineDDLCopy
+)
+
+func (algorithm onlineDDLAlgorithm) String() string {
+	return [...]string{"INSTANT", "INPLACE", "COPY"}[algorithm]
+}
+
+type onlineDDLLock int
+
+const (
+	onlineDDLLockNone onlineDDLLock = iota
+	onlineDDLLockShared
+)
+
+func (lock onlineDDLLock) String() string {
+	return [...]string{"NONE", "SHARED"}[lock]
+}
+
+type onlineDDLImpact struct {
+	Action    string
+	Algorithm onlineDDLAlgorithm
+	Lock      onlineDDLLock
+	Rebuild   bool
+	Note      string
+	Span      sourceSpan
+}
+
+func (impact onlineDDLImpact) risky() bool {
+	return impact.Algorithm == onlineDDLCopy || impact.Rebuild || impact.Lock != onlineDDLLockNone
+}
+
+func (impact onlineDDLImpact) describe() string {
+	text := fmt.Sprintf("%s（ALGORITHM=%s, LOCK=%s", impact.Action, impact.Algorithm, impact.Lock)
+	if impact.Rebuild {
+		text += "，重建表"
+	}
+	if impact.Note != "" {
+		text += "，" + impact.Note
+	}
+	return text + "）"
+}
+
+type mysqlAlterPlan struct {
+	Table     string
+	Token     sqlToken
+	Impacts   []onlineDDLImpact
+	Algorithm string
+	Lock      string
+	Renames   bool
+}
+
+type mysqlVersion [3]int
+
+var (
+	reMySQLVersion      = regexp.MustCompile(`^(\d+)(?:\.(\d+))?(?:\.(\d+))?`)
+	reVarcharLength     = regexp.MustCompile(`^(?:VARCHAR|CHARACTER VARYING)\((\d+)\)$`)
+	defaultMySQLVersion = "8.0.35"
+)
+
+var mysqlVersionParam = RuleParam{Name: "mysql_version", Type: RuleParamString, Default: defaultMySQLVersion, Description: "目标 MySQL 版本，用于判断 INSTANT / INPLACE 是否可用"}
+
+func parseMySQLVersion(text string) mysqlVersion {
+	match := reMySQLVersion.FindStringSubmatch(strings.TrimSpace(text))
+	if match == nil {
+		match = reMySQLVersion.FindStringSubmatch(defaultMySQLVersion)
+	}
+	var version mysqlVersion
+	for i := range version {
+		version[i], _ = strconv.Atoi(match[i+1])
+	}
+	return version
+}
+
+func (version mysqlVersion) atLeast(major, minor, patch int) bool {
+	other := mysqlVersion{major, minor, patch}
+	for i := range version {
+		if version[i] != other[i] {
+			return version[i] > other[i]
+		}
+	}
+	return true
+}
+
+func (version mysqlVersion) instant(since mysqlVersion, rebuild bool) (onlineDDLAlgorithm, bool) {
+	if version.atLeast(since[0], since[1], since[2]) {
+		return onlineDDLInstant, false
+	}
+	return onlineDDLInplace, rebuild
+}
+
+func (ctx *RuleContext) mysqlVersion() mysqlVersion {
+	return parseMySQLVersion(ctx.stringParam("mysql_version"))
+}
+
+func planMySQLAlter(stmt *sqlStatement, catalog *schemaCatalog, version mysqlVersion) (mysqlAlterPlan, bool) {
+	if stmt == nil || stmt.Kind != sqlStmtAlterTable || len(stmt.Tables) == 0 {
+		return mysqlAlterPlan{}, false
+	}
+	plan := mysqlAlterPlan{Table: stmt.Tables[0].Name, Token: stmt.Tables[0].Token}
+	var table *SchemaTable
+	if catalog != nil {
+		table, _ = catalog.table(plan.Table)
+	}
+
+	addsPrimary := false
+	for _, clause := range stmt.AlterClauses {
+		if len(clause) < 2 || clause[0].Upper != "ADD" {
+			continue
+		}
+		rest := clause[1:]
+		if rest[0].Upper == "CONSTRAINT" && len(rest) > 1 {
+			rest = rest[1:]
+			if rest[0].Upper != "PRIMARY" {
+				rest = rest[1:]
+			}
+		}
+		if len(rest) > 0 && rest[0].Upper == "PRIMARY" {
+			addsPrimary = true
+		}
+	}
+
+	for _, clause := range stmt.AlterClauses {
+		if len(clause) == 0 {
+			continue
+		}
+		span := sourceSpan{Start: clause[0].Start, End: clause[len(clause)-1].End}
+		switch clause[0].Upper {
+		case "ALGORITHM", "LOCK":
+			value := clause[1:]
+			if len(value) > 0 && value[0].Text == "=" {
+				value = value[1:]
+			}
+			if len(value) > 0 {
+				if clause[0].Upper == "ALGORITHM" {
+					plan.Algorithm = value[0].Upper
+				} else {
+					plan.Lock = value[0].Upper
+				}
+			}
+			continue
+		case "RENAME":
+			if len(clause) > 1 && clause[1].Upper != "COLUMN" && clause[1].Upper != "INDEX" && clause[1].Upper != "KEY" {
+				plan.Renames = true
+				continue
+			}
+		}
+		impact, found := classifyMySQLAlterClause(clause, table, version, addsPrimary)
+		if !found {
+			continue
+		}
+		impact.Span = span
+		plan.Impacts = append(plan.Impacts, impact)
+	}
+	return plan, true
+}
+
+func classifyMySQLAlterClause(clause []sqlToken, table *SchemaTable, version mysqlVersion, addsPrimary bool) (onlineDDLImpact, bool) {
+	rest := clause[1:]
+	word := func(i int) string {
+		if i < len(rest) {
+			return rest[i].Upper
+		}
+		return ""
+	}
+
+	switch clause[0].Upper {
+	case "ADD":
+		explicitColumn := word(0) == "COLUMN"
+		rest = trimSQLWords(trimSQLWords(rest, "COLUMN"), "IF", "NOT", "EXISTS")
+		if !explicitColumn && word(0) == "CONSTRAINT" {
+			rest = rest[1:]
+			if len(rest) > 0 && word(0) != "PRIMARY" && word(0) != "UNIQUE" && word(0) != "FOREIGN" && word(0) != "CHECK" {
+				rest = rest[1:]
+			}
+		}
+		if len(rest) == 0 {
+			return onlineDDLImpact{}, false
+		}
+		if !explicitColumn {
+			switch word(0) {
+			case "INDEX", "KEY":
+				return onlineDDLImpact{Action: "添加索引", Algorithm: onlineDDLInplace}, true
+			case "UNIQUE":
+				return onlineDDLImpact{Action: "添加唯一索引", Algorithm: onlineDDLInplace}, true
+			case "FULLTEXT":
+				return onlineDDLImpact{Action: "添加全文索引", Algorithm: onlineDDLInplace, Lock: onlineDDLLockShared, Rebuild: true, Note: "首个全文索引需重建表"}, true
+			case "SPATIAL":
+				return onlineDDLImpact{Action: "添加空间索引", Algorithm: onlineDDLInplace, Lock: onlineDDLLockShared}, true
+			case "PRIMARY":
+				return onlineDDLImpact{Action: "添加主键", Algorithm: onlineDDLInplace, Rebuild: true}, true
+			case "FOREIGN":
+				return onlineDDLImpact{Action: "添加外键", Algorithm: onlineDDLCopy, Lock: onlineDDLLockShared, Note: "foreign_key_checks=1 时只能 COPY"}, true
+			case "CHECK":
+				return onlineDDLImpact{Action: "添加 CHECK 约束", Algorithm: onlineDDLCopy, Lock: onlineDDLLockShared, Note: "需校验全表数据"}, true
+			case "PARTITION":
+				return onlineDDLImpact{}, false
+			}
+		}
+		if rest[0].Kind == sqlTokenOperator && rest[0].Text == "(" {
+			algorithm, rebuild := version.instant(mysqlVersion{8, 0, 12}, true)
+			return onlineDDLImpact{Action: "添加列", Algorithm: algorithm, Rebuild: rebuild}, true
+		}
+		name := sqlIdentifierName(rest[0])
+		switch {
+		case hasSQLWordAt(rest, rest[0].Depth, "STORED"):
+			return onlineDDLImpact{Action: "添加 STORED 生成列 " + name, Algorithm: onlineDDLCopy, Lock: onlineDDLLockShared}, true
+		case hasSQLWordAt(rest, rest[0].Depth, "AUTO_INCREMENT"):
+			return onlineDDLImpact{Action: "添加自增列 " + name, Algorithm: onlineDDLInplace, Lock: onlineDDLLockShared, Rebuild: true}, true
+		case hasSQLWordAt(rest, rest[0].Depth, "FIRST", "AFTER"):
+			algorithm, rebuild := version.instant(mysqlVersion{8, 0, 29}, true)
+			return onlineDDLImpact{Action: "在中间位置添加列 " + name, Algorithm: algorithm, Rebuild: rebuild, Note: versionNote(algorithm, "8.0.29")}, true
+		default:
+			algorithm, rebuild := version.instant(mysqlVersion{8, 0, 12}, true)
+			return onlineDDLImpact{Action: "添加列 " + name, Algorithm: algorithm, Rebuild: rebuild}, true
+		}
+	case "DROP":
+		switch word(0) {
+		case "INDEX", "KEY":
+			return onlineDDLImpact{Action: "删除索引", Algorithm: onlineDDLInplace}, true
+		case "PRIMARY":
+			if addsPrimary {
+				return onlineDDLImpact{Action: "替换主键", Algorithm: onlineDDLInplace, Rebuild: true}, true
+			}
+			return onlineDDLImpact{Action: "删除主键", Algorithm: onlineDDLCopy, Lock: onlineDDLLockShared}, true
+		case "FOREIGN", "CHECK", "CONSTRAINT":
+			return onlineDDLImpact{Action: "删除约束", Algorithm: onlineDDLInplace}, true
+		case "PARTITION", "DEFAULT", "SYSTEM", "":
+			return onlineDDLImpact{}, false
+		}
+		rest = trimSQLWords(trimSQLWords(rest, "COLUMN"), "IF", "EXISTS")
+		if len(rest) == 0 {
+			return onlineDDLImpact{}, false
+		}
+		algorithm, rebuild := version.instant(mysqlVersion{8, 0, 29}, true)
+		return onlineDDLImpact{Action: "删除列 " + sqlIdentifierName(rest[0]), Algorithm: algorithm, Rebuild: rebuild, Note: versionNote(algorithm, "8.0.29")}, true
+	case "MODIFY", "CHANGE":
+		rest = trimSQLWords(rest, "COLUMN")
+		oldName := ""
+		if clause[0].Upper == "CHANGE" {
+			if len(rest) < 2 {
+				return onlineDDLImpact{}, false
+			}
+			oldName, rest = sqlIdentifierName(rest[0]), rest[1:]
+		}
+		if len(rest) == 0 {
+			return onlineDDLImpact{}, false
+		}
+		definition, found := sqlColumnDefinition(rest)
+		if !found {
+			return onlineDDLImpact{}, false
+		}
+		if oldName == "" {
+			oldName = definition.Name
+		}
+		return classifyMySQLColumnChange(table, oldName, definition, hasSQLWordAt(rest, rest[0].Depth, "FIRST", "AFTER"), version), true
+	case "ALTER":
+		switch {
+		case word(0) == "INDEX":
+			return onlineDDLImpact{Action: "修改索引可见性", Algorithm: onlineDDLInstant}, true
+		case hasSQLWordAt(rest, clause[0].Depth, "DEFAULT", "VISIBLE", "INVISIBLE"):
+			algorithm, rebuild := version.instant(mysqlVersion{8, 0, 12}, false)
+			return onlineDDLImpact{Action: "修改列默认值", Algorithm: algorithm, Rebuild: rebuild}, true
+		}
+		return onlineDDLImpact{}, false
+	case "RENAME":
+		switch word(0) {
+		case "COLUMN":
+			algorithm, rebuild := version.instant(mysqlVersion{8, 0, 28}, false)
+			return onlineDDLImpact{Action: "重命名列", Algorithm: algorithm, Rebuild: rebuild}, true
+		case "INDEX", "KEY":
+			return onlineDDLImpact{Action: "重命名索引", Algorithm: onlineDDLInplace}, true
+		}
+	case "CONVERT":
+		return onlineDDLImpact{Action: "转换表字符集", Algorithm: onlineDDLCopy, Lock: onlineDDLLockShared}, true
+	case "DEFAULT", "CHARACTER", "CHARSET", "COLLATE":
+		return onlineDDLImpact{Action: "修改表默认字符集", Algorithm: onlineDDLInplace, Rebuild: true, Note: "字符集不同时重建"}, true
+	case "ENGINE", "FORCE":
+		return onlineDDLImpact{Action: "重建表", Algorithm: onlineDDLInplace, Rebuild: true}, true
+	case "ROW_FORMAT", "KEY_BLOCK_SIZE":
+		return onlineDDLImpact{Action: "修改行格式", Algorithm: onlineDDLInplace, Rebuild: true}, true
+	case "ORDER":
+		return onlineDDLImpact{Action: "ORDER BY 重排", Algorithm: onlineDDLCopy, Lock: onlineDDLLockShared}, true
+	case "COMMENT":
+		return onlineDDLImpact{Action: "修改表注释", Algorithm: onlineDDLInstant}, true
+	case "AUTO_INCREMENT":
+		return onlineDDLImpact{Action: "修改自增起始值", Algorithm: onlineDDLInplace}, true
+	}
+	return onlineDDLImpact{}, false
+}
+
+func classifyMySQLColumnChange(table *SchemaTable, oldName string, definition SchemaColumn, positioned bool, version mysqlVersion) onlineDDLImpact {
+	action := "修改列 " + oldName
+	var existing *SchemaColumn
+	if table != nil {
+		existing, _ = table.column(oldName)
+	}
+	if existing == nil {
+		return onlineDDLImpact{Action: action, Algorithm: onlineDDLCopy, Lock: onlineDDLLockShared, Note: "未提供结构快照，按修改列类型估算"}
+	}
+
+	if !strings.EqualFold(existing.Type, definition.Type) {
+		if varcharExtends(existing.Type, definition.Type) {
+			return onlineDDLImpact{Action: action + " 的 VARCHAR 长度", Algorithm: onlineDDLInplace, Note: "长度跨越 255 字节边界时需 COPY"}
+		}
+		return onlineDDLImpact{Action: fmt.Sprintf("修改列 %s 类型 %s → %s", oldName, existing.Type, definition.Type), Algorithm: onlineDDLCopy, Lock: onlineDDLLockShared}
+	}
+	if existing.Nullable != definition.Nullable {
+		return onlineDDLImpact{Action: action + " 的 NULL 属性", Algorithm: onlineDDLInplace, Rebuild: true}
+	}
+	if positioned {
+		return onlineDDLImpact{Action: action + " 的位置", Algorithm: onlineDDLInplace, Rebuild: true}
+	}
+	if !strings.EqualFold(oldName, definition.Name) {
+		algorithm, rebuild := version.instant(mysqlVersion{8, 0, 28}, false)
+		return onlineDDLImpact{Action: "重命名列 " + oldName, Algorithm: algorithm, Rebuild: rebuild}
+	}
+	algorithm, rebuild := version.instant(mysqlVersion{8, 0, 12}, false)
+	return onlineDDLImpact{Action: action + " 的默认值或注释", Algorithm: algorithm, Rebuild: rebuild}
+}
+
+func sqlColumnDefinition(element []sqlToken) (SchemaColumn, bool) {
+	scratch := &SchemaTable{}
+	scratch.applyColumnDefinition(element, "")
+	if len(scratch.Columns) == 0 {
+		return SchemaColumn{}, false
+	}
+	return scratch.Columns[0], true
+}
+
+func varcharExtends(from, to string) bool {
+	fromMatch := reVarcharLength.FindStringSubmatch(strings.ToUpper(from))
+	toMatch := reVarcharLength.FindStringSubmatch(strings.ToUpper(to))
+	if fromMatch == nil || toMatch == nil {
+		return false
+	}
+	fromLength, _ := strconv.Atoi(fromMatch[1])
+	toLength, _ := strconv.Atoi(toMatch[1])
+	return toLength >= fromLength
+}
+
+func versionNote(algorithm onlineDDLAlgorithm, since string) string {
+	if algorithm == onlineDDLInstant {
+		return ""
+	}
+	return since + " 之前不支持 INSTANT"
+}
+
+func hasSQLWordAt(tokens []sqlToken, depth int, words ...string) bool {
+	for _, token := range tokens {
+		if token.Kind != sqlTokenWord || token.Depth != depth {
+			continue
+		}
+		for _, word := range words {
+			if token.Upper == word {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (plan mysqlAlterPlan) filter(keep func(onlineDDLImpact) bool) []onlineDDLImpact {
+	items := make([]onlineDDLImpact, 0)
+	for _, impact := range plan.Impacts {
+		if keep(impact) {
+			items = append(items, impact)
+		}
+	}
+	return items
+}
+
+func describeOnlineDDLImpacts(items []onlineDDLImpact) string {
+	parts := make([]string, 0, len(items))
+	for _, impact := range items {
+		parts = append(parts, impact.describe())
+	}
+	return strings.Join(parts, "；")
+}
+
+func alterTableCopyRule() Rule {
+	return newStatementRule(
+		RuleDefinition{Code: "alter_table_copy", Level: LevelWarning, Category: "DDL锁影响", Description: "ALTER TABLE 需要 ALGORITHM=COPY，复制全表期间阻塞写入", Params: []RuleParam{mysqlVersionParam}},
+		func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+			plan, found := planMySQLAlter(stmt.SQL, stmt.Catalog, ctx.mysqlVersion())
+			if !found {
+				return Issue{}, false
+			}
+			items := plan.filter(func(impact onlineDDLImpact) bool { return impact.Algorithm == onlineDDLCopy })
+			if len(items) == 0 {
+				return Issue{}, false
+			}
+			return ctx.Source.locate(Issue{
+				Message:    fmt.Sprintf("ALTER TABLE %s 需要以 COPY 方式执行：%s", plan.Table, describeOnlineDDLImpacts(items)),
+				Suggestion: "复制全表期间只读，大表请在低峰期执行，或使用 gh-ost / pt-online-schema-change 等在线变更工具",
+			}, items[0].Span), true
+		},
+	)
+}
+
+func alterTableRebuildRule() Rule {
+	return newStatementRule(
+		RuleDefinition{Code: "alter_table_rebuild", Level: LevelInfo, Category: "DDL锁影响", Description: "ALTER TABLE 以 INPLACE 方式重建表，耗时长并可能造成复制延迟", Params: []RuleParam{mysqlVersionParam}},
+		func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+			plan, found := planMySQLAlter(stmt.SQL, stmt.Catalog, ctx.mysqlVersion())
+			if !found || len(plan.filter(func(impact onlineDDLImpact) bool { return impact.Algorithm == onlineDDLCopy })) > 0 {
+				return Issue{}, false
+			}
+			items := plan.filter(func(impact onlineDDLImpact) bool { return impact.Rebuild })
+			if len(items) == 0 {
+				return Issue{}, false
+			}
+			return ctx.Source.locate(Issue{
+				Message:    fmt.Sprintf("ALTER TABLE %s 将重建表：%s", plan.Table, describeOnlineDDLImpacts(items)),
+				Suggestion: "重建期间允许并发读写，但大表耗时长、占用双倍空间并可能造成主从延迟，请评估表大小后在低峰期执行",
+			}, items[0].Span), true
+		},
+	)
+}
+
+func alterWithoutAlgorithmLockRule() Rule {
+	return newStatementRule(
+		RuleDefinition{Code: "alter_without_algorithm_lock", Level: LevelWarning, Category: "DDL锁影响", Description: "高影响 ALTER TABLE 未显式指定 ALGORITHM / LOCK", Params: []RuleParam{mysqlVersionParam}},
+		func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+			plan, found := planMySQLAlter(stmt.SQL, stmt.Catalog, ctx.mysqlVersion())
+			if !found || (plan.Algorithm != "" && plan.Lock != "") {
+				return Issue{}, false
+			}
+			items := plan.filter(onlineDDLImpact.risky)
+			if len(items) == 0 {
+				return Issue{}, false
+			}
+
+			algorithm, lock := onlineDDLInstant, onlineDDLLockNone
+			for _, impact := range plan.Impacts {
+				algorithm = max(algorithm, impact.Algorithm)
+				lock = max(lock, impact.Lock)
+			}
+			missing := make([]string, 0, 2)
+			if plan.Algorithm == "" {
+				missing = append(missing, "ALGORITHM")
+			}
+			if plan.Lock == "" {
+				missing = append(missing, "LOCK")
+			}
+			return ctx.Source.locate(Issue{
+				Message:    fmt.Sprintf("ALTER TABLE %s 包含高影响变更但未指定 %s：%s", plan.Table, strings.Join(missing, " / "), describeOnlineDDLImpacts(items)),
+				Suggestion: fmt.Sprintf("建议显式追加 ALGORITHM=%s, LOCK=%s，条件不满足时直接报错，避免静默升级为更重的锁", algorithm, lock),
+			}, items[0].Span), true
+		},
+	)
+}
+
+func alterTableNotMergedRule() Rule {
+	return newScriptRule(
+		RuleDefinition{Code: "alter_table_not_merged", Level: LevelInfo, Category: "DDL锁影响", Description: "同一张表存在多条 ALTER TABLE，建议合并"},
+		func(ctx *RuleContext) []Issue {
+			type alterGroup struct {
+				name       string
+				statements []RuleStatement
+			}
+			groups := make(map[string]*alterGroup)
+			order := make([]string, 0)
+			for _, stmt := range ctx.Statements {
+				plan, found := planMySQLAlter(stmt.SQL, nil, parseMySQLVersion(defaultMySQLVersion))
+				if !found {
+					continue
+				}
+				key := catalogKey(plan.Table)
+				group, exists := groups[key]
+				if !exists {
+					group = &alterGroup{name: plan.Table}
+					groups[key] = group
+					order = append(order, key)
+				}
+				if len(plan.Impacts) > 0 {
+					group.statements = append(group.statements, stmt)
+				}
+				if plan.Renames {
+					group.statements = nil
+				}
+			}
+
+			issues := make([]Issue, 0)
+			for _, key := range order {
+				group, found := groups[key]
+				if !found || len(group.statements) < 2 {
+					continue
+				}
+				indexes := make([]string, 0, len(group.statements))
+				for _, stmt := range group.statements {
+					indexes = append(indexes, fmt.Sprintf("#%d", stmt.Index))
+				}
+				second := group.statements[1]
+				issues = append(issues, ctx.Source.locate(Issue{
+					StatementIndex: second.Index,
+					Statement:      second.Text,
+					Message:        fmt.Sprintf("表 %s 有 %d 条 ALTER TABLE（语句 %s），每条都会单独加元数据锁，且可能各自重建表", group.name, len(group.statements), strings.Join(indexes, "、")),
+					Suggestion:     "建议合并为一条 ALTER TABLE，多个变更以逗号分隔，只需一次加锁与重建",
+				}, second.SQL.Tables[0].Token.span()))
+			}
+			return issues
+		},
+	)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPlanMySQLAlterClassifiesClauses(t *testing.T) {
+	parse := func(sql string) *sqlStatement { return parseSQLStatement(sql, mysqlDialect) }
+	latest := parseMySQLVersion("8.0.35")
+
+	plan, found := planMySQLAlter(parse("ALTER TABLE t ADD COLUMN a INT, ADD COLUMN b INT AFTER id, ADD INDEX idx_a (a), ALGORITHM=INPLACE, LOCK=NONE"), nil, latest)
+	if !found || plan.Algorithm != "INPLACE" || plan.Lock != "NONE" || len(plan.Impacts) != 3 {
+		t.Fatalf("unexpected plan: %+v", plan)
+	}
+	for _, impact := range plan.Impacts[:2] {
+		if impact.Algorithm != onlineDDLInstant {
+			t.Fatalf("expected INSTANT column add on 8.0.35, got %+v", impact)
+		}
+	}
+
+	old, _ := planMySQLAlter(parse("ALTER TABLE t ADD COLUMN b INT FIRST, DROP COLUMN c"), nil, parseMySQLVersion("5.7"))
+	for _, impact := range old.Impacts {
+		if impact.Algorithm != onlineDDLInplace || !impact.Rebuild {
+			t.Fatalf("expected INPLACE rebuild on 5.7, got %+v", impact)
+		}
+	}
+
+	replace, _ := planMySQLAlter(parse("ALTER TABLE t DROP PRIMARY KEY, ADD PRIMARY KEY (id, tenant_id)"), nil, latest)
+	if replace.Impacts[0].Algorithm != onlineDDLInplace {
+		t.Fatalf("dropping and re-adding the primary key should run INPLACE, got %+v", replace.Impacts[0])
+	}
+	renamed, _ := planMySQLAlter(parse("ALTER TABLE t RENAME TO t_old"), nil, latest)
+	if !renamed.Renames || len(renamed.Impacts) != 0 {
+		t.Fatalf("expected table rename without impacts, got %+v", renamed)
+	}
+	if parseMySQLVersion("bogus") != parseMySQLVersion(defaultMySQLVersion) {
+		t.Fatalf("invalid versions should fall back to the default")
+	}
+}
+
+func TestAnalyzeSQLOnlineDDLRules(t *testing.T) {
+	script := strings.Join([]string{
+		"ALTER TABLE orders MODIFY amount DECIMAL(12,2);",
+		"ALTER TABLE users ADD FULLTEXT INDEX ft_email (email);",
+		"ALTER TABLE users CONVERT TO CHARACTER SET utf8mb4, ALGORITHM=COPY, LOCK=SHARED;",
+		"ALTER TABLE orders ADD INDEX idx_amount (amount);",
+		"ALTER TABLE logs ADD COLUMN note TEXT;",
+	}, "\n")
+
+	issues := AnalyzeSQL(script).Issues
+	rules := issuesByStatement(issues)
+	rules.expect(t, 1, "alter_table_copy", true)
+	rules.expect(t, 1, "alter_without_algorithm_lock", true)
+	rules.expect(t, 2, "alter_table_rebuild", true)
+	rules.expect(t, 2, "alter_table_copy", false)
+	rules.expect(t, 3, "alter_table_copy", true)
+	rules.expect(t, 3, "alter_without_algorithm_lock", false)
+	rules.expect(t, 4, "alter_without_algorithm_lock", false)
+	rules.expect(t, 4, "alter_table_not_merged", true)
+	rules.expect(t, 5, "alter_table_rebuild", false)
+}
+
+func TestOnlineDDLRulesUseCatalogAndVersion(t *testing.T) {
+	catalog, err := buildSchemaCatalog(EngineMySQL, testMySQLSchema)
+	if err != nil {
+		t.Fatalf("build catalog err: %v", err)
+	}
+	script := "ALTER TABLE users MODIFY email VARCHAR(200) NOT NULL;\nALTER TABLE orders ADD COLUMN note TEXT;\n"
+
+	options := AnalyzeOptions{Catalog: catalog, RuleParams: map[string]map[string]any{"alter_table_rebuild": {"mysql_version": "5.7.44"}}}
+	rules := issueRules(AnalyzeSQLWithOptions(script, options).Issues)
+	if rules["alter_table_copy"] != 0 {
+		t.Fatalf("extending a VARCHAR known from the catalog should not need COPY, got %+v", rules)
+	}
+	if rules["alter_table_rebuild"] != 1 {
+		t.Fatalf("adding a column on 5.7 should rebuild the table, got %+v", rules)
+	}
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+var oracleSQLPlusCommands = map[string]struct{}{
+	"PROMPT": {}, "REM": {}, "REMARK": {}, "SPOOL": {}, "WHENEVER": {}, "SET": {}, "DEFINE": {}, "UNDEFINE": {},
+	"SHOW": {}, "@": {}, "@@": {},
+}
+
+func BuiltInOracleRules() []RuleDefinition {
+	return ruleDefinitions(RegisteredRules(EngineOracle))
+}
+
+func oracleRules() []Rule {
+	return []Rule{
+		emptyInputRule("SQL 内容为空", "请上传 SQL / PL/SQL 脚本或粘贴语句后再检查"),
+		tooManyStatementsRule(60, "建议按业务模块拆分后分批审核与执行"),
+		sqlFullwidthTerminatorRule(),
+		newStatementRule(
+			RuleDefinition{Code: "ora_dangerous_drop", Level: LevelError, Category: "高危DDL", Description: "检测到 DROP 高危对象删除"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				return Issue{Message: "检测到 DROP 高风险语句", Suggestion: "生产建议禁用 DROP；确需执行请先备份并审批，误删的表可通过 FLASHBACK TABLE ... TO BEFORE DROP 从回收站恢复"}, stmt.SQL.isDangerousDrop() && !stmt.SQL.hasTopLevelWord("PURGE")
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "ora_drop_purge", Level: LevelError, Category: "高危DDL", Description: "DROP ... PURGE / PURGE 绕过回收站永久删除"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				purge := stmt.SQL.Kind == sqlStmtDrop && stmt.SQL.hasTopLevelWord("PURGE")
+				if len(stmt.SQL.Tokens) > 0 && stmt.SQL.Tokens[0].Upper == "PURGE" {
+					purge = true
+				}
+				return Issue{Message: "检测到 PURGE，对象将绕过回收站被永久删除，无法通过 FLASHBACK 恢复", Suggestion: "生产建议去掉 PURGE 保留回收站兜底；确需释放空间请先完成备份并审批"}, purge
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "ora_dangerous_truncate", Level: LevelError, Category: "高危DDL", Description: "检测到 TRUNCATE 全表清理"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				return Issue{Message: "检测到 TRUNCATE 语句", Suggestion: "TRUNCATE 为 DDL，会隐式提交且无法回滚，也不能通过 FLASHBACK TABLE 恢复，请确认窗口期与恢复方案"}, stmt.SQL.Kind == sqlStmtTruncate
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "ora_truncate_reuse_storage", Level: LevelInfo, Category: "存储管理", Description: "TRUNCATE ... REUSE STORAGE 不释放已分配空间"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				token, found := stmt.SQL.findTokenSequence("REUSE", "STORAGE")
+				return ctx.Source.locate(Issue{Message: "TRUNCATE ... REUSE STORAGE 保留已分配的区，空间不会归还表空间", Suggestion: "仅在随后立即重新装载同等数据量时使用；如需释放空间请使用默认的 DROP STORAGE 或 DROP ALL STORAGE"}, token.span()), found && stmt.SQL.Kind == sqlStmtTruncate
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "ora_update_without_where", Level: LevelError, Category: "DML安全", Description: "UPDATE 无 WHERE"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				return Issue{Message: "UPDATE 缺少 WHERE 条件", Suggestion: "请添加精确 WHERE 条件，避免全表更新"}, stmt.SQL.Kind == sqlStmtUpdate && stmt.SQL.Where == nil
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "ora_delete_without_where", Level: LevelError, Category: "DML安全", Description: "DELETE 无 WHERE"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				return Issue{Message: "DELETE 缺少 WHERE 条件", Suggestion: "请添加 WHERE 条件，或改为按 ROWNUM 分批删除并及时提交"}, stmt.SQL.Kind == sqlStmtDelete && stmt.SQL.Where == nil
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "ora_merge_without_condition", Level: LevelError, Category: "DML安全", Description: "MERGE INTO 缺少有效的 ON 匹配条件"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				token, found := stmt.SQL.findMergeWithoutCondition()
+				return ctx.Source.locate(Issue{Message: "MERGE INTO 的 ON 匹配条件缺失或恒为真，源表每一行都会匹配目标表全部行", Suggestion: "请在 ON 中使用目标表主键或唯一键与源表关联，避免全表更新或删除"}, token.span()), found
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "ora_execute_immediate", Level: LevelWarning, Category: "动态SQL", Description: "EXECUTE IMMEDIATE 动态 SQL 绕过静态审核"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				token, args, found := stmt.SQL.findExecuteImmediate()
+				if !found {
+					return Issue{}, false
+				}
+				issue := Issue{
+					Message:    "EXECUTE IMMEDIATE 执行拼接或变量中的动态 SQL，无法静态审核且存在 SQL 注入风险",
+					Suggestion: "请使用 USING 绑定变量传参，对象名通过 DBMS_ASSERT 校验，并在变更单中附上实际执行的 SQL",
+				}
+				if len(args) == 1 && args[0].Kind == sqlTokenString {
+					inner := parseSQLStatement(sqlStringValue(args[0]), oracleDialect)
+					if len(inner.Tokens) > 0 {
+						issue.Message = fmt.Sprintf("EXECUTE IMMEDIATE 动态执行 %s 语句，绕过了脚本的静态审核", inner.Tokens[0].Upper)
+						issue.Suggestion = "固定 SQL 建议直接写在脚本中，以便按规则审核并评估影响"
+					}
+				}
+				return ctx.Source.locate(issue, token.span()), true
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "ora_select_star", Level: LevelWarning, Category: "查询规范", Description: "SELECT * 可维护性与性能风险"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				token, found := stmt.SQL.findSelectStar()
+				return ctx.Source.locate(Issue{Message: "SELECT * 可能带来性能和兼容风险", Suggestion: "建议显式列出字段，减少 I/O 并降低结构变更影响"}, token.span()), found
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "ora_select_without_limit", Level: LevelInfo, Category: "查询规范", Description: "SELECT 未使用 ROWNUM 或 FETCH FIRST 限制行数"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				return Issue{Message: "SELECT 未检测到 ROWNUM 或 FETCH FIRST 行数限制", Suggestion: "在线查询建议补充 FETCH FIRST n ROWS ONLY（12c+）或 WHERE ROWNUM <= n"}, stmt.SQL.Kind == sqlStmtSelect && stmt.SQL.From != nil && !stmt.SQL.hasLimit() && !stmt.SQL.hasRownumLimit() && !stmt.SQL.selectsFromDual()
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "ora_like_leading_wildcard", Level: LevelWarning, Category: "查询性能", Description: "LIKE 前导 % 可能导致索引失效"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				token, found := stmt.SQL.findLeadingWildcardLike("LIKE")
+				return ctx.Source.locate(Issue{Message: "LIKE 前导通配符可能导致索引失效", Suggestion: "可考虑 Oracle Text（CONTAINS）或改写匹配策略"}, token.span()), found
+			},
+		),
+		engineDetectionAmbiguousRule(),
+		invalidSuppressionRule(),
+	}
+}
+
+func AnalyzeOracleWithOptions(content string, options AnalyzeOptions) CheckResponse {
+	ctx := newSQLRuleContextFromSpans(EngineOracle, content, splitOracleStatementSpans(content), oracleDialect)
+	for _, stmt := range ctx.Statements {
+		if !isOraclePLSQLStart(stmt.SQL.Tokens) {
+			continue
+		}
+		ctx.ContainsRoutine = true
+		if first := stmt.SQL.Tokens[0].Upper; first == "BEGIN" || first == "DECLARE" {
+			stmt.SQL.Kind = sqlStmtBlock
+		}
+	}
+	return analyzeWithRules(ctx, options, "请输入待审核 SQL 后重试")
+}
+
+func splitOracleStatementSpans(content string) []sqlStatementSpan {
+	items := make([]sqlStatementSpan, 0)
+	tokens := significantSQLTokens(tokenizeSQL(content, oracleDialect))
+	start, end := -1, 0
+	block := false
+
+	flush := func() {
+		if start >= 0 {
+			items = append(items, sqlStatementSpan{Text: content[start:end], Span: sourceSpan{Start: start, End: end}})
+		}
+		start = -1
+	}
+	skipLine := func(i, lineEnd int) int {
+		for i+1 < len(tokens) && tokens[i+1].Start < lineEnd {
+			i++
+		}
+		return i
+	}
+
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		lineStart, lineEnd := sqlLineBounds(content, token)
+		startsLine := strings.TrimSpace(content[lineStart:token.Start]) == ""
+		switch {
+		case start < 0 && startsLine && isSQLPlusCommand(tokens[i:min(i+2, len(tokens))]):
+			i = skipLine(i, lineEnd)
+			continue
+		case token.Kind == sqlTokenOperator && token.Text == "/" && startsLine && strings.TrimSpace(content[token.End:lineEnd]) == "":
+			flush()
+			block = false
+			continue
+		case token.Kind == sqlTokenOperator && (token.Text == ";" || token.Text == "；") && !block:
+			flush()
+			continue
+		case start < 0 && (token.Upper == "EXEC" || (token.Upper == "EXECUTE" && i+1 < len(tokens) && tokens[i+1].Upper != "IMMEDIATE")):
+			start = token.Start
+			for i+1 < len(tokens) && tokens[i+1].Start < lineEnd && tokens[i+1].Text != ";" {
+				i++
+			}
+			end = tokens[i].End
+			flush()
+			continue
+		}
+		if start < 0 {
+			start = token.Start
+			block = isOraclePLSQLStart(tokens[i:min(i+6, len(tokens))])
+		}
+		end = token.End
+	}
+
+	flush()
+	return items
+}
+
+func isSQLPlusCommand(tokens []sqlToken) bool {
+	if len(tokens) == 0 {
+		return false
+	}
+	word := tokens[0].Upper
+	if tokens[0].Kind == sqlTokenVariable && strings.HasPrefix(tokens[0].Text, "@") {
+		word = strings.Repeat("@", len(tokens[0].Text)-len(strings.TrimLeft(tokens[0].Text, "@")))
+	}
+	if _, found := oracleSQLPlusCommands[word]; !found {
+		return false
+	}
+	if word == "SET" && len(tokens) > 1 {
+		switch tokens[1].Upper {
+		case "TRANSACTION", "ROLE", "CONSTRAINT", "CONSTRAINTS":
+			return false
+		}
+	}
+	return true
+}
+
+func isOraclePLSQLStart(tokens []sqlToken) bool {
+	word := func(i int) string {
+		if i >= len(tokens) {
+			return ""
+		}
+		return tokens[i].Upper
+	}
+	switch word(0) {
+	case "BEGIN", "DECLARE":
+		return true
+	case "CREATE":
+	default:
+		return false
+	}
+	i := 1
+	if word(i) == "OR" && word(i+1) == "REPLACE" {
+		i += 2
+	}
+	if word(i) == "EDITIONABLE" || word(i) == "NONEDITIONABLE" {
+		i++
+	}
+	switch word(i) {
+	case "PROCEDURE", "FUNCTION", "PACKAGE", "TRIGGER", "TYPE":
+		return true
+	}
+	return false
+}
+
+func (stmt *sqlStatement) findMergeWithoutCondition() (sqlToken, bool) {
+	if len(stmt.Tokens) < 2 || stmt.Tokens[0].Upper != "MERGE" {
+		return sqlToken{}, false
+	}
+	p := &sqlParser{tokens: stmt.Tokens, base: stmt.Tokens[0].Depth}
+	on := p.findTop(1, len(stmt.Tokens), "ON")
+	if on < 0 {
+		return stmt.Tokens[0], true
+	}
+	end := p.findTop(on+1, len(stmt.Tokens), "WHEN")
+	if end < 0 {
+		end = len(stmt.Tokens)
+	}
+	condition := stmt.Tokens[on+1 : end]
+	for len(condition) >= 2 && condition[0].Text == "(" && condition[len(condition)-1].Text == ")" {
+		condition = condition[1 : len(condition)-1]
+	}
+	if len(condition) == 0 {
+		return stmt.Tokens[on], true
+	}
+	literal := func(token sqlToken) bool {
+		return token.Kind == sqlTokenNumber || token.Kind == sqlTokenString
+	}
+	tautology := len(condition) == 3 && condition[1].Text == "=" && literal(condition[0]) && condition[0].Kind == condition[2].Kind && condition[0].Text == condition[2].Text
+	return stmt.Tokens[on], tautology
+}
+
+func (stmt *sqlStatement) findExecuteImmediate() (sqlToken, []sqlToken, bool) {
+	tokens := stmt.Tokens
+	for i := 0; i+1 < len(tokens); i++ {
+		if tokens[i].Upper != "EXECUTE" || tokens[i+1].Upper != "IMMEDIATE" {
+			continue
+		}
+		end := i + 2
+		for end < len(tokens) && tokens[end].Text != ";" && !hasSQLWordAt(tokens[end:end+1], tokens[i].Depth, "INTO", "USING", "RETURNING", "RETURN", "BULK") {
+			end++
+		}
+		token := tokens[i]
+		token.End = tokens[i+1].End
+		return token, tokens[i+2 : end], true
+	}
+	return sqlToken{}, nil, false
+}
+
+func (stmt *sqlStatement) hasRownumLimit() bool {
+	return stmt.Where != nil && hasSQLWordAt(stmt.Where.Tokens, stmt.Where.Keyword.Depth, "ROWNUM")
+}
+
+func (stmt *sqlStatement) selectsFromDual() bool {
+	return len(stmt.Tables) == 1 && strings.EqualFold(stmt.Tables[0].Name, "DUAL")
+}
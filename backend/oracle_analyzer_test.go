@@ -0,0 +1,73 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitOracleStatementSpansHandlesPLSQLBlocks(t *testing.T) {
+	script := strings.Join([]string{
+		"SET DEFINE OFF",
+		"PROMPT creating procedure",
+		"CREATE OR REPLACE PROCEDURE purge_logs AS",
+		"BEGIN",
+		"  DELETE FROM logs WHERE created_at < SYSDATE - 30;",
+		"  COMMIT;",
+		"END;",
+		"/",
+		"INSERT INTO notes (body) VALUES (q'[it's; done]');",
+		"EXEC purge_logs",
+		"SELECT 10 / 2 FROM dual;",
+	}, "\n")
+	spans := splitOracleStatementSpans(script)
+	if len(spans) != 4 {
+		t.Fatalf("expected 4 statements, got %d: %+v", len(spans), spans)
+	}
+	if !strings.HasPrefix(spans[0].Text, "CREATE OR REPLACE PROCEDURE") || !strings.HasSuffix(spans[0].Text, "END;") {
+		t.Fatalf("PL/SQL block should end at the / line, got %q", spans[0].Text)
+	}
+	insert := parseSQLStatementAt(script, spans[1].Span, oracleDialect)
+	if values := insert.Tokens[len(insert.Tokens)-2]; sqlStringValue(values) != "it's; done" {
+		t.Fatalf("unexpected q-quoted string value %q", sqlStringValue(values))
+	}
+	if spans[2].Text != "EXEC purge_logs" {
+		t.Fatalf("EXEC should end at line end, got %q", spans[2].Text)
+	}
+}
+
+func TestAnalyzeOracleRules(t *testing.T) {
+	script := strings.Join([]string{
+		"DROP TABLE orders PURGE;",
+		"DROP TABLE orders_bak;",
+		"TRUNCATE TABLE staging REUSE STORAGE;",
+		"MERGE INTO users u USING staging s ON (1 = 1) WHEN MATCHED THEN UPDATE SET u.name = s.name;",
+		"MERGE INTO users u USING staging s ON (u.id = s.id) WHEN MATCHED THEN UPDATE SET u.name = s.name;",
+		"SELECT id FROM users WHERE ROWNUM <= 10;",
+		"SELECT id FROM users FETCH FIRST 10 ROWS ONLY;",
+		"SELECT id FROM users;",
+		"BEGIN",
+		"  EXECUTE IMMEDIATE 'DROP TABLE ' || v_table;",
+		"END;",
+		"/",
+		"BEGIN EXECUTE IMMEDIATE 'TRUNCATE TABLE staging'; END;",
+	}, "\n")
+
+	issues := AnalyzeByEngine(EngineOracle, script, AnalyzeOptions{}).Issues
+	rules := issuesByStatement(issues)
+	messages := messagesByStatement(issues)
+	rules.expect(t, 1, "ora_drop_purge", true)
+	rules.expect(t, 1, "ora_dangerous_drop", false)
+	rules.expect(t, 2, "ora_dangerous_drop", true)
+	rules.expect(t, 3, "ora_dangerous_truncate", true)
+	rules.expect(t, 3, "ora_truncate_reuse_storage", true)
+	rules.expect(t, 4, "ora_merge_without_condition", true)
+	rules.expect(t, 5, "ora_merge_without_condition", false)
+	rules.expect(t, 6, "ora_select_without_limit", false)
+	rules.expect(t, 7, "ora_select_without_limit", false)
+	rules.expect(t, 8, "ora_select_without_limit", true)
+	rules.expect(t, 9, "ora_execute_immediate", true)
+	rules.expect(t, 10, "ora_execute_immediate", true)
+	if !strings.Contains(messages[9], "注入") || !strings.Contains(messages[10], "TRUNCATE") {
+		t.Fatalf("unexpected EXECUTE IMMEDIATE messages: %+v", messages)
+	}
+}
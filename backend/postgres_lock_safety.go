@@ -0,0 +1,530 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+type pgMigrationStep struct {
+	InTransaction  bool
+	LockTimeout    bool
+	NewTable       bool
+	NotNullChecked []string
+}
+
+type pgNotNullCheck struct {
+	Table     string
+	Column    string
+	Validated bool
+}
+
+var pgVolatileFunctions = map[string]struct{}{
+	"RANDOM": {}, "CLOCK_TIMESTAMP": {}, "TIMEOFDAY": {}, "GEN_RANDOM_UUID": {}, "UUIDV4": {}, "UUIDV7": {},
+	"UUID_GENERATE_V1": {}, "UUID_GENERATE_V1MC": {}, "UUID_GENERATE_V4": {}, "NEXTVAL": {}, "SETSEED": {},
+}
+
+var pgSerialTypes = map[string]struct{}{"SERIAL": {}, "BIGSERIAL": {}, "SMALLSERIAL": {}, "SERIAL2": {}, "SERIAL4": {}, "SERIAL8": {}}
+
+var pgTableConstraintWords = map[string]struct{}{"CONSTRAINT": {}, "PRIMARY": {}, "UNIQUE": {}, "FOREIGN": {}, "CHECK": {}, "EXCLUDE": {}}
+
+var rePGZeroTimeout = regexp.MustCompile(`^0+(\.0+)?\s*(us|ms|s|min|h|d)?$`)
+
+func (ctx *RuleContext) replayPGMigration() {
+	inTransaction := false
+	sessionTimeout, sessionAtBegin := false, false
+	localTimeout, localSet := false, false
+	created := make(map[string]struct{})
+	checks := make(map[string]pgNotNullCheck)
+
+	for i := range ctx.Statements {
+		stmt := ctx.Statements[i].SQL
+		step := &pgMigrationStep{InTransaction: inTransaction, LockTimeout: sessionTimeout}
+		if localSet {
+			step.LockTimeout = localTimeout
+		}
+		if stmt != nil && len(stmt.Tables) > 0 {
+			key := catalogKey(stmt.Tables[0].Name)
+			_, step.NewTable = created[key]
+			for _, check := range checks {
+				if check.Validated && check.Table == key {
+					step.NotNullChecked = append(step.NotNullChecked, check.Column)
+				}
+			}
+		}
+		ctx.Statements[i].Migration = step
+		if stmt == nil {
+			continue
+		}
+
+		switch stmt.Kind {
+		case sqlStmtBegin:
+			if !inTransaction {
+				inTransaction, sessionAtBegin = true, sessionTimeout
+			}
+		case sqlStmtCommit:
+			inTransaction, localSet = false, false
+		case sqlStmtRollback:
+			if !stmt.hasTopLevelWord("TO") {
+				inTransaction, localSet, sessionTimeout = false, false, sessionAtBegin
+			}
+		case sqlStmtCreateTable:
+			if len(stmt.Tables) > 0 {
+				created[catalogKey(stmt.Tables[0].Name)] = struct{}{}
+			}
+		case sqlStmtDrop:
+			if stmt.ObjectType == "TABLE" {
+				for _, ref := range stmt.Tables {
+					delete(created, catalogKey(ref.Name))
+				}
+			}
+		case sqlStmtAlterTable:
+			if len(stmt.Tables) > 0 {
+				replayPGAlterTable(stmt, created, checks)
+			}
+		case sqlStmtOther:
+			scope, enabled, found := pgLockTimeoutSetting(stmt.Tokens)
+			switch {
+			case !found:
+			case scope == "LOCAL":
+				if inTransaction {
+					localTimeout, localSet = enabled, true
+				}
+			default:
+				sessionTimeout, localSet = enabled, false
+			}
+		}
+	}
+}
+
+func replayPGAlterTable(stmt *sqlStatement, created map[string]struct{}, checks map[string]pgNotNullCheck) {
+	table := catalogKey(stmt.Tables[0].Name)
+	for _, clause := range stmt.AlterClauses {
+		if len(clause) < 2 {
+			continue
+		}
+		switch clause[0].Upper {
+		case "ADD":
+			name, kind, body := pgAlterConstraint(clause)
+			if kind != "CHECK" {
+				continue
+			}
+			if column, found := pgNotNullCheckColumn(body); found {
+				checks[table+"."+strings.ToLower(firstNonEmpty(name, "#"+column))] = pgNotNullCheck{Table: table, Column: column, Validated: !hasSQLWordAt(clause, clause[0].Depth, "VALID")}
+			}
+		case "VALIDATE":
+			if clause[1].Upper != "CONSTRAINT" || len(clause) < 3 {
+				continue
+			}
+			key := table + "." + strings.ToLower(sqlIdentifierName(clause[2]))
+			if check, found := checks[key]; found {
+				check.Validated = true
+				checks[key] = check
+			}
+		case "DROP":
+			rest := trimSQLWords(trimSQLWords(clause[1:], "CONSTRAINT"), "IF", "EXISTS")
+			if clause[1].Upper == "CONSTRAINT" && len(rest) > 0 {
+				delete(checks, table+"."+strings.ToLower(sqlIdentifierName(rest[0])))
+			}
+		case "RENAME":
+			rest := clause[1:]
+			if rest[0].Upper != "TO" || len(rest) < 2 {
+				continue
+			}
+			renamed := catalogKey(sqlIdentifierName(rest[len(rest)-1]))
+			if _, found := created[table]; found {
+				delete(created, table)
+				created[renamed] = struct{}{}
+			}
+			for key, check := range checks {
+				if check.Table == table {
+					check.Table = renamed
+					checks[key] = check
+				}
+			}
+		}
+	}
+}
+
+func pgLockTimeoutSetting(tokens []sqlToken) (string, bool, bool) {
+	if len(tokens) < 2 {
+		return "", false, false
+	}
+	switch tokens[0].Upper {
+	case "RESET":
+		return "", false, tokens[1].Upper == "LOCK_TIMEOUT" || tokens[1].Upper == "ALL"
+	case "SET":
+	default:
+		return "", false, false
+	}
+	scope, rest := "", tokens[1:]
+	if rest[0].Upper == "SESSION" || rest[0].Upper == "LOCAL" {
+		scope, rest = rest[0].Upper, rest[1:]
+	}
+	if len(rest) < 3 || rest[0].Upper != "LOCK_TIMEOUT" || (rest[1].Text != "=" && rest[1].Upper != "TO") {
+		return "", false, false
+	}
+	value := strings.TrimSpace(strings.Trim(sqlStringValue(rest[2]), `'"`))
+	return scope, rest[2].Upper != "DEFAULT" && !rePGZeroTimeout.MatchString(strings.ToLower(value)), true
+}
+
+type pgTableLock int
+
+const (
+	pgLockShareUpdateExclusive pgTableLock = iota
+	pgLockShare
+	pgLockShareRowExclusive
+	pgLockAccessExclusive
+)
+
+func (lock pgTableLock) String() string {
+	switch lock {
+	case pgLockShareUpdateExclusive:
+		return "SHARE UPDATE EXCLUSIVE"
+	case pgLockShare:
+		return "SHARE"
+	case pgLockShareRowExclusive:
+		return "SHARE ROW EXCLUSIVE"
+	default:
+		return "ACCESS EXCLUSIVE"
+	}
+}
+
+func pgAlterClauseLock(clause []sqlToken) pgTableLock {
+	if len(clause) == 0 {
+		return pgLockAccessExclusive
+	}
+	depth := clause[0].Depth
+	switch clause[0].Upper {
+	case "VALIDATE", "CLUSTER", "ATTACH":
+		return pgLockShareUpdateExclusive
+	case "DETACH":
+		if hasSQLWordAt(clause, depth, "CONCURRENTLY", "FINALIZE") {
+			return pgLockShareUpdateExclusive
+		}
+	case "ENABLE", "DISABLE":
+		if len(clause) > 1 && clause[1].Upper == "TRIGGER" {
+			return pgLockShareRowExclusive
+		}
+	case "SET":
+		if len(trimSQLWords(clause, "SET", "WITHOUT", "CLUSTER")) < len(clause) {
+			return pgLockShareUpdateExclusive
+		}
+	case "ADD":
+		if len(clause) > 1 {
+			if _, kind, _ := pgAlterConstraint(clause); kind == "FOREIGN" {
+				return pgLockShareRowExclusive
+			}
+		}
+	case "ALTER":
+		if _, rest, found := pgAlterColumnClause(clause); found {
+			if len(trimSQLWords(rest, "SET", "STATISTICS")) < len(rest) {
+				return pgLockShareUpdateExclusive
+			}
+			if len(rest) > 1 && (rest[0].Upper == "SET" || rest[0].Upper == "RESET") && rest[1].Text == "(" {
+				return pgLockShareUpdateExclusive
+			}
+		}
+	}
+	return pgLockAccessExclusive
+}
+
+func pgAlterConstraint(clause []sqlToken) (string, string, []sqlToken) {
+	rest := clause[1:]
+	name := ""
+	if rest[0].Upper == "CONSTRAINT" {
+		if len(rest) < 3 {
+			return "", "", nil
+		}
+		name, rest = sqlIdentifierName(rest[1]), rest[2:]
+	}
+	if _, found := pgTableConstraintWords[rest[0].Upper]; !found || rest[0].Kind != sqlTokenWord {
+		return "", "", nil
+	}
+	return name, rest[0].Upper, rest[1:]
+}
+
+func pgNotNullCheckColumn(body []sqlToken) (string, bool) {
+	if len(body) < 6 || body[0].Text != "(" {
+		return "", false
+	}
+	closing, ok := matchingSQLParen(body, 0)
+	if !ok {
+		return "", false
+	}
+	inner := body[1:closing]
+	if len(inner) != 4 || inner[1].Upper != "IS" || inner[2].Upper != "NOT" || inner[3].Upper != "NULL" {
+		return "", false
+	}
+	return strings.ToLower(sqlIdentifierName(inner[0])), true
+}
+
+func pgAlterColumnClause(clause []sqlToken) (string, []sqlToken, bool) {
+	if len(clause) < 3 || clause[0].Upper != "ALTER" {
+		return "", nil, false
+	}
+	rest := trimSQLWords(clause[1:], "COLUMN")
+	if len(rest) < 2 {
+		return "", nil, false
+	}
+	return sqlIdentifierName(rest[0]), rest[1:], true
+}
+
+func pgAddColumnClause(clause []sqlToken) (string, []sqlToken, bool) {
+	if len(clause) < 3 || clause[0].Upper != "ADD" {
+		return "", nil, false
+	}
+	explicit := clause[1].Upper == "COLUMN"
+	rest := trimSQLWords(trimSQLWords(clause[1:], "COLUMN"), "IF", "NOT", "EXISTS")
+	if len(rest) < 2 {
+		return "", nil, false
+	}
+	if _, found := pgTableConstraintWords[rest[0].Upper]; found && !explicit && rest[0].Kind == sqlTokenWord {
+		return "", nil, false
+	}
+	return sqlIdentifierName(rest[0]), rest[1:], true
+}
+
+func pgVolatileDefault(definition []sqlToken) (string, bool) {
+	if _, found := pgSerialTypes[definition[0].Upper]; found {
+		return definition[0].Upper + " 类型隐含 nextval() 默认值", true
+	}
+	depth := definition[0].Depth
+	for i, token := range definition {
+		if token.Depth == depth && token.Upper == "STORED" {
+			return "STORED 生成列", true
+		}
+		if token.Kind != sqlTokenWord || i+1 >= len(definition) || definition[i+1].Text != "(" {
+			continue
+		}
+		if _, found := pgVolatileFunctions[token.Upper]; found && hasSQLWordAt(definition[:i], depth, "DEFAULT") {
+			return "默认值 " + strings.ToLower(token.Text) + "() 为易变函数", true
+		}
+	}
+	return "", false
+}
+
+func pgTypeChangeIsSafe(from, to string, hasUsing bool) bool {
+	if hasUsing {
+		return false
+	}
+	from, to = strings.ToUpper(from), strings.ToUpper(to)
+	if varcharExtends(from, to) {
+		return true
+	}
+	textual := func(t string) bool { return t == "TEXT" || t == "VARCHAR" || t == "CHARACTER VARYING" }
+	return (textual(from) || reVarcharLength.MatchString(from)) && textual(to)
+}
+
+func (stmt RuleStatement) pgExistingTable() (string, bool) {
+	if stmt.Migration == nil || stmt.SQL == nil || len(stmt.SQL.Tables) == 0 || stmt.Migration.NewTable {
+		return "", false
+	}
+	return stmt.SQL.Tables[0].Name, true
+}
+
+func pgAddColumnVolatileDefaultRule() Rule {
+	return newStatementRule(
+		RuleDefinition{Code: "pg_add_column_volatile_default", Level: LevelWarning, Category: "DDL锁影响", Description: "ADD COLUMN 使用易变默认值，需重写全表"},
+		func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+			table, found := stmt.pgExistingTable()
+			if !found || stmt.SQL.Kind != sqlStmtAlterTable {
+				return Issue{}, false
+			}
+			for _, clause := range stmt.SQL.AlterClauses {
+				column, definition, found := pgAddColumnClause(clause)
+				if !found {
+					continue
+				}
+				if reason, volatile := pgVolatileDefault(definition); volatile {
+					return ctx.Source.locate(Issue{
+						Message:    fmt.Sprintf("ALTER TABLE %s 添加列 %s：%s，需在 ACCESS EXCLUSIVE 锁下重写全表", table, column, reason),
+						Suggestion: "先添加无默认值或常量默认值的列，再分批回填数据，最后通过 ALTER COLUMN ... SET DEFAULT 设置默认值",
+					}, sourceSpan{Start: clause[0].Start, End: clause[len(clause)-1].End}), true
+				}
+			}
+			return Issue{}, false
+		},
+	)
+}
+
+func pgSetNotNullWithoutCheckRule() Rule {
+	return newStatementRule(
+		RuleDefinition{Code: "pg_set_not_null_without_check", Level: LevelWarning, Category: "DDL锁影响", Description: "SET NOT NULL 前没有已校验的 CHECK (col IS NOT NULL) 约束"},
+		func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+			table, found := stmt.pgExistingTable()
+			if !found || stmt.SQL.Kind != sqlStmtAlterTable {
+				return Issue{}, false
+			}
+			for _, clause := range stmt.SQL.AlterClauses {
+				column, rest, found := pgAlterColumnClause(clause)
+				if !found || len(trimSQLWords(rest, "SET", "NOT", "NULL")) == len(rest) {
+					continue
+				}
+				if slices.Contains(stmt.Migration.NotNullChecked, strings.ToLower(column)) || stmt.catalogColumnNotNull(table, column) {
+					continue
+				}
+				return ctx.Source.locate(Issue{
+					Message:    fmt.Sprintf("ALTER TABLE %s 对列 %s 执行 SET NOT NULL，将在 ACCESS EXCLUSIVE 锁下全表扫描校验", table, column),
+					Suggestion: fmt.Sprintf("先 ADD CONSTRAINT ... CHECK (%s IS NOT NULL) NOT VALID，再 VALIDATE CONSTRAINT，之后 SET NOT NULL（PostgreSQL 12+ 跳过全表扫描），最后删除该 CHECK 约束", column),
+				}, sourceSpan{Start: clause[0].Start, End: clause[len(clause)-1].End}), true
+			}
+			return Issue{}, false
+		},
+	)
+}
+
+func (stmt RuleStatement) catalogColumnNotNull(table, column string) bool {
+	if stmt.Catalog == nil {
+		return false
+	}
+	schemaTable, found := stmt.Catalog.table(table)
+	if !found {
+		return false
+	}
+	existing, found := schemaTable.column(column)
+	return found && !existing.Nullable
+}
+
+func pgConstraintWithoutNotValidRule() Rule {
+	return newStatementRule(
+		RuleDefinition{Code: "pg_constraint_without_not_valid", Level: LevelWarning, Category: "DDL锁影响", Description: "添加外键或 CHECK 约束未使用 NOT VALID"},
+		func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+			table, found := stmt.pgExistingTable()
+			if !found || stmt.SQL.Kind != sqlStmtAlterTable {
+				return Issue{}, false
+			}
+			for _, clause := range stmt.SQL.AlterClauses {
+				if len(clause) < 2 || clause[0].Upper != "ADD" {
+					continue
+				}
+				_, kind, _ := pgAlterConstraint(clause)
+				if (kind != "FOREIGN" && kind != "CHECK") || hasSQLWordAt(clause, clause[0].Depth, "VALID") {
+					continue
+				}
+				label := "CHECK 约束"
+				if kind == "FOREIGN" {
+					label = "外键"
+				}
+				return ctx.Source.locate(Issue{
+					Message:    fmt.Sprintf("ALTER TABLE %s 添加%s未使用 NOT VALID，将在持锁期间校验全表数据", table, label),
+					Suggestion: "先以 NOT VALID 添加约束，再单独执行 ALTER TABLE ... VALIDATE CONSTRAINT（仅需 SHARE UPDATE EXCLUSIVE 锁，不阻塞读写）",
+				}, sourceSpan{Start: clause[0].Start, End: clause[len(clause)-1].End}), true
+			}
+			return Issue{}, false
+		},
+	)
+}
+
+func pgAlterColumnTypeRule() Rule {
+	return newStatementRule(
+		RuleDefinition{Code: "pg_alter_column_type", Level: LevelWarning, Category: "DDL锁影响", Description: "ALTER COLUMN TYPE 重写全表并重建索引"},
+		func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+			table, found := stmt.pgExistingTable()
+			if !found || stmt.SQL.Kind != sqlStmtAlterTable {
+				return Issue{}, false
+			}
+			for _, clause := range stmt.SQL.AlterClauses {
+				column, rest, found := pgAlterColumnClause(clause)
+				if !found {
+					continue
+				}
+				rest = trimSQLWords(rest, "SET", "DATA")
+				if len(rest) < 2 || rest[0].Upper != "TYPE" {
+					continue
+				}
+				target := joinSQLTokens(trimSQLTypeTail(rest[1:]))
+				if stmt.Catalog != nil {
+					if schemaTable, found := stmt.Catalog.table(table); found {
+						if existing, found := schemaTable.column(column); found && pgTypeChangeIsSafe(existing.Type, target, hasSQLWordAt(rest, rest[0].Depth, "USING")) {
+							continue
+						}
+					}
+				}
+				return ctx.Source.locate(Issue{
+					Message:    fmt.Sprintf("ALTER TABLE %s 修改列 %s 类型为 %s，将在 ACCESS EXCLUSIVE 锁下重写全表并重建相关索引", table, column, target),
+					Suggestion: "大表建议新增列、分批回填、切换读写后再删除旧列；仅放宽 VARCHAR 长度或改为 TEXT 时无需重写（提供结构快照可自动识别）",
+				}, sourceSpan{Start: clause[0].Start, End: clause[len(clause)-1].End}), true
+			}
+			return Issue{}, false
+		},
+	)
+}
+
+func pgConcurrentlyInTransactionRule() Rule {
+	return newStatementRule(
+		RuleDefinition{Code: "pg_concurrently_in_transaction", Level: LevelError, Category: "DDL并发", Description: "CONCURRENTLY 操作位于事务块内，执行会直接失败"},
+		func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+			if stmt.Migration == nil || !stmt.Migration.InTransaction || stmt.SQL == nil {
+				return Issue{}, false
+			}
+			token, found := stmt.SQL.findTokenSequence("CONCURRENTLY")
+			if !found || (stmt.SQL.Kind != sqlStmtCreateIndex && stmt.SQL.Kind != sqlStmtDrop && stmt.SQL.Kind != sqlStmtAlterTable && stmt.SQL.Tokens[0].Upper != "REINDEX") {
+				return Issue{}, false
+			}
+			return ctx.Source.locate(Issue{
+				Message:    "CONCURRENTLY 操作不能在事务块（BEGIN ... COMMIT）中执行，会报错 cannot run inside a transaction block",
+				Suggestion: "将该语句移到事务之外单独执行；使用迁移工具时需关闭该迁移文件的事务包裹",
+			}, token.span()), true
+		},
+	)
+}
+
+func pgRenameLiveObjectRule() Rule {
+	return newStatementRule(
+		RuleDefinition{Code: "pg_rename_live_object", Level: LevelWarning, Category: "兼容性", Description: "重命名线上表或列会导致旧版本应用报错"},
+		func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+			table, found := stmt.pgExistingTable()
+			if !found || stmt.SQL.Kind != sqlStmtAlterTable {
+				return Issue{}, false
+			}
+			for _, clause := range stmt.SQL.AlterClauses {
+				if len(clause) < 3 || clause[0].Upper != "RENAME" || clause[1].Upper == "CONSTRAINT" {
+					continue
+				}
+				target := "表 " + table
+				if clause[1].Upper != "TO" {
+					target = fmt.Sprintf("表 %s 的列 %s", table, sqlIdentifierName(trimSQLWords(clause[1:], "COLUMN")[0]))
+				}
+				return ctx.Source.locate(Issue{
+					Message:    fmt.Sprintf("重命名%s，仍在运行的旧版本应用会立即报错", target),
+					Suggestion: "采用扩展-收缩方式：先新增列或兼容视图并双写，待应用全部切换后再删除旧对象",
+				}, sourceSpan{Start: clause[0].Start, End: clause[len(clause)-1].End}), true
+			}
+			return Issue{}, false
+		},
+	)
+}
+
+func pgMissingLockTimeoutRule() Rule {
+	return newStatementRule(
+		RuleDefinition{Code: "pg_missing_lock_timeout", Level: LevelWarning, Category: "DDL锁影响", Description: "获取表级强锁前未设置 lock_timeout"},
+		func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+			table, found := stmt.pgExistingTable()
+			if !found || stmt.Migration.LockTimeout {
+				return Issue{}, false
+			}
+			lock := pgLockShareUpdateExclusive
+			switch {
+			case stmt.SQL.Kind == sqlStmtAlterTable:
+				for _, clause := range stmt.SQL.AlterClauses {
+					lock = max(lock, pgAlterClauseLock(clause))
+				}
+			case stmt.SQL.Kind == sqlStmtCreateIndex && !stmt.SQL.Concurrently:
+				lock = pgLockShare
+			}
+			if lock == pgLockShareUpdateExclusive {
+				return Issue{}, false
+			}
+			blocked := "写入"
+			if lock == pgLockAccessExclusive {
+				blocked = "读写"
+			}
+			return ctx.Source.locate(Issue{
+				Message:    fmt.Sprintf("未设置 lock_timeout 即对表 %s 申请 %s 锁，排队等待期间会阻塞该表后续所有%s", table, lock, blocked),
+				Suggestion: "变更前执行 SET lock_timeout = '5s'（事务内可用 SET LOCAL），拿不到锁时快速失败后重试，避免长时间阻塞业务",
+			}, stmt.SQL.Tables[0].Token.span()), true
+		},
+	)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReplayPGMigrationTracksTransactionAndTimeout(t *testing.T) {
+	script := strings.Join([]string{
+		"SET lock_timeout = '3s';",
+		"BEGIN;",
+		"SET LOCAL lock_timeout = 0;",
+		"ALTER TABLE users ADD COLUMN a INT;",
+		"ROLLBACK;",
+		"RESET lock_timeout;",
+		"CREATE TABLE audit (id BIGINT);",
+		"ALTER TABLE users ADD CONSTRAINT users_email_nn CHECK (email IS NOT NULL) NOT VALID;",
+		"ALTER TABLE users VALIDATE CONSTRAINT users_email_nn;",
+		"ALTER TABLE audit ADD COLUMN note TEXT;",
+		"ALTER TABLE users ALTER COLUMN email SET NOT NULL;",
+	}, "\n")
+	ctx := newSQLRuleContext(EnginePostgreSQL, script, postgresDialect)
+	ctx.replayPGMigration()
+
+	expect := func(index int, want pgMigrationStep) {
+		t.Helper()
+		got := ctx.Statements[index-1].Migration
+		if got.InTransaction != want.InTransaction || got.LockTimeout != want.LockTimeout || got.NewTable != want.NewTable {
+			t.Fatalf("statement %d: want %+v, got %+v", index, want, *got)
+		}
+	}
+	expect(2, pgMigrationStep{LockTimeout: true})
+	expect(4, pgMigrationStep{InTransaction: true})
+	expect(6, pgMigrationStep{LockTimeout: true})
+	expect(7, pgMigrationStep{})
+	expect(10, pgMigrationStep{NewTable: true})
+	if checked := ctx.Statements[10].Migration.NotNullChecked; len(checked) != 1 || checked[0] != "email" {
+		t.Fatalf("expected validated NOT NULL check on email, got %+v", checked)
+	}
+}
+
+func TestAnalyzePostgresLockSafetyRules(t *testing.T) {
+	script := strings.Join([]string{
+		"ALTER TABLE users ADD COLUMN token UUID DEFAULT gen_random_uuid();",
+		"ALTER TABLE users ADD COLUMN created_at TIMESTAMPTZ DEFAULT now();",
+		"ALTER TABLE users ALTER COLUMN name SET NOT NULL;",
+		"SET lock_timeout = '5s';",
+		"ALTER TABLE orders ADD CONSTRAINT fk_orders_user FOREIGN KEY (user_id) REFERENCES users (id);",
+		"ALTER TABLE orders ADD CONSTRAINT ck_amount CHECK (amount > 0) NOT VALID;",
+		"ALTER TABLE orders ALTER COLUMN amount TYPE NUMERIC(12,2);",
+		"ALTER TABLE users RENAME COLUMN name TO full_name;",
+		"BEGIN;",
+		"CREATE INDEX CONCURRENTLY idx_users_email ON users (email);",
+		"COMMIT;",
+		"CREATE TABLE audit (id BIGINT);",
+		"ALTER TABLE audit ADD COLUMN id2 BIGSERIAL, RENAME COLUMN id TO audit_id;",
+		"RESET lock_timeout;",
+		"ALTER TABLE orders VALIDATE CONSTRAINT ck_amount;",
+		"ALTER TABLE orders ADD CONSTRAINT fk_orders_coupon FOREIGN KEY (coupon_id) REFERENCES coupons (id) NOT VALID;",
+		"ALTER TABLE orders ALTER COLUMN amount SET STATISTICS 500, VALIDATE CONSTRAINT fk_orders_coupon;",
+		"ALTER TABLE orders VALIDATE CONSTRAINT fk_orders_coupon, DROP COLUMN note;",
+	}, "\n")
+
+	issues := AnalyzePostgresWithOptions(script, AnalyzeOptions{}).Issues
+	rules := issuesByStatement(issues)
+	messages := messagesByStatement(issues)
+	rules.expect(t, 1, "pg_add_column_volatile_default", true)
+	rules.expect(t, 1, "pg_missing_lock_timeout", true)
+	rules.expect(t, 2, "pg_add_column_volatile_default", false)
+	rules.expect(t, 3, "pg_set_not_null_without_check", true)
+	rules.expect(t, 5, "pg_constraint_without_not_valid", true)
+	rules.expect(t, 5, "pg_missing_lock_timeout", false)
+	rules.expect(t, 6, "pg_constraint_without_not_valid", false)
+	rules.expect(t, 7, "pg_alter_column_type", true)
+	rules.expect(t, 8, "pg_rename_live_object", true)
+	rules.expect(t, 10, "pg_concurrently_in_transaction", true)
+	rules.expect(t, 13, "pg_add_column_volatile_default", false)
+	rules.expect(t, 13, "pg_rename_live_object", false)
+	rules.expect(t, 13, "pg_missing_lock_timeout", false)
+	rules.expect(t, 15, "pg_missing_lock_timeout", false)
+	rules.expect(t, 16, "pg_missing_lock_timeout", true)
+	rules.expect(t, 17, "pg_missing_lock_timeout", false)
+	rules.expect(t, 18, "pg_missing_lock_timeout", true)
+	if !strings.Contains(messages[16], "SHARE ROW EXCLUSIVE") || strings.Contains(messages[16], "读写") {
+		t.Fatalf("expected SHARE ROW EXCLUSIVE lock blocking writes, got %q", messages[16])
+	}
+	if !strings.Contains(messages[18], "ACCESS EXCLUSIVE") {
+		t.Fatalf("expected ACCESS EXCLUSIVE lock, got %q", messages[18])
+	}
+}
+
+func TestPostgresLockSafetyRulesUseCatalog(t *testing.T) {
+	catalog, err := buildSchemaCatalog(EnginePostgreSQL, "CREATE TABLE users (id BIGINT PRIMARY KEY, email VARCHAR(64) NOT NULL, name VARCHAR(32), age INT);")
+	if err != nil {
+		t.Fatalf("build catalog err: %v", err)
+	}
+	script := strings.Join([]string{
+		"SET lock_timeout = '5s';",
+		"ALTER TABLE users ALTER COLUMN name TYPE VARCHAR(128);",
+		"ALTER TABLE users ALTER COLUMN email TYPE TEXT;",
+		"ALTER TABLE users ALTER COLUMN age TYPE BIGINT;",
+		"ALTER TABLE users ALTER COLUMN email SET NOT NULL;",
+	}, "\n")
+	rules := issueRules(AnalyzePostgresWithOptions(script, AnalyzeOptions{Catalog: catalog}).Issues)
+	if rules["pg_alter_column_type"] != 1 {
+		t.Fatalf("only the INT to BIGINT change should rewrite the table, got %+v", rules)
+	}
+	if rules["pg_set_not_null_without_check"] != 0 {
+		t.Fatalf("email is already NOT NULL in the catalog, got %+v", rules)
+	}
+}
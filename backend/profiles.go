@@ -0,0 +1,262 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type RuleProfile struct {
+	ID            int64    `json:"id"`
+	Name          string   `json:"name"`
+	Engine        DBEngine `json:"engine"`
+	Description   string   `json:"description"`
+	DisabledRules []string `json:"disabledRules"`
+	RuleOverrides
+	IsDefault bool   `json:"isDefault"`
+	CreatedAt string `json:"createdAt"`
+	UpdatedAt string `json:"updatedAt"`
+}
+
+type ruleProfileRequest struct {
+	Name          string   `json:"name"`
+	Engine        string   `json:"engine"`
+	Description   string   `json:"description"`
+	DisabledRules []string `json:"disabledRules"`
+	RuleOverrides
+	IsDefault bool `json:"isDefault"`
+}
+
+var reProfileName = regexp.MustCompile(`^[\p{L}\p{N}_.-]{1,64}$`)
+
+func (req ruleProfileRequest) toProfile(engine DBEngine) (RuleProfile, error) {
+	name := strings.TrimSpace(req.Name)
+	if !reProfileName.MatchString(name) {
+		return RuleProfile{}, errors.New("invalid profile name: use 1-64 letters, digits, '_', '-' or '.'")
+	}
+
+	disabledRules, err := validateProfileRules(engine, req.DisabledRules)
+	if err != nil {
+		return RuleProfile{}, err
+	}
+	overrides, err := req.RuleOverrides.validate(engine)
+	if err != nil {
+		return RuleProfile{}, err
+	}
+
+	return RuleProfile{
+		Name:          name,
+		Engine:        engine,
+		Description:   strings.TrimSpace(req.Description),
+		DisabledRules: disabledRules,
+		RuleOverrides: overrides,
+		IsDefault:     req.IsDefault,
+	}, nil
+}
+
+func validateProfileRules(engine DBEngine, codes []string) ([]string, error) {
+	_, definitions := RulesForEngine(engine)
+	known := make(map[string]RuleDefinition, len(definitions))
+	for _, definition := range definitions {
+		known[definition.Code] = definition
+	}
+
+	seen := make(map[string]struct{}, len(codes))
+	normalized := make([]string, 0, len(codes))
+	for _, code := range codes {
+		trimmed := strings.TrimSpace(code)
+		if trimmed == "" {
+			continue
+		}
+		if _, exists := seen[trimmed]; exists {
+			continue
+		}
+		definition, found := known[trimmed]
+		if !found {
+			return nil, fmt.Errorf("unknown rule for engine %s: %s", engine, trimmed)
+		}
+		if definition.AlwaysEnabled {
+			return nil, fmt.Errorf("always-enabled rule cannot be disabled: %s", trimmed)
+		}
+		seen[trimmed] = struct{}{}
+		normalized = append(normalized, trimmed)
+	}
+	sort.Strings(normalized)
+	return normalized, nil
+}
+
+func resolveCheckProfile(ctx context.Context, engine DBEngine, name string, explicitRules bool) (*RuleProfile, error) {
+	if historyStore == nil {
+		return nil, nil
+	}
+
+	name = strings.TrimSpace(name)
+	if name != "" {
+		profile, err := historyStore.GetProfileByName(ctx, engine, name)
+		if err != nil {
+			return nil, err
+		}
+		return &profile, nil
+	}
+	if explicitRules {
+		return nil, nil
+	}
+
+	profile, err := historyStore.DefaultProfile(ctx, engine)
+	if errors.Is(err, ErrProfileNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+func handleProfiles(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		engine := DBEngine("")
+		if raw := strings.TrimSpace(r.URL.Query().Get("engine")); raw != "" {
+			resolved, err := ResolveEngine(raw)
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+				return
+			}
+			engine = resolved
+		}
+		profiles, err := historyStore.ListProfiles(r.Context(), engine)
+		if err != nil {
+			log.Printf("list rule profiles failed: %v", err)
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "failed to list rule profiles"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"items": profiles})
+	case http.MethodPost:
+		var req ruleProfileRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid profile payload"})
+			return
+		}
+		engine, err := ResolveEngine(req.Engine)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+			return
+		}
+		profile, err := req.toProfile(engine)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+			return
+		}
+		created, err := historyStore.CreateProfile(r.Context(), profile)
+		if err != nil {
+			writeProfileStoreError(w, "create", err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, created)
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "only GET and POST are allowed"})
+	}
+}
+
+func handleProfileDetail(w http.ResponseWriter, r *http.Request) {
+	id, action, err := parseProfilePath(r.URL.Path)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
+	}
+
+	if action == "default" {
+		if r.Method != http.MethodPost && r.Method != http.MethodPut {
+			writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "only POST and PUT are allowed"})
+			return
+		}
+		profile, err := historyStore.SetDefaultProfile(r.Context(), id)
+		if err != nil {
+			writeProfileStoreError(w, "set default", err)
+			return
+		}
+		writeJSON(w, http.StatusOK, profile)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		profile, err := historyStore.GetProfile(r.Context(), id)
+		if err != nil {
+			writeProfileStoreError(w, "get", err)
+			return
+		}
+		writeJSON(w, http.StatusOK, profile)
+	case http.MethodPut:
+		existing, err := historyStore.GetProfile(r.Context(), id)
+		if err != nil {
+			writeProfileStoreError(w, "get", err)
+			return
+		}
+		var req ruleProfileRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid profile payload"})
+			return
+		}
+		if err := checkUnchangedEngine(req.Engine, existing.Engine, "profile"); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+			return
+		}
+		profile, err := req.toProfile(existing.Engine)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+			return
+		}
+		updated, err := historyStore.UpdateProfile(r.Context(), id, profile)
+		if err != nil {
+			writeProfileStoreError(w, "update", err)
+			return
+		}
+		writeJSON(w, http.StatusOK, updated)
+	case http.MethodDelete:
+		if err := historyStore.DeleteProfile(r.Context(), id); err != nil {
+			writeProfileStoreError(w, "delete", err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"deleted": 1})
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "only GET, PUT and DELETE are allowed"})
+	}
+}
+
+func parseProfilePath(path string) (int64, string, error) {
+	rest := strings.Trim(strings.TrimPrefix(path, "/api/v1/profiles/"), "/")
+	if rest == "" {
+		return 0, "", errors.New("missing profile id")
+	}
+
+	idText, action, _ := strings.Cut(rest, "/")
+	if action != "" && action != "default" {
+		return 0, "", fmt.Errorf("unknown profile action: %s", action)
+	}
+
+	id, err := strconv.ParseInt(strings.TrimSpace(idText), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, "", errors.New("invalid profile id")
+	}
+	return id, action, nil
+}
+
+func writeProfileStoreError(w http.ResponseWriter, operation string, err error) {
+	switch {
+	case errors.Is(err, ErrProfileNotFound):
+		writeJSON(w, http.StatusNotFound, errorResponse{Error: "rule profile not found"})
+	case errors.Is(err, ErrProfileExists):
+		writeJSON(w, http.StatusConflict, errorResponse{Error: "rule profile with the same name already exists for this engine"})
+	default:
+		log.Printf("%s rule profile failed: %v", operation, err)
+		writeJSON(w, http.StatusInternalServerError, errorResponse{Error: fmt.Sprintf("failed to %s rule profile", operation)})
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateProfileRules(t *testing.T) {
+	codes, err := validateProfileRules(EngineMySQL, []string{" select_star ", "select_without_limit", "select_star", ""})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if strings.Join(codes, ",") != "select_star,select_without_limit" {
+		t.Fatalf("expected sorted unique codes, got %v", codes)
+	}
+
+	if _, err := validateProfileRules(EngineMySQL, []string{"pg_select_star"}); err == nil {
+		t.Fatalf("rules from another engine should be rejected")
+	}
+	if _, err := validateProfileRules(EngineMySQL, []string{"missing_statement_terminator"}); err == nil {
+		t.Fatalf("always-enabled rules should be rejected")
+	}
+}
+
+func TestParseProfilePath(t *testing.T) {
+	id, action, err := parseProfilePath("/api/v1/profiles/12/default")
+	if err != nil || id != 12 || action != "default" {
+		t.Fatalf("unexpected parse result: id=%d action=%s err=%v", id, action, err)
+	}
+	if _, _, err := parseProfilePath("/api/v1/profiles/12/rename"); err == nil {
+		t.Fatalf("unknown actions should be rejected")
+	}
+	if _, _, err := parseProfilePath("/api/v1/profiles/abc"); err == nil {
+		t.Fatalf("non-numeric ids should be rejected")
+	}
+}
+
+func TestRuleProfileRequestRejectsInvalidName(t *testing.T) {
+	if _, err := (ruleProfileRequest{Name: "prod safe"}).toProfile(EngineMySQL); err == nil {
+		t.Fatalf("names with spaces should be rejected")
+	}
+	profile, err := (ruleProfileRequest{Name: " 生产-安全 ", DisabledRules: []string{"select_star"}}).toProfile(EngineMySQL)
+	if err != nil || profile.Name != "生产-安全" {
+		t.Fatalf("unexpected profile: %+v err=%v", profile, err)
+	}
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	redisScriptScanPattern   = regexp.MustCompile(`(?i)redis\.p?call\s*\(\s*['"](keys|scan)['"]`)
+	redisScriptDeletePattern = regexp.MustCompile(`(?i)redis\.p?call\s*\(\s*['"](del|unlink)['"]`)
+)
+
+var redisBulkWriteCommands = map[string]struct{}{
+	"SET": {}, "SETNX": {}, "SETEX": {}, "PSETEX": {}, "MSET": {}, "MSETNX": {}, "GETSET": {}, "APPEND": {},
+	"HSET": {}, "HMSET": {}, "HSETNX": {}, "SADD": {}, "LPUSH": {}, "RPUSH": {}, "ZADD": {}, "INCR": {}, "INCRBY": {},
+}
+
+var redisExpireCommands = map[string]struct{}{
+	"EXPIRE": {}, "PEXPIRE": {}, "EXPIREAT": {}, "PEXPIREAT": {},
+}
+
+var redisUnboundedReadCommands = map[string]string{
+	"SMEMBERS": "SSCAN", "HGETALL": "HSCAN", "HKEYS": "HSCAN", "HVALS": "HSCAN",
+	"LRANGE": "LRANGE 分段读取", "ZRANGE": "ZSCAN 或 ZRANGE 分段读取",
+}
+
+func BuiltInRedisRules() []RuleDefinition {
+	return ruleDefinitions(RegisteredRules(EngineRedis))
+}
+
+func redisRules() []Rule {
+	return []Rule{
+		emptyInputRule("Redis 脚本内容为空", "请上传 redis-cli 命令文件或粘贴命令后再检查"),
+		tooManyStatementsRule(1000, "大批量导入建议使用 redis-cli --pipe 并按批次执行"),
+		newStatementRule(
+			RuleDefinition{Code: "redis_unterminated_quote", Level: LevelError, Category: "脚本语法", Description: "命令中的引号未闭合", AlwaysEnabled: true},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				return Issue{Message: "命令中的引号未闭合，redis-cli 会拒绝执行，且后续各行都被当作同一个参数", Suggestion: "补齐引号；参数中的引号请使用 \\\" 转义"}, stmt.Redis != nil && stmt.Redis.Unterminated
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "redis_flush", Level: LevelError, Category: "高危操作", Description: "FLUSHALL / FLUSHDB 清空数据"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				cmd := stmt.Redis
+				if cmd == nil || (cmd.Name != "FLUSHALL" && cmd.Name != "FLUSHDB") {
+					return Issue{}, false
+				}
+				scope := "当前数据库"
+				if cmd.Name == "FLUSHALL" {
+					scope = "实例内所有数据库"
+				}
+				return ctx.Source.locate(Issue{
+					Message:    fmt.Sprintf("检测到 %s，将清空%s的全部键，且不可恢复", cmd.Name, scope),
+					Suggestion: "生产环境应通过 rename-command 禁用该命令；确需执行请确认目标实例与库号并先完成 RDB 备份",
+				}, cmd.NameSpan), true
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "redis_keys_command", Level: LevelError, Category: "阻塞风险", Description: "KEYS 遍历整个键空间"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				cmd := stmt.Redis
+				if cmd == nil || cmd.Name != "KEYS" {
+					return Issue{}, false
+				}
+				return ctx.Source.locate(Issue{
+					Message:    fmt.Sprintf("KEYS %s 会一次性遍历整个键空间，键数量大时长时间阻塞 Redis 主线程", cmd.arg(0)),
+					Suggestion: "改用 SCAN 0 MATCH <pattern> COUNT 1000 游标迭代",
+				}, cmd.NameSpan), true
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "redis_del_wildcard", Level: LevelWarning, Category: "删除安全", Description: "DEL / UNLINK 参数包含通配符"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				cmd := stmt.Redis
+				if cmd == nil || (cmd.Name != "DEL" && cmd.Name != "UNLINK") {
+					return Issue{}, false
+				}
+				for _, arg := range cmd.Args {
+					if strings.ContainsAny(arg.Text, "*?[") {
+						return ctx.Source.locate(Issue{
+							Message:    fmt.Sprintf("%s 不支持通配符，%s 会被当作字面量键名，匹配的键不会被删除", cmd.Name, arg.Text),
+							Suggestion: "按模式删除请用 SCAN MATCH 分批获取键名后 UNLINK，例如 redis-cli --scan --pattern 'user:*' | xargs redis-cli unlink",
+						}, arg.Span), true
+					}
+				}
+				return Issue{}, false
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "redis_script_wildcard_delete", Level: LevelError, Category: "删除安全", Description: "EVAL 脚本按模式遍历并删除键"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				cmd := stmt.Redis
+				if cmd == nil || (cmd.Name != "EVAL" && cmd.Name != "EVAL_RO") || len(cmd.Args) == 0 {
+					return Issue{}, false
+				}
+				script := cmd.arg(0)
+				scan := redisScriptScanPattern.FindStringSubmatch(script)
+				if scan == nil || !redisScriptDeletePattern.MatchString(script) {
+					return Issue{}, false
+				}
+				return ctx.Source.locate(Issue{
+					Message:    fmt.Sprintf("EVAL 脚本通过 %s 按模式匹配键后批量删除，脚本执行期间原子阻塞整个实例，匹配键多时可能触发 lua-time-limit 并导致主从延迟", strings.ToUpper(scan[1])),
+					Suggestion: "在客户端用 SCAN MATCH 游标分批获取键名，每批 UNLINK 几百个；不要在 Lua 中遍历键空间",
+				}, cmd.Args[0].Span), true
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "redis_config_set", Level: LevelWarning, Category: "配置变更", Description: "CONFIG SET 在线修改实例配置"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				cmd := stmt.Redis
+				if cmd == nil || cmd.Name != "CONFIG" || cmd.subcommand() != "SET" {
+					return Issue{}, false
+				}
+				message := fmt.Sprintf("CONFIG SET %s 会立即修改运行中实例的配置", cmd.arg(1))
+				if !redisHasCommandAfter(ctx, stmt.Index, "CONFIG", "REWRITE") {
+					message += "，脚本中未执行 CONFIG REWRITE，重启后配置将丢失"
+				}
+				return ctx.Source.locate(Issue{
+					Message:    message,
+					Suggestion: "配置变更应走配置文件与发布流程；确需在线修改请记录原值（CONFIG GET）以便回滚，并同步到所有主从节点",
+				}, cmd.NameSpan), true
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "redis_debug", Level: LevelError, Category: "高危操作", Description: "DEBUG 调试命令"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				cmd := stmt.Redis
+				if cmd == nil || cmd.Name != "DEBUG" {
+					return Issue{}, false
+				}
+				return ctx.Source.locate(Issue{
+					Message:    fmt.Sprintf("检测到 DEBUG %s，调试命令可能阻塞（SLEEP / RELOAD）或直接使实例崩溃（SEGFAULT）", cmd.subcommand()),
+					Suggestion: "生产变更脚本中禁止使用 DEBUG 命令，7.0+ 可通过 enable-debug-command 关闭",
+				}, cmd.NameSpan), true
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "redis_shutdown", Level: LevelError, Category: "高危操作", Description: "SHUTDOWN 停止实例"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				cmd := stmt.Redis
+				if cmd == nil || cmd.Name != "SHUTDOWN" {
+					return Issue{}, false
+				}
+				message := "检测到 SHUTDOWN，实例将停止服务"
+				if cmd.hasOption("NOSAVE") {
+					message += "，NOSAVE 会丢弃上次持久化之后的全部写入"
+				}
+				return ctx.Source.locate(Issue{
+					Message:    message,
+					Suggestion: "停机应通过运维流程执行并提前切换流量；高可用部署请使用 FAILOVER 或哨兵 / 集群主从切换",
+				}, cmd.NameSpan), true
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "redis_unbounded_collection_read", Level: LevelWarning, Category: "阻塞风险", Description: "SMEMBERS / HGETALL 等一次性读取整个集合"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				cmd := stmt.Redis
+				if cmd == nil {
+					return Issue{}, false
+				}
+				alternative, found := redisUnboundedReadCommands[cmd.Name]
+				if !found || ((cmd.Name == "LRANGE" || cmd.Name == "ZRANGE") && (cmd.arg(1) != "0" || cmd.arg(2) != "-1")) {
+					return Issue{}, false
+				}
+				return ctx.Source.locate(Issue{
+					Message:    fmt.Sprintf("%s %s 一次返回整个集合，大 key 上会长时间阻塞主线程并占用大量网络带宽", cmd.Name, cmd.arg(0)),
+					Suggestion: fmt.Sprintf("先用 MEMORY USAGE / SCARD / HLEN 确认大小，改用 %s 分批读取", alternative),
+				}, cmd.NameSpan), true
+			},
+		),
+		redisSetWithoutExpireRule(),
+		engineDetectionAmbiguousRule(),
+		invalidSuppressionRule(),
+	}
+}
+
+func AnalyzeRedisWithOptions(content string, options AnalyzeOptions) CheckResponse {
+	return analyzeWithRules(newRedisRuleContext(content), options, "请输入待审核 Redis 命令后重试")
+}
+
+func redisSetWithoutExpireRule() Rule {
+	return newScriptRule(
+		RuleDefinition{
+			Code: "redis_set_without_expire", Level: LevelWarning, Category: "内存治理", Description: "批量写入中 SET 未设置过期时间",
+			Params: []RuleParam{{Name: "bulk_min_writes", Type: RuleParamInt, Default: 20, Min: intPtr(1), Description: "写命令数达到该值时视为批量导入"}},
+		},
+		func(ctx *RuleContext) []Issue {
+			writes := 0
+			expiring := make(map[string]struct{})
+			for _, stmt := range ctx.Statements {
+				if stmt.Redis == nil {
+					continue
+				}
+				if _, found := redisBulkWriteCommands[stmt.Redis.Name]; found {
+					writes++
+				}
+				if _, found := redisExpireCommands[stmt.Redis.Name]; found {
+					expiring[stmt.Redis.arg(0)] = struct{}{}
+				}
+			}
+			if writes < ctx.intParam("bulk_min_writes") {
+				return nil
+			}
+
+			missing := make([]RuleStatement, 0)
+			for _, stmt := range ctx.Statements {
+				if stmt.Redis != nil && stmt.Redis.setsKeyWithoutExpire(expiring) {
+					missing = append(missing, stmt)
+				}
+			}
+			if len(missing) == 0 {
+				return nil
+			}
+			first := missing[0]
+			return []Issue{ctx.Source.location(first.Span).apply(Issue{
+				StatementIndex: first.Index,
+				Statement:      first.Text,
+				Message:        fmt.Sprintf("批量写入脚本（%d 条写命令）中有 %d 条 SET 未设置过期时间，导入的键将永久占用内存", writes, len(missing)),
+				Suggestion:     "缓存类数据请使用 SET key value EX <秒> 或 SETEX，MSET 写入后补充 EXPIRE；确为持久数据时可抑制本规则",
+			})}
+		},
+	)
+}
+
+func (cmd *redisCommand) setsKeyWithoutExpire(expiring map[string]struct{}) bool {
+	keys := make([]string, 0)
+	switch cmd.Name {
+	case "SET":
+		if len(cmd.Args) < 2 {
+			return false
+		}
+		for _, arg := range cmd.Args[2:] {
+			switch strings.ToUpper(arg.Text) {
+			case "EX", "PX", "EXAT", "PXAT", "KEEPTTL":
+				return false
+			}
+		}
+		keys = append(keys, cmd.arg(0))
+	case "SETNX":
+		keys = append(keys, cmd.arg(0))
+	case "MSET", "MSETNX":
+		for i := 0; i < len(cmd.Args); i += 2 {
+			keys = append(keys, cmd.Args[i].Text)
+		}
+	}
+	for _, key := range keys {
+		if _, found := expiring[key]; !found {
+			return true
+		}
+	}
+	return false
+}
+
+func redisHasCommandAfter(ctx *RuleContext, index int, name, subcommand string) bool {
+	for _, stmt := range ctx.Statements {
+		if stmt.Index > index && stmt.Redis != nil && stmt.Redis.Name == name && stmt.Redis.subcommand() == subcommand {
+			return true
+		}
+	}
+	return false
+}
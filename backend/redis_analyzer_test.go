@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParseRedisScriptSplitsLinesAndQuotes(t *testing.T) {
+	script := strings.Join([]string{
+		"# warm up cache",
+		"SET \"user:1\" 'O\\'Brien' EX 60",
+		"EVAL \"local k = KEYS[1]",
+		"return redis.call('del', k)\" 1 a\\x41",
+		"  ",
+		"HGETALL profile:1",
+	}, "\n")
+	commands, comments := parseRedisScript(script)
+	if len(commands) != 3 || len(comments) != 1 {
+		t.Fatalf("expected 3 commands and 1 comment, got %d / %d: %+v", len(commands), len(comments), commands)
+	}
+	if commands[0].Name != "SET" || commands[0].arg(0) != "user:1" || commands[0].arg(1) != "O'Brien" {
+		t.Fatalf("unexpected SET parse: %+v", commands[0])
+	}
+	if commands[1].Name != "EVAL" || !strings.Contains(commands[1].arg(0), "\nreturn") || commands[1].arg(2) != "a\\x41" {
+		t.Fatalf("quoted EVAL script should span lines: %+v", commands[1])
+	}
+
+	unterminated, _ := parseRedisScript("SET a \"open\nGET a")
+	if len(unterminated) != 1 || !unterminated[0].Unterminated {
+		t.Fatalf("expected one unterminated command, got %+v", unterminated)
+	}
+}
+
+func TestAnalyzeRedisRules(t *testing.T) {
+	script := strings.Join([]string{
+		"FLUSHDB",
+		"KEYS *",
+		"DEL session:*",
+		"EVAL \"for _, k in ipairs(redis.call('KEYS', ARGV[1])) do redis.call('DEL', k) end\" 0 tmp:*",
+		"CONFIG SET maxmemory 4gb",
+		"DEBUG SLEEP 10",
+		"SHUTDOWN NOSAVE",
+		"SMEMBERS online_users",
+		"LRANGE queue 0 -1",
+		"LRANGE queue 0 99",
+		"CONFIG REWRITE",
+		"CONFIG SET timeout 300",
+	}, "\n")
+
+	issues := AnalyzeByEngine(EngineRedis, script, AnalyzeOptions{}).Issues
+	rules := issuesByStatement(issues)
+	messages := messagesByStatement(issues)
+	rules.expect(t, 1, "redis_flush", true)
+	rules.expect(t, 2, "redis_keys_command", true)
+	rules.expect(t, 3, "redis_del_wildcard", true)
+	rules.expect(t, 4, "redis_script_wildcard_delete", true)
+	rules.expect(t, 5, "redis_config_set", true)
+	if strings.Contains(messages[5], "CONFIG REWRITE") {
+		t.Fatalf("CONFIG SET followed by REWRITE should not mention it: %s", messages[5])
+	}
+	rules.expect(t, 6, "redis_debug", true)
+	rules.expect(t, 7, "redis_shutdown", true)
+	rules.expect(t, 8, "redis_unbounded_collection_read", true)
+	rules.expect(t, 9, "redis_unbounded_collection_read", true)
+	rules.expect(t, 10, "redis_unbounded_collection_read", false)
+	rules.expect(t, 12, "redis_config_set", true)
+	if !strings.Contains(messages[12], "CONFIG REWRITE") {
+		t.Fatalf("CONFIG SET without a later REWRITE should warn about restarts: %s", messages[12])
+	}
+}
+
+func TestRedisSetWithoutExpireInBulkLoads(t *testing.T) {
+	lines := make([]string, 0)
+	for i := 0; i < 20; i++ {
+		lines = append(lines, fmt.Sprintf("SET cache:%d v EX 60", i))
+	}
+	bulk := append(lines, "SET cache:permanent v", "MSET a 1 b 2", "EXPIRE a 60", "EXPIRE b 60")
+	issues := AnalyzeByEngine(EngineRedis, strings.Join(bulk, "\n"), AnalyzeOptions{}).Issues
+	if issueRules(issues)["redis_set_without_expire"] != 1 {
+		t.Fatalf("expected one redis_set_without_expire issue, got %+v", issues)
+	}
+	for _, issue := range issues {
+		if issue.Rule == "redis_set_without_expire" && (issue.StatementIndex != 21 || !strings.Contains(issue.Message, "1 条 SET")) {
+			t.Fatalf("unexpected issue: %+v", issue)
+		}
+	}
+
+	small := AnalyzeByEngine(EngineRedis, "SET a 1\nSET b 2", AnalyzeOptions{}).Issues
+	if issueRules(small)["redis_set_without_expire"] != 0 {
+		t.Fatalf("small scripts are not bulk loads: %+v", small)
+	}
+}
+
+func TestAnalyzeRedisSuppressionAndDetection(t *testing.T) {
+	result := AnalyzeByEngine(EngineRedis, "# sql-review:disable-next-line redis_flush reason=测试环境重置\nFLUSHALL\n", AnalyzeOptions{})
+	if len(result.Issues) != 0 || len(result.Suppressed) != 1 {
+		t.Fatalf("expected FLUSHALL to be suppressed, got %+v / %+v", result.Issues, result.Suppressed)
+	}
+
+	detection := DetectEngine("HSET user:1 name alice\nEXPIRE user:1 3600\n", "")
+	if detection.Engine != EngineRedis || detection.Ambiguous {
+		t.Fatalf("expected redis detection, got %+v", detection)
+	}
+	if byExtension := DetectEngine("GET a", "seed.redis"); byExtension.Engine != EngineRedis {
+		t.Fatalf("expected .redis extension to detect redis, got %+v", byExtension)
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+type redisArg struct {
+	Text   string
+	Quoted bool
+	Span   sourceSpan
+}
+
+type redisCommand struct {
+	Name         string
+	NameSpan     sourceSpan
+	Args         []redisArg
+	Span         sourceSpan
+	Unterminated bool
+}
+
+func parseRedisScript(content string) ([]redisCommand, []sourceSpan) {
+	commands := make([]redisCommand, 0)
+	comments := make([]sourceSpan, 0)
+	words := make([]redisArg, 0)
+	unterminated := false
+
+	flush := func() {
+		if len(words) > 0 {
+			commands = append(commands, redisCommand{
+				Name:         strings.ToUpper(words[0].Text),
+				NameSpan:     words[0].Span,
+				Args:         words[1:],
+				Span:         sourceSpan{Start: words[0].Span.Start, End: words[len(words)-1].Span.End},
+				Unterminated: unterminated,
+			})
+		}
+		words = make([]redisArg, 0)
+		unterminated = false
+	}
+
+	for i := 0; i < len(content); {
+		switch ch := content[i]; {
+		case ch == '\n':
+			flush()
+			i++
+		case ch == ' ' || ch == '\t' || ch == '\r':
+			i++
+		case ch == '#' && len(words) == 0:
+			end := strings.IndexByte(content[i:], '\n')
+			if end < 0 {
+				end = len(content)
+			} else {
+				end += i
+			}
+			comments = append(comments, sourceSpan{Start: i, End: end})
+			i = end
+		default:
+			arg, closed := scanRedisArg(content, i)
+			words = append(words, arg)
+			unterminated = unterminated || !closed
+			i = arg.Span.End
+		}
+	}
+	flush()
+	return commands, comments
+}
+
+func scanRedisArg(content string, start int) (redisArg, bool) {
+	quote := content[start]
+	if quote != '"' && quote != '\'' {
+		end := start
+		for end < len(content) && !strings.ContainsRune(" \t\r\n", rune(content[end])) {
+			end++
+		}
+		return redisArg{Text: content[start:end], Span: sourceSpan{Start: start, End: end}}, true
+	}
+
+	var builder strings.Builder
+	for i := start + 1; i < len(content); i++ {
+		ch := content[i]
+		switch {
+		case ch == quote:
+			return redisArg{Text: builder.String(), Quoted: true, Span: sourceSpan{Start: start, End: i + 1}}, true
+		case ch == '\\' && i+1 < len(content) && quote == '\'':
+			if content[i+1] == '\'' {
+				i++
+				ch = '\''
+			}
+			builder.WriteByte(ch)
+		case ch == '\\' && i+1 < len(content):
+			i++
+			switch content[i] {
+			case 'n':
+				builder.WriteByte('\n')
+			case 'r':
+				builder.WriteByte('\r')
+			case 't':
+				builder.WriteByte('\t')
+			case 'x':
+				if i+2 < len(content) {
+					if value, err := strconv.ParseUint(content[i+1:i+3], 16, 8); err == nil {
+						builder.WriteByte(byte(value))
+						i += 2
+						continue
+					}
+				}
+				builder.WriteByte('x')
+			default:
+				builder.WriteByte(content[i])
+			}
+		default:
+			builder.WriteByte(ch)
+		}
+	}
+	return redisArg{Text: builder.String(), Quoted: true, Span: sourceSpan{Start: start, End: len(content)}}, false
+}
+
+func (cmd *redisCommand) arg(index int) string {
+	if index < 0 || index >= len(cmd.Args) {
+		return ""
+	}
+	return cmd.Args[index].Text
+}
+
+func (cmd *redisCommand) subcommand() string {
+	return strings.ToUpper(cmd.arg(0))
+}
+
+func (cmd *redisCommand) hasOption(options ...string) bool {
+	for _, arg := range cmd.Args {
+		for _, option := range options {
+			if strings.EqualFold(arg.Text, option) {
+				return true
+			}
+		}
+	}
+	return false
+}
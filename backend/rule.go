@@ -0,0 +1,482 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type RuleScope string
+
+const (
+	RuleScopeScript    RuleScope = "script"
+	RuleScopeStatement RuleScope = "statement"
+)
+
+type RuleParamType string
+
+const (
+	RuleParamInt    RuleParamType = "int"
+	RuleParamString RuleParamType = "string"
+	RuleParamBool   RuleParamType = "bool"
+)
+
+type RuleParam struct {
+	Name        string        `json:"name"`
+	Type        RuleParamType `json:"type"`
+	Default     any           `json:"default"`
+	Min         *int          `json:"min,omitempty"`
+	Max         *int          `json:"max,omitempty"`
+	Description string        `json:"description"`
+}
+
+type Rule interface {
+	Definition() RuleDefinition
+	Check(ctx *RuleContext) []Issue
+}
+
+type RuleContext struct {
+	Engine          DBEngine
+	Content         string
+	Source          *sourceIndex
+	Statements      []RuleStatement
+	ContainsRoutine bool
+	Directives      []suppressionDirective
+
+	rule                RuleDefinition
+	options             AnalyzeOptions
+	invalidSuppressions []Issue
+}
+
+type RuleStatement struct {
+	Index     int
+	Text      string
+	Span      sourceSpan
+	SQL       *sqlStatement
+	Mongo     *mongoOperation
+	Redis     *redisCommand
+	Catalog   *schemaCatalog
+	Migration *pgMigrationStep
+}
+
+func newSQLRuleContext(engine DBEngine, content string, dialect sqlDialect) *RuleContext {
+	return newSQLRuleContextFromSpans(engine, content, splitSQLStatementSpans(content), dialect)
+}
+
+func newSQLRuleContextFromSpans(engine DBEngine, content string, spans []sqlStatementSpan, dialect sqlDialect) *RuleContext {
+	statements := make([]RuleStatement, 0, len(spans))
+	for i, span := range spans {
+		statements = append(statements, RuleStatement{
+			Index: i + 1,
+			Text:  strings.TrimSpace(span.Text),
+			Span:  span.Span,
+			SQL:   parseSQLStatementAt(content, span.Span, dialect),
+		})
+	}
+	return &RuleContext{
+		Engine:     engine,
+		Content:    content,
+		Source:     newSourceIndex(content),
+		Statements: statements,
+		Directives: parseSuppressionDirectives(content, sqlCommentSpans(content, dialect)),
+	}
+}
+
+func newMongoRuleContext(content string) *RuleContext {
+	ops := parseMongoOperations(content)
+	statements := make([]RuleStatement, 0, len(ops))
+	for i := range ops {
+		statements = append(statements, RuleStatement{
+			Index: i + 1,
+			Text:  strings.TrimSpace(ops[i].Text),
+			Span:  ops[i].Span,
+			Mongo: &ops[i],
+		})
+	}
+	return &RuleContext{
+		Engine:     EngineMongoDB,
+		Content:    content,
+		Source:     newSourceIndex(content),
+		Statements: statements,
+		Directives: parseSuppressionDirectives(content, mongoCommentSpans(content)),
+	}
+}
+
+func newRedisRuleContext(content string) *RuleContext {
+	commands, comments := parseRedisScript(content)
+	statements := make([]RuleStatement, 0, len(commands))
+	for i := range commands {
+		statements = append(statements, RuleStatement{
+			Index: i + 1,
+			Text:  strings.TrimSpace(content[commands[i].Span.Start:commands[i].Span.End]),
+			Span:  commands[i].Span,
+			Redis: &commands[i],
+		})
+	}
+	return &RuleContext{
+		Engine:     EngineRedis,
+		Content:    content,
+		Source:     newSourceIndex(content),
+		Statements: statements,
+		Directives: parseSuppressionDirectives(content, comments),
+	}
+}
+
+func (ctx *RuleContext) isEmpty() bool {
+	return strings.TrimSpace(ctx.Content) == ""
+}
+
+func (ctx *RuleContext) param(name string) any {
+	if value, found := ctx.options.RuleParams[ctx.rule.Code][name]; found {
+		return value
+	}
+	for _, param := range ctx.rule.Params {
+		if param.Name == name {
+			return param.Default
+		}
+	}
+	return nil
+}
+
+func (ctx *RuleContext) intParam(name string) int {
+	switch value := ctx.param(name).(type) {
+	case int:
+		return value
+	case int64:
+		return int(value)
+	case float64:
+		return int(value)
+	}
+	return 0
+}
+
+func (ctx *RuleContext) stringParam(name string) string {
+	value, _ := ctx.param(name).(string)
+	return value
+}
+
+func (ctx *RuleContext) statementSpans() []sqlStatementSpan {
+	spans := make([]sqlStatementSpan, 0, len(ctx.Statements))
+	for _, stmt := range ctx.Statements {
+		spans = append(spans, sqlStatementSpan{Text: stmt.Text, Span: stmt.Span, Batch: stmt.SQL != nil && stmt.SQL.isCQLBatch()})
+	}
+	return spans
+}
+
+type scriptRule struct {
+	definition RuleDefinition
+	check      func(ctx *RuleContext) []Issue
+}
+
+func newScriptRule(definition RuleDefinition, check func(ctx *RuleContext) []Issue) Rule {
+	definition.Scope = RuleScopeScript
+	return scriptRule{definition: definition, check: check}
+}
+
+func (rule scriptRule) Definition() RuleDefinition {
+	return rule.definition
+}
+
+func (rule scriptRule) Check(ctx *RuleContext) []Issue {
+	return rule.check(ctx)
+}
+
+type statementRule struct {
+	definition RuleDefinition
+	check      func(ctx *RuleContext, stmt RuleStatement) (Issue, bool)
+}
+
+func newStatementRule(definition RuleDefinition, check func(ctx *RuleContext, stmt RuleStatement) (Issue, bool)) Rule {
+	definition.Scope = RuleScopeStatement
+	return statementRule{definition: definition, check: check}
+}
+
+func (rule statementRule) Definition() RuleDefinition {
+	return rule.definition
+}
+
+func (rule statementRule) Check(ctx *RuleContext) []Issue {
+	issues := make([]Issue, 0)
+	for _, stmt := range ctx.Statements {
+		if stmt.Text == "" {
+			continue
+		}
+		issue, found := rule.check(ctx, stmt)
+		if !found {
+			continue
+		}
+		issue.StatementIndex = stmt.Index
+		issue.Statement = stmt.Text
+		if issue.StartLine == 0 {
+			issue = ctx.Source.locate(issue, stmt.Span)
+		}
+		issues = append(issues, issue)
+	}
+	return issues
+}
+
+type engineRuleSet struct {
+	Version string
+	Rules   []Rule
+}
+
+type ruleRegistry struct {
+	engines     map[DBEngine]*engineRuleSet
+	definitions []engineDefinition
+	aliases     map[string]DBEngine
+}
+
+var (
+	defaultRuleRegistryOnce  sync.Once
+	defaultRuleRegistryValue *ruleRegistry
+)
+
+func defaultRuleRegistry() *ruleRegistry {
+	defaultRuleRegistryOnce.Do(func() {
+		defaultRuleRegistryValue = newDefaultRuleRegistry()
+	})
+	return defaultRuleRegistryValue
+}
+
+func newDefaultRuleRegistry() *ruleRegistry {
+	registry := &ruleRegistry{engines: make(map[DBEngine]*engineRuleSet), aliases: make(map[string]DBEngine)}
+	for _, definition := range engineDefinitions() {
+		registry.registerEngine(definition)
+	}
+	return registry
+}
+
+func (registry *ruleRegistry) registerEngine(definition engineDefinition) {
+	for _, name := range append([]string{string(definition.Engine)}, definition.Aliases...) {
+		if existing, found := registry.aliases[name]; found {
+			panic(fmt.Sprintf("engine alias %s already registered for engine %s", name, existing))
+		}
+		registry.aliases[name] = definition.Engine
+	}
+	registry.definitions = append(registry.definitions, definition)
+	registry.register(definition.Engine, definition.Version, definition.Rules()...)
+}
+
+func (registry *ruleRegistry) lookupEngine(raw string) (engineDefinition, bool) {
+	engine, found := registry.aliases[strings.ToLower(strings.TrimSpace(raw))]
+	if !found {
+		return engineDefinition{}, false
+	}
+	for _, definition := range registry.definitions {
+		if definition.Engine == engine {
+			return definition, true
+		}
+	}
+	return engineDefinition{}, false
+}
+
+func (registry *ruleRegistry) register(engine DBEngine, version string, rules ...Rule) {
+	set, found := registry.engines[engine]
+	if !found {
+		set = &engineRuleSet{}
+		registry.engines[engine] = set
+	}
+	if version != "" {
+		set.Version = version
+	}
+	for _, rule := range rules {
+		code := rule.Definition().Code
+		for _, existing := range set.Rules {
+			if existing.Definition().Code == code {
+				panic(fmt.Sprintf("duplicate rule %s for engine %s", code, engine))
+			}
+		}
+		set.Rules = append(set.Rules, rule)
+	}
+}
+
+func (registry *ruleRegistry) ruleSet(engine DBEngine) engineRuleSet {
+	if set, found := registry.engines[engine]; found {
+		return *set
+	}
+	return engineRuleSet{}
+}
+
+func (registry *ruleRegistry) alwaysEnabledCodes() map[string]struct{} {
+	codes := make(map[string]struct{})
+	for _, set := range registry.engines {
+		for _, rule := range set.Rules {
+			if definition := rule.Definition(); definition.AlwaysEnabled {
+				codes[definition.Code] = struct{}{}
+			}
+		}
+	}
+	return codes
+}
+
+func RegisteredRules(engine DBEngine) []Rule {
+	return defaultRuleRegistry().ruleSet(registeredEngine(engine).Engine).Rules
+}
+
+func ruleDefinitions(rules []Rule) []RuleDefinition {
+	definitions := make([]RuleDefinition, 0, len(rules))
+	for _, rule := range rules {
+		definitions = append(definitions, rule.Definition())
+	}
+	return definitions
+}
+
+func runRules(ctx *RuleContext, rules []Rule, options AnalyzeOptions) []Issue {
+	ctx.options = options
+	issues := make([]Issue, 0)
+	for _, rule := range rules {
+		definition := rule.Definition()
+		if _, disabled := options.DisabledRules[definition.Code]; disabled {
+			continue
+		}
+		level := definition.Level
+		if override, found := options.SeverityOverrides[definition.Code]; found {
+			level = override
+		}
+		if level == LevelOff {
+			continue
+		}
+
+		ctx.rule = definition
+		for _, issue := range rule.Check(ctx) {
+			issue.Rule = definition.Code
+			issue.Level = level
+			issues = append(issues, issue)
+		}
+	}
+	ctx.rule = RuleDefinition{}
+	sortIssues(issues)
+	return issues
+}
+
+func analyzeWithRules(ctx *RuleContext, options AnalyzeOptions, emptyAdvice string) CheckResponse {
+	set := defaultRuleRegistry().ruleSet(ctx.Engine)
+	ranges, invalid := buildSuppressionRanges(ctx, set.Rules)
+	ctx.invalidSuppressions = invalid
+	if options.Catalog != nil {
+		ctx.replayCatalog(options.Catalog)
+	}
+	issues, suppressed := applySuppressions(ctx, ranges, runRules(ctx, set.Rules, options))
+	var diff *DiffScope
+	if options.Changes != nil {
+		issues, suppressed, diff = ctx.limitToChanges(set.Rules, options.Changes, issues, suppressed)
+	}
+	summary := summarizeIssues(len(ctx.Statements), issues)
+	summary.SuppressedCount = len(suppressed)
+
+	advice := []string{emptyAdvice}
+	if !ctx.isEmpty() {
+		advice = buildAdvice(summary)
+	}
+
+	return CheckResponse{
+		RulesVersion: set.Version,
+		CheckedAt:    time.Now().Format(time.RFC3339),
+		Summary:      summary,
+		Issues:       issues,
+		Suppressed:   suppressed,
+		Diff:         diff,
+		Advice:       advice,
+	}
+}
+
+func sortIssues(issues []Issue) {
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].StatementIndex == issues[j].StatementIndex {
+			return severityWeight(issues[i].Level) > severityWeight(issues[j].Level)
+		}
+		return issues[i].StatementIndex < issues[j].StatementIndex
+	})
+}
+
+func emptyInputRule(message, suggestion string) Rule {
+	return newScriptRule(
+		RuleDefinition{Code: "empty_input", Level: LevelError, Category: "输入校验", Description: "输入为空", AlwaysEnabled: true},
+		func(ctx *RuleContext) []Issue {
+			if !ctx.isEmpty() {
+				return nil
+			}
+			return []Issue{{Message: message, Suggestion: suggestion}}
+		},
+	)
+}
+
+func tooManyStatementsRule(limit int, suggestion string) Rule {
+	return newScriptRule(
+		RuleDefinition{
+			Code: "too_many_statements", Level: LevelWarning, Category: "变更规模", Description: "语句数过多，建议拆批执行",
+			Params: []RuleParam{{Name: "max_statements", Type: RuleParamInt, Default: limit, Min: intPtr(1), Description: "脚本语句数超过该值时告警"}},
+		},
+		func(ctx *RuleContext) []Issue {
+			if len(ctx.Statements) <= ctx.intParam("max_statements") {
+				return nil
+			}
+			return []Issue{{Message: fmt.Sprintf("SQL 语句数量较多（%d 条）", len(ctx.Statements)), Suggestion: suggestion}}
+		},
+	)
+}
+
+func sqlFullwidthTerminatorRule() Rule {
+	return newScriptRule(
+		RuleDefinition{Code: "fullwidth_statement_terminator", Level: LevelError, Category: "脚本语法", Description: "检测到中文结束符（；）", AlwaysEnabled: true},
+		func(ctx *RuleContext) []Issue {
+			items := detectFullwidthTerminatorStatements(ctx.Content, ctx.ContainsRoutine)
+			if len(items) == 0 {
+				return nil
+			}
+			return []Issue{items[0].Location.apply(Issue{
+				StatementIndex: items[0].Index,
+				Message:        buildFullwidthTerminatorIssueMessage(items),
+				Suggestion:     "请将中文结束符（；）替换为英文半角分号（;），避免解析歧义",
+				Statement:      buildMissingTerminatorStatementSnippet(items),
+			})}
+		},
+	)
+}
+
+func sqlMissingTerminatorRule() Rule {
+	return newScriptRule(
+		RuleDefinition{Code: "missing_statement_terminator", Level: LevelError, Category: "脚本语法", Description: "多条 SQL 场景疑似缺少结束符", AlwaysEnabled: true},
+		func(ctx *RuleContext) []Issue {
+			items := detectMissingTerminatorStatements(ctx.Content, ctx.statementSpans(), ctx.ContainsRoutine)
+			items = excludeMissingTerminatorStatements(items, detectFullwidthTerminatorStatements(ctx.Content, ctx.ContainsRoutine))
+			if len(items) == 0 {
+				return nil
+			}
+			return []Issue{items[0].Location.apply(Issue{
+				StatementIndex: items[0].Index,
+				Message:        buildMissingTerminatorIssueMessage(items),
+				Suggestion:     "建议为每条语句补齐结束符，避免自动审查/执行阶段误拆分",
+				Statement:      buildMissingTerminatorStatementSnippet(items),
+			})}
+		},
+	)
+}
+
+func riskyWritesWithoutTransactionRule(suggestion string) Rule {
+	return newScriptRule(
+		RuleDefinition{Code: "risky_writes_without_transaction", Level: LevelWarning, Category: "事务一致性", Description: "多条写语句未显式事务包裹"},
+		func(ctx *RuleContext) []Issue {
+			containsRiskWrite, hasBegin, hasCommit := false, false, false
+			for _, stmt := range ctx.Statements {
+				if stmt.SQL == nil {
+					continue
+				}
+				switch {
+				case stmt.SQL.isWrite():
+					containsRiskWrite = true
+				case stmt.SQL.Kind == sqlStmtBegin:
+					hasBegin = true
+				case stmt.SQL.Kind == sqlStmtCommit:
+					hasCommit = true
+				}
+			}
+			if !containsRiskWrite || len(ctx.Statements) <= 1 || (hasBegin && hasCommit) {
+				return nil
+			}
+			return []Issue{{Message: "检测到多条写语句但未发现完整事务边界", Suggestion: suggestion}}
+		},
+	)
+}
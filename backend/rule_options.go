@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+type RuleOverrides struct {
+	SeverityOverrides map[string]IssueLevel     `json:"severityOverrides,omitempty"`
+	RuleParams        map[string]map[string]any `json:"ruleParams,omitempty"`
+}
+
+func intPtr(value int) *int {
+	return &value
+}
+
+func (overrides RuleOverrides) isEmpty() bool {
+	return len(overrides.SeverityOverrides) == 0 && len(overrides.RuleParams) == 0
+}
+
+func (overrides RuleOverrides) validate(engine DBEngine) (RuleOverrides, error) {
+	_, definitions := RulesForEngine(engine)
+	known := make(map[string]RuleDefinition, len(definitions))
+	for _, definition := range definitions {
+		known[definition.Code] = definition
+	}
+
+	normalized := RuleOverrides{}
+	for _, code := range sortedKeys(overrides.SeverityOverrides) {
+		trimmed := strings.TrimSpace(code)
+		definition, found := known[trimmed]
+		if !found {
+			return RuleOverrides{}, fmt.Errorf("unknown rule for engine %s: %s", engine, trimmed)
+		}
+		level := IssueLevel(strings.ToLower(strings.TrimSpace(string(overrides.SeverityOverrides[code]))))
+		switch level {
+		case LevelError, LevelWarning, LevelInfo:
+		case LevelOff:
+			if definition.AlwaysEnabled {
+				return RuleOverrides{}, fmt.Errorf("always-enabled rule cannot be turned off: %s", trimmed)
+			}
+		default:
+			return RuleOverrides{}, fmt.Errorf("invalid severity for rule %s: %s (expected error, warning, info or off)", trimmed, overrides.SeverityOverrides[code])
+		}
+		if normalized.SeverityOverrides == nil {
+			normalized.SeverityOverrides = make(map[string]IssueLevel)
+		}
+		normalized.SeverityOverrides[trimmed] = level
+	}
+
+	for _, code := range sortedKeys(overrides.RuleParams) {
+		trimmed := strings.TrimSpace(code)
+		definition, found := known[trimmed]
+		if !found {
+			return RuleOverrides{}, fmt.Errorf("unknown rule for engine %s: %s", engine, trimmed)
+		}
+		for _, name := range sortedKeys(overrides.RuleParams[code]) {
+			param, found := findRuleParam(definition, name)
+			if !found {
+				return RuleOverrides{}, fmt.Errorf("unknown parameter for rule %s: %s", trimmed, name)
+			}
+			value, err := param.coerce(overrides.RuleParams[code][name])
+			if err != nil {
+				return RuleOverrides{}, fmt.Errorf("invalid parameter %s.%s: %w", trimmed, name, err)
+			}
+			if normalized.RuleParams == nil {
+				normalized.RuleParams = make(map[string]map[string]any)
+			}
+			if normalized.RuleParams[trimmed] == nil {
+				normalized.RuleParams[trimmed] = make(map[string]any)
+			}
+			normalized.RuleParams[trimmed][name] = value
+		}
+	}
+	return normalized, nil
+}
+
+func (overrides RuleOverrides) merge(other RuleOverrides) RuleOverrides {
+	merged := RuleOverrides{}
+	for _, source := range []RuleOverrides{overrides, other} {
+		for code, level := range source.SeverityOverrides {
+			if merged.SeverityOverrides == nil {
+				merged.SeverityOverrides = make(map[string]IssueLevel)
+			}
+			merged.SeverityOverrides[code] = level
+		}
+		for code, params := range source.RuleParams {
+			if merged.RuleParams == nil {
+				merged.RuleParams = make(map[string]map[string]any)
+			}
+			if merged.RuleParams[code] == nil {
+				merged.RuleParams[code] = make(map[string]any)
+			}
+			for name, value := range params {
+				merged.RuleParams[code][name] = value
+			}
+		}
+	}
+	return merged
+}
+
+func (overrides RuleOverrides) apply(options AnalyzeOptions) AnalyzeOptions {
+	options.SeverityOverrides = overrides.SeverityOverrides
+	options.RuleParams = overrides.RuleParams
+	return options
+}
+
+func findRuleParam(definition RuleDefinition, name string) (RuleParam, bool) {
+	for _, param := range definition.Params {
+		if param.Name == name {
+			return param, true
+		}
+	}
+	return RuleParam{}, false
+}
+
+func (param RuleParam) coerce(value any) (any, error) {
+	switch param.Type {
+	case RuleParamInt:
+		var number int
+		switch typed := value.(type) {
+		case int:
+			number = typed
+		case float64:
+			if typed != math.Trunc(typed) || math.Abs(typed) > math.MaxInt32 {
+				return nil, fmt.Errorf("expected integer, got %v", typed)
+			}
+			number = int(typed)
+		default:
+			return nil, fmt.Errorf("expected integer, got %T", value)
+		}
+		if param.Min != nil && number < *param.Min {
+			return nil, fmt.Errorf("must be >= %d", *param.Min)
+		}
+		if param.Max != nil && number > *param.Max {
+			return nil, fmt.Errorf("must be <= %d", *param.Max)
+		}
+		return number, nil
+	case RuleParamBool:
+		flag, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("expected boolean, got %T", value)
+		}
+		return flag, nil
+	case RuleParamString:
+		text, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected string, got %T", value)
+		}
+		return text, nil
+	default:
+		return nil, fmt.Errorf("unsupported parameter type %s", param.Type)
+	}
+}
+
+func sortedKeys[V any](items map[string]V) []string {
+	keys := make([]string, 0, len(items))
+	for key := range items {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,159 @@
+package main
+
+import "testing"
+
+func TestRegisteredRulesMatchCatalog(t *testing.T) {
+	scripts := map[DBEngine]string{
+		EngineMySQL: `UPDATE users SET status = 1;
+DELETE FROM orders;
+SELECT * FROM users WHERE name LIKE '%tom' ORDER BY RAND();
+DROP TABLE logs；`,
+		EnginePostgreSQL: `CREATE INDEX idx_users_name ON users(name);
+SELECT * FROM users WHERE name ILIKE '%tom';
+TRUNCATE TABLE logs`,
+		EngineMongoDB: `db.users.updateMany({}, {$set: {a: 1}})
+db.users.find({$where: "this.a > 1"})`,
+	}
+
+	for engine, script := range scripts {
+		_, definitions := RulesForEngine(engine)
+		catalog := make(map[string]RuleDefinition, len(definitions))
+		for _, definition := range definitions {
+			if definition.Scope != RuleScopeScript && definition.Scope != RuleScopeStatement {
+				t.Fatalf("%s rule %s has no scope", engine, definition.Code)
+			}
+			catalog[definition.Code] = definition
+		}
+
+		result := AnalyzeByEngine(engine, script, AnalyzeOptions{})
+		if len(result.Issues) == 0 {
+			t.Fatalf("%s: expected issues", engine)
+		}
+		for _, issue := range result.Issues {
+			definition, found := catalog[issue.Rule]
+			if !found {
+				t.Fatalf("%s: issue rule %s is missing from the catalog", engine, issue.Rule)
+			}
+			if issue.Level != definition.Level {
+				t.Fatalf("%s: issue %s level %s differs from catalog level %s", engine, issue.Rule, issue.Level, definition.Level)
+			}
+		}
+	}
+}
+
+func TestRuleRegistryRejectsDuplicateCodes(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected duplicate registration to panic")
+		}
+	}()
+	registry := &ruleRegistry{engines: make(map[DBEngine]*engineRuleSet)}
+	registry.register(EngineMySQL, rulesVersion, emptyInputRule("a", "b"), emptyInputRule("c", "d"))
+}
+
+func TestAnalyzePostgresUsesParsedStatements(t *testing.T) {
+	script := `UPDATE users SET remark = ' WHERE id = 1 ';
+CREATE INDEX CONCURRENTLY idx_users_email ON users(email);
+SELECT id FROM (SELECT * FROM users) u LIMIT 10;`
+
+	result := AnalyzeByEngine(EnginePostgreSQL, script, AnalyzeOptions{})
+	if !hasRule(result.Issues, "pg_update_without_where") {
+		t.Fatalf("WHERE inside a string literal should not satisfy pg_update_without_where, got: %+v", result.Issues)
+	}
+	if hasRule(result.Issues, "pg_create_index_without_concurrently") {
+		t.Fatalf("CREATE INDEX CONCURRENTLY should not be reported, got: %+v", result.Issues)
+	}
+	if !hasRule(result.Issues, "pg_select_star") {
+		t.Fatalf("expected pg_select_star from subquery, got: %+v", result.Issues)
+	}
+	if hasRule(result.Issues, "pg_select_without_limit") {
+		t.Fatalf("outer LIMIT should satisfy pg_select_without_limit, got: %+v", result.Issues)
+	}
+}
+
+func TestAlwaysEnabledRulesComeFromRegistry(t *testing.T) {
+	for _, code := range []string{"empty_input", "missing_statement_terminator", "mongo_missing_statement_terminator", "fullwidth_statement_terminator"} {
+		if _, found := alwaysEnabledRules[code]; !found {
+			t.Fatalf("expected %s to be always enabled", code)
+		}
+	}
+	if _, found := alwaysEnabledRules["select_star"]; found {
+		t.Fatalf("select_star should be configurable")
+	}
+}
+
+func TestSeverityOverridesAndRuleParams(t *testing.T) {
+	script := "SELECT * FROM users;\nSELECT id FROM orders;\nSELECT id FROM logs;"
+
+	result := AnalyzeByEngine(EngineMySQL, script, AnalyzeOptions{
+		SeverityOverrides: map[string]IssueLevel{"select_star": LevelError, "select_without_limit": LevelOff},
+		RuleParams:        map[string]map[string]any{"too_many_statements": {"max_statements": 2}},
+	})
+	if hasRule(result.Issues, "select_without_limit") {
+		t.Fatalf("select_without_limit should be turned off, got: %+v", result.Issues)
+	}
+	for _, issue := range result.Issues {
+		if issue.Rule == "select_star" && issue.Level != LevelError {
+			t.Fatalf("select_star should be upgraded to error, got %s", issue.Level)
+		}
+	}
+	if !hasRule(result.Issues, "too_many_statements") {
+		t.Fatalf("max_statements=2 should flag a 3-statement script, got: %+v", result.Issues)
+	}
+	if result.Summary.ErrorCount != 1 {
+		t.Fatalf("summary should count the overridden level, got %+v", result.Summary)
+	}
+
+	if hasRule(AnalyzeByEngine(EngineMySQL, script, AnalyzeOptions{}).Issues, "too_many_statements") {
+		t.Fatalf("default threshold should not flag 3 statements")
+	}
+}
+
+func TestRuleOverridesValidate(t *testing.T) {
+	normalized, err := RuleOverrides{
+		SeverityOverrides: map[string]IssueLevel{" select_star ": "ERROR"},
+		RuleParams:        map[string]map[string]any{"too_many_statements": {"max_statements": float64(120)}},
+	}.validate(EngineMySQL)
+	if err != nil {
+		t.Fatalf("validate err: %v", err)
+	}
+	if normalized.SeverityOverrides["select_star"] != LevelError || normalized.RuleParams["too_many_statements"]["max_statements"] != 120 {
+		t.Fatalf("unexpected normalized overrides: %+v", normalized)
+	}
+
+	invalid := []RuleOverrides{
+		{SeverityOverrides: map[string]IssueLevel{"pg_select_star": LevelError}},
+		{SeverityOverrides: map[string]IssueLevel{"select_star": "fatal"}},
+		{SeverityOverrides: map[string]IssueLevel{"empty_input": LevelOff}},
+		{RuleParams: map[string]map[string]any{"select_star": {"max": 1}}},
+		{RuleParams: map[string]map[string]any{"too_many_statements": {"max_statements": "60"}}},
+		{RuleParams: map[string]map[string]any{"too_many_statements": {"max_statements": 1.5}}},
+		{RuleParams: map[string]map[string]any{"too_many_statements": {"max_statements": 0}}},
+	}
+	for _, overrides := range invalid {
+		if _, err := overrides.validate(EngineMySQL); err == nil {
+			t.Fatalf("expected validation error for %+v", overrides)
+		}
+	}
+}
+
+func TestTooManyStatementsDefaultsPerEngine(t *testing.T) {
+	defaults := map[DBEngine]int{EngineMySQL: 60, EnginePostgreSQL: 80}
+	for engine, expected := range defaults {
+		_, definitions := RulesForEngine(engine)
+		found := false
+		for _, definition := range definitions {
+			if definition.Code != "too_many_statements" {
+				continue
+			}
+			param, ok := findRuleParam(definition, "max_statements")
+			if !ok || param.Type != RuleParamInt || param.Default != expected {
+				t.Fatalf("%s: unexpected max_statements schema: %+v", engine, param)
+			}
+			found = true
+		}
+		if !found {
+			t.Fatalf("%s: too_many_statements is missing", engine)
+		}
+	}
+}
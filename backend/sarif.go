@@ -0,0 +1,228 @@
+package main
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	sarifVersion     = "2.1.0"
+	sarifSchemaURI   = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifContentType = "application/sarif+json"
+	sarifToolName    = "sql-review"
+)
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool       sarifTool       `json:"tool"`
+	ColumnKind string          `json:"columnKind"`
+	Artifacts  []sarifArtifact `json:"artifacts"`
+	Results    []sarifResult   `json:"results"`
+	Properties map[string]any  `json:"properties,omitempty"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name    string                     `json:"name"`
+	Version string                     `json:"version"`
+	Rules   []sarifReportingDescriptor `json:"rules"`
+}
+
+type sarifReportingDescriptor struct {
+	ID                   string             `json:"id"`
+	ShortDescription     sarifMessage       `json:"shortDescription"`
+	DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+	Properties           map[string]any     `json:"properties,omitempty"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifArtifact struct {
+	Location sarifArtifactLocation `json:"location"`
+}
+
+type sarifArtifactLocation struct {
+	URI   string `json:"uri"`
+	Index int    `json:"index"`
+}
+
+type sarifResult struct {
+	RuleID       string             `json:"ruleId"`
+	RuleIndex    *int               `json:"ruleIndex,omitempty"`
+	Level        string             `json:"level"`
+	Message      sarifMessage       `json:"message"`
+	Locations    []sarifLocation    `json:"locations"`
+	Suppressions []sarifSuppression `json:"suppressions,omitempty"`
+	Properties   map[string]any     `json:"properties,omitempty"`
+}
+
+type sarifSuppression struct {
+	Kind          string `json:"kind"`
+	Justification string `json:"justification,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+	EndLine     int `json:"endLine,omitempty"`
+	EndColumn   int `json:"endColumn,omitempty"`
+}
+
+type sarifInput struct {
+	URI    string
+	Result CheckResponse
+}
+
+func buildSARIFLog(engine DBEngine, inputs []sarifInput) sarifLog {
+	engine = registeredEngine(engine).Engine
+	version, rules := RulesForEngine(engine)
+
+	ruleIndexes := make(map[string]int, len(rules))
+	descriptors := make([]sarifReportingDescriptor, 0, len(rules))
+	for i, rule := range rules {
+		ruleIndexes[rule.Code] = i
+		descriptors = append(descriptors, sarifReportingDescriptor{
+			ID:                   rule.Code,
+			ShortDescription:     sarifMessage{Text: rule.Description},
+			DefaultConfiguration: sarifConfiguration{Level: sarifLevel(rule.Level)},
+			Properties: map[string]any{
+				"category": rule.Category,
+				"tags":     []string{rule.Category},
+			},
+		})
+	}
+
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:    sarifToolName,
+			Version: version,
+			Rules:   descriptors,
+		}},
+		ColumnKind: "unicodeCodePoints",
+		Artifacts:  make([]sarifArtifact, 0, len(inputs)),
+		Results:    make([]sarifResult, 0),
+		Properties: map[string]any{"engine": engine},
+	}
+
+	for artifactIndex, input := range inputs {
+		artifact := sarifArtifactLocation{URI: input.URI, Index: artifactIndex}
+		run.Artifacts = append(run.Artifacts, sarifArtifact{Location: artifact})
+
+		for _, issue := range input.Result.Issues {
+			run.Results = append(run.Results, buildSARIFResult(issue, artifact, ruleIndexes))
+		}
+		for _, item := range input.Result.Suppressed {
+			result := buildSARIFResult(item.Issue, artifact, ruleIndexes)
+			result.Suppressions = []sarifSuppression{{Kind: "inSource", Justification: item.Reason}}
+			run.Results = append(run.Results, result)
+		}
+	}
+
+	return sarifLog{Schema: sarifSchemaURI, Version: sarifVersion, Runs: []sarifRun{run}}
+}
+
+func buildSARIFResult(issue Issue, artifact sarifArtifactLocation, ruleIndexes map[string]int) sarifResult {
+	result := sarifResult{
+		RuleID:  issue.Rule,
+		Level:   sarifLevel(issue.Level),
+		Message: sarifMessage{Text: issue.Message},
+		Locations: []sarifLocation{{
+			PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: artifact},
+		}},
+	}
+	if index, found := ruleIndexes[issue.Rule]; found {
+		result.RuleIndex = &index
+	}
+	if issue.StartLine > 0 {
+		result.Locations[0].PhysicalLocation.Region = &sarifRegion{
+			StartLine:   issue.StartLine,
+			StartColumn: issue.StartColumn,
+			EndLine:     issue.EndLine,
+			EndColumn:   issue.EndColumn,
+		}
+	}
+	properties := map[string]any{}
+	if issue.StatementIndex > 0 {
+		properties["statementIndex"] = issue.StatementIndex
+	}
+	if issue.Suggestion != "" {
+		properties["suggestion"] = issue.Suggestion
+	}
+	if len(properties) > 0 {
+		result.Properties = properties
+	}
+	return result
+}
+
+func sarifLevel(level IssueLevel) string {
+	switch level {
+	case LevelError:
+		return "error"
+	case LevelWarning:
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+func sarifInputURI(fileName string, engine DBEngine) string {
+	if trimmed := strings.TrimSpace(fileName); trimmed != "" {
+		return trimmed
+	}
+	if extensions := engineExtensions(engine); len(extensions) > 0 {
+		return "input" + extensions[0]
+	}
+	return "input.sql"
+}
+
+func acceptsSARIF(r *http.Request) bool {
+	sarifQuality, jsonQuality := 0.0, 0.0
+	for _, value := range r.Header.Values("Accept") {
+		for _, mediaRange := range strings.Split(value, ",") {
+			mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+			if err != nil {
+				continue
+			}
+			quality := 1.0
+			if raw, found := params["q"]; found {
+				parsed, err := strconv.ParseFloat(raw, 64)
+				if err != nil || parsed < 0 || parsed > 1 {
+					continue
+				}
+				quality = parsed
+			}
+			switch mediaType {
+			case sarifContentType:
+				sarifQuality = max(sarifQuality, quality)
+			case "application/json", "application/*", "*/*":
+				jsonQuality = max(jsonQuality, quality)
+			}
+		}
+	}
+	return sarifQuality > 0 && sarifQuality >= jsonQuality
+}
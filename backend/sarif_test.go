@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestBuildSARIFLogMapsIssues(t *testing.T) {
+	content := "SELECT * FROM users LIMIT 1;\nDROP TABLE users;\n"
+	result := AnalyzeByEngine(EngineMySQL, content, AnalyzeOptions{})
+	report := buildSARIFLog(EngineMySQL, []sarifInput{{URI: "migrations/001.sql", Result: result}})
+
+	if report.Version != sarifVersion || len(report.Runs) != 1 {
+		t.Fatalf("unexpected sarif envelope: %+v", report)
+	}
+	run := report.Runs[0]
+	version, rules := RulesForEngine(EngineMySQL)
+	if run.Tool.Driver.Version != version || len(run.Tool.Driver.Rules) != len(rules) {
+		t.Fatalf("driver should describe engine rules, got version=%s rules=%d", run.Tool.Driver.Version, len(run.Tool.Driver.Rules))
+	}
+	if len(run.Results) != len(result.Issues) {
+		t.Fatalf("expected %d results, got %d", len(result.Issues), len(run.Results))
+	}
+
+	var selectStar, dropTable *sarifResult
+	for i := range run.Results {
+		switch run.Results[i].RuleID {
+		case "select_star":
+			selectStar = &run.Results[i]
+		case "dangerous_drop":
+			dropTable = &run.Results[i]
+		}
+	}
+	if selectStar == nil || dropTable == nil {
+		t.Fatalf("expected select_star and dangerous_drop results, got %+v", run.Results)
+	}
+	if selectStar.Level != "warning" || dropTable.Level != "error" {
+		t.Fatalf("unexpected levels: select_star=%s dangerous_drop=%s", selectStar.Level, dropTable.Level)
+	}
+	if selectStar.RuleIndex == nil || run.Tool.Driver.Rules[*selectStar.RuleIndex].ID != "select_star" {
+		t.Fatalf("ruleIndex should point at the driver rule")
+	}
+	location := selectStar.Locations[0].PhysicalLocation
+	if location.ArtifactLocation.URI != "migrations/001.sql" || location.Region == nil {
+		t.Fatalf("unexpected location: %+v", location)
+	}
+	if location.Region.StartLine != 1 || location.Region.StartColumn != 8 {
+		t.Fatalf("unexpected region: %+v", location.Region)
+	}
+}
+
+func TestSARIFLevelMapsInfoToNote(t *testing.T) {
+	if got := sarifLevel(LevelInfo); got != "note" {
+		t.Fatalf("expected note, got %s", got)
+	}
+}
+
+func TestAcceptsSARIF(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/check", nil)
+	if acceptsSARIF(req) {
+		t.Fatalf("request without Accept header should not negotiate sarif")
+	}
+	cases := map[string]bool{
+		"application/sarif+json":                            true,
+		"application/json;q=0.5, application/sarif+json":    true,
+		"application/sarif+json, */*":                       true,
+		"application/json, application/sarif+json;q=0.9":    false,
+		"application/sarif+json;q=0":                        false,
+		"application/json, application/sarif+json;q=0.1":    false,
+		"Application/SARIF+JSON; charset=utf-8, text/plain": true,
+		"application/sarif+json;q=oops, application/json":   false,
+	}
+	for accept, want := range cases {
+		req.Header.Set("Accept", accept)
+		if got := acceptsSARIF(req); got != want {
+			t.Fatalf("Accept %q: want sarif=%v, got %v", accept, want, got)
+		}
+	}
+}
+
+func TestRunLintCommandSARIFFormat(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := runLintCommand([]string{"--format", "sarif", "--fail-on", "info"}, strings.NewReader("SELECT * FROM users;\n"), &stdout, &stderr)
+	if code != lintExitThreshold {
+		t.Fatalf("expected threshold exit, got %d, stderr=%s", code, stderr.String())
+	}
+
+	var report sarifLog
+	if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+		t.Fatalf("output should be sarif json: %v", err)
+	}
+	if report.Version != sarifVersion || len(report.Runs) != 1 || len(report.Runs[0].Artifacts) != 1 {
+		t.Fatalf("unexpected sarif output: %s", stdout.String())
+	}
+	if len(report.Runs[0].Results) == 0 {
+		t.Fatalf("expected results in sarif output: %s", stdout.String())
+	}
+}
+
+func TestBuildSARIFLogMarksSuppressedResults(t *testing.T) {
+	content := "-- sql-review:disable-next-line dangerous_drop reason=\"approved\"\nDROP TABLE logs;\n"
+	result := AnalyzeByEngine(EngineMySQL, content, AnalyzeOptions{})
+	report := buildSARIFLog(EngineMySQL, []sarifInput{{URI: "a.sql", Result: result}})
+
+	found := false
+	for _, item := range report.Runs[0].Results {
+		if item.RuleID != "dangerous_drop" {
+			continue
+		}
+		found = true
+		if len(item.Suppressions) != 1 || item.Suppressions[0].Kind != "inSource" || item.Suppressions[0].Justification != "approved" {
+			t.Fatalf("unexpected suppressions: %+v", item.Suppressions)
+		}
+	}
+	if !found {
+		t.Fatalf("suppressed issue should still be reported in sarif")
+	}
+}
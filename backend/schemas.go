@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type SchemaSnapshot struct {
+	ID          int64         `json:"id"`
+	Name        string        `json:"name"`
+	Engine      DBEngine      `json:"engine"`
+	Description string        `json:"description"`
+	DDL         string        `json:"ddl"`
+	Tables      []SchemaTable `json:"tables,omitempty"`
+	CreatedAt   string        `json:"createdAt"`
+	UpdatedAt   string        `json:"updatedAt"`
+}
+
+var errSchemaLookupFailed = errors.New("schema snapshot lookup failed")
+
+type schemaSnapshotRequest struct {
+	Name        string `json:"name"`
+	Engine      string `json:"engine"`
+	Description string `json:"description"`
+	DDL         string `json:"ddl"`
+}
+
+func supportsSchemaCatalog(engine DBEngine) bool {
+	return engine == EngineMySQL || engine == EnginePostgreSQL || engine == EngineClickHouse || engine == EngineCQL
+}
+
+func (req schemaSnapshotRequest) toSnapshot(engine DBEngine) (SchemaSnapshot, error) {
+	name := strings.TrimSpace(req.Name)
+	if !reProfileName.MatchString(name) {
+		return SchemaSnapshot{}, errors.New("invalid schema name: use 1-64 letters, digits, '_', '-' or '.'")
+	}
+	if !supportsSchemaCatalog(engine) {
+		return SchemaSnapshot{}, fmt.Errorf("schema snapshots are not supported for engine %s", engine)
+	}
+	if len(req.DDL) > maxPayloadBytes {
+		return SchemaSnapshot{}, errors.New("schema ddl is too large")
+	}
+	if _, err := buildSchemaCatalog(engine, req.DDL); err != nil {
+		return SchemaSnapshot{}, fmt.Errorf("invalid schema: %w", err)
+	}
+
+	return SchemaSnapshot{
+		Name:        name,
+		Engine:      engine,
+		Description: strings.TrimSpace(req.Description),
+		DDL:         req.DDL,
+	}, nil
+}
+
+func (snapshot SchemaSnapshot) withTables() SchemaSnapshot {
+	if catalog, err := buildSchemaCatalog(snapshot.Engine, snapshot.DDL); err == nil {
+		snapshot.Tables = catalog.Tables()
+	}
+	return snapshot
+}
+
+func resolveCheckSchema(ctx context.Context, engine DBEngine, ddl, name string) (*schemaCatalog, string, error) {
+	name = strings.TrimSpace(name)
+	if strings.TrimSpace(ddl) == "" && name == "" {
+		return nil, "", nil
+	}
+	if !supportsSchemaCatalog(engine) {
+		return nil, "", fmt.Errorf("schema snapshots are not supported for engine %s", engine)
+	}
+
+	if strings.TrimSpace(ddl) == "" {
+		if historyStore == nil {
+			return nil, name, ErrSchemaNotFound
+		}
+		snapshot, err := historyStore.GetSchemaByName(ctx, engine, name)
+		if errors.Is(err, ErrSchemaNotFound) {
+			return nil, name, err
+		}
+		if err != nil {
+			return nil, name, fmt.Errorf("%w: %v", errSchemaLookupFailed, err)
+		}
+		ddl = snapshot.DDL
+	} else {
+		name = ""
+	}
+
+	catalog, err := buildSchemaCatalog(engine, ddl)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid schema: %w", err)
+	}
+	return catalog, name, nil
+}
+
+func handleSchemas(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		engine := DBEngine("")
+		if raw := strings.TrimSpace(r.URL.Query().Get("engine")); raw != "" {
+			resolved, err := ResolveEngine(raw)
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+				return
+			}
+			engine = resolved
+		}
+		snapshots, err := historyStore.ListSchemas(r.Context(), engine)
+		if err != nil {
+			log.Printf("list schema snapshots failed: %v", err)
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "failed to list schema snapshots"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"items": snapshots})
+	case http.MethodPost:
+		r.Body = http.MaxBytesReader(w, r.Body, maxPayloadBytes*2)
+		var req schemaSnapshotRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid schema payload"})
+			return
+		}
+		engine, err := ResolveEngine(req.Engine)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+			return
+		}
+		snapshot, err := req.toSnapshot(engine)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+			return
+		}
+		created, err := historyStore.CreateSchema(r.Context(), snapshot)
+		if err != nil {
+			writeSchemaStoreError(w, "create", err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, created.withTables())
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "only GET and POST are allowed"})
+	}
+}
+
+func handleSchemaDetail(w http.ResponseWriter, r *http.Request) {
+	id, err := parseSchemaPath(r.URL.Path)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		snapshot, err := historyStore.GetSchema(r.Context(), id)
+		if err != nil {
+			writeSchemaStoreError(w, "get", err)
+			return
+		}
+		writeJSON(w, http.StatusOK, snapshot.withTables())
+	case http.MethodPut:
+		existing, err := historyStore.GetSchema(r.Context(), id)
+		if err != nil {
+			writeSchemaStoreError(w, "get", err)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, maxPayloadBytes*2)
+		var req schemaSnapshotRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid schema payload"})
+			return
+		}
+		if err := checkUnchangedEngine(req.Engine, existing.Engine, "schema"); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+			return
+		}
+		snapshot, err := req.toSnapshot(existing.Engine)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+			return
+		}
+		updated, err := historyStore.UpdateSchema(r.Context(), id, snapshot)
+		if err != nil {
+			writeSchemaStoreError(w, "update", err)
+			return
+		}
+		writeJSON(w, http.StatusOK, updated.withTables())
+	case http.MethodDelete:
+		if err := historyStore.DeleteSchema(r.Context(), id); err != nil {
+			writeSchemaStoreError(w, "delete", err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"deleted": 1})
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "only GET, PUT and DELETE are allowed"})
+	}
+}
+
+func parseSchemaPath(path string) (int64, error) {
+	rest := strings.Trim(strings.TrimPrefix(path, "/api/v1/schemas/"), "/")
+	if rest == "" {
+		return 0, errors.New("missing schema id")
+	}
+	id, err := strconv.ParseInt(rest, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, errors.New("invalid schema id")
+	}
+	return id, nil
+}
+
+func writeSchemaStoreError(w http.ResponseWriter, operation string, err error) {
+	switch {
+	case errors.Is(err, ErrSchemaNotFound):
+		writeJSON(w, http.StatusNotFound, errorResponse{Error: "schema snapshot not found"})
+	case errors.Is(err, ErrSchemaExists):
+		writeJSON(w, http.StatusConflict, errorResponse{Error: "schema snapshot with the same name already exists for this engine"})
+	default:
+		log.Printf("%s schema snapshot failed: %v", operation, err)
+		writeJSON(w, http.StatusInternalServerError, errorResponse{Error: fmt.Sprintf("failed to %s schema snapshot", operation)})
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSchemaSnapshotRequestValidation(t *testing.T) {
+	snapshot, err := (schemaSnapshotRequest{Name: " shop ", DDL: "CREATE TABLE t (id INT);"}).toSnapshot(EngineMySQL)
+	if err != nil || snapshot.Name != "shop" {
+		t.Fatalf("unexpected snapshot: %+v err=%v", snapshot, err)
+	}
+	if _, err := (schemaSnapshotRequest{Name: "shop", DDL: "SELECT 1;"}).toSnapshot(EngineMySQL); err == nil {
+		t.Fatalf("ddl without CREATE TABLE should be rejected")
+	}
+	if _, err := (schemaSnapshotRequest{Name: "shop", DDL: "CREATE TABLE t (id INT);"}).toSnapshot(EngineMongoDB); err == nil {
+		t.Fatalf("mongodb schema snapshots should be rejected")
+	}
+}
+
+func TestParseSchemaPath(t *testing.T) {
+	if id, err := parseSchemaPath("/api/v1/schemas/7"); err != nil || id != 7 {
+		t.Fatalf("unexpected parse result: id=%d err=%v", id, err)
+	}
+	if _, err := parseSchemaPath("/api/v1/schemas/x"); err == nil {
+		t.Fatalf("non-numeric ids should be rejected")
+	}
+}
+
+func TestResolveCheckSchemaPrefersInlineDDL(t *testing.T) {
+	catalog, name, err := resolveCheckSchema(context.Background(), EngineMySQL, "CREATE TABLE t (id INT);", "ignored")
+	if err != nil || catalog == nil || name != "" {
+		t.Fatalf("expected inline catalog, got name=%q err=%v", name, err)
+	}
+	if catalog, _, err := resolveCheckSchema(context.Background(), EngineMySQL, "", ""); err != nil || catalog != nil {
+		t.Fatalf("expected no catalog without schema, got err=%v", err)
+	}
+}
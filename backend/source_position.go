@@ -0,0 +1,115 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+type sourceSpan struct {
+	Start int
+	End   int
+}
+
+type sourceLocation struct {
+	StartLine   int
+	StartColumn int
+	EndLine     int
+	EndColumn   int
+}
+
+type sourceIndex struct {
+	content    string
+	lineStarts []int
+}
+
+func newSourceIndex(content string) *sourceIndex {
+	lineStarts := []int{0}
+	for i := 0; i < len(content); i++ {
+		if content[i] == '\n' {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+	return &sourceIndex{content: content, lineStarts: lineStarts}
+}
+
+func (idx *sourceIndex) position(offset int) (int, int) {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > len(idx.content) {
+		offset = len(idx.content)
+	}
+	line := sort.Search(len(idx.lineStarts), func(i int) bool { return idx.lineStarts[i] > offset }) - 1
+	column := utf8.RuneCountInString(idx.content[idx.lineStarts[line]:offset]) + 1
+	return line + 1, column
+}
+
+func (idx *sourceIndex) location(span sourceSpan) sourceLocation {
+	if span.End < span.Start {
+		span.End = span.Start
+	}
+	startLine, startColumn := idx.position(span.Start)
+	endLine, endColumn := idx.position(span.End)
+	return sourceLocation{StartLine: startLine, StartColumn: startColumn, EndLine: endLine, EndColumn: endColumn}
+}
+
+func (idx *sourceIndex) locate(issue Issue, span sourceSpan) Issue {
+	return idx.location(span).apply(issue)
+}
+
+func (loc sourceLocation) apply(issue Issue) Issue {
+	if loc.StartLine <= 0 {
+		return issue
+	}
+	issue.StartLine = loc.StartLine
+	issue.StartColumn = loc.StartColumn
+	issue.EndLine = loc.EndLine
+	issue.EndColumn = loc.EndColumn
+	return issue
+}
+
+func (token sqlToken) span() sourceSpan {
+	return sourceSpan{Start: token.Start, End: token.End}
+}
+
+func (idx *sourceIndex) offset(line, column int) int {
+	if line <= 0 {
+		return 0
+	}
+	if line > len(idx.lineStarts) {
+		return len(idx.content)
+	}
+	offset := idx.lineStarts[line-1]
+	for column > 1 && offset < len(idx.content) && idx.content[offset] != '\n' {
+		_, size := utf8.DecodeRuneInString(idx.content[offset:])
+		offset += size
+		column--
+	}
+	return offset
+}
+
+func (idx *sourceIndex) lineSpan(line int) sourceSpan {
+	if line <= 0 || line > len(idx.lineStarts) {
+		return sourceSpan{Start: len(idx.content), End: len(idx.content)}
+	}
+	end := len(idx.content)
+	if line < len(idx.lineStarts) {
+		end = idx.lineStarts[line] - 1
+	}
+	return sourceSpan{Start: idx.lineStarts[line-1], End: end}
+}
+
+func (idx *sourceIndex) nextContentLine(line int) int {
+	for next := line + 1; next <= len(idx.lineStarts); next++ {
+		span := idx.lineSpan(next)
+		if strings.TrimSpace(idx.content[span.Start:span.End]) != "" {
+			return next
+		}
+	}
+	return line + 1
+}
+
+func (span sourceSpan) contains(offset int) bool {
+	return offset >= span.Start && offset < span.End
+}
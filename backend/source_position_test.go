@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestSourceIndexPositionCountsRunes(t *testing.T) {
+	idx := newSourceIndex("SELECT 1;\n-- 中文\nSELECT 2;")
+	line, column := idx.position(len("SELECT 1;\n-- 中文\nSELECT "))
+	if line != 3 || column != 8 {
+		t.Fatalf("unexpected position, line=%d column=%d", line, column)
+	}
+	line, column = idx.position(len("SELECT 1;\n-- 中文"))
+	if line != 2 || column != 6 {
+		t.Fatalf("unexpected position after multibyte runes, line=%d column=%d", line, column)
+	}
+}
+
+func TestSplitSQLStatementSpansTracksOffsets(t *testing.T) {
+	content := "-- header\nUPDATE t SET a = 1;\n\n  /* note */ DELETE FROM t WHERE id = 2;"
+	spans := splitSQLStatementSpans(content)
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 statements, got %d", len(spans))
+	}
+	if got := content[spans[0].Span.Start:spans[0].Span.End]; got != "UPDATE t SET a = 1" {
+		t.Fatalf("unexpected first span text: %q", got)
+	}
+	if got := content[spans[1].Span.Start:spans[1].Span.End]; got != "DELETE FROM t WHERE id = 2" {
+		t.Fatalf("unexpected second span text: %q", got)
+	}
+}
+
+func TestAnalyzeSQLIssuePositions(t *testing.T) {
+	sql := `UPDATE users SET status = 'off';
+
+SELECT id
+FROM users
+WHERE name LIKE '%tom' LIMIT 1;
+SELECT * FROM orders LIMIT 1;`
+
+	res := AnalyzeSQL(sql)
+	update := getIssueByRule(res.Issues, "update_without_where")
+	if update == nil || update.StartLine != 1 || update.StartColumn != 1 || update.EndLine != 1 || update.EndColumn != 32 {
+		t.Fatalf("unexpected update_without_where location: %+v", update)
+	}
+	like := getIssueByRule(res.Issues, "like_leading_wildcard")
+	if like == nil || like.StartLine != 5 || like.StartColumn != 12 || like.EndColumn != 23 {
+		t.Fatalf("unexpected like_leading_wildcard location: %+v", like)
+	}
+	star := getIssueByRule(res.Issues, "select_star")
+	if star == nil || star.StartLine != 6 || star.StartColumn != 8 || star.EndColumn != 9 {
+		t.Fatalf("unexpected select_star location: %+v", star)
+	}
+}
+
+func TestAnalyzeMongoIssuePositionsWithCRLF(t *testing.T) {
+	script := "db.users.find({ status: 1 }).limit(1);\r\ndb.users.deleteMany({});\r\n"
+	result := AnalyzeByEngine(EngineMongoDB, script, AnalyzeOptions{})
+	issue := getIssueByRule(result.Issues, "mongo_delete_many_without_filter")
+	if issue == nil || issue.StartLine != 2 || issue.StartColumn != 1 || issue.EndLine != 2 || issue.EndColumn != 24 {
+		t.Fatalf("unexpected mongo issue location: %+v", issue)
+	}
+}
@@ -0,0 +1,378 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type sqlTokenKind int
+
+const (
+	sqlTokenWord sqlTokenKind = iota
+	sqlTokenQuotedIdent
+	sqlTokenString
+	sqlTokenNumber
+	sqlTokenVariable
+	sqlTokenOperator
+	sqlTokenComment
+)
+
+type sqlToken struct {
+	Kind  sqlTokenKind
+	Text  string
+	Upper string
+	Start int
+	End   int
+	Depth int
+}
+
+type sqlDialect struct {
+	BacktickIdentifiers    bool
+	DoubleQuoteIdentifiers bool
+	HashComments           bool
+	BackslashEscapes       bool
+	DollarQuotedStrings    bool
+	BracketIdentifiers     bool
+	HashTempTables         bool
+	QQuotedStrings         bool
+	SlashComments          bool
+	TopClause              bool
+}
+
+var (
+	mysqlDialect      = sqlDialect{BacktickIdentifiers: true, HashComments: true, BackslashEscapes: true}
+	postgresDialect   = sqlDialect{DoubleQuoteIdentifiers: true, DollarQuotedStrings: true}
+	sqlserverDialect  = sqlDialect{DoubleQuoteIdentifiers: true, BracketIdentifiers: true, HashTempTables: true, TopClause: true}
+	oracleDialect     = sqlDialect{DoubleQuoteIdentifiers: true, QQuotedStrings: true}
+	sqliteDialect     = sqlDialect{BacktickIdentifiers: true, DoubleQuoteIdentifiers: true, BracketIdentifiers: true}
+	clickhouseDialect = sqlDialect{BacktickIdentifiers: true, DoubleQuoteIdentifiers: true, BackslashEscapes: true}
+	cqlDialect        = sqlDialect{DoubleQuoteIdentifiers: true, DollarQuotedStrings: true, SlashComments: true}
+)
+
+var sqlMultiCharOperators = []string{"<=>", "->>", "<=", ">=", "<>", "!=", "::", "||", "&&", ":=", "->", "<<", ">>"}
+
+func tokenizeSQL(text string, dialect sqlDialect) []sqlToken {
+	tokens := make([]sqlToken, 0, len(text)/4)
+	depth := 0
+	pos := 0
+
+	emit := func(kind sqlTokenKind, start, end int) {
+		raw := text[start:end]
+		token := sqlToken{Kind: kind, Text: raw, Start: start, End: end, Depth: depth}
+		if kind == sqlTokenWord {
+			token.Upper = strings.ToUpper(raw)
+		}
+		if kind == sqlTokenOperator {
+			switch raw {
+			case "(":
+				depth++
+			case ")":
+				if depth > 0 {
+					depth--
+				}
+				token.Depth = depth
+			}
+		}
+		tokens = append(tokens, token)
+	}
+
+	for pos < len(text) {
+		ch, size := utf8.DecodeRuneInString(text[pos:])
+		next := byte(0)
+		if pos+size < len(text) {
+			next = text[pos+size]
+		}
+
+		switch {
+		case unicode.IsSpace(ch):
+			pos += size
+		case ch == '-' && next == '-', ch == '#' && dialect.HashComments, ch == '/' && next == '/' && dialect.SlashComments:
+			end := strings.IndexByte(text[pos:], '\n')
+			if end < 0 {
+				end = len(text)
+			} else {
+				end += pos
+			}
+			emit(sqlTokenComment, pos, end)
+			pos = end
+		case ch == '/' && next == '*':
+			end := strings.Index(text[pos+2:], "*/")
+			if end < 0 {
+				end = len(text)
+			} else {
+				end += pos + 4
+			}
+			emit(sqlTokenComment, pos, end)
+			pos = end
+		case ch == '\'':
+			end := scanSQLQuoted(text, pos, '\'', dialect.BackslashEscapes)
+			emit(sqlTokenString, pos, end)
+			pos = end
+		case ch == '"':
+			end := scanSQLQuoted(text, pos, '"', dialect.BackslashEscapes && !dialect.DoubleQuoteIdentifiers)
+			if dialect.DoubleQuoteIdentifiers {
+				emit(sqlTokenQuotedIdent, pos, end)
+			} else {
+				emit(sqlTokenString, pos, end)
+			}
+			pos = end
+		case ch == '`' && dialect.BacktickIdentifiers:
+			end := scanSQLQuoted(text, pos, '`', false)
+			emit(sqlTokenQuotedIdent, pos, end)
+			pos = end
+		case ch == '#' && dialect.HashTempTables:
+			end := pos + 1
+			for end < len(text) && text[end] == '#' {
+				end++
+			}
+			end = scanSQLWordEnd(text, end)
+			emit(sqlTokenWord, pos, end)
+			pos = end
+		case ch == '[' && dialect.BracketIdentifiers:
+			end := scanSQLQuoted(text, pos, ']', false)
+			emit(sqlTokenQuotedIdent, pos, end)
+			pos = end
+		case ch == '$' && dialect.DollarQuotedStrings && isDollarQuoteStart(text, pos):
+			end := scanDollarQuoted(text, pos)
+			emit(sqlTokenString, pos, end)
+			pos = end
+		case ch == '$' && next >= '0' && next <= '9':
+			end := pos + 1
+			for end < len(text) && text[end] >= '0' && text[end] <= '9' {
+				end++
+			}
+			emit(sqlTokenVariable, pos, end)
+			pos = end
+		case ch == '@':
+			end := pos + 1
+			for end < len(text) && text[end] == '@' {
+				end++
+			}
+			end = scanSQLWordEnd(text, end)
+			emit(sqlTokenVariable, pos, end)
+			pos = end
+		case ch == '?':
+			emit(sqlTokenVariable, pos, pos+1)
+			pos++
+		case ch == ':' && next != ':' && next != '=' && pos+1 < len(text) && isSQLWordStart(rune(next)) && (pos == 0 || text[pos-1] != ':'):
+			end := scanSQLWordEnd(text, pos+1)
+			emit(sqlTokenVariable, pos, end)
+			pos = end
+		case ch >= '0' && ch <= '9', ch == '.' && next >= '0' && next <= '9':
+			end := scanSQLNumberEnd(text, pos)
+			emit(sqlTokenNumber, pos, end)
+			pos = end
+		case isSQLWordStart(ch):
+			end := scanSQLWordEnd(text, pos)
+			if dialect.QQuotedStrings && end < len(text) && text[end] == '\'' && (strings.EqualFold(text[pos:end], "q") || strings.EqualFold(text[pos:end], "nq")) {
+				end = scanSQLQQuoted(text, end)
+				emit(sqlTokenString, pos, end)
+				pos = end
+				continue
+			}
+			if end < len(text) && text[end] == '\'' && end-pos == 1 && strings.ContainsRune("EeNnBbXx", ch) {
+				end = scanSQLQuoted(text, end, '\'', dialect.BackslashEscapes || ch == 'E' || ch == 'e')
+				emit(sqlTokenString, pos, end)
+				pos = end
+				continue
+			}
+			emit(sqlTokenWord, pos, end)
+			pos = end
+		default:
+			matched := ""
+			for _, op := range sqlMultiCharOperators {
+				if strings.HasPrefix(text[pos:], op) {
+					matched = op
+					break
+				}
+			}
+			if matched == "" {
+				matched = text[pos : pos+size]
+			}
+			emit(sqlTokenOperator, pos, pos+len(matched))
+			pos += len(matched)
+		}
+	}
+
+	return tokens
+}
+
+func significantSQLTokens(tokens []sqlToken) []sqlToken {
+	items := make([]sqlToken, 0, len(tokens))
+	for _, token := range tokens {
+		if token.Kind == sqlTokenComment {
+			continue
+		}
+		items = append(items, token)
+	}
+	return items
+}
+
+func sqlLineBounds(content string, token sqlToken) (int, int) {
+	lineStart := strings.LastIndexByte(content[:token.Start], '\n') + 1
+	lineEnd := strings.IndexByte(content[token.End:], '\n')
+	if lineEnd < 0 {
+		return lineStart, len(content)
+	}
+	return lineStart, token.End + lineEnd
+}
+
+func scanSQLQQuoted(text string, quote int) int {
+	if quote+1 >= len(text) {
+		return len(text)
+	}
+	open, size := utf8.DecodeRuneInString(text[quote+1:])
+	closing := open
+	switch open {
+	case '[':
+		closing = ']'
+	case '{':
+		closing = '}'
+	case '(':
+		closing = ')'
+	case '<':
+		closing = '>'
+	}
+	end := strings.Index(text[quote+1+size:], string(closing)+"'")
+	if end < 0 {
+		return len(text)
+	}
+	return quote + 1 + size + end + utf8.RuneLen(closing) + 1
+}
+
+func scanSQLQuoted(text string, start int, quote byte, backslashEscapes bool) int {
+	i := start + 1
+	for i < len(text) {
+		c := text[i]
+		if backslashEscapes && c == '\\' {
+			i += 2
+			continue
+		}
+		if c == quote {
+			if i+1 < len(text) && text[i+1] == quote {
+				i += 2
+				continue
+			}
+			return i + 1
+		}
+		i++
+	}
+	return len(text)
+}
+
+func isDollarQuoteStart(text string, pos int) bool {
+	end := strings.IndexByte(text[pos+1:], '$')
+	if end < 0 {
+		return false
+	}
+	tag := text[pos+1 : pos+1+end]
+	for i, r := range tag {
+		if !(r == '_' || unicode.IsLetter(r) || (i > 0 && unicode.IsDigit(r))) {
+			return false
+		}
+	}
+	return true
+}
+
+func scanDollarQuoted(text string, start int) int {
+	tagEnd := strings.IndexByte(text[start+1:], '$') + start + 2
+	tag := text[start:tagEnd]
+	closing := strings.Index(text[tagEnd:], tag)
+	if closing < 0 {
+		return len(text)
+	}
+	return tagEnd + closing + len(tag)
+}
+
+func isSQLWordStart(ch rune) bool {
+	return ch == '_' || unicode.IsLetter(ch)
+}
+
+func isSQLWordPart(ch rune) bool {
+	return ch == '_' || ch == '$' || unicode.IsLetter(ch) || unicode.IsDigit(ch)
+}
+
+func scanSQLWordEnd(text string, start int) int {
+	end := start
+	for end < len(text) {
+		r, size := utf8.DecodeRuneInString(text[end:])
+		if !isSQLWordPart(r) {
+			break
+		}
+		end += size
+	}
+	return end
+}
+
+func scanSQLNumberEnd(text string, start int) int {
+	end := start
+	if strings.HasPrefix(text[start:], "0x") || strings.HasPrefix(text[start:], "0X") {
+		end += 2
+		for end < len(text) && strings.IndexByte("0123456789abcdefABCDEF", text[end]) >= 0 {
+			end++
+		}
+		return end
+	}
+	for end < len(text) && (text[end] >= '0' && text[end] <= '9' || text[end] == '.') {
+		end++
+	}
+	if end < len(text) && (text[end] == 'e' || text[end] == 'E') {
+		exp := end + 1
+		if exp < len(text) && (text[exp] == '+' || text[exp] == '-') {
+			exp++
+		}
+		if exp < len(text) && text[exp] >= '0' && text[exp] <= '9' {
+			end = exp
+			for end < len(text) && text[end] >= '0' && text[end] <= '9' {
+				end++
+			}
+		}
+	}
+	return end
+}
+
+func sqlIdentifierName(token sqlToken) string {
+	if token.Kind != sqlTokenQuotedIdent || len(token.Text) < 2 {
+		return token.Text
+	}
+	quote := token.Text[:1]
+	if quote == "[" {
+		quote = "]"
+	}
+	inner := token.Text[1 : len(token.Text)-1]
+	return strings.ReplaceAll(inner, quote+quote, quote)
+}
+
+func sqlStringValue(token sqlToken) string {
+	text := token.Text
+	if token.Kind != sqlTokenString {
+		return text
+	}
+	if idx := strings.IndexAny(text, "'\"$"); idx > 0 {
+		if prefix := text[:idx]; strings.HasSuffix(prefix, "q") || strings.HasSuffix(prefix, "Q") {
+			_, open := utf8.DecodeRuneInString(text[idx+1:])
+			_, closing := utf8.DecodeLastRuneInString(text[:len(text)-1])
+			if len(text) >= idx+1+open+closing+1 {
+				return text[idx+1+open : len(text)-1-closing]
+			}
+		}
+		text = text[idx:]
+	}
+	if strings.HasPrefix(text, "$") {
+		tagEnd := strings.IndexByte(text[1:], '$') + 2
+		if len(text) >= tagEnd*2 {
+			return text[tagEnd : len(text)-tagEnd]
+		}
+		return text
+	}
+	if len(text) < 2 {
+		return text
+	}
+	quote := text[:1]
+	inner := text[1:]
+	if strings.HasSuffix(inner, quote) {
+		inner = inner[:len(inner)-1]
+	}
+	return strings.ReplaceAll(inner, quote+quote, quote)
+}
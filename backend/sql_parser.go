@@ -0,0 +1,945 @@
+package main
+
+import (
+	"strings"
+)
+
+type sqlStatementKind string
+
+const (
+	sqlStmtEmpty         sqlStatementKind = "EMPTY"
+	sqlStmtSelect        sqlStatementKind = "SELECT"
+	sqlStmtInsert        sqlStatementKind = "INSERT"
+	sqlStmtReplace       sqlStatementKind = "REPLACE"
+	sqlStmtUpdate        sqlStatementKind = "UPDATE"
+	sqlStmtDelete        sqlStatementKind = "DELETE"
+	sqlStmtCreateTable   sqlStatementKind = "CREATE_TABLE"
+	sqlStmtCreateIndex   sqlStatementKind = "CREATE_INDEX"
+	sqlStmtCreateRoutine sqlStatementKind = "CREATE_ROUTINE"
+	sqlStmtCreateOther   sqlStatementKind = "CREATE_OTHER"
+	sqlStmtAlterTable    sqlStatementKind = "ALTER_TABLE"
+	sqlStmtAlterOther    sqlStatementKind = "ALTER_OTHER"
+	sqlStmtDrop          sqlStatementKind = "DROP"
+	sqlStmtTruncate      sqlStatementKind = "TRUNCATE"
+	sqlStmtBegin         sqlStatementKind = "BEGIN"
+	sqlStmtCommit        sqlStatementKind = "COMMIT"
+	sqlStmtRollback      sqlStatementKind = "ROLLBACK"
+	sqlStmtBlock         sqlStatementKind = "BLOCK"
+	sqlStmtOther         sqlStatementKind = "OTHER"
+)
+
+type sqlClause struct {
+	Keyword sqlToken
+	Tokens  []sqlToken
+}
+
+type sqlTableRef struct {
+	Name  string
+	Alias string
+	Token sqlToken
+}
+
+type sqlSelectItem struct {
+	Tokens []sqlToken
+	Star   bool
+}
+
+type sqlStatement struct {
+	Kind          sqlStatementKind
+	Tokens        []sqlToken
+	ObjectType    string
+	Temporary     bool
+	IfExists      bool
+	IfNotExists   bool
+	Concurrently  bool
+	Tables        []sqlTableRef
+	Columns       []string
+	HasColumnList bool
+	Projection    []sqlSelectItem
+	From          *sqlClause
+	Where         *sqlClause
+	GroupBy       *sqlClause
+	OrderBy       *sqlClause
+	Limit         *sqlClause
+	Source        *sqlStatement
+	Subqueries    []*sqlStatement
+	CTENames      []string
+	AlterClauses  [][]sqlToken
+}
+
+var sqlSelectClauseKeywords = map[string]struct{}{
+	"FROM": {}, "WHERE": {}, "GROUP": {}, "HAVING": {}, "WINDOW": {}, "ORDER": {}, "LIMIT": {}, "OFFSET": {},
+	"FETCH": {}, "FOR": {}, "INTO": {}, "UNION": {}, "INTERSECT": {}, "EXCEPT": {}, "LOCK": {}, "RETURNING": {},
+	"PREWHERE": {},
+}
+
+var sqlDMLClauseKeywords = map[string]struct{}{
+	"SET": {}, "FROM": {}, "USING": {}, "WHERE": {}, "ORDER": {}, "LIMIT": {}, "RETURNING": {}, "OUTPUT": {},
+}
+
+var sqlTableAliasStopWords = map[string]struct{}{
+	"ON": {}, "USING": {}, "JOIN": {}, "INNER": {}, "LEFT": {}, "RIGHT": {}, "FULL": {}, "OUTER": {}, "CROSS": {},
+	"NATURAL": {}, "STRAIGHT_JOIN": {}, "WHERE": {}, "GROUP": {}, "HAVING": {}, "ORDER": {}, "LIMIT": {}, "SET": {},
+	"UNION": {}, "INTERSECT": {}, "EXCEPT": {}, "FOR": {}, "LOCK": {}, "WINDOW": {}, "PARTITION": {}, "USE": {},
+	"FORCE": {}, "IGNORE": {}, "RETURNING": {}, "VALUES": {}, "VALUE": {}, "SELECT": {}, "OFFSET": {}, "FETCH": {},
+	"WITH": {}, "TABLESAMPLE": {}, "INTO": {}, "OUTPUT": {}, "FROM": {}, "DEFAULT": {},
+	"FINAL": {}, "SAMPLE": {}, "PREWHERE": {}, "ARRAY": {}, "GLOBAL": {}, "ANY": {}, "ASOF": {}, "SEMI": {}, "ANTI": {},
+}
+
+func parseSQLStatement(text string, dialect sqlDialect) *sqlStatement {
+	return parseSQLTokens(significantSQLTokens(tokenizeSQL(text, dialect)), dialect)
+}
+
+func parseSQLStatementAt(content string, span sourceSpan, dialect sqlDialect) *sqlStatement {
+	tokens := tokenizeSQL(content[span.Start:span.End], dialect)
+	for i := range tokens {
+		tokens[i].Start += span.Start
+		tokens[i].End += span.Start
+	}
+	return parseSQLTokens(significantSQLTokens(tokens), dialect)
+}
+
+func parseSQLTokens(tokens []sqlToken, dialect sqlDialect) *sqlStatement {
+	stmt := &sqlStatement{Kind: sqlStmtOther, Tokens: tokens}
+	if len(tokens) == 0 {
+		stmt.Kind = sqlStmtEmpty
+		return stmt
+	}
+
+	p := sqlParser{tokens: tokens, base: tokens[0].Depth, subqueryEnd: len(tokens), dialect: dialect}
+	switch p.upper(0) {
+	case "SELECT":
+		p.parseSelect(stmt, 0)
+	case "WITH":
+		return p.parseWith(stmt)
+	case "INSERT":
+		p.parseInsert(stmt, sqlStmtInsert)
+	case "REPLACE":
+		p.parseInsert(stmt, sqlStmtReplace)
+	case "UPDATE":
+		p.parseUpdate(stmt)
+	case "DELETE":
+		p.parseDelete(stmt)
+	case "CREATE":
+		p.parseCreate(stmt)
+	case "ALTER":
+		p.parseAlter(stmt)
+	case "DROP":
+		p.parseDrop(stmt)
+	case "TRUNCATE":
+		stmt.Kind = sqlStmtTruncate
+		idx := 1
+		if p.upper(idx) == "TABLE" {
+			idx++
+		}
+		if p.upper(idx) == "IF" && p.upper(idx+1) == "EXISTS" {
+			stmt.IfExists = true
+			idx += 2
+		}
+		stmt.Tables = p.parseTableNameList(idx, len(tokens))
+	case "BEGIN":
+		stmt.Kind = sqlStmtBegin
+	case "START":
+		if p.upper(1) == "TRANSACTION" {
+			stmt.Kind = sqlStmtBegin
+		}
+	case "COMMIT", "END":
+		stmt.Kind = sqlStmtCommit
+	case "ROLLBACK", "ABORT":
+		stmt.Kind = sqlStmtRollback
+	case "(":
+		if p.upper(1) == "SELECT" || p.upper(1) == "WITH" {
+			stmt.Kind = sqlStmtSelect
+			stmt.Limit = p.clause(p.findTop(0, len(tokens), "LIMIT"), len(tokens))
+		}
+	}
+
+	if stmt.Kind != sqlStmtCreateRoutine {
+		stmt.Subqueries = append(stmt.Subqueries, p.collectSubqueries()...)
+	}
+	return stmt
+}
+
+type sqlParser struct {
+	tokens      []sqlToken
+	base        int
+	subqueryEnd int
+	dialect     sqlDialect
+}
+
+func (p *sqlParser) upper(i int) string {
+	if i < 0 || i >= len(p.tokens) {
+		return ""
+	}
+	token := p.tokens[i]
+	if token.Kind == sqlTokenWord {
+		return token.Upper
+	}
+	if token.Kind == sqlTokenOperator {
+		return token.Text
+	}
+	return ""
+}
+
+func (p *sqlParser) isTop(i int) bool {
+	return i >= 0 && i < len(p.tokens) && p.tokens[i].Depth == p.base
+}
+
+func (p *sqlParser) findTop(from, to int, words ...string) int {
+	for i := from; i < to && i < len(p.tokens); i++ {
+		if !p.isTop(i) {
+			continue
+		}
+		value := p.upper(i)
+		for _, word := range words {
+			if value == word {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func (p *sqlParser) findTopIn(from, to int, words map[string]struct{}) int {
+	for i := from; i < to && i < len(p.tokens); i++ {
+		if !p.isTop(i) || p.tokens[i].Kind != sqlTokenWord {
+			continue
+		}
+		if _, found := words[p.tokens[i].Upper]; found {
+			return i
+		}
+	}
+	return -1
+}
+
+func matchingSQLParen(tokens []sqlToken, open int) (int, bool) {
+	depth := tokens[open].Depth
+	for i := open + 1; i < len(tokens); i++ {
+		if tokens[i].Kind == sqlTokenOperator && tokens[i].Text == ")" && tokens[i].Depth == depth {
+			return i, true
+		}
+	}
+	return len(tokens), false
+}
+
+func (p *sqlParser) clause(start, end int) *sqlClause {
+	if start < 0 {
+		return nil
+	}
+	if end < start+1 {
+		end = start + 1
+	}
+	end = min(end, len(p.tokens))
+	return &sqlClause{Keyword: p.tokens[start], Tokens: p.tokens[start+1 : end]}
+}
+
+func (p *sqlParser) nextClauseEnd(from int, words map[string]struct{}) int {
+	if end := p.findTopIn(from, len(p.tokens), words); end >= 0 {
+		return end
+	}
+	return len(p.tokens)
+}
+
+func (p *sqlParser) parseSelect(stmt *sqlStatement, start int) {
+	stmt.Kind = sqlStmtSelect
+	end := len(p.tokens)
+	partEnd := p.findTop(start+1, end, "UNION", "INTERSECT", "EXCEPT")
+	if partEnd < 0 {
+		partEnd = end
+	}
+
+	projectionStart := start + 1
+	for projectionStart < partEnd {
+		if p.dialect.TopClause && p.upper(projectionStart) == "TOP" {
+			topEnd := p.skipTop(projectionStart)
+			stmt.Limit = p.clause(projectionStart, topEnd)
+			projectionStart = topEnd
+			continue
+		}
+		if !isSQLSelectModifier(p.upper(projectionStart)) {
+			break
+		}
+		projectionStart++
+	}
+	projectionEnd := partEnd
+	if idx := p.findTopIn(projectionStart, partEnd, sqlSelectClauseKeywords); idx >= 0 {
+		projectionEnd = idx
+	}
+	stmt.Projection = p.splitSelectItems(projectionStart, projectionEnd)
+
+	if from := p.findTop(projectionEnd, partEnd, "FROM"); from >= 0 {
+		stmt.From = p.clause(from, p.nextClauseEndExcept(from+1, partEnd, "FROM"))
+		stmt.Tables = p.parseTableRefs(from+1, from+1+len(stmt.From.Tokens))
+	}
+	if where := p.findTop(projectionEnd, partEnd, "WHERE"); where >= 0 {
+		stmt.Where = p.clause(where, p.nextClauseEndExcept(where+1, partEnd, "WHERE"))
+	}
+	if group := p.findTop(projectionEnd, partEnd, "GROUP"); group >= 0 && p.upper(group+1) == "BY" {
+		stmt.GroupBy = p.clause(group, p.nextClauseEndExcept(group+2, partEnd, "GROUP"))
+	}
+	if order := p.findTop(projectionEnd, end, "ORDER"); order >= 0 && p.upper(order+1) == "BY" {
+		stmt.OrderBy = p.clause(order, p.nextClauseEndExcept(order+2, end, "ORDER"))
+	}
+	if limit := p.findTop(projectionEnd, end, "LIMIT", "FETCH"); limit >= 0 {
+		stmt.Limit = p.clause(limit, p.nextClauseEndExcept(limit+1, end, "LIMIT", "FETCH", "OFFSET"))
+	}
+
+	if partEnd < end {
+		next := partEnd + 1
+		for next < end && (p.upper(next) == "ALL" || p.upper(next) == "DISTINCT") {
+			next++
+		}
+		if p.upper(next) == "SELECT" {
+			stmt.Subqueries = append(stmt.Subqueries, parseSQLTokens(p.tokens[next:], p.dialect))
+			p.subqueryEnd = next
+		}
+	}
+}
+
+func (p *sqlParser) nextClauseEndExcept(from, limit int, skip ...string) int {
+	for i := from; i < limit && i < len(p.tokens); i++ {
+		if !p.isTop(i) || p.tokens[i].Kind != sqlTokenWord {
+			continue
+		}
+		word := p.tokens[i].Upper
+		if _, found := sqlSelectClauseKeywords[word]; !found {
+			continue
+		}
+		skipped := false
+		for _, item := range skip {
+			if word == item {
+				skipped = true
+				break
+			}
+		}
+		if !skipped {
+			return i
+		}
+	}
+	return limit
+}
+
+func (p *sqlParser) skipTop(top int) int {
+	i := top + 1
+	if p.upper(i) == "(" {
+		closing, ok := matchingSQLParen(p.tokens, i)
+		if !ok {
+			return closing
+		}
+		i = closing
+	}
+	i++
+	if p.upper(i) == "PERCENT" {
+		i++
+	}
+	if p.upper(i) == "WITH" && p.upper(i+1) == "TIES" {
+		i += 2
+	}
+	return min(i, len(p.tokens))
+}
+
+func isSQLSelectModifier(word string) bool {
+	switch word {
+	case "DISTINCT", "ALL", "DISTINCTROW", "HIGH_PRIORITY", "STRAIGHT_JOIN", "SQL_SMALL_RESULT", "SQL_BIG_RESULT",
+		"SQL_BUFFER_RESULT", "SQL_NO_CACHE", "SQL_CACHE", "SQL_CALC_FOUND_ROWS":
+		return true
+	}
+	return false
+}
+
+func (p *sqlParser) splitSelectItems(start, end int) []sqlSelectItem {
+	items := make([]sqlSelectItem, 0)
+	itemStart := start
+	flush := func(itemEnd int) {
+		if itemEnd <= itemStart {
+			return
+		}
+		tokens := p.tokens[itemStart:itemEnd]
+		last := tokens[len(tokens)-1]
+		star := last.Kind == sqlTokenOperator && last.Text == "*" &&
+			(len(tokens) == 1 || (len(tokens) >= 3 && tokens[len(tokens)-2].Text == "."))
+		items = append(items, sqlSelectItem{Tokens: tokens, Star: star})
+	}
+	for i := start; i < end; i++ {
+		if p.isTop(i) && p.tokens[i].Kind == sqlTokenOperator && p.tokens[i].Text == "," {
+			flush(i)
+			itemStart = i + 1
+		}
+	}
+	flush(end)
+	return items
+}
+
+func (p *sqlParser) readQualifiedName(i int) (string, sqlToken, int) {
+	if i >= len(p.tokens) {
+		return "", sqlToken{}, i
+	}
+	first := p.tokens[i]
+	if first.Kind != sqlTokenWord && first.Kind != sqlTokenQuotedIdent {
+		return "", sqlToken{}, i
+	}
+	parts := []string{sqlIdentifierName(first)}
+	j := i + 1
+	for j+1 < len(p.tokens) && p.tokens[j].Kind == sqlTokenOperator && p.tokens[j].Text == "." &&
+		(p.tokens[j+1].Kind == sqlTokenWord || p.tokens[j+1].Kind == sqlTokenQuotedIdent) {
+		parts = append(parts, sqlIdentifierName(p.tokens[j+1]))
+		j += 2
+	}
+	token := first
+	token.End = p.tokens[j-1].End
+	return strings.Join(parts, "."), token, j
+}
+
+func (p *sqlParser) parseTableRefs(start, end int) []sqlTableRef {
+	refs := make([]sqlTableRef, 0)
+	expectTable := true
+	for i := start; i < end && i < len(p.tokens); {
+		if !p.isTop(i) {
+			i++
+			continue
+		}
+		token := p.tokens[i]
+		word := p.upper(i)
+		switch {
+		case word == "," || word == "JOIN" || word == "STRAIGHT_JOIN":
+			expectTable = true
+			i++
+			continue
+		case word == "ONLY" || word == "LATERAL":
+			i++
+			continue
+		case word == "(":
+			expectTable = false
+			closing, _ := matchingSQLParen(p.tokens, i)
+			i = closing + 1
+			continue
+		}
+
+		if !expectTable || (token.Kind != sqlTokenWord && token.Kind != sqlTokenQuotedIdent) {
+			i++
+			continue
+		}
+		if _, stop := sqlTableAliasStopWords[word]; stop && token.Kind == sqlTokenWord {
+			i++
+			continue
+		}
+
+		name, nameToken, next := p.readQualifiedName(i)
+		ref := sqlTableRef{Name: name, Token: nameToken}
+		if p.upper(next) == "AS" {
+			next++
+		}
+		if next < end && p.isTop(next) && (p.tokens[next].Kind == sqlTokenWord || p.tokens[next].Kind == sqlTokenQuotedIdent) {
+			if _, stop := sqlTableAliasStopWords[p.upper(next)]; !stop || p.tokens[next].Kind == sqlTokenQuotedIdent {
+				ref.Alias = sqlIdentifierName(p.tokens[next])
+				next++
+			}
+		}
+		refs = append(refs, ref)
+		expectTable = false
+		i = next
+	}
+	return refs
+}
+
+func (p *sqlParser) parseTableNameList(start, end int) []sqlTableRef {
+	refs := make([]sqlTableRef, 0)
+	for i := start; i < end && i < len(p.tokens); {
+		name, token, next := p.readQualifiedName(i)
+		if name == "" {
+			i++
+			continue
+		}
+		if _, stop := sqlTableAliasStopWords[p.upper(i)]; stop && p.tokens[i].Kind == sqlTokenWord {
+			i = next
+			continue
+		}
+		refs = append(refs, sqlTableRef{Name: name, Token: token})
+		i = next
+		for i < end && p.upper(i) != "," {
+			i++
+		}
+	}
+	return refs
+}
+
+func (p *sqlParser) parseWith(stmt *sqlStatement) *sqlStatement {
+	i := 1
+	if p.upper(i) == "RECURSIVE" {
+		i++
+	}
+	ctes := make([]*sqlStatement, 0)
+	names := make([]string, 0)
+	for i < len(p.tokens) {
+		name, _, next := p.readQualifiedName(i)
+		if name == "" {
+			break
+		}
+		names = append(names, name)
+		i = next
+		if p.upper(i) == "(" {
+			closing, _ := matchingSQLParen(p.tokens, i)
+			i = closing + 1
+		}
+		if p.upper(i) == "AS" {
+			i++
+		}
+		for p.upper(i) == "NOT" || p.upper(i) == "MATERIALIZED" {
+			i++
+		}
+		if p.upper(i) != "(" {
+			break
+		}
+		closing, ok := matchingSQLParen(p.tokens, i)
+		if closing <= i {
+			break
+		}
+		ctes = append(ctes, parseSQLTokens(p.tokens[i+1:closing], p.dialect))
+		if !ok {
+			i = closing
+			break
+		}
+		i = closing + 1
+		if p.upper(i) != "," {
+			break
+		}
+		i++
+	}
+
+	if i >= len(p.tokens) {
+		stmt.Subqueries = ctes
+		stmt.CTENames = names
+		return stmt
+	}
+	main := parseSQLTokens(p.tokens[i:], p.dialect)
+	main.Tokens = p.tokens
+	main.Subqueries = append(ctes, main.Subqueries...)
+	main.CTENames = names
+	return main
+}
+
+func (p *sqlParser) parseInsert(stmt *sqlStatement, kind sqlStatementKind) {
+	stmt.Kind = kind
+	i := 1
+	for isSQLInsertModifier(p.upper(i)) {
+		i++
+	}
+	if p.upper(i) == "INTO" {
+		i++
+	}
+	name, token, next := p.readQualifiedName(i)
+	if name == "" {
+		return
+	}
+	stmt.Tables = []sqlTableRef{{Name: name, Token: token}}
+	i = next
+	if p.upper(i) == "PARTITION" && p.upper(i+1) == "(" {
+		closing, _ := matchingSQLParen(p.tokens, i+1)
+		i = closing + 1
+	}
+	if p.upper(i) == "AS" || (p.isTop(i) && p.tokens[i].Kind == sqlTokenWord && !isSQLInsertSourceKeyword(p.upper(i))) {
+		if p.upper(i) == "AS" {
+			i++
+		}
+		i++
+	}
+	if p.upper(i) == "(" && p.upper(i+1) != "SELECT" && p.upper(i+1) != "WITH" {
+		closing, _ := matchingSQLParen(p.tokens, i)
+		stmt.HasColumnList = true
+		for j := i + 1; j < closing; j++ {
+			if p.tokens[j].Kind == sqlTokenWord || p.tokens[j].Kind == sqlTokenQuotedIdent {
+				stmt.Columns = append(stmt.Columns, sqlIdentifierName(p.tokens[j]))
+			}
+		}
+		i = closing + 1
+	}
+	switch p.upper(i) {
+	case "SET":
+		stmt.HasColumnList = true
+	case "SELECT", "WITH":
+		stmt.Source = parseSQLTokens(p.tokens[i:], p.dialect)
+		p.subqueryEnd = i
+	case "(":
+		if p.upper(i+1) == "SELECT" || p.upper(i+1) == "WITH" {
+			closing, _ := matchingSQLParen(p.tokens, i)
+			stmt.Source = parseSQLTokens(p.tokens[i+1:closing], p.dialect)
+			p.subqueryEnd = i
+		}
+	}
+}
+
+func isSQLInsertModifier(word string) bool {
+	switch word {
+	case "LOW_PRIORITY", "DELAYED", "HIGH_PRIORITY", "IGNORE", "OR", "ABORT", "FAIL", "ROLLBACK", "REPLACE":
+		return true
+	}
+	return false
+}
+
+func isSQLInsertSourceKeyword(word string) bool {
+	switch word {
+	case "VALUES", "VALUE", "SELECT", "WITH", "SET", "TABLE", "DEFAULT", "ON", "OVERRIDING", "OUTPUT":
+		return true
+	}
+	return false
+}
+
+func (p *sqlParser) parseUpdate(stmt *sqlStatement) {
+	stmt.Kind = sqlStmtUpdate
+	i := 1
+	for p.upper(i) == "LOW_PRIORITY" || p.upper(i) == "IGNORE" || p.upper(i) == "ONLY" {
+		i++
+	}
+	i = p.parseDMLTop(stmt, i)
+	set := p.findTop(i, len(p.tokens), "SET")
+	if set < 0 {
+		set = len(p.tokens)
+	}
+	stmt.Tables = p.parseTableRefs(i, set)
+	if from := p.findTop(set, len(p.tokens), "FROM"); from >= 0 {
+		stmt.From = p.clause(from, p.nextClauseEnd(from+1, map[string]struct{}{"WHERE": {}, "ORDER": {}, "LIMIT": {}, "RETURNING": {}, "OUTPUT": {}, "OPTION": {}}))
+	}
+	p.parseDMLTail(stmt, set)
+}
+
+func (p *sqlParser) parseDelete(stmt *sqlStatement) {
+	stmt.Kind = sqlStmtDelete
+	i := 1
+	for p.upper(i) == "LOW_PRIORITY" || p.upper(i) == "QUICK" || p.upper(i) == "IGNORE" {
+		i++
+	}
+	i = p.parseDMLTop(stmt, i)
+	from := p.findTop(i, len(p.tokens), "FROM")
+	if from == i {
+		end := p.nextClauseEnd(from+1, map[string]struct{}{"USING": {}, "WHERE": {}, "ORDER": {}, "LIMIT": {}, "RETURNING": {}, "OUTPUT": {}})
+		stmt.Tables = p.parseTableRefs(from+1, end)
+		stmt.From = p.clause(from, end)
+	} else if from > i {
+		stmt.Tables = p.parseTableRefs(i, from)
+		end := p.nextClauseEnd(from+1, map[string]struct{}{"WHERE": {}, "ORDER": {}, "LIMIT": {}, "RETURNING": {}})
+		stmt.From = p.clause(from, end)
+	} else {
+		end := p.nextClauseEnd(i, sqlDMLClauseKeywords)
+		stmt.Tables = p.parseTableRefs(i, end)
+	}
+	p.parseDMLTail(stmt, i)
+}
+
+func (p *sqlParser) parseDMLTop(stmt *sqlStatement, i int) int {
+	if p.upper(i) != "TOP" {
+		return i
+	}
+	end := p.skipTop(i)
+	stmt.Limit = p.clause(i, end)
+	return end
+}
+
+func (p *sqlParser) parseDMLTail(stmt *sqlStatement, from int) {
+	if where := p.findTop(from, len(p.tokens), "WHERE"); where >= 0 {
+		end := p.nextClauseEnd(where+1, map[string]struct{}{"ORDER": {}, "LIMIT": {}, "RETURNING": {}})
+		stmt.Where = p.clause(where, end)
+	}
+	if order := p.findTop(from, len(p.tokens), "ORDER"); order >= 0 && p.upper(order+1) == "BY" {
+		end := p.nextClauseEnd(order+2, map[string]struct{}{"LIMIT": {}, "RETURNING": {}})
+		stmt.OrderBy = p.clause(order, end)
+	}
+	if limit := p.findTop(from, len(p.tokens), "LIMIT"); limit >= 0 {
+		stmt.Limit = p.clause(limit, p.nextClauseEnd(limit+1, map[string]struct{}{"RETURNING": {}}))
+	}
+}
+
+func (p *sqlParser) parseCreate(stmt *sqlStatement) {
+	stmt.Kind = sqlStmtCreateOther
+	i := 1
+	for i < len(p.tokens) {
+		word := p.upper(i)
+		switch {
+		case word == "OR" && (p.upper(i+1) == "REPLACE" || p.upper(i+1) == "ALTER"):
+			i += 2
+			continue
+		case word == "TEMPORARY" || word == "TEMP":
+			stmt.Temporary = true
+			i++
+			continue
+		case word == "UNIQUE" || word == "FULLTEXT" || word == "SPATIAL" || word == "ALGORITHM" ||
+			word == "SQL" || word == "UNLOGGED" || word == "GLOBAL" || word == "LOCAL" || word == "CLUSTERED" || word == "NONCLUSTERED" ||
+			word == "EDITIONABLE" || word == "NONEDITIONABLE":
+			if word == "UNIQUE" || word == "FULLTEXT" || word == "SPATIAL" {
+				stmt.ObjectType = word
+			}
+			i++
+			continue
+		case word == "DEFINER":
+			i++
+			for i < len(p.tokens) && !isSQLRoutineKeyword(p.upper(i)) && p.upper(i) != "VIEW" && p.upper(i) != "SQL" {
+				i++
+			}
+			continue
+		}
+		break
+	}
+
+	word := p.upper(i)
+	switch {
+	case word == "TABLE":
+		stmt.Kind = sqlStmtCreateTable
+		stmt.ObjectType = "TABLE"
+		i++
+		if p.upper(i) == "IF" && p.upper(i+1) == "NOT" && p.upper(i+2) == "EXISTS" {
+			stmt.IfNotExists = true
+			i += 3
+		}
+		if name, token, _ := p.readQualifiedName(i); name != "" {
+			stmt.Tables = []sqlTableRef{{Name: name, Token: token}}
+		}
+	case word == "INDEX" || word == "KEY":
+		stmt.Kind = sqlStmtCreateIndex
+		if stmt.ObjectType == "" {
+			stmt.ObjectType = "INDEX"
+		}
+		if p.upper(i+1) == "CONCURRENTLY" {
+			stmt.Concurrently = true
+		}
+		if on := p.findTop(i, len(p.tokens), "ON"); on >= 0 {
+			next := on + 1
+			if p.upper(next) == "ONLY" {
+				next++
+			}
+			if name, token, _ := p.readQualifiedName(next); name != "" {
+				stmt.Tables = []sqlTableRef{{Name: name, Token: token}}
+			}
+		}
+	case isSQLRoutineKeyword(word):
+		stmt.Kind = sqlStmtCreateRoutine
+		stmt.ObjectType = word
+	default:
+		stmt.ObjectType = word
+	}
+}
+
+func isSQLRoutineKeyword(word string) bool {
+	switch word {
+	case "PROCEDURE", "PROC", "FUNCTION", "TRIGGER", "EVENT", "PACKAGE":
+		return true
+	}
+	return false
+}
+
+func (p *sqlParser) parseAlter(stmt *sqlStatement) {
+	stmt.Kind = sqlStmtAlterOther
+	i := 1
+	for p.upper(i) == "ONLINE" || p.upper(i) == "OFFLINE" || p.upper(i) == "IGNORE" {
+		i++
+	}
+	stmt.ObjectType = p.upper(i)
+	if stmt.ObjectType != "TABLE" {
+		return
+	}
+	stmt.Kind = sqlStmtAlterTable
+	i++
+	if p.upper(i) == "IF" && p.upper(i+1) == "EXISTS" {
+		stmt.IfExists = true
+		i += 2
+	}
+	if p.upper(i) == "ONLY" {
+		i++
+	}
+	name, token, next := p.readQualifiedName(i)
+	if name == "" {
+		return
+	}
+	stmt.Tables = []sqlTableRef{{Name: name, Token: token}}
+	if p.upper(next) == "ON" && p.upper(next+1) == "CLUSTER" {
+		next += 3
+	}
+
+	clauseStart := next
+	for j := next; j <= len(p.tokens); j++ {
+		if j == len(p.tokens) || (p.isTop(j) && p.upper(j) == ",") {
+			if j > clauseStart {
+				stmt.AlterClauses = append(stmt.AlterClauses, p.tokens[clauseStart:j])
+			}
+			clauseStart = j + 1
+		}
+	}
+}
+
+func (p *sqlParser) parseDrop(stmt *sqlStatement) {
+	stmt.Kind = sqlStmtDrop
+	i := 1
+	if p.upper(i) == "TEMPORARY" {
+		stmt.Temporary = true
+		i++
+	}
+	stmt.ObjectType = p.upper(i)
+	i++
+	if p.upper(i) == "CONCURRENTLY" {
+		stmt.Concurrently = true
+		i++
+	}
+	if p.upper(i) == "IF" && p.upper(i+1) == "EXISTS" {
+		stmt.IfExists = true
+		i += 2
+	}
+	stmt.Tables = p.parseTableNameList(i, p.nextClauseEnd(i, map[string]struct{}{"ON": {}, "CASCADE": {}, "RESTRICT": {}, "PURGE": {}}))
+}
+
+func (p *sqlParser) collectSubqueries() []*sqlStatement {
+	items := make([]*sqlStatement, 0)
+	for i := 0; i < p.subqueryEnd; i++ {
+		if p.tokens[i].Kind != sqlTokenOperator || p.tokens[i].Text != "(" {
+			continue
+		}
+		if p.upper(i+1) != "SELECT" && p.upper(i+1) != "WITH" {
+			continue
+		}
+		closing, _ := matchingSQLParen(p.tokens, i)
+		items = append(items, parseSQLTokens(p.tokens[i+1:closing], p.dialect))
+		i = closing
+	}
+	return items
+}
+
+func (stmt *sqlStatement) walkSelects(visit func(*sqlStatement)) {
+	if stmt == nil {
+		return
+	}
+	if stmt.Kind == sqlStmtSelect {
+		visit(stmt)
+	}
+	stmt.Source.walkSelects(visit)
+	for _, sub := range stmt.Subqueries {
+		sub.walkSelects(visit)
+	}
+}
+
+func (stmt *sqlStatement) isWrite() bool {
+	switch stmt.Kind {
+	case sqlStmtInsert, sqlStmtReplace, sqlStmtUpdate, sqlStmtDelete, sqlStmtAlterTable, sqlStmtDrop, sqlStmtTruncate:
+		return true
+	}
+	return false
+}
+
+func (stmt *sqlStatement) isDangerousDrop() bool {
+	if stmt.Kind != sqlStmtDrop || stmt.Temporary {
+		return false
+	}
+	switch stmt.ObjectType {
+	case "TABLE", "DATABASE", "SCHEMA", "VIEW", "INDEX":
+		return true
+	}
+	return false
+}
+
+func (stmt *sqlStatement) findAlterDropColumn() (sqlToken, bool) {
+	for _, clause := range stmt.AlterClauses {
+		if len(clause) < 2 || clause[0].Upper != "DROP" {
+			continue
+		}
+		if clause[1].Upper == "COLUMN" {
+			return clause[0], true
+		}
+		if clause[1].Kind == sqlTokenQuotedIdent {
+			return clause[0], true
+		}
+		switch clause[1].Upper {
+		case "INDEX", "KEY", "PRIMARY", "FOREIGN", "CONSTRAINT", "CHECK", "PARTITION", "DEFAULT", "SYSTEM", "UNIQUE", "FULLTEXT", "SPATIAL":
+			continue
+		}
+		if clause[1].Kind == sqlTokenWord {
+			return clause[0], true
+		}
+	}
+	return sqlToken{}, false
+}
+
+func (stmt *sqlStatement) findSelectStar() (sqlToken, bool) {
+	var found sqlToken
+	ok := false
+	stmt.walkSelects(func(sel *sqlStatement) {
+		if ok {
+			return
+		}
+		for _, item := range sel.Projection {
+			if item.Star {
+				found = item.Tokens[len(item.Tokens)-1]
+				ok = true
+				return
+			}
+		}
+	})
+	return found, ok
+}
+
+func (stmt *sqlStatement) hasLimit() bool {
+	return stmt.Limit != nil
+}
+
+func (stmt *sqlStatement) findWhereOneEqOne() (sqlToken, bool) {
+	tokens := stmt.Tokens
+	for i := 0; i+3 < len(tokens); i++ {
+		if tokens[i].Upper != "WHERE" {
+			continue
+		}
+		if tokens[i+1].Kind == sqlTokenNumber && tokens[i+1].Text == "1" && tokens[i+2].Text == "=" &&
+			tokens[i+3].Kind == sqlTokenNumber && tokens[i+3].Text == "1" {
+			return tokens[i], true
+		}
+	}
+	return sqlToken{}, false
+}
+
+func (stmt *sqlStatement) findLeadingWildcardLike(operators ...string) (sqlToken, bool) {
+	tokens := stmt.Tokens
+	for i := 0; i+1 < len(tokens); i++ {
+		if tokens[i].Kind != sqlTokenWord {
+			continue
+		}
+		matched := false
+		for _, op := range operators {
+			if tokens[i].Upper == op {
+				matched = true
+				break
+			}
+		}
+		if !matched || tokens[i+1].Kind != sqlTokenString {
+			continue
+		}
+		if strings.HasPrefix(sqlStringValue(tokens[i+1]), "%") {
+			token := tokens[i]
+			token.End = tokens[i+1].End
+			return token, true
+		}
+	}
+	return sqlToken{}, false
+}
+
+func (stmt *sqlStatement) findOrderByRand() (sqlToken, bool) {
+	tokens := stmt.Tokens
+	for i := 0; i+3 < len(tokens); i++ {
+		if tokens[i].Upper == "ORDER" && tokens[i+1].Upper == "BY" && tokens[i+2].Upper == "RAND" && tokens[i+3].Text == "(" {
+			return tokens[i+2], true
+		}
+	}
+	return sqlToken{}, false
+}
+
+func (stmt *sqlStatement) findTokenSequence(words ...string) (sqlToken, bool) {
+	tokens := stmt.Tokens
+	for i := 0; i+len(words) <= len(tokens); i++ {
+		matched := true
+		for j, word := range words {
+			if tokens[i+j].Kind != sqlTokenWord || tokens[i+j].Upper != word {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			token := tokens[i]
+			token.End = tokens[i+len(words)-1].End
+			return token, true
+		}
+	}
+	return sqlToken{}, false
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestParseSQLStatementKindsAndTables(t *testing.T) {
+	cases := []struct {
+		sql    string
+		kind   sqlStatementKind
+		tables []string
+	}{
+		{"SELECT id FROM users u JOIN orders o ON o.uid = u.id", sqlStmtSelect, []string{"users", "orders"}},
+		{"UPDATE `shop`.`users` SET status = 1", sqlStmtUpdate, []string{"shop.users"}},
+		{"DELETE FROM orders WHERE id = 1", sqlStmtDelete, []string{"orders"}},
+		{"DELETE o FROM orders o JOIN users u ON u.id = o.uid", sqlStmtDelete, []string{"o"}},
+		{"INSERT IGNORE INTO logs (id, msg) VALUES (1, 'a')", sqlStmtInsert, []string{"logs"}},
+		{"CREATE TABLE IF NOT EXISTS t (id INT)", sqlStmtCreateTable, []string{"t"}},
+		{"ALTER TABLE t ADD COLUMN c INT, DROP INDEX idx_a", sqlStmtAlterTable, []string{"t"}},
+		{"DROP TABLE IF EXISTS a, b", sqlStmtDrop, []string{"a", "b"}},
+		{"TRUNCATE TABLE t", sqlStmtTruncate, []string{"t"}},
+		{"START TRANSACTION", sqlStmtBegin, nil},
+		{"CREATE DEFINER=`root`@`%` PROCEDURE p() BEGIN SELECT 1; END", sqlStmtCreateRoutine, nil},
+	}
+
+	for _, tc := range cases {
+		stmt := parseSQLStatement(tc.sql, mysqlDialect)
+		if stmt.Kind != tc.kind {
+			t.Fatalf("%q: kind mismatch, got=%s want=%s", tc.sql, stmt.Kind, tc.kind)
+		}
+		if len(stmt.Tables) != len(tc.tables) {
+			t.Fatalf("%q: tables mismatch, got=%+v want=%v", tc.sql, stmt.Tables, tc.tables)
+		}
+		for i, name := range tc.tables {
+			if stmt.Tables[i].Name != name {
+				t.Fatalf("%q: table %d mismatch, got=%s want=%s", tc.sql, i, stmt.Tables[i].Name, name)
+			}
+		}
+	}
+}
+
+func TestParseSQLStatementTopLevelWhere(t *testing.T) {
+	cases := map[string]bool{
+		"UPDATE t SET a = (SELECT b FROM s WHERE s.id = 1)": false,
+		"UPDATE t SET note = ' WHERE id = 1 '":              false,
+		"UPDATE t SET a = 1 /* WHERE id = 1 */":             false,
+		"UPDATE t SET a = 1 -- WHERE id = 1":                false,
+		"UPDATE t SET a = 1 WHERE id IN (SELECT id FROM s)": true,
+		"DELETE FROM t WHERE `where` = 1":                   true,
+	}
+	for sql, want := range cases {
+		stmt := parseSQLStatement(sql, mysqlDialect)
+		if got := stmt.Where != nil; got != want {
+			t.Fatalf("%q: top-level WHERE mismatch, got=%v want=%v", sql, got, want)
+		}
+	}
+}
+
+func TestParseSQLStatementTruncatedCTE(t *testing.T) {
+	stmt := parseSQLStatement("WITH a AS (", mysqlDialect)
+	if len(stmt.CTENames) != 1 || stmt.CTENames[0] != "a" {
+		t.Fatalf("unexpected truncated cte parse: %+v", stmt)
+	}
+
+	full := "WITH a AS (SELECT id FROM t), b AS (SELECT id FROM a) SELECT * FROM b"
+	for end := 1; end <= len(full); end++ {
+		parseSQLStatement(full[:end], mysqlDialect)
+	}
+	for _, engine := range []DBEngine{EngineMySQL, EnginePostgreSQL, EngineSQLServer, EngineOracle, EngineSQLite, EngineClickHouse, EngineCQL} {
+		AnalyzeByEngine(engine, "WITH a AS (", AnalyzeOptions{})
+	}
+}
+
+func TestMatchingSQLParenReportsUnbalanced(t *testing.T) {
+	tokens := significantSQLTokens(tokenizeSQL("f((a), b) + (c", mysqlDialect))
+	if closing, ok := matchingSQLParen(tokens, 1); !ok || tokens[closing].Text != ")" || closing != 7 {
+		t.Fatalf("unexpected match: %d %v", closing, ok)
+	}
+	if closing, ok := matchingSQLParen(tokens, 9); ok || closing != len(tokens) {
+		t.Fatalf("unbalanced paren should report len(tokens), got %d %v", closing, ok)
+	}
+}
+
+func TestParseSQLStatementProjectionAndLimit(t *testing.T) {
+	stmt := parseSQLStatement("SELECT id FROM (SELECT * FROM t) x LIMIT 10", mysqlDialect)
+	if len(stmt.Projection) != 1 || stmt.Projection[0].Star {
+		t.Fatalf("unexpected top-level projection: %+v", stmt.Projection)
+	}
+	if !stmt.hasLimit() {
+		t.Fatalf("expected top-level LIMIT")
+	}
+	if _, found := stmt.findSelectStar(); !found {
+		t.Fatalf("expected SELECT * inside subquery to be found")
+	}
+
+	inner := parseSQLStatement("SELECT id FROM t WHERE id IN (SELECT uid FROM s LIMIT 5)", mysqlDialect)
+	if inner.hasLimit() {
+		t.Fatalf("LIMIT inside subquery should not count as top-level LIMIT")
+	}
+
+	union := parseSQLStatement("SELECT a FROM t UNION ALL SELECT b.* FROM b LIMIT 3", mysqlDialect)
+	if !union.hasLimit() {
+		t.Fatalf("expected LIMIT applying to union")
+	}
+	if _, found := union.findSelectStar(); !found {
+		t.Fatalf("expected qualified star in union branch to be found")
+	}
+}
+
+func TestParseSQLStatementTopClauseIsSQLServerOnly(t *testing.T) {
+	column := parseSQLStatement("SELECT top, id FROM scores", mysqlDialect)
+	if column.hasLimit() || len(column.Projection) != 2 {
+		t.Fatalf("a column named top should not be a TOP clause: limit=%+v projection=%+v", column.Limit, column.Projection)
+	}
+	if issues := AnalyzeByEngine(EngineMySQL, "SELECT top, id FROM scores;", AnalyzeOptions{}).Issues; issueRules(issues)["select_without_limit"] != 1 {
+		t.Fatalf("expected select_without_limit for a column named top, got %+v", issues)
+	}
+	if top := parseSQLStatement("SELECT TOP (10) PERCENT id FROM scores", sqlserverDialect); !top.hasLimit() || len(top.Projection) != 1 {
+		t.Fatalf("expected sqlserver TOP clause, got limit=%+v projection=%+v", top.Limit, top.Projection)
+	}
+
+	for _, dialect := range []sqlDialect{mysqlDialect, postgresDialect, sqlserverDialect, oracleDialect, sqliteDialect, clickhouseDialect, cqlDialect} {
+		parseSQLStatement("SELECT TOP", dialect)
+		parseSQLStatement("SELECT TOP (", dialect)
+	}
+}
+
+func TestTokenizeSQLTracksOffsets(t *testing.T) {
+	text := "SELECT 'a;b', `x` -- tail\nFROM t"
+	tokens := tokenizeSQL(text, mysqlDialect)
+	for _, token := range tokens {
+		if text[token.Start:token.End] != token.Text {
+			t.Fatalf("token offset mismatch: %+v", token)
+		}
+	}
+	if tokens[1].Kind != sqlTokenString || tokens[3].Kind != sqlTokenQuotedIdent || tokens[4].Kind != sqlTokenComment {
+		t.Fatalf("unexpected token kinds: %+v", tokens)
+	}
+}
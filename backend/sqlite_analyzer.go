@@ -0,0 +1,452 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type sqliteTableRebuild struct {
+	Table    string
+	NewTable string
+	Create   *RuleStatement
+	Drop     RuleStatement
+	Rename   RuleStatement
+}
+
+func BuiltInSQLiteRules() []RuleDefinition {
+	return ruleDefinitions(RegisteredRules(EngineSQLite))
+}
+
+func sqliteRules() []Rule {
+	return []Rule{
+		emptyInputRule("SQL 内容为空", "请上传 SQLite 迁移脚本或粘贴 SQL 语句后再检查"),
+		tooManyStatementsRule(60, "建议按业务模块拆分后分批审核与执行"),
+		sqlMissingTerminatorRule(),
+		sqlFullwidthTerminatorRule(),
+		newStatementRule(
+			RuleDefinition{Code: "sqlite_dangerous_drop", Level: LevelError, Category: "高危DDL", Description: "检测到 DROP 高危对象删除（表重建中的 DROP 除外）"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				if !stmt.SQL.isDangerousDrop() {
+					return Issue{}, false
+				}
+				for _, rebuild := range sqliteTableRebuilds(ctx) {
+					if rebuild.Drop.Index == stmt.Index {
+						return Issue{}, false
+					}
+				}
+				return Issue{Message: "检测到 DROP 高风险语句", Suggestion: "生产建议禁用 DROP；确需执行请先备份数据库文件并审批"}, true
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "sqlite_update_without_where", Level: LevelError, Category: "DML安全", Description: "UPDATE 无 WHERE"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				return Issue{Message: "UPDATE 缺少 WHERE 条件", Suggestion: "请添加精确 WHERE 条件，避免全表更新"}, stmt.SQL.Kind == sqlStmtUpdate && stmt.SQL.Where == nil
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "sqlite_delete_without_where", Level: LevelError, Category: "DML安全", Description: "DELETE 无 WHERE"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				return Issue{Message: "DELETE 缺少 WHERE 条件", Suggestion: "请添加 WHERE 条件，或按 rowid 范围分批删除"}, stmt.SQL.Kind == sqlStmtDelete && stmt.SQL.Where == nil
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "sqlite_unsupported_alter", Level: LevelError, Category: "DDL兼容", Description: "SQLite 不支持的 ALTER TABLE 写法"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				token, reason, found := stmt.SQL.findSQLiteUnsupportedAlter()
+				return ctx.Source.locate(Issue{
+					Message:    "SQLite 的 ALTER TABLE 仅支持 RENAME TO、RENAME COLUMN、ADD COLUMN 与 DROP COLUMN：" + reason,
+					Suggestion: "改用 12 步表重建：新建目标结构的表 → INSERT INTO ... SELECT 复制数据 → DROP 旧表 → RENAME 新表 → 重建索引、触发器与视图",
+				}, token.span()), found
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "sqlite_alter_drop_column", Level: LevelWarning, Category: "DDL兼容", Description: "DROP COLUMN 需 SQLite 3.35.0+ 且受约束限制"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				token, found := stmt.SQL.findAlterDropColumn()
+				return ctx.Source.locate(Issue{
+					Message:    "ALTER TABLE DROP COLUMN 仅 SQLite 3.35.0 及以上支持，且列为主键、UNIQUE、被索引、外键或被视图/触发器引用时会执行失败",
+					Suggestion: "确认客户端与服务端内置的 SQLite 版本；需兼容旧版本时改用 12 步表重建",
+				}, token.span()), found
+			},
+		),
+		sqliteTableRebuildRule(),
+		newStatementRule(
+			RuleDefinition{Code: "sqlite_pragma_foreign_keys", Level: LevelWarning, Category: "约束完整性", Description: "PRAGMA foreign_keys 关闭后未恢复或在事务内设置"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				name, value, token, found := stmt.SQL.sqlitePragma()
+				if !found || name != "FOREIGN_KEYS" || value == "" {
+					return Issue{}, false
+				}
+				if sqliteInTransaction(ctx, stmt.Index) {
+					return ctx.Source.locate(Issue{
+						Message:    "PRAGMA foreign_keys 在事务内设置不生效（SQLite 会静默忽略）",
+						Suggestion: "在 BEGIN 之前关闭、COMMIT 之后恢复外键检查",
+					}, token.span()), true
+				}
+				if sqlitePragmaEnabled(value) || sqliteForeignKeysRestored(ctx, stmt.Index) {
+					return Issue{}, false
+				}
+				return ctx.Source.locate(Issue{
+					Message:    "PRAGMA foreign_keys 关闭后脚本中未恢复，后续写入将不再校验外键约束",
+					Suggestion: "变更完成后执行 PRAGMA foreign_key_check 校验，并执行 PRAGMA foreign_keys = ON 恢复",
+				}, token.span()), true
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "sqlite_pragma_journal_mode", Level: LevelWarning, Category: "持久性", Description: "PRAGMA journal_mode 切换为 OFF / MEMORY / WAL"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				name, value, token, found := stmt.SQL.sqlitePragma()
+				if !found || name != "JOURNAL_MODE" {
+					return Issue{}, false
+				}
+				issue := Issue{}
+				switch value {
+				case "OFF", "MEMORY":
+					issue = Issue{
+						Message:    fmt.Sprintf("journal_mode = %s 下事务中途崩溃或断电可能导致数据库文件损坏，且 ROLLBACK 行为不可靠", value),
+						Suggestion: "迁移与线上写入保持 DELETE 或 WAL 模式，不要为提速关闭回滚日志",
+					}
+				case "WAL":
+					issue = Issue{
+						Message:    "journal_mode = WAL 是持久化设置，会生成 -wal / -shm 文件，网络文件系统上不可用，且跨 ATTACH 库的事务不再保证原子性",
+						Suggestion: "确认部署环境与备份脚本会同时处理 -wal / -shm 文件，并由应用启动时统一设置",
+					}
+				default:
+					return Issue{}, false
+				}
+				return ctx.Source.locate(issue, token.span()), true
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "sqlite_insert_or_replace", Level: LevelWarning, Category: "DML安全", Description: "INSERT OR REPLACE / REPLACE 先删后插"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				token, found := stmt.SQL.findTokenSequence("OR", "REPLACE")
+				if stmt.SQL.Kind == sqlStmtReplace {
+					token, found = stmt.SQL.Tokens[0], true
+				}
+				return ctx.Source.locate(Issue{
+					Message:    "INSERT OR REPLACE 冲突时会先删除旧行再插入：未指定的列被重置为默认值，rowid 改变，并触发外键 ON DELETE 级联",
+					Suggestion: "改用 INSERT ... ON CONFLICT (...) DO UPDATE SET ...（SQLite 3.24.0+）只更新需要的列",
+				}, token.span()), found
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "sqlite_without_rowid", Level: LevelInfo, Category: "表结构", Description: "WITHOUT ROWID 表的适用性与主键要求"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				token, found := stmt.SQL.findTokenSequence("WITHOUT", "ROWID")
+				if !found || stmt.SQL.Kind != sqlStmtCreateTable {
+					return Issue{}, false
+				}
+				issue := Issue{
+					Message:    "WITHOUT ROWID 表需 SQLite 3.8.2+，适合非整数主键且单行较小的场景；单行超过页大小约 1/20 时性能反而下降，且不支持 AUTOINCREMENT",
+					Suggestion: "确认主键为业务访问路径且行数据较小，否则保留默认 rowid 表",
+				}
+				if _, hasPrimaryKey := stmt.SQL.findTokenSequence("PRIMARY", "KEY"); !hasPrimaryKey {
+					issue = Issue{
+						Message:    "WITHOUT ROWID 表必须声明 PRIMARY KEY，否则建表会失败",
+						Suggestion: "为该表补充 PRIMARY KEY，或去掉 WITHOUT ROWID",
+					}
+				}
+				return ctx.Source.locate(issue, token.span()), true
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "sqlite_attach_database", Level: LevelWarning, Category: "跨库操作", Description: "ATTACH DATABASE 跨文件操作"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				if stmt.SQL.Kind != sqlStmtOther || stmt.SQL.Tokens[0].Upper != "ATTACH" {
+					return Issue{}, false
+				}
+				return ctx.Source.locate(Issue{
+					Message:    "ATTACH DATABASE 依赖运行环境的文件路径，不存在时会静默创建空库；WAL 模式下跨库事务不保证原子性",
+					Suggestion: "确认目标文件路径与权限，跨库写入完成后 DETACH，并避免在 WAL 模式下依赖跨库事务一致性",
+				}, stmt.SQL.Tokens[0].span()), true
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "sqlite_select_star", Level: LevelWarning, Category: "查询规范", Description: "SELECT * 可维护性与性能风险"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				token, found := stmt.SQL.findSelectStar()
+				for _, rebuild := range sqliteTableRebuilds(ctx) {
+					if stmt.SQL.Kind == sqlStmtInsert && sqliteReferencesTable(stmt.SQL, rebuild.NewTable) {
+						return Issue{}, false
+					}
+				}
+				return ctx.Source.locate(Issue{Message: "SELECT * 可能带来性能和兼容风险", Suggestion: "建议显式列出字段"}, token.span()), found
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "sqlite_select_without_limit", Level: LevelInfo, Category: "查询规范", Description: "SELECT 未设置 LIMIT"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				return Issue{Message: "SELECT 未检测到 LIMIT", Suggestion: "在线查询建议补充 LIMIT"}, stmt.SQL.Kind == sqlStmtSelect && stmt.SQL.From != nil && !stmt.SQL.hasLimit()
+			},
+		),
+		riskyWritesWithoutTransactionRule("建议使用 BEGIN IMMEDIATE/COMMIT 包裹，既保证一致性，也能显著减少逐条提交的 fsync 开销"),
+		engineDetectionAmbiguousRule(),
+		invalidSuppressionRule(),
+	}
+}
+
+func AnalyzeSQLiteWithOptions(content string, options AnalyzeOptions) CheckResponse {
+	ctx := newSQLRuleContextFromSpans(EngineSQLite, content, splitSQLiteStatementSpans(content), sqliteDialect)
+	for _, stmt := range ctx.Statements {
+		if stmt.SQL.Kind == sqlStmtCreateRoutine {
+			ctx.ContainsRoutine = true
+		}
+	}
+	return analyzeWithRules(ctx, options, "请输入待审核 SQL 后重试")
+}
+
+func splitSQLiteStatementSpans(content string) []sqlStatementSpan {
+	items := make([]sqlStatementSpan, 0)
+	tokens := significantSQLTokens(tokenizeSQL(content, sqliteDialect))
+	start, end := -1, 0
+	trigger := false
+	nesting := 0
+
+	flush := func() {
+		if start >= 0 {
+			items = append(items, sqlStatementSpan{Text: content[start:end], Span: sourceSpan{Start: start, End: end}})
+		}
+		start = -1
+	}
+
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		if token.Kind == sqlTokenOperator && (token.Text == ";" || token.Text == "；") && nesting == 0 {
+			flush()
+			continue
+		}
+		if start < 0 {
+			start = token.Start
+			trigger = isSQLiteTriggerStart(tokens[i:min(i+4, len(tokens))])
+			nesting = 0
+		}
+		if trigger && token.Kind == sqlTokenWord {
+			switch token.Upper {
+			case "BEGIN", "CASE":
+				nesting++
+			case "END":
+				nesting = max(nesting-1, 0)
+			}
+		}
+		end = token.End
+	}
+
+	flush()
+	return items
+}
+
+func isSQLiteTriggerStart(tokens []sqlToken) bool {
+	if len(tokens) < 2 || tokens[0].Upper != "CREATE" {
+		return false
+	}
+	next := 1
+	if tokens[next].Upper == "TEMP" || tokens[next].Upper == "TEMPORARY" {
+		next++
+	}
+	return next < len(tokens) && tokens[next].Upper == "TRIGGER"
+}
+
+func (stmt *sqlStatement) sqlitePragma() (string, string, sqlToken, bool) {
+	if len(stmt.Tokens) < 2 || stmt.Tokens[0].Upper != "PRAGMA" {
+		return "", "", sqlToken{}, false
+	}
+	i := 1
+	if i+2 < len(stmt.Tokens) && stmt.Tokens[i+1].Text == "." {
+		i += 2
+	}
+	name := strings.ToUpper(sqlIdentifierName(stmt.Tokens[i]))
+	value := ""
+	if i+2 < len(stmt.Tokens) && (stmt.Tokens[i+1].Text == "=" || stmt.Tokens[i+1].Text == "(") {
+		value = strings.ToUpper(sqlStringValue(stmt.Tokens[i+2]))
+		if stmt.Tokens[i+2].Kind != sqlTokenString {
+			value = strings.ToUpper(stmt.Tokens[i+2].Text)
+		}
+	}
+	token := stmt.Tokens[0]
+	token.End = stmt.Tokens[len(stmt.Tokens)-1].End
+	return name, value, token, true
+}
+
+func sqlitePragmaEnabled(value string) bool {
+	switch value {
+	case "ON", "1", "TRUE", "YES":
+		return true
+	}
+	return false
+}
+
+func sqliteForeignKeysRestored(ctx *RuleContext, index int) bool {
+	for _, stmt := range ctx.Statements {
+		if stmt.Index <= index {
+			continue
+		}
+		if name, value, _, found := stmt.SQL.sqlitePragma(); found && name == "FOREIGN_KEYS" && sqlitePragmaEnabled(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func sqliteInTransaction(ctx *RuleContext, index int) bool {
+	inTransaction := false
+	for _, stmt := range ctx.Statements {
+		if stmt.Index >= index {
+			break
+		}
+		switch stmt.SQL.Kind {
+		case sqlStmtBegin:
+			inTransaction = true
+		case sqlStmtCommit:
+			inTransaction = false
+		case sqlStmtRollback:
+			if !stmt.SQL.hasTopLevelWord("TO") {
+				inTransaction = false
+			}
+		}
+	}
+	return inTransaction
+}
+
+func (stmt *sqlStatement) findSQLiteUnsupportedAlter() (sqlToken, string, bool) {
+	if stmt.Kind != sqlStmtAlterTable || len(stmt.AlterClauses) == 0 {
+		return sqlToken{}, "", false
+	}
+	if len(stmt.AlterClauses) > 1 {
+		return stmt.AlterClauses[1][0], "每条 ALTER TABLE 只能包含一个操作", true
+	}
+	clause := stmt.AlterClauses[0]
+	first, second := clause[0].Upper, ""
+	if len(clause) > 1 {
+		second = clause[1].Upper
+	}
+	switch first {
+	case "RENAME":
+		return sqlToken{}, "", false
+	case "ALTER", "MODIFY", "CHANGE":
+		return clause[0], "不支持修改列类型、默认值或 NOT NULL 约束", true
+	case "ADD":
+		switch second {
+		case "CONSTRAINT", "PRIMARY", "UNIQUE", "FOREIGN", "CHECK", "INDEX", "KEY":
+			return clause[0], "不支持添加表约束，请使用 CREATE UNIQUE INDEX 或表重建", true
+		}
+		if _, found := findSQLWordSequence(clause, "PRIMARY", "KEY"); found {
+			return clause[0], "ADD COLUMN 不能声明 PRIMARY KEY", true
+		}
+		if token, found := findSQLWordSequence(clause, "UNIQUE"); found {
+			return token, "ADD COLUMN 不能声明 UNIQUE，请在加列后单独 CREATE UNIQUE INDEX", true
+		}
+		if token, found := findSQLWordSequence(clause, "NOT", "NULL"); found {
+			if _, hasDefault := findSQLWordSequence(clause, "DEFAULT"); !hasDefault {
+				return token, "ADD COLUMN 声明 NOT NULL 时必须带非 NULL 的 DEFAULT", true
+			}
+		}
+		return sqlToken{}, "", false
+	case "DROP":
+		switch second {
+		case "CONSTRAINT", "PRIMARY", "FOREIGN", "CHECK", "INDEX", "KEY", "UNIQUE", "DEFAULT":
+			return clause[0], "不支持删除约束，请使用 DROP INDEX 或表重建", true
+		}
+		return sqlToken{}, "", false
+	}
+	return clause[0], fmt.Sprintf("不支持 %s 操作", first), true
+}
+
+func findSQLWordSequence(tokens []sqlToken, words ...string) (sqlToken, bool) {
+	return (&sqlStatement{Tokens: tokens}).findTokenSequence(words...)
+}
+
+func sqliteTableRebuilds(ctx *RuleContext) []sqliteTableRebuild {
+	items := make([]sqliteTableRebuild, 0)
+	for i, stmt := range ctx.Statements {
+		if stmt.SQL.Kind != sqlStmtAlterTable || len(stmt.SQL.AlterClauses) != 1 || len(stmt.SQL.Tables) == 0 {
+			continue
+		}
+		clause := stmt.SQL.AlterClauses[0]
+		if len(clause) < 3 || clause[0].Upper != "RENAME" || clause[1].Upper != "TO" {
+			continue
+		}
+		source, target := catalogKey(stmt.SQL.Tables[0].Name), catalogKey(sqlIdentifierName(clause[2]))
+		for j := i - 1; j >= 0; j-- {
+			drop := ctx.Statements[j]
+			if drop.SQL.Kind != sqlStmtDrop || drop.SQL.ObjectType != "TABLE" || !sqliteReferencesTable(drop.SQL, target) {
+				continue
+			}
+			rebuild := sqliteTableRebuild{Table: target, NewTable: source, Drop: drop, Rename: stmt}
+			for k := j - 1; k >= 0; k-- {
+				if create := ctx.Statements[k]; create.SQL.Kind == sqlStmtCreateTable && sqliteReferencesTable(create.SQL, source) {
+					rebuild.Create = &ctx.Statements[k]
+					break
+				}
+			}
+			items = append(items, rebuild)
+			break
+		}
+	}
+	return items
+}
+
+func sqliteReferencesTable(stmt *sqlStatement, table string) bool {
+	for _, ref := range stmt.Tables {
+		if catalogKey(ref.Name) == table {
+			return true
+		}
+	}
+	return false
+}
+
+func sqliteTableRebuildRule() Rule {
+	return newScriptRule(
+		RuleDefinition{Code: "sqlite_table_rebuild", Level: LevelWarning, Category: "DDL变更", Description: "检测到 12 步表重建（新建表 → 复制 → DROP → RENAME）"},
+		func(ctx *RuleContext) []Issue {
+			issues := make([]Issue, 0)
+			for _, rebuild := range sqliteTableRebuilds(ctx) {
+				first := rebuild.Drop.Index
+				if rebuild.Create != nil {
+					first = rebuild.Create.Index
+				}
+				missing := make([]string, 0, 3)
+				if !sqliteForeignKeysDisabledBefore(ctx, first) {
+					missing = append(missing, "事务外先执行 PRAGMA foreign_keys = OFF")
+				}
+				if !sqliteInTransaction(ctx, first) || !sqliteInTransaction(ctx, rebuild.Rename.Index+1) {
+					missing = append(missing, "BEGIN/COMMIT 事务包裹")
+				}
+				if !sqliteHasPragmaAfter(ctx, rebuild.Rename.Index, "FOREIGN_KEY_CHECK") {
+					missing = append(missing, "完成后执行 PRAGMA foreign_key_check")
+				}
+				message := fmt.Sprintf("检测到表 %s 的重建（新建表 → 复制数据 → DROP → RENAME），会整表复制数据，原表上的索引、触发器需重新创建", rebuild.Table)
+				if len(missing) > 0 {
+					message += "；缺少：" + strings.Join(missing, "、")
+				}
+				issues = append(issues, ctx.Source.location(rebuild.Rename.Span).apply(Issue{
+					StatementIndex: rebuild.Rename.Index,
+					Message:        message,
+					Suggestion:     "按官方 12 步流程执行：关闭外键 → BEGIN → 建新表并复制 → DROP 旧表 → RENAME → 重建索引/触发器/视图 → foreign_key_check → COMMIT → 恢复外键",
+				}))
+			}
+			return issues
+		},
+	)
+}
+
+func sqliteForeignKeysDisabledBefore(ctx *RuleContext, index int) bool {
+	for _, stmt := range ctx.Statements {
+		if stmt.Index >= index {
+			break
+		}
+		if name, value, _, found := stmt.SQL.sqlitePragma(); found && name == "FOREIGN_KEYS" && value != "" && !sqlitePragmaEnabled(value) && !sqliteInTransaction(ctx, stmt.Index) {
+			return true
+		}
+	}
+	return false
+}
+
+func sqliteHasPragmaAfter(ctx *RuleContext, index int, pragma string) bool {
+	for _, stmt := range ctx.Statements {
+		if name, _, _, found := stmt.SQL.sqlitePragma(); found && stmt.Index > index && name == pragma {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitSQLiteStatementSpansKeepsTriggerBodies(t *testing.T) {
+	script := strings.Join([]string{
+		"CREATE TRIGGER audit_users AFTER UPDATE ON users BEGIN",
+		"  INSERT INTO audit (v) VALUES (CASE WHEN new.name IS NULL THEN 'x' ELSE new.name END);",
+		"  UPDATE stats SET n = n + 1;",
+		"END;",
+		"INSERT OR REPLACE INTO [user settings] (id, v) VALUES (1, 'a;b');",
+	}, "\n")
+	spans := splitSQLiteStatementSpans(script)
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 statements, got %d: %+v", len(spans), spans)
+	}
+	if !strings.HasSuffix(spans[0].Text, "END") {
+		t.Fatalf("trigger body should stay in one statement, got %q", spans[0].Text)
+	}
+	insert := parseSQLStatementAt(script, spans[1].Span, sqliteDialect)
+	if insert.Kind != sqlStmtInsert || insert.Tables[0].Name != "user settings" {
+		t.Fatalf("unexpected INSERT OR REPLACE parse: %+v", insert)
+	}
+}
+
+func TestAnalyzeSQLiteRules(t *testing.T) {
+	script := strings.Join([]string{
+		"PRAGMA foreign_keys = OFF;",
+		"BEGIN;",
+		"CREATE TABLE users_new (id INTEGER PRIMARY KEY, name TEXT NOT NULL);",
+		"INSERT INTO users_new SELECT * FROM users;",
+		"DROP TABLE users;",
+		"ALTER TABLE users_new RENAME TO users;",
+		"COMMIT;",
+		"ALTER TABLE orders ALTER COLUMN total TYPE REAL;",
+		"ALTER TABLE orders ADD COLUMN status TEXT NOT NULL;",
+		"ALTER TABLE orders DROP COLUMN legacy;",
+		"PRAGMA journal_mode = WAL;",
+		"REPLACE INTO kv (k, v) VALUES ('a', 'b');",
+		"CREATE TABLE tags (name TEXT) WITHOUT ROWID;",
+		"ATTACH DATABASE 'archive.db' AS archive;",
+		"DROP TABLE archive.logs;",
+	}, "\n")
+
+	issues := AnalyzeByEngine(EngineSQLite, script, AnalyzeOptions{}).Issues
+	rules := issuesByStatement(issues)
+	messages := messagesByStatement(issues)
+	rules.expect(t, 1, "sqlite_pragma_foreign_keys", true)
+	rules.expect(t, 4, "sqlite_select_star", false)
+	rules.expect(t, 5, "sqlite_dangerous_drop", false)
+	rules.expect(t, 6, "sqlite_table_rebuild", true)
+	if !strings.Contains(messages[6], "foreign_key_check") || strings.Contains(messages[6], "事务包裹") {
+		t.Fatalf("unexpected rebuild message: %s", messages[6])
+	}
+	rules.expect(t, 8, "sqlite_unsupported_alter", true)
+	rules.expect(t, 9, "sqlite_unsupported_alter", true)
+	rules.expect(t, 10, "sqlite_alter_drop_column", true)
+	rules.expect(t, 10, "sqlite_unsupported_alter", false)
+	rules.expect(t, 11, "sqlite_pragma_journal_mode", true)
+	rules.expect(t, 12, "sqlite_insert_or_replace", true)
+	rules.expect(t, 13, "sqlite_without_rowid", true)
+	if !strings.Contains(messages[13], "PRIMARY KEY") {
+		t.Fatalf("WITHOUT ROWID without primary key should be reported, got %s", messages[13])
+	}
+	rules.expect(t, 14, "sqlite_attach_database", true)
+	rules.expect(t, 15, "sqlite_dangerous_drop", true)
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"strings"
+)
+
+func BuiltInSQLServerRules() []RuleDefinition {
+	return ruleDefinitions(RegisteredRules(EngineSQLServer))
+}
+
+func sqlserverRules() []Rule {
+	return []Rule{
+		emptyInputRule("SQL 内容为空", "请上传 T-SQL 脚本或粘贴 SQL 语句后再检查"),
+		tooManyStatementsRule(60, "建议按 GO 批次或业务模块拆分后分批审核与执行"),
+		sqlFullwidthTerminatorRule(),
+		newStatementRule(
+			RuleDefinition{Code: "mssql_dangerous_drop", Level: LevelError, Category: "高危DDL", Description: "检测到 DROP 高危对象删除"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				return Issue{Message: "检测到 DROP 高风险语句", Suggestion: "生产建议禁用 DROP；确需执行请先完整备份并审批"}, stmt.SQL.isDangerousDrop() && !stmt.SQL.targetsOnlyTempTables()
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "mssql_dangerous_truncate", Level: LevelError, Category: "高危DDL", Description: "检测到 TRUNCATE 全表清理"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				return Issue{Message: "检测到 TRUNCATE TABLE 语句", Suggestion: "TRUNCATE 不逐行记录日志且会重置 IDENTITY，请确认窗口期与恢复方案"}, stmt.SQL.Kind == sqlStmtTruncate && !stmt.SQL.targetsOnlyTempTables()
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "mssql_alter_drop_column", Level: LevelWarning, Category: "DDL兼容", Description: "检测到 DROP COLUMN 结构破坏性变更"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				token, found := stmt.SQL.findAlterDropColumn()
+				return ctx.Source.locate(Issue{Message: "检测到 ALTER TABLE DROP COLUMN", Suggestion: "请确认上下游代码兼容，并提前完成历史数据归档"}, token.span()), found
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "mssql_update_without_where", Level: LevelError, Category: "DML安全", Description: "UPDATE 缺少顶层 WHERE 条件"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				return Issue{Message: "UPDATE 缺少 WHERE 条件", Suggestion: "请添加精确 WHERE 条件；JOIN 的 ON 条件只决定行如何关联，不能替代 WHERE 限定更新范围"}, stmt.SQL.Kind == sqlStmtUpdate && stmt.SQL.Where == nil
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "mssql_delete_without_where", Level: LevelError, Category: "DML安全", Description: "DELETE 缺少顶层 WHERE 条件"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				return Issue{Message: "DELETE 缺少 WHERE 条件", Suggestion: "请添加 WHERE 条件，或改为 DELETE TOP (n) 分批删除"}, stmt.SQL.Kind == sqlStmtDelete && stmt.SQL.Where == nil
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "mssql_select_star", Level: LevelWarning, Category: "查询规范", Description: "SELECT * 可维护性与性能风险"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				token, found := stmt.SQL.findSelectStar()
+				return ctx.Source.locate(Issue{Message: "SELECT * 可能带来性能和兼容风险", Suggestion: "建议显式列出字段，减少 I/O 并降低结构变更影响"}, token.span()), found
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "mssql_select_without_top", Level: LevelInfo, Category: "查询规范", Description: "SELECT 未设置 TOP 或 OFFSET ... FETCH"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				_, into := stmt.SQL.findSelectInto()
+				return Issue{Message: "SELECT 未检测到 TOP 或 OFFSET ... FETCH", Suggestion: "在线查询建议补充 TOP (n) 或 ORDER BY ... OFFSET ... FETCH NEXT，避免大结果集拖慢实例"}, stmt.SQL.Kind == sqlStmtSelect && stmt.SQL.From != nil && !stmt.SQL.hasLimit() && !into
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "mssql_select_into", Level: LevelWarning, Category: "DDL兼容", Description: "SELECT ... INTO 隐式创建持久表"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				token, found := stmt.SQL.findSelectInto()
+				return ctx.Source.locate(Issue{Message: "SELECT ... INTO 会按查询结果隐式建表，字段类型、约束与索引均不可控", Suggestion: "建议先显式 CREATE TABLE，再使用 INSERT INTO ... SELECT；临时表（#tmp）不受此限制"}, token.span()), found
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "mssql_nolock_hint", Level: LevelWarning, Category: "查询一致性", Description: "WITH (NOLOCK) / READ UNCOMMITTED 脏读"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				token, found := stmt.SQL.findReadUncommitted()
+				return ctx.Source.locate(Issue{Message: "检测到 NOLOCK / READ UNCOMMITTED，可能读到未提交、重复或缺失的数据", Suggestion: "如需避免读写阻塞，建议开启 READ_COMMITTED_SNAPSHOT 或使用 SNAPSHOT 隔离级别"}, token.span()), found
+			},
+		),
+		newStatementRule(
+			RuleDefinition{Code: "mssql_like_leading_wildcard", Level: LevelWarning, Category: "查询性能", Description: "LIKE 前导 % 可能导致索引失效"},
+			func(ctx *RuleContext, stmt RuleStatement) (Issue, bool) {
+				token, found := stmt.SQL.findLeadingWildcardLike("LIKE")
+				return ctx.Source.locate(Issue{Message: "LIKE 前导通配符可能导致索引失效", Suggestion: "可考虑全文索引（CONTAINS）或改写匹配策略"}, token.span()), found
+			},
+		),
+		riskyWritesWithoutTransactionRule("建议用 BEGIN TRANSACTION/COMMIT 包裹，并配合 SET XACT_ABORT ON 保证出错时整体回滚"),
+		engineDetectionAmbiguousRule(),
+		invalidSuppressionRule(),
+	}
+}
+
+func AnalyzeSQLServerWithOptions(content string, options AnalyzeOptions) CheckResponse {
+	ctx := newSQLRuleContextFromSpans(EngineSQLServer, content, splitTSQLStatementSpans(content), sqlserverDialect)
+	for _, stmt := range ctx.Statements {
+		if stmt.SQL.Kind == sqlStmtCreateRoutine {
+			ctx.ContainsRoutine = true
+		}
+	}
+	return analyzeWithRules(ctx, options, "请输入待审核 T-SQL 后重试")
+}
+
+func splitTSQLStatementSpans(content string) []sqlStatementSpan {
+	items := make([]sqlStatementSpan, 0)
+	tokens := significantSQLTokens(tokenizeSQL(content, sqlserverDialect))
+	start, end := -1, 0
+	routine := false
+
+	flush := func() {
+		if start >= 0 {
+			items = append(items, sqlStatementSpan{Text: content[start:end], Span: sourceSpan{Start: start, End: end}})
+		}
+		start = -1
+	}
+
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		if lineEnd, found := tsqlBatchSeparatorEnd(content, token); found {
+			flush()
+			routine = false
+			for i+1 < len(tokens) && tokens[i+1].Start < lineEnd {
+				i++
+			}
+			continue
+		}
+		if token.Kind == sqlTokenOperator && (token.Text == ";" || token.Text == "；") && !routine {
+			flush()
+			continue
+		}
+		if start < 0 {
+			start = token.Start
+			routine = isTSQLRoutineStart(tokens[i:min(i+4, len(tokens))])
+		}
+		end = token.End
+	}
+
+	flush()
+	return items
+}
+
+func tsqlBatchSeparatorEnd(content string, token sqlToken) (int, bool) {
+	if token.Kind != sqlTokenWord || token.Upper != "GO" {
+		return 0, false
+	}
+	lineStart, lineEnd := sqlLineBounds(content, token)
+	if strings.TrimSpace(content[lineStart:token.Start]) != "" {
+		return 0, false
+	}
+	rest := content[token.End:lineEnd]
+	if idx := strings.Index(rest, "--"); idx >= 0 {
+		rest = rest[:idx]
+	}
+	if strings.Trim(strings.TrimSpace(rest), "0123456789") != "" {
+		return 0, false
+	}
+	return lineEnd, true
+}
+
+func isTSQLRoutineStart(tokens []sqlToken) bool {
+	if len(tokens) < 2 || (tokens[0].Upper != "CREATE" && tokens[0].Upper != "ALTER") {
+		return false
+	}
+	next := 1
+	if tokens[1].Upper == "OR" {
+		next = 3
+	}
+	return next < len(tokens) && isSQLRoutineKeyword(tokens[next].Upper)
+}
+
+func (stmt *sqlStatement) findSelectInto() (sqlToken, bool) {
+	if stmt.Kind != sqlStmtSelect || len(stmt.Tokens) == 0 {
+		return sqlToken{}, false
+	}
+	p := &sqlParser{tokens: stmt.Tokens, base: stmt.Tokens[0].Depth}
+	into := p.findTop(1, len(stmt.Tokens), "INTO")
+	if into < 0 || strings.HasPrefix(p.upper(into+1), "#") {
+		return sqlToken{}, false
+	}
+	return stmt.Tokens[into], true
+}
+
+func (stmt *sqlStatement) targetsOnlyTempTables() bool {
+	for _, table := range stmt.Tables {
+		if !strings.HasPrefix(table.Name, "#") {
+			return false
+		}
+	}
+	return len(stmt.Tables) > 0
+}
+
+func (stmt *sqlStatement) findReadUncommitted() (sqlToken, bool) {
+	for _, token := range stmt.Tokens {
+		if token.Kind == sqlTokenWord && (token.Upper == "NOLOCK" || token.Upper == "READUNCOMMITTED") {
+			return token, true
+		}
+	}
+	return stmt.findTokenSequence("READ", "UNCOMMITTED")
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitTSQLStatementSpansHandlesGoBatches(t *testing.T) {
+	script := strings.Join([]string{
+		"USE [shop]",
+		"GO",
+		"CREATE PROCEDURE dbo.purge AS",
+		"BEGIN",
+		"  DELETE FROM [order items] WHERE id < 10;",
+		"  UPDATE users SET name = 'go';",
+		"END",
+		"GO 2",
+		"SELECT [a]]b] FROM #tmp; SELECT 1",
+	}, "\n")
+	spans := splitTSQLStatementSpans(script)
+	if len(spans) != 4 {
+		t.Fatalf("expected 4 statements, got %d: %+v", len(spans), spans)
+	}
+	if !strings.HasPrefix(spans[1].Text, "CREATE PROCEDURE") || !strings.HasSuffix(spans[1].Text, "END") {
+		t.Fatalf("procedure body should stay in one batch, got %q", spans[1].Text)
+	}
+	parsed := parseSQLStatementAt(script, spans[2].Span, sqlserverDialect)
+	if sqlIdentifierName(parsed.Projection[0].Tokens[0]) != "a]b" || parsed.Tables[0].Name != "#tmp" {
+		t.Fatalf("unexpected bracket/temp table parse: %+v", parsed)
+	}
+}
+
+func TestAnalyzeSQLServerRules(t *testing.T) {
+	script := strings.Join([]string{
+		"UPDATE o SET o.status = 2 FROM orders o INNER JOIN users u ON u.id = o.user_id;",
+		"UPDATE o SET o.status = 2 FROM orders o LEFT JOIN users u ON u.id = o.user_id;",
+		"DELETE TOP (1000) FROM [dbo].[logs];",
+		"SELECT TOP 10 id FROM users WITH (NOLOCK);",
+		"SELECT id, name INTO users_backup FROM users;",
+		"SELECT id INTO #ids FROM users;",
+		"DROP TABLE #ids;",
+		"TRUNCATE TABLE [dbo].[logs];",
+		"SELECT id FROM users ORDER BY id OFFSET 0 ROWS FETCH NEXT 20 ROWS ONLY;",
+		"DELETE o FROM orders o INNER JOIN users u ON u.id = o.user_id;",
+		"DELETE o FROM orders o INNER JOIN users u ON u.id = o.user_id WHERE u.status = 0;",
+	}, "\n")
+
+	issues := AnalyzeByEngine(EngineSQLServer, script, AnalyzeOptions{}).Issues
+	rules := issuesByStatement(issues)
+	rules.expect(t, 1, "mssql_update_without_where", true)
+	rules.expect(t, 2, "mssql_update_without_where", true)
+	rules.expect(t, 3, "mssql_delete_without_where", true)
+	rules.expect(t, 4, "mssql_select_without_top", false)
+	rules.expect(t, 4, "mssql_nolock_hint", true)
+	rules.expect(t, 5, "mssql_select_into", true)
+	rules.expect(t, 5, "mssql_select_without_top", false)
+	rules.expect(t, 6, "mssql_select_into", false)
+	rules.expect(t, 7, "mssql_dangerous_drop", false)
+	rules.expect(t, 8, "mssql_dangerous_truncate", true)
+	rules.expect(t, 9, "mssql_select_without_top", false)
+	rules.expect(t, 10, "mssql_delete_without_where", true)
+	rules.expect(t, 11, "mssql_delete_without_where", false)
+	rules.expect(t, 0, "missing_statement_terminator", false)
+}
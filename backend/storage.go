@@ -1,21 +1,39 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/url"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"strconv"
 	"strings"
 	"time"
+
+	"github.com/mattn/go-sqlite3"
 )
 
-var ErrHistoryNotFound = errors.New("history not found")
+var (
+	ErrHistoryNotFound = errors.New("history not found")
+	ErrProfileNotFound = errors.New("rule profile not found")
+	ErrProfileExists   = errors.New("rule profile already exists")
+	ErrSchemaNotFound  = errors.New("schema snapshot not found")
+	ErrSchemaExists    = errors.New("schema snapshot already exists")
+)
+
+const (
+	sqliteMaxOpenConns = 8
+	sqliteBusyTimeout  = 5000
+)
 
 type HistoryStore struct {
 	dbPath string
+	db     *sql.DB
+
+	insertHistoryStmt *sql.Stmt
+	getHistoryStmt    *sql.Stmt
 }
 
 type SaveHistoryInput struct {
@@ -25,30 +43,37 @@ type SaveHistoryInput struct {
 	FileName      string
 	SQLText       string
 	DisabledRules []string
+	ProfileName   string
+	Profile       *RuleProfile
+	Overrides     RuleOverrides
 	CheckResult   CheckResponse
 }
 
 type HistoryItem struct {
-	ID         int64    `json:"id"`
-	RequestID  string   `json:"requestId"`
-	Engine     DBEngine `json:"engine"`
-	Source     string   `json:"source"`
-	FileName   string   `json:"fileName"`
-	CreatedAt  string   `json:"createdAt"`
-	Summary    Summary  `json:"summary"`
-	SQLPreview string   `json:"sqlPreview"`
+	ID          int64    `json:"id"`
+	RequestID   string   `json:"requestId"`
+	Engine      DBEngine `json:"engine"`
+	Source      string   `json:"source"`
+	FileName    string   `json:"fileName"`
+	CreatedAt   string   `json:"createdAt"`
+	ProfileName string   `json:"profileName,omitempty"`
+	Summary     Summary  `json:"summary"`
+	SQLPreview  string   `json:"sqlPreview"`
 }
 
 type HistoryDetail struct {
-	ID            int64         `json:"id"`
-	RequestID     string        `json:"requestId"`
-	Engine        DBEngine      `json:"engine"`
-	Source        string        `json:"source"`
-	FileName      string        `json:"fileName"`
-	CreatedAt     string        `json:"createdAt"`
-	SQLText       string        `json:"sqlText"`
-	DisabledRules []string      `json:"disabledRules"`
-	CheckResult   CheckResponse `json:"checkResult"`
+	ID            int64          `json:"id"`
+	RequestID     string         `json:"requestId"`
+	Engine        DBEngine       `json:"engine"`
+	Source        string         `json:"source"`
+	FileName      string         `json:"fileName"`
+	CreatedAt     string         `json:"createdAt"`
+	SQLText       string         `json:"sqlText"`
+	DisabledRules []string       `json:"disabledRules"`
+	ProfileName   string         `json:"profileName,omitempty"`
+	Profile       *RuleProfile   `json:"profile,omitempty"`
+	Overrides     *RuleOverrides `json:"ruleOverrides,omitempty"`
+	CheckResult   CheckResponse  `json:"checkResult"`
 }
 
 func NewHistoryStore(dbPath string) (*HistoryStore, error) {
@@ -57,16 +82,30 @@ func NewHistoryStore(dbPath string) (*HistoryStore, error) {
 		resolvedPath = "./data/sql_review.db"
 	}
 
-	if _, err := exec.LookPath("sqlite3"); err != nil {
-		return nil, errors.New("sqlite3 command not found, please install sqlite3")
+	if err := os.MkdirAll(filepath.Dir(resolvedPath), 0o755); err != nil {
+		return nil, err
 	}
 
-	if err := os.MkdirAll(filepath.Dir(resolvedPath), 0o755); err != nil {
+	query := url.Values{}
+	query.Set("_busy_timeout", fmt.Sprint(sqliteBusyTimeout))
+	query.Set("_journal_mode", "WAL")
+	query.Set("_txlock", "immediate")
+	db, err := sql.Open("sqlite3", "file:"+resolvedPath+"?"+query.Encode())
+	if err != nil {
 		return nil, err
 	}
+	db.SetMaxOpenConns(sqliteMaxOpenConns)
+	db.SetMaxIdleConns(sqliteMaxOpenConns)
+	db.SetConnMaxIdleTime(5 * time.Minute)
 
-	store := &HistoryStore{dbPath: resolvedPath}
-	if err := store.initSchema(); err != nil {
+	store := &HistoryStore{dbPath: resolvedPath, db: db}
+	ctx := context.Background()
+	if err := store.initSchema(ctx); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	if err := store.prepareStatements(ctx); err != nil {
+		_ = store.Close()
 		return nil, err
 	}
 
@@ -74,13 +113,16 @@ func NewHistoryStore(dbPath string) (*HistoryStore, error) {
 }
 
 func (store *HistoryStore) Close() error {
-	return nil
+	for _, stmt := range []*sql.Stmt{store.insertHistoryStmt, store.getHistoryStmt} {
+		if stmt != nil {
+			_ = stmt.Close()
+		}
+	}
+	return store.db.Close()
 }
 
-func (store *HistoryStore) initSchema() error {
+func (store *HistoryStore) initSchema(ctx context.Context) error {
 	query := `
-PRAGMA journal_mode = WAL;
-PRAGMA busy_timeout = 5000;
 CREATE TABLE IF NOT EXISTS review_history (
   id INTEGER PRIMARY KEY AUTOINCREMENT,
   request_id TEXT NOT NULL,
@@ -97,28 +139,106 @@ CREATE TABLE IF NOT EXISTS review_history (
   created_at TEXT NOT NULL
 );
 CREATE INDEX IF NOT EXISTS idx_review_history_created_at ON review_history(created_at DESC);
+CREATE TABLE IF NOT EXISTS rule_profiles (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  name TEXT NOT NULL,
+  engine TEXT NOT NULL,
+  description TEXT NOT NULL DEFAULT '',
+  disabled_rules_json TEXT NOT NULL,
+  rule_overrides_json TEXT NOT NULL DEFAULT '',
+  is_default INTEGER NOT NULL DEFAULT 0,
+  created_at TEXT NOT NULL,
+  updated_at TEXT NOT NULL,
+  UNIQUE (engine, name)
+);
+CREATE TABLE IF NOT EXISTS schema_snapshots (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  name TEXT NOT NULL,
+  engine TEXT NOT NULL,
+  description TEXT NOT NULL DEFAULT '',
+  ddl TEXT NOT NULL,
+  created_at TEXT NOT NULL,
+  updated_at TEXT NOT NULL,
+  UNIQUE (engine, name)
+);
 `
-	if err := store.execQuery(query); err != nil {
+	if _, err := store.db.ExecContext(ctx, query); err != nil {
 		return err
 	}
 
-	if err := store.ensureColumn("engine", "TEXT NOT NULL DEFAULT 'mysql'"); err != nil {
+	if err := store.ensureColumn(ctx, "review_history", "engine", "TEXT NOT NULL DEFAULT 'mysql'"); err != nil {
 		return err
 	}
-	if err := store.migrateLegacyHistorySchema(); err != nil {
+	if err := store.migrateLegacyHistorySchema(ctx); err != nil {
+		return err
+	}
+	if err := store.ensureColumn(ctx, "review_history", "profile_name", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := store.ensureColumn(ctx, "review_history", "profile_snapshot_json", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := store.ensureColumn(ctx, "review_history", "rule_overrides_json", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := store.ensureColumn(ctx, "rule_profiles", "rule_overrides_json", "TEXT NOT NULL DEFAULT ''"); err != nil {
 		return err
 	}
 
-	return nil
+	return store.ensureHistorySearchIndex(ctx)
 }
 
-func (store *HistoryStore) migrateLegacyHistorySchema() error {
-	hasProfile, err := store.hasColumn("profile")
+func (store *HistoryStore) ensureHistorySearchIndex(ctx context.Context) error {
+	var existing int
+	if err := store.db.QueryRowContext(ctx, "SELECT COUNT(1) FROM sqlite_master WHERE name IN ('review_history_fts', 'review_history_rules');").Scan(&existing); err != nil {
+		return err
+	}
+
+	query := `
+CREATE VIRTUAL TABLE IF NOT EXISTS review_history_fts USING fts4(content="review_history", sql_text, file_name, tokenize=unicode61);
+CREATE TABLE IF NOT EXISTS review_history_rules (
+  history_id INTEGER NOT NULL,
+  rule TEXT NOT NULL,
+  PRIMARY KEY (history_id, rule)
+);
+CREATE INDEX IF NOT EXISTS idx_review_history_rules_rule ON review_history_rules(rule);
+CREATE TRIGGER IF NOT EXISTS review_history_search_insert AFTER INSERT ON review_history BEGIN
+  INSERT INTO review_history_fts (docid, sql_text, file_name) VALUES (new.id, new.sql_text, new.file_name);
+  INSERT OR IGNORE INTO review_history_rules (history_id, rule)
+    SELECT new.id, json_extract(value, '$.rule') FROM json_each(new.result_json, '$.issues')
+    WHERE json_extract(value, '$.rule') IS NOT NULL;
+END;
+CREATE TRIGGER IF NOT EXISTS review_history_search_delete BEFORE DELETE ON review_history BEGIN
+  DELETE FROM review_history_fts WHERE docid = old.id;
+  DELETE FROM review_history_rules WHERE history_id = old.id;
+END;
+`
+	backfill := `
+INSERT INTO review_history_fts (review_history_fts) VALUES ('rebuild');
+INSERT OR IGNORE INTO review_history_rules (history_id, rule)
+  SELECT h.id, json_extract(i.value, '$.rule') FROM review_history h, json_each(h.result_json, '$.issues') i
+  WHERE json_extract(i.value, '$.rule') IS NOT NULL;
+`
+
+	return store.withTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, query); err != nil {
+			return err
+		}
+		if existing == 2 {
+			return nil
+		}
+		_, err := tx.ExecContext(ctx, backfill)
+		return err
+	})
+}
+
+func (store *HistoryStore) migrateLegacyHistorySchema(ctx context.Context) error {
+	hasProfile, err := store.hasColumn(ctx, "review_history", "profile")
 	if err != nil {
 		return err
 	}
 
-	hasScore, err := store.hasColumn("score")
+	hasScore, err := store.hasColumn(ctx, "review_history", "score")
 	if err != nil {
 		return err
 	}
@@ -128,7 +248,6 @@ func (store *HistoryStore) migrateLegacyHistorySchema() error {
 	}
 
 	migration := `
-BEGIN IMMEDIATE;
 CREATE TABLE review_history_new (
   id INTEGER PRIMARY KEY AUTOINCREMENT,
   request_id TEXT NOT NULL,
@@ -167,18 +286,16 @@ FROM review_history;
 DROP TABLE review_history;
 ALTER TABLE review_history_new RENAME TO review_history;
 CREATE INDEX IF NOT EXISTS idx_review_history_created_at ON review_history(created_at DESC);
-COMMIT;
 `
 
-	if err := store.execQuery(migration); err != nil {
-		_ = store.execQuery("ROLLBACK;")
+	return store.withTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, migration)
 		return err
-	}
-	return nil
+	})
 }
 
-func (store *HistoryStore) ensureColumn(columnName, columnDef string) error {
-	has, err := store.hasColumn(columnName)
+func (store *HistoryStore) ensureColumn(ctx context.Context, tableName, columnName, columnDef string) error {
+	has, err := store.hasColumn(ctx, tableName, columnName)
 	if err != nil {
 		return err
 	}
@@ -186,29 +303,82 @@ func (store *HistoryStore) ensureColumn(columnName, columnDef string) error {
 		return nil
 	}
 
-	alterQuery := fmt.Sprintf("ALTER TABLE review_history ADD COLUMN %s %s;", columnName, columnDef)
-	return store.execQuery(alterQuery)
+	alterQuery := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s;", tableName, columnName, columnDef)
+	_, err = store.db.ExecContext(ctx, alterQuery)
+	return err
 }
 
-func (store *HistoryStore) hasColumn(columnName string) (bool, error) {
-	type tableInfoRow struct {
-		Name string `json:"name"`
-	}
-
-	var rows []tableInfoRow
-	if err := store.queryJSON(`PRAGMA table_info(review_history);`, &rows); err != nil {
+func (store *HistoryStore) hasColumn(ctx context.Context, tableName, columnName string) (bool, error) {
+	rows, err := store.db.QueryContext(ctx, "SELECT name FROM pragma_table_info(?);", tableName)
+	if err != nil {
 		return false, err
 	}
+	defer rows.Close()
 
-	for _, row := range rows {
-		if strings.EqualFold(strings.TrimSpace(row.Name), columnName) {
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return false, err
+		}
+		if strings.EqualFold(strings.TrimSpace(name), columnName) {
 			return true, nil
 		}
 	}
-	return false, nil
+	return false, rows.Err()
+}
+
+func (store *HistoryStore) prepareStatements(ctx context.Context) error {
+	statements := []struct {
+		target **sql.Stmt
+		query  string
+	}{
+		{&store.insertHistoryStmt, `
+INSERT INTO review_history (
+  request_id, engine, source, file_name, sql_text,
+  disabled_rules_json, result_json, profile_name, profile_snapshot_json, rule_overrides_json,
+  statement_count, error_count, warning_count, info_count, created_at
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`},
+		{&store.getHistoryStmt, `
+SELECT
+  id,
+  request_id,
+  engine,
+  source,
+  file_name,
+  created_at,
+  sql_text,
+  disabled_rules_json,
+  result_json,
+  profile_name,
+  profile_snapshot_json,
+  rule_overrides_json
+FROM review_history
+WHERE id = ?;`},
+	}
+
+	for _, item := range statements {
+		stmt, err := store.db.PrepareContext(ctx, item.query)
+		if err != nil {
+			return err
+		}
+		*item.target = stmt
+	}
+	return nil
 }
 
-func (store *HistoryStore) Save(input SaveHistoryInput) (int64, error) {
+func (store *HistoryStore) withTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (store *HistoryStore) Save(ctx context.Context, input SaveHistoryInput) (int64, error) {
 	disabledRulesJSON, err := json.Marshal(input.DisabledRules)
 	if err != nil {
 		return 0, err
@@ -219,208 +389,227 @@ func (store *HistoryStore) Save(input SaveHistoryInput) (int64, error) {
 		return 0, err
 	}
 
-	engine := NormalizeEngine(string(input.Engine))
+	profileSnapshotJSON := ""
+	if input.Profile != nil {
+		snapshot, err := json.Marshal(input.Profile)
+		if err != nil {
+			return 0, err
+		}
+		profileSnapshotJSON = string(snapshot)
+	}
+
+	overridesJSON := ""
+	if !input.Overrides.isEmpty() {
+		encoded, err := json.Marshal(input.Overrides)
+		if err != nil {
+			return 0, err
+		}
+		overridesJSON = string(encoded)
+	}
+
+	engine, err := ResolveEngine(string(input.Engine))
+	if err != nil {
+		return 0, err
+	}
 
 	createdAt := time.Now().UTC().Format(time.RFC3339Nano)
-	insertQuery := fmt.Sprintf(`
-INSERT INTO review_history (
-  request_id, engine, source, file_name, sql_text,
-  disabled_rules_json, result_json,
-  statement_count, error_count, warning_count, info_count, created_at
-) VALUES (
-  %s, %s, %s, %s, %s,
-  %s, %s,
-  %d, %d, %d, %d, %s
-);
-`,
-		sqlQuote(input.RequestID),
-		sqlQuote(string(engine)),
-		sqlQuote(input.Source),
-		sqlQuote(input.FileName),
-		sqlQuote(input.SQLText),
-		sqlQuote(string(disabledRulesJSON)),
-		sqlQuote(string(resultJSON)),
+	result, err := store.insertHistoryStmt.ExecContext(ctx,
+		input.RequestID,
+		string(engine),
+		input.Source,
+		input.FileName,
+		input.SQLText,
+		string(disabledRulesJSON),
+		string(resultJSON),
+		input.ProfileName,
+		profileSnapshotJSON,
+		overridesJSON,
 		input.CheckResult.Summary.StatementCount,
 		input.CheckResult.Summary.ErrorCount,
 		input.CheckResult.Summary.WarningCount,
 		input.CheckResult.Summary.InfoCount,
-		sqlQuote(createdAt),
+		createdAt,
 	)
-
-	if err := store.execQuery(insertQuery); err != nil {
-		return 0, err
-	}
-
-	type idRow struct {
-		ID int64 `json:"id"`
-	}
-	var rows []idRow
-	if err := store.queryJSON(fmt.Sprintf(
-		`SELECT id FROM review_history WHERE request_id = %s ORDER BY id DESC LIMIT 1;`,
-		sqlQuote(input.RequestID),
-	), &rows); err != nil {
+	if err != nil {
 		return 0, err
 	}
-	if len(rows) == 0 {
-		return 0, errors.New("failed to fetch last insert id")
-	}
-	return rows[0].ID, nil
+	return result.LastInsertId()
 }
 
-func (store *HistoryStore) List(limit, offset int) ([]HistoryItem, int, error) {
-	if limit <= 0 {
-		limit = 20
-	}
-	if limit > 100 {
-		limit = 100
-	}
-	if offset < 0 {
-		offset = 0
-	}
-
-	type listRow struct {
-		ID             int64  `json:"id"`
-		RequestID      string `json:"requestId"`
-		Engine         string `json:"engine"`
-		Source         string `json:"source"`
-		FileName       string `json:"fileName"`
-		CreatedAt      string `json:"createdAt"`
-		StatementCount int    `json:"statementCount"`
-		ErrorCount     int    `json:"errorCount"`
-		WarningCount   int    `json:"warningCount"`
-		InfoCount      int    `json:"infoCount"`
-		SQLPreview     string `json:"sqlPreview"`
-	}
-
-	query := fmt.Sprintf(`
+const historyListSelect = `
 SELECT
   id,
-  request_id AS requestId,
+  request_id,
   engine,
   source,
-  file_name AS fileName,
-  created_at AS createdAt,
-  statement_count AS statementCount,
-  error_count AS errorCount,
-  warning_count AS warningCount,
-  info_count AS infoCount,
+  file_name,
+  created_at,
+  profile_name,
+  statement_count,
+  error_count,
+  warning_count,
+  info_count,
   CASE
     WHEN length(replace(replace(sql_text, char(10), ' '), char(13), ' ')) > 200
       THEN substr(replace(replace(sql_text, char(10), ' '), char(13), ' '), 1, 200) || '...'
     ELSE replace(replace(sql_text, char(10), ' '), char(13), ' ')
-  END AS sqlPreview
-FROM review_history
-ORDER BY id DESC
-LIMIT %d OFFSET %d;
-`, limit, offset)
+  END AS sql_preview
+FROM review_history`
+
+func (store *HistoryStore) List(ctx context.Context, query HistoryQuery) ([]HistoryItem, int, error) {
+	query = query.normalized()
+	where, args := query.where()
 
-	var rows []listRow
-	if err := store.queryJSON(query, &rows); err != nil {
+	rows, err := store.db.QueryContext(ctx,
+		historyListSelect+where+" ORDER BY "+query.orderBy()+" LIMIT ? OFFSET ?;",
+		append(args, query.Limit, query.Offset)...,
+	)
+	if err != nil {
 		return nil, 0, err
 	}
-
-	items := make([]HistoryItem, 0, len(rows))
-	for _, row := range rows {
-		items = append(items, HistoryItem{
-			ID:        row.ID,
-			RequestID: row.RequestID,
-			Engine:    NormalizeEngine(row.Engine),
-			Source:    row.Source,
-			FileName:  row.FileName,
-			CreatedAt: row.CreatedAt,
-			Summary: Summary{
-				StatementCount: row.StatementCount,
-				ErrorCount:     row.ErrorCount,
-				WarningCount:   row.WarningCount,
-				InfoCount:      row.InfoCount,
-			},
-			SQLPreview: row.SQLPreview,
-		})
-	}
-
-	type countRow struct {
-		Total int `json:"total"`
-	}
-	var countRows []countRow
-	if err := store.queryJSON(`SELECT COUNT(1) AS total FROM review_history;`, &countRows); err != nil {
+	defer rows.Close()
+
+	items := make([]HistoryItem, 0, query.Limit)
+	for rows.Next() {
+		var item HistoryItem
+		var engine string
+		if err := rows.Scan(
+			&item.ID,
+			&item.RequestID,
+			&engine,
+			&item.Source,
+			&item.FileName,
+			&item.CreatedAt,
+			&item.ProfileName,
+			&item.Summary.StatementCount,
+			&item.Summary.ErrorCount,
+			&item.Summary.WarningCount,
+			&item.Summary.InfoCount,
+			&item.SQLPreview,
+		); err != nil {
+			return nil, 0, err
+		}
+		item.Engine = storedEngine(engine)
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
 		return nil, 0, err
 	}
-	if len(countRows) == 0 {
-		return items, 0, nil
+
+	var total int
+	if err := store.db.QueryRowContext(ctx, "SELECT COUNT(1) FROM review_history"+where+";", args...).Scan(&total); err != nil {
+		return nil, 0, err
 	}
 
-	return items, countRows[0].Total, nil
+	return items, total, nil
 }
 
-func (store *HistoryStore) GetByID(id int64) (HistoryDetail, error) {
-	type detailRow struct {
-		ID                int64  `json:"id"`
-		RequestID         string `json:"requestId"`
-		Engine            string `json:"engine"`
-		Source            string `json:"source"`
-		FileName          string `json:"fileName"`
-		CreatedAt         string `json:"createdAt"`
-		SQLText           string `json:"sqlText"`
-		DisabledRulesJSON string `json:"disabledRulesJson"`
-		ResultJSON        string `json:"resultJson"`
+func (store *HistoryStore) Facets(ctx context.Context, query HistoryQuery) (HistoryFacets, error) {
+	withoutEngine := query
+	withoutEngine.Engine = ""
+	where, args := withoutEngine.where()
+	engines, err := store.queryFacets(ctx,
+		"SELECT engine, COUNT(1) FROM review_history"+where+" GROUP BY engine ORDER BY COUNT(1) DESC, engine;",
+		args...,
+	)
+	if err != nil {
+		return HistoryFacets{}, err
 	}
 
-	query := fmt.Sprintf(`
-SELECT
-  id,
-  request_id AS requestId,
-  engine,
-  source,
-  file_name AS fileName,
-  created_at AS createdAt,
-  sql_text AS sqlText,
-  disabled_rules_json AS disabledRulesJson,
-  result_json AS resultJson
-FROM review_history
-WHERE id = %d
-LIMIT 1;
-`, id)
+	withoutRule := query
+	withoutRule.Rule = ""
+	where, args = withoutRule.where()
+	rules, err := store.queryFacets(ctx,
+		"SELECT rule, COUNT(1) FROM review_history_rules WHERE history_id IN (SELECT id FROM review_history"+where+") GROUP BY rule ORDER BY COUNT(1) DESC, rule LIMIT 50;",
+		args...,
+	)
+	if err != nil {
+		return HistoryFacets{}, err
+	}
 
-	var rows []detailRow
-	if err := store.queryJSON(query, &rows); err != nil {
-		return HistoryDetail{}, err
+	return HistoryFacets{Engines: engines, Rules: rules}, nil
+}
+
+func (store *HistoryStore) queryFacets(ctx context.Context, query string, args ...any) ([]HistoryFacet, error) {
+	rows, err := store.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
 	}
-	if len(rows) == 0 {
-		return HistoryDetail{}, ErrHistoryNotFound
+	defer rows.Close()
+
+	facets := make([]HistoryFacet, 0)
+	for rows.Next() {
+		var facet HistoryFacet
+		if err := rows.Scan(&facet.Value, &facet.Count); err != nil {
+			return nil, err
+		}
+		facets = append(facets, facet)
 	}
+	return facets, rows.Err()
+}
 
-	row := rows[0]
-	detail := HistoryDetail{
-		ID:        row.ID,
-		RequestID: row.RequestID,
-		Engine:    NormalizeEngine(row.Engine),
-		Source:    row.Source,
-		FileName:  row.FileName,
-		CreatedAt: row.CreatedAt,
-		SQLText:   row.SQLText,
+func (store *HistoryStore) GetByID(ctx context.Context, id int64) (HistoryDetail, error) {
+	var detail HistoryDetail
+	var engine, disabledRulesJSON, resultJSON, profileJSON, overridesJSON string
+	err := store.getHistoryStmt.QueryRowContext(ctx, id).Scan(
+		&detail.ID,
+		&detail.RequestID,
+		&engine,
+		&detail.Source,
+		&detail.FileName,
+		&detail.CreatedAt,
+		&detail.SQLText,
+		&disabledRulesJSON,
+		&resultJSON,
+		&detail.ProfileName,
+		&profileJSON,
+		&overridesJSON,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return HistoryDetail{}, ErrHistoryNotFound
 	}
+	if err != nil {
+		return HistoryDetail{}, err
+	}
+	detail.Engine = storedEngine(engine)
 
 	detail.DisabledRules = make([]string, 0)
-	if strings.TrimSpace(row.DisabledRulesJSON) != "" {
-		if err := json.Unmarshal([]byte(row.DisabledRulesJSON), &detail.DisabledRules); err != nil {
+	if strings.TrimSpace(disabledRulesJSON) != "" {
+		if err := json.Unmarshal([]byte(disabledRulesJSON), &detail.DisabledRules); err != nil {
 			return HistoryDetail{}, err
 		}
 	}
 
-	if err := json.Unmarshal([]byte(row.ResultJSON), &detail.CheckResult); err != nil {
+	if err := json.Unmarshal([]byte(resultJSON), &detail.CheckResult); err != nil {
 		return HistoryDetail{}, err
 	}
 
+	if strings.TrimSpace(profileJSON) != "" {
+		var profile RuleProfile
+		if err := json.Unmarshal([]byte(profileJSON), &profile); err != nil {
+			return HistoryDetail{}, err
+		}
+		detail.Profile = &profile
+	}
+
+	if strings.TrimSpace(overridesJSON) != "" {
+		var overrides RuleOverrides
+		if err := json.Unmarshal([]byte(overridesJSON), &overrides); err != nil {
+			return HistoryDetail{}, err
+		}
+		detail.Overrides = &overrides
+	}
+
 	return detail, nil
 }
 
-func (store *HistoryStore) DeleteByIDs(ids []int64) (int, error) {
+func (store *HistoryStore) DeleteByIDs(ctx context.Context, ids []int64) (int, error) {
 	if len(ids) == 0 {
 		return 0, nil
 	}
 
 	seen := make(map[int64]struct{}, len(ids))
-	normalizedIDs := make([]int64, 0, len(ids))
+	args := make([]any, 0, len(ids))
 	for _, id := range ids {
 		if id <= 0 {
 			continue
@@ -429,94 +618,381 @@ func (store *HistoryStore) DeleteByIDs(ids []int64) (int, error) {
 			continue
 		}
 		seen[id] = struct{}{}
-		normalizedIDs = append(normalizedIDs, id)
+		args = append(args, id)
 	}
-	if len(normalizedIDs) == 0 {
+	if len(args) == 0 {
 		return 0, nil
 	}
 
-	idTexts := make([]string, 0, len(normalizedIDs))
-	for _, id := range normalizedIDs {
-		idTexts = append(idTexts, strconv.FormatInt(id, 10))
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(args)), ",")
+	result, err := store.db.ExecContext(ctx, fmt.Sprintf(`DELETE FROM review_history WHERE id IN (%s);`, placeholders), args...)
+	if err != nil {
+		return 0, err
 	}
-	whereIn := strings.Join(idTexts, ",")
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(deleted), nil
+}
 
-	type countRow struct {
-		Total int `json:"total"`
+const ruleProfileSelect = `
+SELECT
+  id,
+  name,
+  engine,
+  description,
+  disabled_rules_json,
+  rule_overrides_json,
+  is_default,
+  created_at,
+  updated_at
+FROM rule_profiles
+`
+
+func scanRuleProfile(scanner interface{ Scan(dest ...any) error }) (RuleProfile, error) {
+	var profile RuleProfile
+	var engine, disabledRulesJSON, overridesJSON string
+	if err := scanner.Scan(
+		&profile.ID,
+		&profile.Name,
+		&engine,
+		&profile.Description,
+		&disabledRulesJSON,
+		&overridesJSON,
+		&profile.IsDefault,
+		&profile.CreatedAt,
+		&profile.UpdatedAt,
+	); err != nil {
+		return RuleProfile{}, err
+	}
+	profile.Engine = storedEngine(engine)
+
+	profile.DisabledRules = make([]string, 0)
+	if strings.TrimSpace(disabledRulesJSON) != "" {
+		if err := json.Unmarshal([]byte(disabledRulesJSON), &profile.DisabledRules); err != nil {
+			return RuleProfile{}, err
+		}
 	}
-	var countRows []countRow
-	countQuery := fmt.Sprintf(`SELECT COUNT(1) AS total FROM review_history WHERE id IN (%s);`, whereIn)
-	if err := store.queryJSON(countQuery, &countRows); err != nil {
-		return 0, err
+	if strings.TrimSpace(overridesJSON) != "" {
+		if err := json.Unmarshal([]byte(overridesJSON), &profile.RuleOverrides); err != nil {
+			return RuleProfile{}, err
+		}
 	}
-	if len(countRows) == 0 || countRows[0].Total <= 0 {
-		return 0, nil
+	return profile, nil
+}
+
+func (store *HistoryStore) queryProfiles(ctx context.Context, where string, args ...any) ([]RuleProfile, error) {
+	rows, err := store.db.QueryContext(ctx, ruleProfileSelect+where+";", args...)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	deleteQuery := fmt.Sprintf(`DELETE FROM review_history WHERE id IN (%s);`, whereIn)
-	if err := store.execQuery(deleteQuery); err != nil {
-		return 0, err
+	profiles := make([]RuleProfile, 0)
+	for rows.Next() {
+		profile, err := scanRuleProfile(rows)
+		if err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, profile)
 	}
+	return profiles, rows.Err()
+}
 
-	return countRows[0].Total, nil
+func (store *HistoryStore) queryProfile(ctx context.Context, where string, args ...any) (RuleProfile, error) {
+	profile, err := scanRuleProfile(store.db.QueryRowContext(ctx, ruleProfileSelect+where+" LIMIT 1;", args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return RuleProfile{}, ErrProfileNotFound
+	}
+	return profile, err
+}
+
+func (store *HistoryStore) ListProfiles(ctx context.Context, engine DBEngine) ([]RuleProfile, error) {
+	if strings.TrimSpace(string(engine)) == "" {
+		return store.queryProfiles(ctx, "ORDER BY engine, name")
+	}
+	resolved, err := ResolveEngine(string(engine))
+	if err != nil {
+		return nil, err
+	}
+	return store.queryProfiles(ctx, "WHERE engine = ? ORDER BY name", string(resolved))
+}
+
+func (store *HistoryStore) GetProfile(ctx context.Context, id int64) (RuleProfile, error) {
+	return store.queryProfile(ctx, "WHERE id = ?", id)
+}
+
+func (store *HistoryStore) GetProfileByName(ctx context.Context, engine DBEngine, name string) (RuleProfile, error) {
+	resolved, err := ResolveEngine(string(engine))
+	if err != nil {
+		return RuleProfile{}, err
+	}
+	return store.queryProfile(ctx, "WHERE engine = ? AND name = ?", string(resolved), strings.TrimSpace(name))
 }
 
-func (store *HistoryStore) execQuery(query string) error {
-	output, err := store.runSQLite(query, false)
+func (store *HistoryStore) DefaultProfile(ctx context.Context, engine DBEngine) (RuleProfile, error) {
+	resolved, err := ResolveEngine(string(engine))
 	if err != nil {
-		trimmed := strings.TrimSpace(string(output))
-		if trimmed != "" {
-			return fmt.Errorf("sqlite3 exec error: %s", trimmed)
+		return RuleProfile{}, err
+	}
+	return store.queryProfile(ctx, "WHERE engine = ? AND is_default = 1", string(resolved))
+}
+
+func (store *HistoryStore) CreateProfile(ctx context.Context, profile RuleProfile) (RuleProfile, error) {
+	engine, err := ResolveEngine(string(profile.Engine))
+	if err != nil {
+		return RuleProfile{}, err
+	}
+	disabledRulesJSON, overridesJSON, err := encodeProfileRules(profile)
+	if err != nil {
+		return RuleProfile{}, err
+	}
+
+	var id int64
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+	err = store.withTx(ctx, func(tx *sql.Tx) error {
+		if profile.IsDefault {
+			if _, err := tx.ExecContext(ctx, "UPDATE rule_profiles SET is_default = 0 WHERE engine = ?;", string(engine)); err != nil {
+				return err
+			}
 		}
+		result, err := tx.ExecContext(ctx, `INSERT INTO rule_profiles (
+  name, engine, description, disabled_rules_json, rule_overrides_json, is_default, created_at, updated_at
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?);`,
+			profile.Name, string(engine), profile.Description, disabledRulesJSON, overridesJSON, profile.IsDefault, now, now,
+		)
+		if err != nil {
+			return err
+		}
+		id, err = result.LastInsertId()
 		return err
+	})
+	if err != nil {
+		return RuleProfile{}, mapUniqueConstraintError(err, ErrProfileExists)
 	}
-	return nil
+	return store.GetProfile(ctx, id)
 }
 
-func (store *HistoryStore) queryJSON(query string, target any) error {
-	output, err := store.runSQLite(query, true)
+func (store *HistoryStore) UpdateProfile(ctx context.Context, id int64, profile RuleProfile) (RuleProfile, error) {
+	existing, err := store.GetProfile(ctx, id)
+	if err != nil {
+		return RuleProfile{}, err
+	}
+	disabledRulesJSON, overridesJSON, err := encodeProfileRules(profile)
 	if err != nil {
-		trimmed := strings.TrimSpace(string(output))
-		if trimmed != "" {
-			return fmt.Errorf("sqlite3 query error: %s", trimmed)
+		return RuleProfile{}, err
+	}
+
+	err = store.withTx(ctx, func(tx *sql.Tx) error {
+		if profile.IsDefault {
+			if _, err := tx.ExecContext(ctx, "UPDATE rule_profiles SET is_default = 0 WHERE engine = ?;", string(existing.Engine)); err != nil {
+				return err
+			}
 		}
+		_, err := tx.ExecContext(ctx, `UPDATE rule_profiles SET
+  name = ?,
+  description = ?,
+  disabled_rules_json = ?,
+  rule_overrides_json = ?,
+  is_default = ?,
+  updated_at = ?
+WHERE id = ?;`,
+			profile.Name, profile.Description, disabledRulesJSON, overridesJSON, profile.IsDefault,
+			time.Now().UTC().Format(time.RFC3339Nano), id,
+		)
 		return err
+	})
+	if err != nil {
+		return RuleProfile{}, mapUniqueConstraintError(err, ErrProfileExists)
 	}
+	return store.GetProfile(ctx, id)
+}
 
-	content := strings.TrimSpace(string(output))
-	if content == "" {
-		content = "[]"
+func (store *HistoryStore) SetDefaultProfile(ctx context.Context, id int64) (RuleProfile, error) {
+	existing, err := store.GetProfile(ctx, id)
+	if err != nil {
+		return RuleProfile{}, err
 	}
 
-	if err := json.Unmarshal([]byte(content), target); err != nil {
-		return fmt.Errorf("decode sqlite json output failed: %w (raw=%s)", err, truncate(content, 200))
+	err = store.withTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, "UPDATE rule_profiles SET is_default = 0 WHERE engine = ?;", string(existing.Engine)); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, "UPDATE rule_profiles SET is_default = 1, updated_at = ? WHERE id = ?;", time.Now().UTC().Format(time.RFC3339Nano), id)
+		return err
+	})
+	if err != nil {
+		return RuleProfile{}, err
+	}
+	return store.GetProfile(ctx, id)
+}
+
+func (store *HistoryStore) DeleteProfile(ctx context.Context, id int64) error {
+	result, err := store.db.ExecContext(ctx, "DELETE FROM rule_profiles WHERE id = ?;", id)
+	if err != nil {
+		return err
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrProfileNotFound
 	}
 	return nil
 }
 
-func (store *HistoryStore) runSQLite(query string, asJSON bool) ([]byte, error) {
-	args := make([]string, 0, 2)
-	if asJSON {
-		args = append(args, "-json")
+func encodeProfileRules(profile RuleProfile) (string, string, error) {
+	disabledRulesJSON, err := json.Marshal(profile.DisabledRules)
+	if err != nil {
+		return "", "", err
+	}
+	overridesJSON, err := json.Marshal(profile.RuleOverrides)
+	if err != nil {
+		return "", "", err
+	}
+	return string(disabledRulesJSON), string(overridesJSON), nil
+}
+
+func mapUniqueConstraintError(err, exists error) error {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+		return exists
+	}
+	return err
+}
+
+const schemaSnapshotSelect = `
+SELECT
+  id,
+  name,
+  engine,
+  description,
+  ddl,
+  created_at,
+  updated_at
+FROM schema_snapshots
+`
+
+func scanSchemaSnapshot(scanner interface{ Scan(dest ...any) error }) (SchemaSnapshot, error) {
+	var snapshot SchemaSnapshot
+	var engine string
+	if err := scanner.Scan(
+		&snapshot.ID,
+		&snapshot.Name,
+		&engine,
+		&snapshot.Description,
+		&snapshot.DDL,
+		&snapshot.CreatedAt,
+		&snapshot.UpdatedAt,
+	); err != nil {
+		return SchemaSnapshot{}, err
+	}
+	snapshot.Engine = storedEngine(engine)
+	return snapshot, nil
+}
+
+func (store *HistoryStore) querySchema(ctx context.Context, where string, args ...any) (SchemaSnapshot, error) {
+	snapshot, err := scanSchemaSnapshot(store.db.QueryRowContext(ctx, schemaSnapshotSelect+where+" LIMIT 1;", args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return SchemaSnapshot{}, ErrSchemaNotFound
+	}
+	return snapshot, err
+}
+
+func (store *HistoryStore) ListSchemas(ctx context.Context, engine DBEngine) ([]SchemaSnapshot, error) {
+	where, args := "ORDER BY engine, name", []any{}
+	if strings.TrimSpace(string(engine)) != "" {
+		resolved, err := ResolveEngine(string(engine))
+		if err != nil {
+			return nil, err
+		}
+		where, args = "WHERE engine = ? ORDER BY name", []any{string(resolved)}
 	}
-	args = append(args, store.dbPath)
+	rows, err := store.db.QueryContext(ctx, schemaSnapshotSelect+where+";", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
-	cmd := exec.Command("sqlite3", args...)
-	cmd.Stdin = strings.NewReader(query + "\n")
-	return cmd.CombinedOutput()
+	snapshots := make([]SchemaSnapshot, 0)
+	for rows.Next() {
+		snapshot, err := scanSchemaSnapshot(rows)
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots, rows.Err()
 }
 
-func sqlQuote(input string) string {
-	escaped := strings.ReplaceAll(input, "'", "''")
-	return "'" + escaped + "'"
+func (store *HistoryStore) GetSchema(ctx context.Context, id int64) (SchemaSnapshot, error) {
+	return store.querySchema(ctx, "WHERE id = ?", id)
 }
 
-func truncate(text string, max int) string {
-	if max <= 0 {
-		return ""
+func (store *HistoryStore) GetSchemaByName(ctx context.Context, engine DBEngine, name string) (SchemaSnapshot, error) {
+	resolved, err := ResolveEngine(string(engine))
+	if err != nil {
+		return SchemaSnapshot{}, err
 	}
-	if len(text) <= max {
-		return text
+	return store.querySchema(ctx, "WHERE engine = ? AND name = ?", string(resolved), strings.TrimSpace(name))
+}
+
+func (store *HistoryStore) CreateSchema(ctx context.Context, snapshot SchemaSnapshot) (SchemaSnapshot, error) {
+	engine, err := ResolveEngine(string(snapshot.Engine))
+	if err != nil {
+		return SchemaSnapshot{}, err
+	}
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+	result, err := store.db.ExecContext(ctx, `INSERT INTO schema_snapshots (
+  name, engine, description, ddl, created_at, updated_at
+) VALUES (?, ?, ?, ?, ?, ?);`,
+		snapshot.Name, string(engine), snapshot.Description, snapshot.DDL, now, now,
+	)
+	if err != nil {
+		return SchemaSnapshot{}, mapUniqueConstraintError(err, ErrSchemaExists)
 	}
-	return text[:max] + "..."
+	id, err := result.LastInsertId()
+	if err != nil {
+		return SchemaSnapshot{}, err
+	}
+	return store.GetSchema(ctx, id)
+}
+
+func (store *HistoryStore) UpdateSchema(ctx context.Context, id int64, snapshot SchemaSnapshot) (SchemaSnapshot, error) {
+	result, err := store.db.ExecContext(ctx, `UPDATE schema_snapshots SET
+  name = ?,
+  description = ?,
+  ddl = ?,
+  updated_at = ?
+WHERE id = ?;`,
+		snapshot.Name, snapshot.Description, snapshot.DDL, time.Now().UTC().Format(time.RFC3339Nano), id,
+	)
+	if err != nil {
+		return SchemaSnapshot{}, mapUniqueConstraintError(err, ErrSchemaExists)
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return SchemaSnapshot{}, err
+	}
+	if updated == 0 {
+		return SchemaSnapshot{}, ErrSchemaNotFound
+	}
+	return store.GetSchema(ctx, id)
+}
+
+func (store *HistoryStore) DeleteSchema(ctx context.Context, id int64) error {
+	result, err := store.db.ExecContext(ctx, "DELETE FROM schema_snapshots WHERE id = ?;", id)
+	if err != nil {
+		return err
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrSchemaNotFound
+	}
+	return nil
 }
@@ -1,6 +1,9 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -8,6 +11,7 @@ import (
 )
 
 func TestHistoryStoreSaveAndFetch(t *testing.T) {
+	ctx := context.Background()
 	dbPath := filepath.Join(t.TempDir(), "history.db")
 	store, err := NewHistoryStore(dbPath)
 	if err != nil {
@@ -27,7 +31,7 @@ func TestHistoryStoreSaveAndFetch(t *testing.T) {
 		}),
 	}
 
-	historyID, err := store.Save(input)
+	historyID, err := store.Save(ctx, input)
 	if err != nil {
 		t.Fatalf("Save err: %v", err)
 	}
@@ -35,7 +39,7 @@ func TestHistoryStoreSaveAndFetch(t *testing.T) {
 		t.Fatalf("invalid history id: %d", historyID)
 	}
 
-	items, total, err := store.List(20, 0)
+	items, total, err := store.List(ctx, HistoryQuery{Limit: 20})
 	if err != nil {
 		t.Fatalf("List err: %v", err)
 	}
@@ -46,7 +50,7 @@ func TestHistoryStoreSaveAndFetch(t *testing.T) {
 		t.Fatalf("engine mismatch in list: %+v", items[0])
 	}
 
-	detail, err := store.GetByID(historyID)
+	detail, err := store.GetByID(ctx, historyID)
 	if err != nil {
 		t.Fatalf("GetByID err: %v", err)
 	}
@@ -65,6 +69,7 @@ func TestHistoryStoreSaveAndFetch(t *testing.T) {
 }
 
 func TestHistoryStoreSaveLargeSQL(t *testing.T) {
+	ctx := context.Background()
 	dbPath := filepath.Join(t.TempDir(), "history-large.db")
 	store, err := NewHistoryStore(dbPath)
 	if err != nil {
@@ -94,7 +99,7 @@ func TestHistoryStoreSaveLargeSQL(t *testing.T) {
 		},
 	}
 
-	historyID, err := store.Save(input)
+	historyID, err := store.Save(ctx, input)
 	if err != nil {
 		t.Fatalf("Save large sql err: %v", err)
 	}
@@ -102,7 +107,7 @@ func TestHistoryStoreSaveLargeSQL(t *testing.T) {
 		t.Fatalf("invalid history id: %d", historyID)
 	}
 
-	detail, err := store.GetByID(historyID)
+	detail, err := store.GetByID(ctx, historyID)
 	if err != nil {
 		t.Fatalf("GetByID err: %v", err)
 	}
@@ -115,6 +120,7 @@ func TestHistoryStoreSaveLargeSQL(t *testing.T) {
 }
 
 func TestHistoryStoreDeleteByIDs(t *testing.T) {
+	ctx := context.Background()
 	dbPath := filepath.Join(t.TempDir(), "history-delete.db")
 	store, err := NewHistoryStore(dbPath)
 	if err != nil {
@@ -123,7 +129,7 @@ func TestHistoryStoreDeleteByIDs(t *testing.T) {
 	defer store.Close()
 
 	saveOne := func(requestID string) int64 {
-		historyID, saveErr := store.Save(SaveHistoryInput{
+		historyID, saveErr := store.Save(ctx, SaveHistoryInput{
 			RequestID: requestID,
 			Engine:    EngineMySQL,
 			Source:    "paste",
@@ -145,7 +151,7 @@ func TestHistoryStoreDeleteByIDs(t *testing.T) {
 	id1 := saveOne("req-delete-1")
 	id2 := saveOne("req-delete-2")
 
-	deleted, err := store.DeleteByIDs([]int64{id1, id2, id2, -1})
+	deleted, err := store.DeleteByIDs(ctx, []int64{id1, id2, id2, -1})
 	if err != nil {
 		t.Fatalf("DeleteByIDs err: %v", err)
 	}
@@ -153,7 +159,7 @@ func TestHistoryStoreDeleteByIDs(t *testing.T) {
 		t.Fatalf("deleted mismatch, got=%d want=2", deleted)
 	}
 
-	items, total, err := store.List(20, 0)
+	items, total, err := store.List(ctx, HistoryQuery{Limit: 20})
 	if err != nil {
 		t.Fatalf("List err: %v", err)
 	}
@@ -161,3 +167,334 @@ func TestHistoryStoreDeleteByIDs(t *testing.T) {
 		t.Fatalf("expected empty history after delete, total=%d len=%d", total, len(items))
 	}
 }
+
+func TestHistoryStoreSearchAndFacets(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewHistoryStore(filepath.Join(t.TempDir(), "history-search.db"))
+	if err != nil {
+		t.Fatalf("NewHistoryStore err: %v", err)
+	}
+	defer store.Close()
+
+	saveOne := func(engine DBEngine, source, fileName, sqlText string) int64 {
+		historyID, saveErr := store.Save(ctx, SaveHistoryInput{
+			RequestID:   "req-" + fileName,
+			Engine:      engine,
+			Source:      source,
+			FileName:    fileName,
+			SQLText:     sqlText,
+			CheckResult: AnalyzeByEngine(engine, sqlText, AnalyzeOptions{}),
+		})
+		if saveErr != nil {
+			t.Fatalf("save err: %v", saveErr)
+		}
+		return historyID
+	}
+
+	dropID := saveOne(EngineMySQL, "upload", "cleanup_orders.sql", "DROP TABLE orders;")
+	saveOne(EngineMySQL, "paste", "", "SELECT * FROM orders_archive;")
+	pgID := saveOne(EnginePostgreSQL, "paste", "", "DROP TABLE users;")
+
+	search := func(query HistoryQuery) []int64 {
+		t.Helper()
+		items, total, err := store.List(ctx, query)
+		if err != nil {
+			t.Fatalf("List(%+v) err: %v", query, err)
+		}
+		ids := make([]int64, 0, len(items))
+		for _, item := range items {
+			ids = append(ids, item.ID)
+		}
+		if total != len(ids) {
+			t.Fatalf("List(%+v) total=%d, items=%v", query, total, ids)
+		}
+		return ids
+	}
+
+	if ids := search(HistoryQuery{Text: "drop orders"}); len(ids) != 1 || ids[0] != dropID {
+		t.Fatalf("full-text query should match the dropped orders table only, got %v", ids)
+	}
+	if ids := search(HistoryQuery{Text: "cleanup"}); len(ids) != 1 || ids[0] != dropID {
+		t.Fatalf("full-text query should match file names, got %v", ids)
+	}
+	if ids := search(HistoryQuery{Text: "orders"}); len(ids) != 2 {
+		t.Fatalf("prefix query should match orders and orders_archive, got %v", ids)
+	}
+	if ids := search(HistoryQuery{Text: "drop", Sort: "oldest"}); len(ids) != 2 || ids[0] != dropID || ids[1] != pgID {
+		t.Fatalf("oldest sort should return both drops in insertion order, got %v", ids)
+	}
+	if ids := search(HistoryQuery{Rule: "pg_dangerous_drop"}); len(ids) != 1 || ids[0] != pgID {
+		t.Fatalf("rule filter should match issues by rule code, got %v", ids)
+	}
+	if ids := search(HistoryQuery{Engine: EnginePostgreSQL, Source: "paste"}); len(ids) != 1 || ids[0] != pgID {
+		t.Fatalf("engine and source filters should narrow to postgresql, got %v", ids)
+	}
+	if ids := search(HistoryQuery{MinErrors: 1, Engine: EngineMySQL}); len(ids) != 1 || ids[0] != dropID {
+		t.Fatalf("min error filter should keep the mysql drop, got %v", ids)
+	}
+	if ids := search(HistoryQuery{From: time.Now().Add(time.Hour)}); len(ids) != 0 {
+		t.Fatalf("future date range should be empty, got %v", ids)
+	}
+	if ids := search(HistoryQuery{From: time.Now().Add(-time.Hour), To: time.Now().Add(time.Hour)}); len(ids) != 3 {
+		t.Fatalf("current date range should include all rows, got %v", ids)
+	}
+
+	facets, err := store.Facets(ctx, HistoryQuery{Engine: EngineMySQL, Text: "drop"})
+	if err != nil {
+		t.Fatalf("Facets err: %v", err)
+	}
+	if len(facets.Engines) != 2 || facets.Engines[0] != (HistoryFacet{Value: "mysql", Count: 1}) || facets.Engines[1] != (HistoryFacet{Value: "postgresql", Count: 1}) {
+		t.Fatalf("engine facets should ignore the engine filter, got %+v", facets.Engines)
+	}
+	if len(facets.Rules) != 1 || facets.Rules[0] != (HistoryFacet{Value: "dangerous_drop", Count: 1}) {
+		t.Fatalf("rule facets should follow the engine filter, got %+v", facets.Rules)
+	}
+
+	facets, err = store.Facets(ctx, HistoryQuery{Engine: EngineMySQL, Rule: "dangerous_drop"})
+	if err != nil {
+		t.Fatalf("Facets err: %v", err)
+	}
+	ruleCounts := make(map[string]int)
+	for _, facet := range facets.Rules {
+		ruleCounts[facet.Value] = facet.Count
+	}
+	if ruleCounts["dangerous_drop"] != 1 || ruleCounts["select_without_limit"] != 1 || ruleCounts["pg_dangerous_drop"] != 0 {
+		t.Fatalf("rule facets should ignore the rule filter, got %+v", facets.Rules)
+	}
+
+	if _, err := store.DeleteByIDs(ctx, []int64{dropID}); err != nil {
+		t.Fatalf("DeleteByIDs err: %v", err)
+	}
+	if ids := search(HistoryQuery{Text: "cleanup"}); len(ids) != 0 {
+		t.Fatalf("deleted rows should leave the search index, got %v", ids)
+	}
+	if ids := search(HistoryQuery{Rule: "dangerous_drop"}); len(ids) != 0 {
+		t.Fatalf("deleted rows should leave the rule index, got %v", ids)
+	}
+}
+
+func TestHistoryStoreRuleProfilesCRUD(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewHistoryStore(filepath.Join(t.TempDir(), "profiles.db"))
+	if err != nil {
+		t.Fatalf("NewHistoryStore err: %v", err)
+	}
+	defer store.Close()
+
+	batch, err := store.CreateProfile(ctx, RuleProfile{Name: "batch", Engine: EngineMySQL, DisabledRules: []string{"select_without_limit"}, IsDefault: true})
+	if err != nil {
+		t.Fatalf("CreateProfile err: %v", err)
+	}
+	if batch.ID <= 0 || !batch.IsDefault || len(batch.DisabledRules) != 1 {
+		t.Fatalf("unexpected created profile: %+v", batch)
+	}
+	if _, err := store.CreateProfile(ctx, RuleProfile{Name: "batch", Engine: EngineMySQL}); !errors.Is(err, ErrProfileExists) {
+		t.Fatalf("expected ErrProfileExists, got %v", err)
+	}
+	if _, err := store.CreateProfile(ctx, RuleProfile{Name: "batch", Engine: EnginePostgreSQL}); err != nil {
+		t.Fatalf("same name on another engine should be allowed: %v", err)
+	}
+	if _, err := store.CreateProfile(ctx, RuleProfile{Name: "typo", Engine: "postgress"}); err == nil || !strings.Contains(err.Error(), "unknown engine") {
+		t.Fatalf("unknown engine should be rejected, got %v", err)
+	}
+	if _, err := store.ListProfiles(ctx, "postgress"); err == nil {
+		t.Fatalf("listing an unknown engine should be rejected")
+	}
+
+	oltp, err := store.CreateProfile(ctx, RuleProfile{
+		Name: "oltp", Engine: EngineMySQL, Description: "it's strict",
+		RuleOverrides: RuleOverrides{SeverityOverrides: map[string]IssueLevel{"select_star": LevelError}},
+	})
+	if err != nil {
+		t.Fatalf("CreateProfile err: %v", err)
+	}
+	if oltp.SeverityOverrides["select_star"] != LevelError {
+		t.Fatalf("severity overrides should round-trip, got %+v", oltp.RuleOverrides)
+	}
+	if _, err := store.SetDefaultProfile(ctx, oltp.ID); err != nil {
+		t.Fatalf("SetDefaultProfile err: %v", err)
+	}
+	defaultProfile, err := store.DefaultProfile(ctx, EngineMySQL)
+	if err != nil || defaultProfile.Name != "oltp" {
+		t.Fatalf("expected oltp as mysql default, got %+v err=%v", defaultProfile, err)
+	}
+	if refreshed, _ := store.GetProfile(ctx, batch.ID); refreshed.IsDefault {
+		t.Fatalf("previous default should be cleared")
+	}
+
+	updated, err := store.UpdateProfile(ctx, batch.ID, RuleProfile{Name: "batch-jobs", DisabledRules: []string{"select_star"}})
+	if err != nil {
+		t.Fatalf("UpdateProfile err: %v", err)
+	}
+	if updated.Name != "batch-jobs" || updated.Engine != EngineMySQL || updated.DisabledRules[0] != "select_star" {
+		t.Fatalf("unexpected updated profile: %+v", updated)
+	}
+	if _, err := store.UpdateProfile(ctx, batch.ID, RuleProfile{Name: "oltp"}); !errors.Is(err, ErrProfileExists) {
+		t.Fatalf("renaming onto an existing profile should fail, got %v", err)
+	}
+
+	profiles, err := store.ListProfiles(ctx, EngineMySQL)
+	if err != nil || len(profiles) != 2 {
+		t.Fatalf("expected 2 mysql profiles, got %d err=%v", len(profiles), err)
+	}
+
+	if err := store.DeleteProfile(ctx, batch.ID); err != nil {
+		t.Fatalf("DeleteProfile err: %v", err)
+	}
+	if _, err := store.GetProfile(ctx, batch.ID); !errors.Is(err, ErrProfileNotFound) {
+		t.Fatalf("expected ErrProfileNotFound after delete, got %v", err)
+	}
+}
+
+func TestHistoryStoreSavesProfileSnapshot(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewHistoryStore(filepath.Join(t.TempDir(), "history-profile.db"))
+	if err != nil {
+		t.Fatalf("NewHistoryStore err: %v", err)
+	}
+	defer store.Close()
+
+	profile := &RuleProfile{ID: 7, Name: "batch", Engine: EngineMySQL, DisabledRules: []string{"select_without_limit"}}
+	historyID, err := store.Save(ctx, SaveHistoryInput{
+		RequestID:     "req-profile",
+		Engine:        EngineMySQL,
+		Source:        "paste",
+		SQLText:       "SELECT * FROM users;",
+		DisabledRules: profile.DisabledRules,
+		ProfileName:   profile.Name,
+		Profile:       profile,
+		CheckResult:   AnalyzeSQL("SELECT * FROM users;"),
+	})
+	if err != nil {
+		t.Fatalf("Save err: %v", err)
+	}
+
+	detail, err := store.GetByID(ctx, historyID)
+	if err != nil {
+		t.Fatalf("GetByID err: %v", err)
+	}
+	if detail.ProfileName != "batch" || detail.Profile == nil || detail.Profile.DisabledRules[0] != "select_without_limit" {
+		t.Fatalf("profile snapshot mismatch: %+v", detail)
+	}
+
+	items, _, err := store.List(ctx, HistoryQuery{Limit: 20})
+	if err != nil || len(items) != 1 || items[0].ProfileName != "batch" {
+		t.Fatalf("expected profile name in list, got %+v err=%v", items, err)
+	}
+}
+
+func TestHistoryStoreMigratesLegacySchema(t *testing.T) {
+	ctx := context.Background()
+	dbPath := filepath.Join(t.TempDir(), "legacy.db")
+	legacy, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("open legacy db err: %v", err)
+	}
+	_, err = legacy.Exec(`
+CREATE TABLE review_history (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  request_id TEXT NOT NULL,
+  source TEXT NOT NULL,
+  file_name TEXT NOT NULL DEFAULT '',
+  sql_text TEXT NOT NULL,
+  profile TEXT NOT NULL DEFAULT '',
+  score INTEGER NOT NULL DEFAULT 0,
+  disabled_rules_json TEXT NOT NULL,
+  result_json TEXT NOT NULL,
+  statement_count INTEGER NOT NULL,
+  error_count INTEGER NOT NULL,
+  warning_count INTEGER NOT NULL,
+  info_count INTEGER NOT NULL,
+  created_at TEXT NOT NULL
+);
+INSERT INTO review_history (request_id, source, sql_text, profile, score, disabled_rules_json, result_json, statement_count, error_count, warning_count, info_count, created_at)
+VALUES ('req-legacy', 'paste', 'SELECT 1;', 'strict', 90, '[]', '{"summary":{"statementCount":1}}', 1, 0, 0, 0, '2024-01-01T00:00:00Z');
+`)
+	if closeErr := legacy.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		t.Fatalf("seed legacy db err: %v", err)
+	}
+
+	store, err := NewHistoryStore(dbPath)
+	if err != nil {
+		t.Fatalf("NewHistoryStore err: %v", err)
+	}
+	defer store.Close()
+
+	for _, column := range []string{"profile", "score"} {
+		if has, err := store.hasColumn(ctx, "review_history", column); err != nil || has {
+			t.Fatalf("legacy column %s should be dropped, has=%v err=%v", column, has, err)
+		}
+	}
+	items, total, err := store.List(ctx, HistoryQuery{Limit: 20})
+	if err != nil || total != 1 || items[0].RequestID != "req-legacy" || items[0].Engine != EngineMySQL {
+		t.Fatalf("legacy row should survive migration, items=%+v total=%d err=%v", items, total, err)
+	}
+	if _, total, err := store.List(ctx, HistoryQuery{Text: "select"}); err != nil || total != 1 {
+		t.Fatalf("legacy row should be backfilled into the search index, total=%d err=%v", total, err)
+	}
+	if _, err := store.Save(ctx, SaveHistoryInput{RequestID: "req-new", Engine: EngineMySQL, Source: "paste", DisabledRules: []string{}}); err != nil {
+		t.Fatalf("Save after migration err: %v", err)
+	}
+	if _, err := store.Save(ctx, SaveHistoryInput{RequestID: "req-typo", Engine: "postgress", Source: "paste", DisabledRules: []string{}}); err == nil {
+		t.Fatalf("Save should reject an unknown engine")
+	}
+}
+
+func TestHistoryStoreHonorsContextCancellation(t *testing.T) {
+	store, err := NewHistoryStore(filepath.Join(t.TempDir(), "cancel.db"))
+	if err != nil {
+		t.Fatalf("NewHistoryStore err: %v", err)
+	}
+	defer store.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := store.Save(ctx, SaveHistoryInput{RequestID: "req-cancel", Engine: EngineMySQL, Source: "paste"}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestHistoryStoreSchemaSnapshotsCRUD(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewHistoryStore(filepath.Join(t.TempDir(), "schemas.db"))
+	if err != nil {
+		t.Fatalf("NewHistoryStore err: %v", err)
+	}
+	defer store.Close()
+
+	created, err := store.CreateSchema(ctx, SchemaSnapshot{Name: "shop", Engine: EngineMySQL, DDL: "CREATE TABLE t (id INT);"})
+	if err != nil || created.ID <= 0 || created.CreatedAt == "" {
+		t.Fatalf("unexpected created schema: %+v err=%v", created, err)
+	}
+	if _, err := store.CreateSchema(ctx, SchemaSnapshot{Name: "shop", Engine: EngineMySQL, DDL: "CREATE TABLE t (id INT);"}); !errors.Is(err, ErrSchemaExists) {
+		t.Fatalf("expected ErrSchemaExists, got %v", err)
+	}
+	if _, err := store.CreateSchema(ctx, SchemaSnapshot{Name: "shop", Engine: "postgress", DDL: "CREATE TABLE t (id INT);"}); err == nil || !strings.Contains(err.Error(), "unknown engine") {
+		t.Fatalf("unknown engine should be rejected, got %v", err)
+	}
+
+	updated, err := store.UpdateSchema(ctx, created.ID, SchemaSnapshot{Name: "shop", Engine: EngineMySQL, Description: "v2", DDL: "CREATE TABLE t2 (id INT);"})
+	if err != nil || updated.Description != "v2" || !strings.Contains(updated.DDL, "t2") {
+		t.Fatalf("unexpected updated schema: %+v err=%v", updated, err)
+	}
+	byName, err := store.GetSchemaByName(ctx, EngineMySQL, "shop")
+	if err != nil || byName.ID != created.ID {
+		t.Fatalf("expected lookup by name, got %+v err=%v", byName, err)
+	}
+	if _, err := store.GetSchemaByName(ctx, EnginePostgreSQL, "shop"); !errors.Is(err, ErrSchemaNotFound) {
+		t.Fatalf("expected ErrSchemaNotFound for another engine, got %v", err)
+	}
+	if list, err := store.ListSchemas(ctx, EngineMySQL); err != nil || len(list) != 1 {
+		t.Fatalf("expected one mysql schema, got %d err=%v", len(list), err)
+	}
+
+	if err := store.DeleteSchema(ctx, created.ID); err != nil {
+		t.Fatalf("DeleteSchema err: %v", err)
+	}
+	if err := store.DeleteSchema(ctx, created.ID); !errors.Is(err, ErrSchemaNotFound) {
+		t.Fatalf("expected ErrSchemaNotFound after delete, got %v", err)
+	}
+}
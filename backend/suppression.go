@@ -0,0 +1,324 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+const (
+	suppressionPrefix      = "sql-review:"
+	suppressionDisable     = "disable"
+	suppressionEnable      = "enable"
+	suppressionDisableLine = "disable-line"
+	suppressionDisableNext = "disable-next-line"
+)
+
+type SuppressedIssue struct {
+	Issue
+	Reason        string `json:"reason,omitempty"`
+	DirectiveLine int    `json:"directiveLine"`
+}
+
+type suppressionDirective struct {
+	Action string
+	Rules  []string
+	Reason string
+	Span   sourceSpan
+	Err    string
+}
+
+type suppressionRange struct {
+	Rule          string
+	Reason        string
+	Span          sourceSpan
+	DirectiveLine int
+	LineOnly      bool
+}
+
+func parseSuppressionDirectives(content string, comments []sourceSpan) []suppressionDirective {
+	directives := make([]suppressionDirective, 0)
+	for _, span := range comments {
+		body := strings.TrimSpace(stripCommentMarkers(content[span.Start:span.End]))
+		if !strings.HasPrefix(body, suppressionPrefix) {
+			continue
+		}
+		directives = append(directives, parseSuppressionDirective(strings.TrimPrefix(body, suppressionPrefix), span))
+	}
+	return directives
+}
+
+func stripCommentMarkers(comment string) string {
+	switch {
+	case strings.HasPrefix(comment, "/*"):
+		return strings.TrimSuffix(strings.TrimPrefix(comment, "/*"), "*/")
+	case strings.HasPrefix(comment, "--"), strings.HasPrefix(comment, "//"):
+		return comment[2:]
+	case strings.HasPrefix(comment, "#"):
+		return comment[1:]
+	}
+	return comment
+}
+
+func parseSuppressionDirective(body string, span sourceSpan) suppressionDirective {
+	action, rest, _ := strings.Cut(strings.TrimSpace(body), " ")
+	directive := suppressionDirective{Action: strings.ToLower(strings.TrimSpace(action)), Span: span}
+
+	if index := strings.Index(rest, "reason="); index >= 0 {
+		directive.Reason = parseSuppressionReason(rest[index+len("reason="):])
+		rest = rest[:index]
+	}
+	for _, code := range strings.FieldsFunc(rest, func(r rune) bool { return r == ',' || unicode.IsSpace(r) }) {
+		directive.Rules = append(directive.Rules, code)
+	}
+
+	switch directive.Action {
+	case suppressionDisable, suppressionDisableLine, suppressionDisableNext:
+		if len(directive.Rules) == 0 {
+			directive.Err = fmt.Sprintf("抑制注释 sql-review:%s 未指定规则", directive.Action)
+		}
+	case suppressionEnable:
+	default:
+		directive.Err = fmt.Sprintf("无法识别的抑制注释指令：sql-review:%s", directive.Action)
+	}
+	return directive
+}
+
+func parseSuppressionReason(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return ""
+	}
+	if quote := raw[0]; quote == '"' || quote == '\'' {
+		if end := strings.IndexByte(raw[1:], quote); end >= 0 {
+			return raw[1 : end+1]
+		}
+		return strings.TrimSpace(raw[1:])
+	}
+	if fields := strings.Fields(raw); len(fields) > 0 {
+		return fields[0]
+	}
+	return ""
+}
+
+func buildSuppressionRanges(ctx *RuleContext, rules []Rule) ([]suppressionRange, []Issue) {
+	known := make(map[string]RuleDefinition, len(rules))
+	for _, rule := range rules {
+		definition := rule.Definition()
+		known[definition.Code] = definition
+	}
+
+	ranges := make([]suppressionRange, 0)
+	invalid := make([]Issue, 0)
+	open := make(map[string]suppressionRange)
+	openOrder := make([]string, 0)
+
+	reject := func(directive suppressionDirective, message string) {
+		invalid = append(invalid, ctx.Source.locate(Issue{
+			Message:    message,
+			Suggestion: "请检查抑制注释中的指令与规则编码，基础规则不允许通过注释抑制",
+			Statement:  strings.TrimSpace(ctx.Content[directive.Span.Start:directive.Span.End]),
+		}, directive.Span))
+	}
+
+	for _, directive := range ctx.Directives {
+		if directive.Err != "" {
+			reject(directive, directive.Err)
+			continue
+		}
+		directiveLine, _ := ctx.Source.position(directive.Span.Start)
+
+		if directive.Action == suppressionEnable {
+			targets := directive.Rules
+			if len(targets) == 0 {
+				targets = openOrder
+			}
+			for _, code := range targets {
+				if item, found := open[code]; found {
+					item.Span.End = directive.Span.Start
+					ranges = append(ranges, item)
+					delete(open, code)
+				}
+			}
+			openOrder = pruneOpenSuppressions(openOrder, open)
+			continue
+		}
+
+		for _, code := range directive.Rules {
+			definition, found := known[code]
+			if !found {
+				reject(directive, fmt.Sprintf("抑制注释引用了未知规则：%s", code))
+				continue
+			}
+			if definition.AlwaysEnabled {
+				reject(directive, fmt.Sprintf("基础规则 %s 不允许通过注释抑制", code))
+				continue
+			}
+
+			item := suppressionRange{Rule: code, Reason: directive.Reason, DirectiveLine: directiveLine}
+			switch directive.Action {
+			case suppressionDisableLine:
+				item.Span, item.LineOnly = ctx.Source.lineSpan(directiveLine), true
+				ranges = append(ranges, item)
+			case suppressionDisableNext:
+				item.Span, item.LineOnly = ctx.Source.lineSpan(ctx.Source.nextContentLine(directiveLine)), true
+				ranges = append(ranges, item)
+			case suppressionDisable:
+				if _, exists := open[code]; exists {
+					continue
+				}
+				item.Span = sourceSpan{Start: directive.Span.End}
+				open[code] = item
+				openOrder = append(openOrder, code)
+			}
+		}
+	}
+
+	for _, code := range openOrder {
+		item := open[code]
+		item.Span.End = len(ctx.Content)
+		ranges = append(ranges, item)
+	}
+	return ranges, invalid
+}
+
+func pruneOpenSuppressions(order []string, open map[string]suppressionRange) []string {
+	kept := order[:0]
+	for _, code := range order {
+		if _, found := open[code]; found {
+			kept = append(kept, code)
+		}
+	}
+	return kept
+}
+
+func applySuppressions(ctx *RuleContext, ranges []suppressionRange, issues []Issue) ([]Issue, []SuppressedIssue) {
+	if len(ranges) == 0 {
+		return issues, nil
+	}
+
+	kept := make([]Issue, 0, len(issues))
+	suppressed := make([]SuppressedIssue, 0)
+	for _, issue := range issues {
+		item, found := ctx.matchSuppression(ranges, issue)
+		if !found {
+			kept = append(kept, issue)
+			continue
+		}
+		suppressed = append(suppressed, SuppressedIssue{Issue: issue, Reason: item.Reason, DirectiveLine: item.DirectiveLine})
+	}
+	return kept, suppressed
+}
+
+func (ctx *RuleContext) matchSuppression(ranges []suppressionRange, issue Issue) (suppressionRange, bool) {
+	issueOffset := -1
+	if issue.StartLine > 0 {
+		issueOffset = ctx.Source.offset(issue.StartLine, issue.StartColumn)
+	}
+	statementOffset := -1
+	if issue.StatementIndex > 0 && issue.StatementIndex <= len(ctx.Statements) {
+		statementOffset = ctx.statementOffset(ctx.Statements[issue.StatementIndex-1].Span)
+	}
+
+	for _, item := range ranges {
+		if item.Rule != issue.Rule {
+			continue
+		}
+		switch {
+		case issueOffset >= 0 && item.Span.contains(issueOffset):
+			return item, true
+		case statementOffset >= 0 && (item.LineOnly || issueOffset < 0) && item.Span.contains(statementOffset):
+			return item, true
+		case issueOffset < 0 && statementOffset < 0 && ctx.coversAllStatements(item.Span):
+			return item, true
+		}
+	}
+	return suppressionRange{}, false
+}
+
+func (ctx *RuleContext) statementOffset(span sourceSpan) int {
+	offset := span.Start
+	for offset < span.End {
+		if unicode.IsSpace(rune(ctx.Content[offset])) {
+			offset++
+			continue
+		}
+		skipped := false
+		for _, directive := range ctx.Directives {
+			if directive.Span.Start == offset {
+				offset, skipped = directive.Span.End, true
+				break
+			}
+		}
+		if !skipped {
+			break
+		}
+	}
+	return offset
+}
+
+func (ctx *RuleContext) coversAllStatements(span sourceSpan) bool {
+	if len(ctx.Statements) == 0 {
+		return false
+	}
+	return span.Start <= ctx.Statements[0].Span.Start && span.End >= ctx.Statements[len(ctx.Statements)-1].Span.End
+}
+
+func invalidSuppressionRule() Rule {
+	return newScriptRule(
+		RuleDefinition{Code: "invalid_suppression", Level: LevelWarning, Category: "抑制注释", Description: "抑制注释无效或试图抑制基础规则"},
+		func(ctx *RuleContext) []Issue {
+			return ctx.invalidSuppressions
+		},
+	)
+}
+
+func sqlCommentSpans(content string, dialect sqlDialect) []sourceSpan {
+	spans := make([]sourceSpan, 0)
+	for _, token := range tokenizeSQL(content, dialect) {
+		if token.Kind == sqlTokenComment {
+			spans = append(spans, token.span())
+		}
+	}
+	return spans
+}
+
+func mongoCommentSpans(content string) []sourceSpan {
+	spans := make([]sourceSpan, 0)
+	var quote byte
+	for i := 0; i < len(content); i++ {
+		ch := content[i]
+		if quote != 0 {
+			if ch == '\\' {
+				i++
+			} else if ch == quote {
+				quote = 0
+			}
+			continue
+		}
+
+		switch {
+		case ch == '"' || ch == '\'' || ch == '`':
+			quote = ch
+		case ch == '/' && i+1 < len(content) && content[i+1] == '/':
+			end := strings.IndexByte(content[i:], '\n')
+			if end < 0 {
+				end = len(content)
+			} else {
+				end += i
+			}
+			spans = append(spans, sourceSpan{Start: i, End: end})
+			i = end
+		case ch == '/' && i+1 < len(content) && content[i+1] == '*':
+			end := strings.Index(content[i+2:], "*/")
+			if end < 0 {
+				end = len(content)
+			} else {
+				end += i + 4
+			}
+			spans = append(spans, sourceSpan{Start: i, End: end})
+			i = end - 1
+		}
+	}
+	return spans
+}
@@ -0,0 +1,103 @@
+package main
+
+import "testing"
+
+func TestDisableNextLineSuppressesWithReason(t *testing.T) {
+	script := `-- sql-review:disable-next-line dangerous_drop reason="approved by DBA"
+DROP TABLE legacy_logs;
+DROP TABLE users;`
+
+	result := AnalyzeByEngine(EngineMySQL, script, AnalyzeOptions{})
+	dropCount := 0
+	for _, issue := range result.Issues {
+		if issue.Rule == "dangerous_drop" {
+			dropCount++
+			if issue.StatementIndex != 2 {
+				t.Fatalf("only the second DROP should remain, got statement %d", issue.StatementIndex)
+			}
+		}
+	}
+	if dropCount != 1 {
+		t.Fatalf("expected one remaining dangerous_drop, got %d: %+v", dropCount, result.Issues)
+	}
+	if len(result.Suppressed) != 1 || result.Summary.SuppressedCount != 1 {
+		t.Fatalf("expected one suppressed issue, got %+v", result.Suppressed)
+	}
+	suppressed := result.Suppressed[0]
+	if suppressed.Rule != "dangerous_drop" || suppressed.Reason != "approved by DBA" || suppressed.DirectiveLine != 1 || suppressed.StartLine != 2 {
+		t.Fatalf("unexpected suppressed issue: %+v", suppressed)
+	}
+}
+
+func TestBlockDisableAndEnable(t *testing.T) {
+	script := `/* sql-review:disable select_star,select_without_limit */
+SELECT *
+FROM users;
+/* sql-review:enable */
+SELECT * FROM orders;`
+
+	result := AnalyzeByEngine(EngineMySQL, script, AnalyzeOptions{})
+	for _, issue := range result.Issues {
+		if (issue.Rule == "select_star" || issue.Rule == "select_without_limit") && issue.StatementIndex == 1 {
+			t.Fatalf("statement inside the block should be suppressed: %+v", issue)
+		}
+	}
+	if !hasRule(result.Issues, "select_star") {
+		t.Fatalf("statement after enable should still be reported: %+v", result.Issues)
+	}
+	if len(result.Suppressed) != 2 {
+		t.Fatalf("expected 2 suppressed issues, got %+v", result.Suppressed)
+	}
+}
+
+func TestSuppressionRefusesAlwaysEnabledAndUnknownRules(t *testing.T) {
+	script := `-- sql-review:disable-next-line missing_statement_terminator, no_such_rule
+UPDATE users SET status = 1 WHERE id = 1
+UPDATE orders SET status = 1 WHERE id = 1;`
+
+	result := AnalyzeByEngine(EngineMySQL, script, AnalyzeOptions{})
+	if !hasRule(result.Issues, "missing_statement_terminator") {
+		t.Fatalf("always-enabled rule must not be suppressed: %+v", result.Issues)
+	}
+	invalid := 0
+	for _, issue := range result.Issues {
+		if issue.Rule == "invalid_suppression" {
+			invalid++
+			if issue.StartLine != 1 {
+				t.Fatalf("invalid directive should be located at the comment, got %+v", issue)
+			}
+		}
+	}
+	if invalid != 2 || len(result.Suppressed) != 0 {
+		t.Fatalf("expected 2 invalid_suppression issues and nothing suppressed, got %+v / %+v", result.Issues, result.Suppressed)
+	}
+}
+
+func TestMongoSuppressionComments(t *testing.T) {
+	script := `// sql-review:disable-next-line mongo_delete_many_without_filter reason="reset fixture"
+db.fixtures.deleteMany({});
+db.users.deleteMany({});
+const note = "// sql-review:disable mongo_where_operator";
+db.users.find({$where: "this.a > 1"}).limit(1);`
+
+	result := AnalyzeByEngine(EngineMongoDB, script, AnalyzeOptions{})
+	if len(result.Suppressed) != 1 || result.Suppressed[0].Reason != "reset fixture" {
+		t.Fatalf("expected the first deleteMany to be suppressed, got %+v", result.Suppressed)
+	}
+	if !hasRule(result.Issues, "mongo_delete_many_without_filter") || !hasRule(result.Issues, "mongo_where_operator") {
+		t.Fatalf("directives inside strings must be ignored, got %+v", result.Issues)
+	}
+}
+
+func TestParseSuppressionDirective(t *testing.T) {
+	directive := parseSuppressionDirective("disable select_star order_by_rand reason='batch job' trailing", sourceSpan{})
+	if directive.Action != suppressionDisable || len(directive.Rules) != 2 || directive.Reason != "batch job" || directive.Err != "" {
+		t.Fatalf("unexpected directive: %+v", directive)
+	}
+	if directive := parseSuppressionDirective("silence select_star", sourceSpan{}); directive.Err == "" {
+		t.Fatalf("unknown action should be rejected")
+	}
+	if directive := parseSuppressionDirective("disable-next-line", sourceSpan{}); directive.Err == "" {
+		t.Fatalf("disable without rules should be rejected")
+	}
+}
@@ -259,7 +259,7 @@ doctor() {
     echo "[OK] 当前非 root 用户"
   fi
 
-  for c in go npm node curl lsof sqlite3; do
+  for c in go npm node curl lsof cc; do
     if ! check_cmd "$c"; then
       errs=$((errs + 1))
     fi